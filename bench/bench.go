@@ -0,0 +1,326 @@
+// Package bench provides the reproducible benchmarks of the hot paths of zetasqlite
+// and the harness to detect the performance regression against the recorded baselines.
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"testing"
+
+	zetasqlite "github.com/goccy/go-zetasqlite"
+)
+
+const (
+	// RegressionEnv is the environment variable to run the regression test by RunRegression.
+	// The benchmarks take minutes, so the regression test is skipped unless it is set.
+	RegressionEnv = "ZETASQLITE_BENCH_REGRESSION"
+	// RecordEnv is the environment variable to re-record the baselines by RunRegression.
+	RecordEnv = "ZETASQLITE_BENCH_RECORD"
+
+	// DefaultThreshold is the ratio of the allowed slowdown from the baseline.
+	DefaultThreshold = 0.3
+)
+
+// Workload is the benchmarked operation.
+type Workload struct {
+	Name string
+	// Setup prepares the data of the workload. It is not measured.
+	Setup func(ctx context.Context, conn *sql.Conn) error
+	// Run runs the measured operation. i is the iteration number to make the names of the created tables unique.
+	Run func(ctx context.Context, conn *sql.Conn, i int) error
+}
+
+// Workloads returns the benchmarked workloads covering the analysis and the formatting of a query, aggregation,
+// window function, UNNEST of a large array and bulk insert.
+func Workloads() []*Workload {
+	return []*Workload{
+		analyzeAndFormatWorkload(),
+		filteredAggregationWorkload(),
+		windowFunctionWorkload(),
+		unnestLargeArrayWorkload(),
+		bulkInsertWorkload(),
+	}
+}
+
+// WorkloadByName returns the workload by name.
+func WorkloadByName(name string) (*Workload, error) {
+	for _, w := range Workloads() {
+		if w.Name == name {
+			return w, nil
+		}
+	}
+	return nil, fmt.Errorf("bench: unknown workload %s", name)
+}
+
+const mediumQuery = `
+WITH recent AS (
+  SELECT id, category, value, DATE_ADD(DATE '2024-01-01', INTERVAL MOD(id, 365) DAY) AS day
+  FROM bench_events
+  WHERE value > 10 AND category IN ('a', 'b', 'c')
+), ranked AS (
+  SELECT category, day, value, ROW_NUMBER() OVER (PARTITION BY category ORDER BY value DESC) AS rank
+  FROM recent
+)
+SELECT r.category, FORMAT_DATE('%Y-%m', r.day) AS month, COUNT(*) AS cnt, SUM(r.value) AS total, ANY_VALUE(c.label) AS label
+FROM ranked AS r
+LEFT JOIN bench_categories AS c ON c.category = r.category
+WHERE r.rank <= 100
+GROUP BY r.category, month
+HAVING cnt > 1
+ORDER BY total DESC`
+
+// analyzeAndFormatWorkload prepares the medium query without the analysis cache to measure the analysis and the formatting.
+func analyzeAndFormatWorkload() *Workload {
+	return &Workload{
+		Name: "analyze_and_format",
+		Setup: func(ctx context.Context, conn *sql.Conn) error {
+			if _, err := conn.ExecContext(ctx, `
+CREATE TABLE bench_events (id INT64, category STRING, value FLOAT64);
+CREATE TABLE bench_categories (category STRING, label STRING);
+`); err != nil {
+				return err
+			}
+			return conn.Raw(func(c interface{}) error {
+				c.(*zetasqlite.ZetaSQLiteConn).SetAnalysisCacheMode(false)
+				return nil
+			})
+		},
+		Run: func(ctx context.Context, conn *sql.Conn, _ int) error {
+			stmt, err := conn.PrepareContext(ctx, mediumQuery)
+			if err != nil {
+				return err
+			}
+			return stmt.Close()
+		},
+	}
+}
+
+func filteredAggregationWorkload() *Workload {
+	const rowNum = 1000000
+	return &Workload{
+		Name: "filtered_aggregation_1m",
+		Setup: func(ctx context.Context, conn *sql.Conn) error {
+			return createEvents(ctx, conn, "bench_aggregation", rowNum)
+		},
+		Run: func(ctx context.Context, conn *sql.Conn, _ int) error {
+			return readAll(ctx, conn, `
+SELECT category, COUNT(*), SUM(value) FROM bench_aggregation WHERE value > 250 GROUP BY category ORDER BY category`)
+		},
+	}
+}
+
+func windowFunctionWorkload() *Workload {
+	const rowNum = 100000
+	return &Workload{
+		Name: "window_function_100k",
+		Setup: func(ctx context.Context, conn *sql.Conn) error {
+			return createEvents(ctx, conn, "bench_window", rowNum)
+		},
+		Run: func(ctx context.Context, conn *sql.Conn, _ int) error {
+			return readAll(ctx, conn, `
+SELECT id, SUM(value) OVER (PARTITION BY category ORDER BY id ROWS BETWEEN 10 PRECEDING AND CURRENT ROW) FROM bench_window`)
+		},
+	}
+}
+
+func unnestLargeArrayWorkload() *Workload {
+	return &Workload{
+		Name:  "unnest_large_array",
+		Setup: func(ctx context.Context, conn *sql.Conn) error { return nil },
+		Run: func(ctx context.Context, conn *sql.Conn, _ int) error {
+			return readAll(ctx, conn, `SELECT COUNT(*), SUM(v) FROM UNNEST(GENERATE_ARRAY(1, 1000000)) AS v`)
+		},
+	}
+}
+
+func bulkInsertWorkload() *Workload {
+	const rowNum = 100000
+	return &Workload{
+		Name:  "bulk_insert_100k",
+		Setup: func(ctx context.Context, conn *sql.Conn) error { return nil },
+		Run: func(ctx context.Context, conn *sql.Conn, i int) error {
+			return createEvents(ctx, conn, fmt.Sprintf("bench_insert_%d", i), rowNum)
+		},
+	}
+}
+
+// createEvents creates the table of the synthetic events having the deterministic values.
+func createEvents(ctx context.Context, conn *sql.Conn, table string, rowNum int) error {
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE %s (id INT64, category STRING, value FLOAT64)`, table)); err != nil {
+		return fmt.Errorf("failed to create %s: %w", table, err)
+	}
+	categories := []string{"a", "b", "c", "d", "e"}
+	return conn.Raw(func(c interface{}) error {
+		return c.(*zetasqlite.ZetaSQLiteConn).InsertRows(ctx, table, func(i int) []interface{} {
+			return []interface{}{int64(i), categories[i%len(categories)], float64(i%1000) / 2}
+		}, rowNum)
+	})
+}
+
+func readAll(ctx context.Context, conn *sql.Conn, query string) error {
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, len(columns))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	for rows.Next() {
+		if err := rows.Scan(values...); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Run runs the workload as the benchmark with the allocation reporting. The database is created in memory for each benchmark.
+func Run(b *testing.B, w *Workload) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer conn.Close()
+	if err := w.Setup(ctx, conn); err != nil {
+		b.Fatalf("failed to set up %s: %v", w.Name, err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := w.Run(ctx, conn, i); err != nil {
+			b.Fatalf("failed to run %s: %v", w.Name, err)
+		}
+	}
+}
+
+// Baseline is the recorded result of the benchmark of the workload.
+type Baseline struct {
+	Name        string `json:"name"`
+	NsPerOp     int64  `json:"nsPerOp"`
+	AllocsPerOp int64  `json:"allocsPerOp"`
+	BytesPerOp  int64  `json:"bytesPerOp"`
+}
+
+func newBaseline(name string, result testing.BenchmarkResult) *Baseline {
+	return &Baseline{
+		Name:        name,
+		NsPerOp:     result.NsPerOp(),
+		AllocsPerOp: result.AllocsPerOp(),
+		BytesPerOp:  result.AllocedBytesPerOp(),
+	}
+}
+
+// LoadBaselines reads the baselines from the JSON file. If the file doesn't exist, no baseline is returned.
+func LoadBaselines(path string) (map[string]*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]*Baseline{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bench: failed to read %s: %w", path, err)
+	}
+	var baselines []*Baseline
+	if err := json.Unmarshal(data, &baselines); err != nil {
+		return nil, fmt.Errorf("bench: failed to decode %s: %w", path, err)
+	}
+	m := make(map[string]*Baseline, len(baselines))
+	for _, baseline := range baselines {
+		m[baseline.Name] = baseline
+	}
+	return m, nil
+}
+
+// SaveBaselines writes the baselines to the JSON file ordered by name.
+func SaveBaselines(path string, baselines map[string]*Baseline) error {
+	list := make([]*Baseline, 0, len(baselines))
+	for _, baseline := range baselines {
+		list = append(list, baseline)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("bench: failed to encode baselines: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("bench: failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Compare returns the regressions of the result from the baseline.
+// The time and the allocations exceeding the baseline by more than the threshold ratio are reported.
+func Compare(baseline, result *Baseline, threshold float64) []string {
+	var regressions []string
+	check := func(metric string, base, got int64) {
+		if base <= 0 {
+			return
+		}
+		if float64(got) > float64(base)*(1+threshold) {
+			regressions = append(regressions, fmt.Sprintf(
+				"%s: %s regressed from %d to %d (+%.1f%%)",
+				result.Name, metric, base, got, (float64(got)/float64(base)-1)*100,
+			))
+		}
+	}
+	check("ns/op", baseline.NsPerOp, result.NsPerOp)
+	check("allocs/op", baseline.AllocsPerOp, result.AllocsPerOp)
+	check("B/op", baseline.BytesPerOp, result.BytesPerOp)
+	return regressions
+}
+
+// RunRegression runs all workloads and fails the test if any of them regresses beyond the threshold against the baselines in path.
+// It is skipped unless RegressionEnv is set. If RecordEnv is set, the baselines are re-recorded instead.
+// The workloads without the baseline are only logged. The baselines depend on the machine, so record them on the machine running the test.
+func RunRegression(t *testing.T, path string, threshold float64) {
+	t.Helper()
+	isRecording := os.Getenv(RecordEnv) != ""
+	if os.Getenv(RegressionEnv) == "" && !isRecording {
+		t.Skipf("set %s to run the benchmark regression test", RegressionEnv)
+	}
+	baselines, err := LoadBaselines(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range Workloads() {
+		w := w
+		result := newBaseline(w.Name, testing.Benchmark(func(b *testing.B) { Run(b, w) }))
+		if result.NsPerOp == 0 {
+			t.Errorf("%s: failed to run the benchmark", w.Name)
+			continue
+		}
+		t.Logf("%s: %d ns/op, %d allocs/op, %d B/op", w.Name, result.NsPerOp, result.AllocsPerOp, result.BytesPerOp)
+		if isRecording {
+			baselines[w.Name] = result
+			continue
+		}
+		baseline, exists := baselines[w.Name]
+		if !exists {
+			t.Logf("%s: no baseline is recorded. set %s to record it", w.Name, RecordEnv)
+			continue
+		}
+		for _, regression := range Compare(baseline, result, threshold) {
+			t.Error(regression)
+		}
+	}
+	if isRecording {
+		if err := SaveBaselines(path, baselines); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package bench_test
+
+import (
+	"testing"
+
+	"github.com/goccy/go-zetasqlite/bench"
+)
+
+func benchmarkWorkload(b *testing.B, name string) {
+	w, err := bench.WorkloadByName(name)
+	if err != nil {
+		b.Fatal(err)
+	}
+	bench.Run(b, w)
+}
+
+func BenchmarkAnalyzeAndFormat(b *testing.B) {
+	benchmarkWorkload(b, "analyze_and_format")
+}
+
+func BenchmarkFilteredAggregation(b *testing.B) {
+	benchmarkWorkload(b, "filtered_aggregation_1m")
+}
+
+func BenchmarkWindowFunction(b *testing.B) {
+	benchmarkWorkload(b, "window_function_100k")
+}
+
+func BenchmarkUnnestLargeArray(b *testing.B) {
+	benchmarkWorkload(b, "unnest_large_array")
+}
+
+func BenchmarkBulkInsert(b *testing.B) {
+	benchmarkWorkload(b, "bulk_insert_100k")
+}
+
+func TestRegression(t *testing.T) {
+	bench.RunRegression(t, "testdata/baselines.json", bench.DefaultThreshold)
+}
+
+func TestCompare(t *testing.T) {
+	baseline := &bench.Baseline{Name: "w", NsPerOp: 1000, AllocsPerOp: 100, BytesPerOp: 0}
+	if regressions := bench.Compare(baseline, &bench.Baseline{Name: "w", NsPerOp: 1299, AllocsPerOp: 90, BytesPerOp: 1 << 20}, 0.3); len(regressions) != 0 {
+		t.Fatalf("unexpected regressions %v", regressions)
+	}
+	regressions := bench.Compare(baseline, &bench.Baseline{Name: "w", NsPerOp: 1500, AllocsPerOp: 200}, 0.3)
+	if len(regressions) != 2 {
+		t.Fatalf("expected regressions of ns/op and allocs/op but got %v", regressions)
+	}
+}
@@ -0,0 +1,204 @@
+package zetasqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	zetasqlite "github.com/goccy/go-zetasqlite"
+)
+
+func BenchmarkInsertRows(b *testing.B) {
+	const rowNum = 100000
+
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer conn.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		table := fmt.Sprintf("bench_rows_%d", i)
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE %s (id INT64, name STRING, score FLOAT64)`, table)); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		if err := conn.Raw(func(c interface{}) error {
+			return c.(*zetasqlite.ZetaSQLiteConn).InsertRows(ctx, table, func(i int) []interface{} {
+				return []interface{}{i, fmt.Sprintf("name%d", i), float64(i) / 2}
+			}, rowNum)
+		}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInsertValues(b *testing.B) {
+	const rowNum = 100000
+
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	rows := make([]string, 0, rowNum)
+	for i := 0; i < rowNum; i++ {
+		rows = append(rows, fmt.Sprintf("(%d, 'name%d', %d.5)", i, i, i))
+	}
+	values := strings.Join(rows, ",")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		table := fmt.Sprintf("bench_values_%d", i)
+		if _, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE %s (id INT64, name STRING, score FLOAT64)`, table)); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		if _, err := db.ExecContext(ctx, fmt.Sprintf(`INSERT %s (id, name, score) VALUES %s`, table, values)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnnestGenerateArray(b *testing.B) {
+	const rowNum = 1000000
+
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM UNNEST(GENERATE_ARRAY(1, %d)) AS v`, rowNum)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var count int64
+		if err := db.QueryRow(query).Scan(&count); err != nil {
+			b.Fatal(err)
+		}
+		if count != rowNum {
+			b.Fatalf("unexpected count %d", count)
+		}
+	}
+}
+
+func BenchmarkParameterizedQuery(b *testing.B) {
+	const queryNum = 10000
+
+	for _, test := range []struct {
+		name            string
+		isAnalysisCache bool
+	}{
+		{name: "cached", isAnalysisCache: true},
+		{name: "uncached", isAnalysisCache: false},
+	} {
+		test := test
+		b.Run(test.name, func(b *testing.B) {
+			db, err := sql.Open("zetasqlite", ":memory:")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer db.Close()
+
+			ctx := context.Background()
+			conn, err := db.Conn(ctx)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer conn.Close()
+
+			if err := conn.Raw(func(c interface{}) error {
+				c.(*zetasqlite.ZetaSQLiteConn).SetAnalysisCacheMode(test.isAnalysisCache)
+				return nil
+			}); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := conn.ExecContext(ctx, `CREATE TABLE bench_items (id INT64, name STRING)`); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := conn.ExecContext(ctx, `INSERT INTO bench_items (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c')`); err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := 0; j < queryNum; j++ {
+					var name string
+					if err := conn.QueryRowContext(ctx, `SELECT name FROM bench_items WHERE id = @id`, sql.Named("id", j%3+1)).Scan(&name); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRestoreSnapshot(b *testing.B) {
+	const statementNum = 1000
+
+	ctx := context.Background()
+	fixture := make([]string, 0, statementNum)
+	fixture = append(fixture, `CREATE TABLE fixture (id INT64, name STRING)`)
+	for i := 1; i < statementNum; i++ {
+		fixture = append(fixture, fmt.Sprintf(`INSERT fixture (id, name) VALUES (%d, 'name%d')`, i, i))
+	}
+	replay := func(b *testing.B, db *sql.DB) {
+		b.Helper()
+		if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS fixture`); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := db.ExecContext(ctx, strings.Join(fixture, ";\n")); err != nil {
+			b.Fatal(err)
+		}
+	}
+	open := func(b *testing.B) *sql.DB {
+		b.Helper()
+		db, err := sql.Open("zetasqlite", filepath.Join(b.TempDir(), "fixture.db"))
+		if err != nil {
+			b.Fatal(err)
+		}
+		db.SetMaxOpenConns(1)
+		replay(b, db)
+		return db
+	}
+
+	b.Run("replay", func(b *testing.B) {
+		db := open(b)
+		defer db.Close()
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			replay(b, db)
+		}
+	})
+	b.Run("restore", func(b *testing.B) {
+		db := open(b)
+		defer db.Close()
+
+		snapshot, err := zetasqlite.Snapshot(ctx, db)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer snapshot.Close()
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := zetasqlite.RestoreSnapshot(ctx, db, snapshot); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
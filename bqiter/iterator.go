@@ -0,0 +1,357 @@
+// Package bqiter provides a row iterator over the results of the zetasqlite driver
+// that behaves like the RowIterator of cloud.google.com/go/bigquery.
+// This makes it possible to reuse application code written for the official BigQuery client.
+package bqiter
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/goccy/go-zetasql/types"
+	"google.golang.org/api/iterator"
+
+	zetasqlite "github.com/goccy/go-zetasqlite"
+)
+
+// RowIterator wraps *sql.Rows returned by the zetasqlite driver.
+type RowIterator struct {
+	rows   *sql.Rows
+	schema bigquery.Schema
+	types  []*zetasqlite.ColumnType
+	// TotalRows is the number of rows read so far.
+	TotalRows uint64
+}
+
+// NewRowIterator creates RowIterator from rows.
+// rows must be created by the zetasqlite driver because the column type metadata is used to build the schema.
+func NewRowIterator(rows *sql.Rows) (*RowIterator, error) {
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get column types: %w", err)
+	}
+	schema := make(bigquery.Schema, 0, len(colTypes))
+	typs := make([]*zetasqlite.ColumnType, 0, len(colTypes))
+	for _, colType := range colTypes {
+		typ, err := zetasqlite.UnmarshalDatabaseTypeName(colType.DatabaseTypeName())
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode column type of %s: %w", colType.Name(), err)
+		}
+		field, err := fieldSchema(colType.Name(), typ)
+		if err != nil {
+			return nil, err
+		}
+		schema = append(schema, field)
+		typs = append(typs, typ)
+	}
+	return &RowIterator{
+		rows:   rows,
+		schema: schema,
+		types:  typs,
+	}, nil
+}
+
+// Schema returns the schema of the result.
+func (it *RowIterator) Schema() bigquery.Schema {
+	return it.schema
+}
+
+// Next loads the next row into dst. Its return value is iterator.Done if there are no more results.
+// dst may be *[]bigquery.Value, *map[string]bigquery.Value, bigquery.ValueLoader or a pointer to a struct.
+// Struct fields are matched to columns by the `bigquery:"name"` tag or the field name (case-insensitive).
+func (it *RowIterator) Next(dst interface{}) error {
+	if !it.rows.Next() {
+		if err := it.rows.Err(); err != nil {
+			return err
+		}
+		return iterator.Done
+	}
+	values := make([]interface{}, len(it.types))
+	ptrs := make([]interface{}, len(it.types))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := it.rows.Scan(ptrs...); err != nil {
+		return fmt.Errorf("failed to scan row: %w", err)
+	}
+	row := make([]bigquery.Value, 0, len(values))
+	for i, v := range values {
+		value, err := convertValue(v, it.types[i])
+		if err != nil {
+			return fmt.Errorf("failed to convert value of %s: %w", it.schema[i].Name, err)
+		}
+		row = append(row, value)
+	}
+	it.TotalRows++
+	return loadRow(dst, row, it.schema)
+}
+
+func loadRow(dst interface{}, row []bigquery.Value, schema bigquery.Schema) error {
+	switch d := dst.(type) {
+	case bigquery.ValueLoader:
+		return d.Load(row, schema)
+	case *[]bigquery.Value:
+		*d = row
+		return nil
+	case *map[string]bigquery.Value:
+		m := make(map[string]bigquery.Value, len(row))
+		for i, field := range schema {
+			m[field.Name] = row[i]
+		}
+		*d = m
+		return nil
+	}
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("unsupported destination type %T", dst)
+	}
+	return setStruct(rv.Elem(), row, schema)
+}
+
+func fieldSchema(name string, typ *zetasqlite.ColumnType) (*bigquery.FieldSchema, error) {
+	if typ.Kind == types.ARRAY {
+		field, err := fieldSchema(name, typ.ElementType)
+		if err != nil {
+			return nil, err
+		}
+		field.Repeated = true
+		return field, nil
+	}
+	field := &bigquery.FieldSchema{Name: name}
+	switch types.TypeKind(typ.Kind) {
+	case types.INT32, types.INT64, types.UINT32, types.UINT64:
+		field.Type = bigquery.IntegerFieldType
+	case types.BOOL:
+		field.Type = bigquery.BooleanFieldType
+	case types.FLOAT, types.DOUBLE:
+		field.Type = bigquery.FloatFieldType
+	case types.STRING, types.ENUM:
+		field.Type = bigquery.StringFieldType
+	case types.BYTES:
+		field.Type = bigquery.BytesFieldType
+	case types.DATE:
+		field.Type = bigquery.DateFieldType
+	case types.DATETIME:
+		field.Type = bigquery.DateTimeFieldType
+	case types.TIME:
+		field.Type = bigquery.TimeFieldType
+	case types.TIMESTAMP:
+		field.Type = bigquery.TimestampFieldType
+	case types.NUMERIC:
+		field.Type = bigquery.NumericFieldType
+	case types.BIG_NUMERIC:
+		field.Type = bigquery.BigNumericFieldType
+	case types.GEOGRAPHY:
+		field.Type = bigquery.GeographyFieldType
+	case types.INTERVAL:
+		field.Type = bigquery.IntervalFieldType
+	case types.JSON:
+		field.Type = bigquery.JSONFieldType
+	case types.STRUCT:
+		field.Type = bigquery.RecordFieldType
+		for _, fieldType := range typ.FieldTypes {
+			child, err := fieldSchema(fieldType.Name, fieldType.Type)
+			if err != nil {
+				return nil, err
+			}
+			field.Schema = append(field.Schema, child)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported column type %s for %s", typ.Name, name)
+	}
+	return field, nil
+}
+
+func convertValue(v interface{}, typ *zetasqlite.ColumnType) (bigquery.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch types.TypeKind(typ.Kind) {
+	case types.ARRAY:
+		array, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected array value %T", v)
+		}
+		ret := make([]bigquery.Value, 0, len(array))
+		for _, elem := range array {
+			value, err := convertValue(elem, typ.ElementType)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, value)
+		}
+		return ret, nil
+	case types.STRUCT:
+		fields, ok := v.([]map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected struct value %T", v)
+		}
+		if len(fields) != len(typ.FieldTypes) {
+			return nil, fmt.Errorf("mismatch struct field num: %d != %d", len(fields), len(typ.FieldTypes))
+		}
+		ret := make([]bigquery.Value, 0, len(fields))
+		for i, field := range fields {
+			fieldType := typ.FieldTypes[i]
+			value, err := convertValue(field[fieldType.Name], fieldType.Type)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, value)
+		}
+		return ret, nil
+	case types.INT32, types.INT64, types.UINT32, types.UINT64:
+		return toInt64(v)
+	case types.FLOAT, types.DOUBLE:
+		switch f := v.(type) {
+		case float64:
+			return f, nil
+		case float32:
+			return float64(f), nil
+		}
+		return nil, fmt.Errorf("unexpected float value %T", v)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return v, nil
+	}
+	switch types.TypeKind(typ.Kind) {
+	case types.BYTES:
+		return base64.StdEncoding.DecodeString(s)
+	case types.DATE:
+		return civil.ParseDate(s)
+	case types.DATETIME:
+		return civil.ParseDateTime(s)
+	case types.TIME:
+		return civil.ParseTime(s)
+	case types.TIMESTAMP:
+		t, err := zetasqlite.TimeFromTimestampValue(s)
+		if err != nil {
+			return nil, err
+		}
+		return t.UTC(), nil
+	case types.NUMERIC, types.BIG_NUMERIC:
+		r, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return nil, fmt.Errorf("failed to parse numeric value %s", s)
+		}
+		return r, nil
+	case types.INTERVAL:
+		return bigquery.ParseInterval(s)
+	}
+	return s, nil
+}
+
+func toInt64(v interface{}) (int64, error) {
+	switch i := v.(type) {
+	case int64:
+		return i, nil
+	case int:
+		return int64(i), nil
+	case int32:
+		return int64(i), nil
+	case uint64:
+		return int64(i), nil
+	case uint32:
+		return int64(i), nil
+	}
+	return 0, fmt.Errorf("unexpected integer value %T", v)
+}
+
+func setStruct(dst reflect.Value, row []bigquery.Value, schema bigquery.Schema) error {
+	nameToIndex := make(map[string]int, len(schema))
+	for i, field := range schema {
+		nameToIndex[strings.ToLower(field.Name)] = i
+	}
+	typ := dst.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name := sf.Name
+		if tag, ok := sf.Tag.Lookup("bigquery"); ok {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		idx, exists := nameToIndex[strings.ToLower(name)]
+		if !exists {
+			continue
+		}
+		if err := setValue(dst.Field(i), row[idx], schema[idx]); err != nil {
+			return fmt.Errorf("failed to set %s field: %w", sf.Name, err)
+		}
+	}
+	return nil
+}
+
+func setValue(dst reflect.Value, src bigquery.Value, field *bigquery.FieldSchema) error {
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr {
+		v := reflect.New(dst.Type().Elem())
+		if err := setValue(v.Elem(), src, field); err != nil {
+			return err
+		}
+		dst.Set(v)
+		return nil
+	}
+	if field.Repeated && dst.Kind() == reflect.Slice && dst.Type().Elem().Kind() != reflect.Uint8 {
+		values, ok := src.([]bigquery.Value)
+		if !ok {
+			return fmt.Errorf("unexpected repeated value %T", src)
+		}
+		elemField := *field
+		elemField.Repeated = false
+		slice := reflect.MakeSlice(dst.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := setValue(slice.Index(i), v, &elemField); err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+		return nil
+	}
+	if field.Type == bigquery.RecordFieldType && !field.Repeated && dst.Kind() == reflect.Struct {
+		values, ok := src.([]bigquery.Value)
+		if !ok {
+			return fmt.Errorf("unexpected record value %T", src)
+		}
+		return setStruct(dst, values, field.Schema)
+	}
+	rv := reflect.ValueOf(src)
+	if rv.Type().AssignableTo(dst.Type()) {
+		dst.Set(rv)
+		return nil
+	}
+	if rv.Kind() == reflect.Ptr && rv.Elem().Type().AssignableTo(dst.Type()) {
+		dst.Set(rv.Elem())
+		return nil
+	}
+	if isNumberKind(rv.Kind()) && isNumberKind(dst.Kind()) {
+		dst.Set(rv.Convert(dst.Type()))
+		return nil
+	}
+	return fmt.Errorf("cannot assign %T to %s", src, dst.Type())
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package bqiter_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/iterator"
+
+	_ "github.com/goccy/go-zetasqlite"
+	"github.com/goccy/go-zetasqlite/bqiter"
+)
+
+func TestRowIterator(t *testing.T) {
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`
+SELECT
+  1 AS id,
+  'alice' AS name,
+  DATE '2022-01-02' AS birthday,
+  DATETIME '2022-01-02 03:04:05' AS updated_at,
+  TIMESTAMP '2022-01-02 03:04:05 UTC' AS created_at,
+  STRUCT(10 AS x, 'y' AS y) AS rec,
+  [1, 2, 3] AS nums,
+  b'abc' AS data`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	it, err := bqiter.NewRowIterator(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedSchema := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "name", Type: bigquery.StringFieldType},
+		{Name: "birthday", Type: bigquery.DateFieldType},
+		{Name: "updated_at", Type: bigquery.DateTimeFieldType},
+		{Name: "created_at", Type: bigquery.TimestampFieldType},
+		{Name: "rec", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+			{Name: "x", Type: bigquery.IntegerFieldType},
+			{Name: "y", Type: bigquery.StringFieldType},
+		}},
+		{Name: "nums", Type: bigquery.IntegerFieldType, Repeated: true},
+		{Name: "data", Type: bigquery.BytesFieldType},
+	}
+	if diff := cmp.Diff(expectedSchema, it.Schema()); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	type record struct {
+		X int64
+		Y string
+	}
+	type row struct {
+		ID        int64          `bigquery:"id"`
+		Name      string         `bigquery:"name"`
+		Birthday  civil.Date     `bigquery:"birthday"`
+		UpdatedAt civil.DateTime `bigquery:"updated_at"`
+		CreatedAt time.Time      `bigquery:"created_at"`
+		Rec       record         `bigquery:"rec"`
+		Nums      []int          `bigquery:"nums"`
+		Data      []byte         `bigquery:"data"`
+		Ignored   string         `bigquery:"-"`
+	}
+	var got row
+	if err := it.Next(&got); err != nil {
+		t.Fatal(err)
+	}
+	expected := row{
+		ID:        1,
+		Name:      "alice",
+		Birthday:  civil.Date{Year: 2022, Month: time.January, Day: 2},
+		UpdatedAt: civil.DateTime{Date: civil.Date{Year: 2022, Month: time.January, Day: 2}, Time: civil.Time{Hour: 3, Minute: 4, Second: 5}},
+		CreatedAt: time.Date(2022, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Rec:       record{X: 10, Y: "y"},
+		Nums:      []int{1, 2, 3},
+		Data:      []byte("abc"),
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	if err := it.Next(&got); err != iterator.Done {
+		t.Fatalf("expected iterator.Done but got %v", err)
+	}
+}
+
+func TestRowIteratorValues(t *testing.T) {
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT * FROM UNNEST([1, 2]) AS v WITH OFFSET AS o ORDER BY o`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	it, err := bqiter.NewRowIterator(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]bigquery.Value
+	for {
+		var values []bigquery.Value
+		err := it.Next(&values)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, values)
+	}
+	expected := [][]bigquery.Value{{int64(1), int64(0)}, {int64(2), int64(1)}}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
@@ -1,6 +1,7 @@
 package zetasqlite
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"reflect"
@@ -9,19 +10,74 @@ import (
 )
 
 type (
-	ChangedCatalog  = internal.ChangedCatalog
-	ChangedTable    = internal.ChangedTable
-	ChangedFunction = internal.ChangedFunction
-	TableSpec       = internal.TableSpec
-	FunctionSpec    = internal.FunctionSpec
-	NameWithType    = internal.NameWithType
-	ColumnSpec      = internal.ColumnSpec
-	Type            = internal.Type
+	ChangedCatalog      = internal.ChangedCatalog
+	ChangedTable        = internal.ChangedTable
+	ChangedFunction     = internal.ChangedFunction
+	TableSpec           = internal.TableSpec
+	FunctionSpec        = internal.FunctionSpec
+	NameWithType        = internal.NameWithType
+	ColumnSpec          = internal.ColumnSpec
+	OptionSpec          = internal.OptionSpec
+	GrantSpec           = internal.GrantSpec
+	RowAccessPolicySpec = internal.RowAccessPolicySpec
+	Type                = internal.Type
+	ModuleResolver      = internal.ModuleResolver
+	DatabaseSnapshot    = internal.DatabaseSnapshot
+	CatalogListing      = internal.CatalogListing
+	DatasetListing      = internal.DatasetListing
+	TableListing        = internal.TableListing
+	ViewListing         = internal.ViewListing
+	FunctionListing     = internal.FunctionListing
 )
 
+// ListCatalog returns the datasets, tables, views and functions in the database.
+// The objects created by DDL and the ones registered by the APIs are listed in the same way as INFORMATION_SCHEMA.
+// The objects created or updated by other connections are included, but the ones dropped by them remain until RefreshCatalog is called.
+func ListCatalog(ctx context.Context, db *sql.DB) (*CatalogListing, error) {
+	var listing *CatalogListing
+	if err := withZetaSQLiteConn(ctx, db, func(conn *ZetaSQLiteConn) error {
+		l, err := conn.Catalog(ctx)
+		if err != nil {
+			return err
+		}
+		listing = l
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return listing, nil
+}
+
+// RefreshCatalog reloads all tables, views and functions from the database.
+// This reflects the changes by other connections to the same database file including the dropped objects.
+// The cached analysis results are invalidated.
+func RefreshCatalog(ctx context.Context, db *sql.DB) error {
+	return withZetaSQLiteConn(ctx, db, func(conn *ZetaSQLiteConn) error {
+		return conn.RefreshCatalog(ctx)
+	})
+}
+
 // ChangedCatalogFromRows retrieve modified catalog information from sql.Rows.
 // NOTE: This API relies on the internal structure of sql.Rows, so not will work for all Go versions.
 func ChangedCatalogFromRows(rows *sql.Rows) (*ChangedCatalog, error) {
+	zetasqliteRows, err := zetasqliteRowsFromRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	return zetasqliteRows.ChangedCatalog(), nil
+}
+
+// ChangedCatalogFromResult retrieve modified catalog information from sql.Result.
+// NOTE: This API relies on the internal structure of sql.Result, so not will work for all Go versions.
+func ChangedCatalogFromResult(result sql.Result) (*ChangedCatalog, error) {
+	zetasqliteResult, err := zetasqliteResultFromResult(result)
+	if err != nil {
+		return nil, err
+	}
+	return zetasqliteResult.ChangedCatalog(), nil
+}
+
+func zetasqliteRowsFromRows(rows *sql.Rows) (*internal.Rows, error) {
 	if rows == nil {
 		return nil, fmt.Errorf("zetasqlite: sql.Rows instance required not nil")
 	}
@@ -34,13 +90,10 @@ func ChangedCatalogFromRows(rows *sql.Rows) (*ChangedCatalog, error) {
 	if driverValue.Type() != reflect.TypeOf(new(internal.Rows)) {
 		return nil, fmt.Errorf("zetasqlite: sql.Rows must be an instance created using the zetasqlite database driver")
 	}
-	zetasqliteRows := (*internal.Rows)(driverValue.UnsafePointer())
-	return zetasqliteRows.ChangedCatalog(), nil
+	return (*internal.Rows)(driverValue.UnsafePointer()), nil
 }
 
-// ChangedCatalogFromResult retrieve modified catalog information from sql.Result.
-// NOTE: This API relies on the internal structure of sql.Result, so not will work for all Go versions.
-func ChangedCatalogFromResult(result sql.Result) (*ChangedCatalog, error) {
+func zetasqliteResultFromResult(result sql.Result) (*internal.Result, error) {
 	rv := reflect.ValueOf(result)
 	if rv.Kind() != reflect.Struct {
 		return nil, fmt.Errorf("zetasqlite: unexpected sql.Result layout. expected sql.Result type is struct but got %T", result)
@@ -53,6 +106,5 @@ func ChangedCatalogFromResult(result sql.Result) (*ChangedCatalog, error) {
 	if driverValue.Type() != reflect.TypeOf(new(internal.Result)) {
 		return nil, fmt.Errorf("zetasqlite: sql.Result must be an instance created using the zetasqlite database driver")
 	}
-	zetasqliteResult := (*internal.Result)(driverValue.UnsafePointer())
-	return zetasqliteResult.ChangedCatalog(), nil
+	return (*internal.Result)(driverValue.UnsafePointer()), nil
 }
@@ -0,0 +1,111 @@
+// Package conformance provides the test harness to check that zetasqlite returns the same results as BigQuery.
+// Each case has a query and the result recorded from BigQuery, so users can record the output of their own queries
+// and assert that zetasqlite matches it. The expectations can be re-recorded against BigQuery when credentials are present.
+package conformance
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/goccy/go-json"
+)
+
+// Case is a query and the result recorded from BigQuery.
+type Case struct {
+	// Name identifies the case in the test output.
+	Name string `json:"name"`
+	// Query is run by both zetasqlite and BigQuery. It must not refer to the tables that exist only in either of them.
+	Query string `json:"query"`
+	// Parameters are the named query parameters.
+	Parameters []*Parameter `json:"parameters,omitempty"`
+	// Ordered compares the rows in order. Otherwise the rows are compared regardless of the order.
+	Ordered bool `json:"ordered,omitempty"`
+	// Schema is the recorded schema of the result.
+	Schema []*Field `json:"schema"`
+	// Rows are the recorded values encoded in the format described by Value.
+	Rows [][]interface{} `json:"rows"`
+}
+
+// Field is the column of the result in the same representation as bigquery.FieldSchema.
+type Field struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Repeated bool     `json:"repeated,omitempty"`
+	Fields   []*Field `json:"fields,omitempty"`
+}
+
+// Parameter is the named query parameter.
+// Type is the scalar type name ( e.g. INT64 ) or the array of it ( e.g. ARRAY<INT64> ),
+// and Value is encoded in the same format as the values of the rows.
+type Parameter struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// LoadFile reads the cases from the JSON file containing the array of Case.
+func LoadFile(path string) ([]*Case, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var cases []*Case
+	if err := json.Unmarshal(b, &cases); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	for idx, c := range cases {
+		if c.Name == "" {
+			return nil, fmt.Errorf("name of case %d in %s is empty", idx, path)
+		}
+		if c.Query == "" {
+			return nil, fmt.Errorf("query of case %q in %s is empty", c.Name, path)
+		}
+	}
+	return cases, nil
+}
+
+// LoadDir reads the cases from all JSON files in dir. The returned map is keyed by the file path.
+func LoadDir(dir string) (map[string][]*Case, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find case files in %s: %w", dir, err)
+	}
+	sort.Strings(paths)
+	ret := make(map[string][]*Case, len(paths))
+	for _, path := range paths {
+		cases, err := LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		ret[path] = cases
+	}
+	return ret, nil
+}
+
+// WriteFile writes the cases to path in the format read by LoadFile.
+func WriteFile(path string, cases []*Case) error {
+	b, err := json.MarshalIndent(cases, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cases: %w", err)
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func newFields(schema bigquery.Schema) []*Field {
+	fields := make([]*Field, 0, len(schema))
+	for _, field := range schema {
+		fields = append(fields, &Field{
+			Name:     field.Name,
+			Type:     string(field.Type),
+			Repeated: field.Repeated,
+			Fields:   newFields(field.Schema),
+		})
+	}
+	return fields
+}
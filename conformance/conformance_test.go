@@ -0,0 +1,128 @@
+package conformance_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/goccy/go-zetasqlite"
+	"github.com/goccy/go-zetasqlite/conformance"
+)
+
+func TestRunFile(t *testing.T) {
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	conformance.RunFile(t, db, "testdata/basic.json", nil)
+}
+
+func TestRunDiffs(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, test := range []struct {
+		name          string
+		c             *conformance.Case
+		opt           *conformance.Options
+		expectedDiffs []string
+	}{
+		{
+			name: "values",
+			c: &conformance.Case{
+				Name:  "values",
+				Query: `SELECT 1 AS i, '' AS s`,
+				Schema: []*conformance.Field{
+					{Name: "i", Type: "INTEGER"},
+					{Name: "s", Type: "STRING"},
+				},
+				Rows: [][]interface{}{{"2", nil}},
+			},
+			expectedDiffs: []string{
+				`row 0.i: expected "2" but got "1"`,
+				`row 0.s: expected NULL but got ""`,
+			},
+		},
+		{
+			name: "rows",
+			c: &conformance.Case{
+				Name:  "rows",
+				Query: `SELECT x FROM UNNEST([1, 2]) AS x`,
+				Schema: []*conformance.Field{
+					{Name: "x", Type: "INTEGER"},
+				},
+				Rows: [][]interface{}{{"1"}, {"2"}, {"3"}},
+			},
+			expectedDiffs: []string{
+				`row 2: missing row ["3"]`,
+			},
+		},
+		{
+			name: "schema",
+			c: &conformance.Case{
+				Name:  "schema",
+				Query: `SELECT 1 AS x`,
+				Schema: []*conformance.Field{
+					{Name: "x", Type: "FLOAT"},
+				},
+				Rows: [][]interface{}{{"1"}},
+			},
+			expectedDiffs: []string{
+				`schema.x: expected type FLOAT but got INTEGER`,
+			},
+		},
+		{
+			name: "timestamp precision",
+			c: &conformance.Case{
+				Name:  "timestamp precision",
+				Query: `SELECT TIMESTAMP '2022-01-02 03:04:05.123456 UTC' AS ts`,
+				Schema: []*conformance.Field{
+					{Name: "ts", Type: "TIMESTAMP"},
+				},
+				Rows: [][]interface{}{{"2022-01-02T03:04:05.123Z"}},
+			},
+			opt: &conformance.Options{TimestampPrecision: time.Millisecond},
+		},
+		{
+			name: "float tolerance",
+			c: &conformance.Case{
+				Name:  "float tolerance",
+				Query: `SELECT 1.001 AS f`,
+				Schema: []*conformance.Field{
+					{Name: "f", Type: "FLOAT"},
+				},
+				Rows: [][]interface{}{{"1"}},
+			},
+			expectedDiffs: []string{
+				`row 0.f: expected "1" but got "1.001"`,
+			},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			result, err := conformance.Run(ctx, db, test.c, test.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Diffs) != len(test.expectedDiffs) {
+				t.Fatalf("expected diffs %q but got %q", test.expectedDiffs, result.Diffs)
+			}
+			for idx, diff := range test.expectedDiffs {
+				if result.Diffs[idx] != diff {
+					t.Fatalf("expected diff %q but got %q", diff, result.Diffs[idx])
+				}
+			}
+			if !result.Passed() && !strings.Contains(result.String(), test.c.Query) {
+				t.Fatalf("the diff output must contain the query: %s", result.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,346 @@
+package conformance
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/iterator"
+
+	zetasqlite "github.com/goccy/go-zetasqlite"
+	"github.com/goccy/go-zetasqlite/bqiter"
+)
+
+const (
+	defaultFloatTolerance     = 1e-9
+	defaultTimestampPrecision = time.Microsecond
+
+	// RecordEnv is the environment variable to re-record the expectations by RunFile.
+	RecordEnv = "ZETASQLITE_CONFORMANCE_RECORD"
+	// ProjectEnv is the environment variable of the project to run the queries on BigQuery when recording.
+	ProjectEnv = "GOOGLE_CLOUD_PROJECT"
+)
+
+// Options is the options to compare the results.
+type Options struct {
+	// FloatTolerance is the relative tolerance to compare FLOAT64 values. The default is 1e-9.
+	FloatTolerance float64
+	// TimestampPrecision is the precision to compare TIMESTAMP values. The default is a microsecond.
+	TimestampPrecision time.Duration
+}
+
+func (o *Options) floatTolerance() float64 {
+	if o == nil || o.FloatTolerance == 0 {
+		return defaultFloatTolerance
+	}
+	return o.FloatTolerance
+}
+
+func (o *Options) timestampPrecision() time.Duration {
+	if o == nil || o.TimestampPrecision == 0 {
+		return defaultTimestampPrecision
+	}
+	return o.TimestampPrecision
+}
+
+// Result is the result of the case run by zetasqlite.
+type Result struct {
+	Case *Case
+	// Diffs are the differences from the recorded result. It is empty if the case passed.
+	Diffs []string
+}
+
+// Passed reports whether zetasqlite returned the recorded result.
+func (r *Result) Passed() bool {
+	return len(r.Diffs) == 0
+}
+
+func (r *Result) String() string {
+	if r.Passed() {
+		return fmt.Sprintf("%s: ok", r.Case.Name)
+	}
+	return fmt.Sprintf("%s:\n  query: %s\n  %s", r.Case.Name, r.Case.Query, strings.Join(r.Diffs, "\n  "))
+}
+
+// Run runs the query of the case by db opened with the zetasqlite driver and compares the result with the recorded one.
+// The returned error is not nil only if the query cannot be run. The differences are reported by Result.
+func Run(ctx context.Context, db *sql.DB, c *Case, opt *Options) (*Result, error) {
+	paramTypes := make(map[string]string, len(c.Parameters))
+	args := make([]interface{}, 0, len(c.Parameters))
+	for _, param := range c.Parameters {
+		value, err := param.goValue()
+		if err != nil {
+			return nil, err
+		}
+		paramTypes[param.Name] = param.Type
+		args = append(args, sql.Named(param.Name, value))
+	}
+	rows, err := db.QueryContext(zetasqlite.WithQueryParamTypes(ctx, paramTypes), c.Query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s: %w", c.Name, err)
+	}
+	defer rows.Close()
+
+	it, err := bqiter.NewRowIterator(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result of %s: %w", c.Name, err)
+	}
+	schema := newFields(it.Schema())
+	actualRows, err := readRows(schema, it.Next)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result of %s: %w", c.Name, err)
+	}
+	return compare(c, schema, actualRows, opt), nil
+}
+
+func compare(c *Case, schema []*Field, actualRows [][]Value, opt *Options) *Result {
+	cmp := &comparator{opt: opt}
+	cmp.compareSchema(c.Schema, schema)
+	if len(cmp.diffs) != 0 {
+		// the values cannot be compared by the different types.
+		return &Result{Case: c, Diffs: cmp.diffs}
+	}
+	expectedRows := c.Rows
+	if !c.Ordered {
+		expectedRows = sortRows(expectedRows)
+		actualRows = sortRows(actualRows)
+	}
+	for idx := 0; idx < len(expectedRows) || idx < len(actualRows); idx++ {
+		switch {
+		case idx >= len(actualRows):
+			cmp.diffs = append(cmp.diffs, fmt.Sprintf("row %d: missing row %s", idx, formatValue(expectedRows[idx])))
+		case idx >= len(expectedRows):
+			cmp.diffs = append(cmp.diffs, fmt.Sprintf("row %d: unexpected row %s", idx, formatValue(actualRows[idx])))
+		default:
+			cmp.compareRow(fmt.Sprintf("row %d", idx), c.Schema, expectedRows[idx], actualRows[idx])
+		}
+	}
+	return &Result{Case: c, Diffs: cmp.diffs}
+}
+
+// sortRows sorts the rows by the encoded values, so that the rows of the unordered result are compared one by one.
+func sortRows(rows [][]Value) [][]Value {
+	type keyedRow struct {
+		key string
+		row []Value
+	}
+	keyed := make([]*keyedRow, 0, len(rows))
+	for _, row := range rows {
+		keyed = append(keyed, &keyedRow{key: formatValue(row), row: row})
+	}
+	sort.SliceStable(keyed, func(i, j int) bool {
+		return keyed[i].key < keyed[j].key
+	})
+	ret := make([][]Value, 0, len(rows))
+	for _, r := range keyed {
+		ret = append(ret, r.row)
+	}
+	return ret
+}
+
+func readRows(schema []*Field, next func(interface{}) error) ([][]Value, error) {
+	rows := [][]Value{}
+	for {
+		var row []bigquery.Value
+		if err := next(&row); err != nil {
+			if err == iterator.Done {
+				break
+			}
+			return nil, err
+		}
+		encoded, err := encodeRow(schema, row)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, encoded)
+	}
+	return rows, nil
+}
+
+// Record runs the query of the case on BigQuery and replaces the recorded schema and rows of the case by the result.
+func Record(ctx context.Context, client *bigquery.Client, c *Case) error {
+	q := client.Query(c.Query)
+	for _, param := range c.Parameters {
+		value, err := param.bigqueryValue()
+		if err != nil {
+			return err
+		}
+		q.Parameters = append(q.Parameters, bigquery.QueryParameter{Name: param.Name, Value: value})
+	}
+	it, err := q.Read(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to run %s on BigQuery: %w", c.Name, err)
+	}
+	var (
+		schema []*Field
+		rows   = [][]Value{}
+	)
+	for {
+		var row []bigquery.Value
+		if err := it.Next(&row); err != nil {
+			if err == iterator.Done {
+				break
+			}
+			return fmt.Errorf("failed to read result of %s from BigQuery: %w", c.Name, err)
+		}
+		if schema == nil {
+			// the schema is available after the first call of Next.
+			schema = newFields(it.Schema)
+		}
+		encoded, err := encodeRow(schema, row)
+		if err != nil {
+			return fmt.Errorf("failed to read result of %s from BigQuery: %w", c.Name, err)
+		}
+		rows = append(rows, encoded)
+	}
+	if schema == nil {
+		schema = newFields(it.Schema)
+	}
+	c.Schema = schema
+	c.Rows = rows
+	return nil
+}
+
+// RunFile runs the cases in path as the subtests of t.
+// If RecordEnv and ProjectEnv are set, the cases are re-recorded on BigQuery by the default credentials
+// and written to path instead of running them by zetasqlite.
+func RunFile(t *testing.T, db *sql.DB, path string, opt *Options) {
+	t.Helper()
+	cases, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if project := os.Getenv(ProjectEnv); project != "" && os.Getenv(RecordEnv) != "" {
+		client, err := bigquery.NewClient(ctx, project)
+		if err != nil {
+			t.Fatalf("failed to create BigQuery client: %v", err)
+		}
+		defer client.Close()
+		for _, c := range cases {
+			if err := Record(ctx, client, c); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := WriteFile(path, cases); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			result, err := Run(ctx, db, c, opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.Passed() {
+				t.Error(result.String())
+			}
+		})
+	}
+}
+
+func (p *Parameter) elementType() (string, bool) {
+	if strings.HasPrefix(p.Type, "ARRAY<") && strings.HasSuffix(p.Type, ">") {
+		return strings.TrimSuffix(strings.TrimPrefix(p.Type, "ARRAY<"), ">"), true
+	}
+	return p.Type, false
+}
+
+// goValue returns the value passed to the zetasqlite driver. The types of the values are declared by WithQueryParamTypes.
+func (p *Parameter) goValue() (interface{}, error) {
+	typ, isArray := p.elementType()
+	if !isArray {
+		return scalarGoValue(p.Name, typ, p.Value)
+	}
+	if p.Value == nil {
+		return nil, nil
+	}
+	values, ok := p.Value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("value of parameter @%s must be an array: %v", p.Name, p.Value)
+	}
+	ret := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		v, err := scalarGoValue(p.Name, typ, value)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, v)
+	}
+	return ret, nil
+}
+
+func scalarGoValue(name, typ string, value Value) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	s, ok := scalarString(value)
+	if !ok {
+		return nil, fmt.Errorf("unsupported value of parameter @%s: %v", name, value)
+	}
+	switch typ {
+	case "INT64":
+		return strconv.ParseInt(s, 10, 64)
+	case "FLOAT64":
+		return strconv.ParseFloat(s, 64)
+	case "BOOL":
+		return strconv.ParseBool(s)
+	case "BYTES":
+		return base64.StdEncoding.DecodeString(s)
+	case "TIMESTAMP":
+		return time.Parse(time.RFC3339Nano, s)
+	case "STRING", "DATE", "DATETIME", "TIME", "NUMERIC", "BIGNUMERIC", "JSON", "GEOGRAPHY", "INTERVAL":
+		return s, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s of parameter @%s", typ, name)
+}
+
+func (p *Parameter) bigqueryValue() (bigquery.QueryParameterValue, error) {
+	typ, isArray := p.elementType()
+	if !isArray {
+		value, err := scalarParameterValue(p.Name, typ, p.Value)
+		if err != nil {
+			return bigquery.QueryParameterValue{}, err
+		}
+		return bigquery.QueryParameterValue{Type: bigquery.StandardSQLDataType{TypeKind: typ}, Value: value}, nil
+	}
+	ret := bigquery.QueryParameterValue{
+		Type: bigquery.StandardSQLDataType{
+			TypeKind:         "ARRAY",
+			ArrayElementType: &bigquery.StandardSQLDataType{TypeKind: typ},
+		},
+	}
+	values, _ := p.Value.([]interface{})
+	for _, value := range values {
+		v, err := scalarParameterValue(p.Name, typ, value)
+		if err != nil {
+			return bigquery.QueryParameterValue{}, err
+		}
+		ret.ArrayValue = append(ret.ArrayValue, bigquery.QueryParameterValue{Value: v})
+	}
+	return ret, nil
+}
+
+func scalarParameterValue(name, typ string, value Value) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	s, ok := scalarString(value)
+	if !ok {
+		return nil, fmt.Errorf("unsupported value of parameter @%s: %v", name, value)
+	}
+	if typ == "BYTES" {
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return s, nil
+}
@@ -0,0 +1,329 @@
+package conformance
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/goccy/go-json"
+)
+
+// Value is the encoded value of the column.
+// NULL is encoded as JSON null, ARRAY as JSON array, STRUCT as JSON object keyed by the field names
+// and the other values as the strings in the format of the BigQuery REST API
+// ( e.g. "1", "1.5", "NaN", "true", "2022-01-02 03:04:05.000006", "2022-01-02T03:04:05.000006Z" and base64 for BYTES ).
+type Value = interface{}
+
+func encodeRow(fields []*Field, row []bigquery.Value) ([]Value, error) {
+	if len(fields) != len(row) {
+		return nil, fmt.Errorf("mismatch column num: %d != %d", len(fields), len(row))
+	}
+	ret := make([]Value, 0, len(row))
+	for idx, field := range fields {
+		v, err := encodeValue(field, field.Repeated, row[idx])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode value of %s: %w", field.Name, err)
+		}
+		ret = append(ret, v)
+	}
+	return ret, nil
+}
+
+func encodeValue(field *Field, repeated bool, v bigquery.Value) (Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if repeated {
+		values, ok := v.([]bigquery.Value)
+		if !ok {
+			return nil, fmt.Errorf("unexpected repeated value %T", v)
+		}
+		ret := make([]Value, 0, len(values))
+		for _, value := range values {
+			elem, err := encodeValue(field, false, value)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, elem)
+		}
+		return ret, nil
+	}
+	if bigquery.FieldType(field.Type) == bigquery.RecordFieldType {
+		values, ok := v.([]bigquery.Value)
+		if !ok {
+			return nil, fmt.Errorf("unexpected record value %T", v)
+		}
+		if len(values) != len(field.Fields) {
+			return nil, fmt.Errorf("mismatch record field num: %d != %d", len(values), len(field.Fields))
+		}
+		ret := make(map[string]Value, len(values))
+		for idx, child := range field.Fields {
+			value, err := encodeValue(child, child.Repeated, values[idx])
+			if err != nil {
+				return nil, err
+			}
+			ret[child.Name] = value
+		}
+		return ret, nil
+	}
+	switch value := v.(type) {
+	case int64:
+		return strconv.FormatInt(value, 10), nil
+	case float64:
+		return formatFloat(value), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case string:
+		return value, nil
+	case []byte:
+		return base64.StdEncoding.EncodeToString(value), nil
+	case civil.Date:
+		return value.String(), nil
+	case civil.DateTime:
+		return bigquery.CivilDateTimeString(value), nil
+	case civil.Time:
+		return bigquery.CivilTimeString(value), nil
+	case time.Time:
+		return value.UTC().Format(time.RFC3339Nano), nil
+	case *big.Rat:
+		if bigquery.FieldType(field.Type) == bigquery.BigNumericFieldType {
+			return bigquery.BigNumericString(value), nil
+		}
+		return bigquery.NumericString(value), nil
+	case *bigquery.IntervalValue:
+		return value.String(), nil
+	}
+	return nil, fmt.Errorf("unsupported value %T", v)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// scalarString returns the string of the scalar value. The numbers and booleans written by hand are also accepted.
+func scalarString(v Value) (string, bool) {
+	switch value := v.(type) {
+	case string:
+		return value, true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case json.Number:
+		return value.String(), true
+	case bool:
+		return strconv.FormatBool(value), true
+	}
+	return "", false
+}
+
+type comparator struct {
+	opt   *Options
+	diffs []string
+}
+
+func (c *comparator) addDiff(path string, expected, actual Value) {
+	c.diffs = append(c.diffs, fmt.Sprintf("%s: expected %s but got %s", path, formatValue(expected), formatValue(actual)))
+}
+
+func (c *comparator) compareSchema(expected, actual []*Field) {
+	c.compareFields("schema", expected, actual)
+}
+
+func (c *comparator) compareFields(path string, expected, actual []*Field) {
+	if len(expected) != len(actual) {
+		c.diffs = append(c.diffs, fmt.Sprintf(
+			"%s: expected %d columns %s but got %d columns %s",
+			path, len(expected), formatFieldNames(expected), len(actual), formatFieldNames(actual),
+		))
+		return
+	}
+	for idx := range expected {
+		e, a := expected[idx], actual[idx]
+		fieldPath := fmt.Sprintf("%s.%s", path, e.Name)
+		if e.Name != a.Name {
+			c.diffs = append(c.diffs, fmt.Sprintf("%s: expected column name %q but got %q", fieldPath, e.Name, a.Name))
+		}
+		if e.Type != a.Type || e.Repeated != a.Repeated {
+			c.diffs = append(c.diffs, fmt.Sprintf(
+				"%s: expected type %s but got %s", fieldPath, formatFieldType(e), formatFieldType(a),
+			))
+			continue
+		}
+		if len(e.Fields) != 0 || len(a.Fields) != 0 {
+			c.compareFields(fieldPath, e.Fields, a.Fields)
+		}
+	}
+}
+
+func (c *comparator) compareRow(path string, fields []*Field, expected, actual []Value) {
+	for idx, field := range fields {
+		var e, a Value
+		if idx < len(expected) {
+			e = expected[idx]
+		}
+		if idx < len(actual) {
+			a = actual[idx]
+		}
+		c.compareValue(fmt.Sprintf("%s.%s", path, field.Name), field, field.Repeated, e, a)
+	}
+}
+
+func (c *comparator) compareValue(path string, field *Field, repeated bool, expected, actual Value) {
+	if repeated {
+		// BigQuery returns NULL array as an empty array, so they are not distinguished.
+		e, eok := expected.([]interface{})
+		a, aok := actual.([]interface{})
+		if (expected != nil && !eok) || (actual != nil && !aok) {
+			c.addDiff(path, expected, actual)
+			return
+		}
+		if len(e) != len(a) {
+			c.addDiff(path, expected, actual)
+			return
+		}
+		for idx := range e {
+			c.compareValue(fmt.Sprintf("%s[%d]", path, idx), field, false, e[idx], a[idx])
+		}
+		return
+	}
+	if expected == nil || actual == nil {
+		if expected != nil || actual != nil {
+			c.addDiff(path, expected, actual)
+		}
+		return
+	}
+	if bigquery.FieldType(field.Type) == bigquery.RecordFieldType {
+		e, eok := expected.(map[string]interface{})
+		a, aok := actual.(map[string]interface{})
+		if !eok || !aok {
+			c.addDiff(path, expected, actual)
+			return
+		}
+		for _, child := range field.Fields {
+			c.compareValue(fmt.Sprintf("%s.%s", path, child.Name), child, child.Repeated, e[child.Name], a[child.Name])
+		}
+		return
+	}
+	e, eok := scalarString(expected)
+	a, aok := scalarString(actual)
+	if !eok || !aok {
+		c.addDiff(path, expected, actual)
+		return
+	}
+	if !c.equalScalar(bigquery.FieldType(field.Type), e, a) {
+		c.addDiff(path, expected, actual)
+	}
+}
+
+func (c *comparator) equalScalar(typ bigquery.FieldType, expected, actual string) bool {
+	if expected == actual {
+		return true
+	}
+	switch typ {
+	case bigquery.FloatFieldType:
+		e, err := strconv.ParseFloat(expected, 64)
+		if err != nil {
+			return false
+		}
+		a, err := strconv.ParseFloat(actual, 64)
+		if err != nil {
+			return false
+		}
+		return equalFloat(e, a, c.opt.floatTolerance())
+	case bigquery.IntegerFieldType:
+		e, ok := new(big.Int).SetString(expected, 10)
+		if !ok {
+			return false
+		}
+		a, ok := new(big.Int).SetString(actual, 10)
+		if !ok {
+			return false
+		}
+		return e.Cmp(a) == 0
+	case bigquery.NumericFieldType, bigquery.BigNumericFieldType:
+		e, ok := new(big.Rat).SetString(expected)
+		if !ok {
+			return false
+		}
+		a, ok := new(big.Rat).SetString(actual)
+		if !ok {
+			return false
+		}
+		return e.Cmp(a) == 0
+	case bigquery.TimestampFieldType:
+		e, err := time.Parse(time.RFC3339Nano, expected)
+		if err != nil {
+			return false
+		}
+		a, err := time.Parse(time.RFC3339Nano, actual)
+		if err != nil {
+			return false
+		}
+		precision := c.opt.timestampPrecision()
+		return e.Truncate(precision).Equal(a.Truncate(precision))
+	case bigquery.JSONFieldType:
+		// the spaces and the order of the keys depend on the formatter.
+		var e, a interface{}
+		if err := json.Unmarshal([]byte(expected), &e); err != nil {
+			return false
+		}
+		if err := json.Unmarshal([]byte(actual), &a); err != nil {
+			return false
+		}
+		return reflect.DeepEqual(e, a)
+	}
+	return false
+}
+
+func equalFloat(expected, actual, tolerance float64) bool {
+	if math.IsNaN(expected) || math.IsNaN(actual) {
+		return math.IsNaN(expected) && math.IsNaN(actual)
+	}
+	if math.IsInf(expected, 0) || math.IsInf(actual, 0) {
+		return expected == actual
+	}
+	diff := math.Abs(expected - actual)
+	if diff <= tolerance {
+		return true
+	}
+	return diff <= tolerance*math.Max(math.Abs(expected), math.Abs(actual))
+}
+
+func formatValue(v Value) string {
+	if v == nil {
+		return "NULL"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func formatFieldNames(fields []*Field) string {
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		names = append(names, field.Name)
+	}
+	return fmt.Sprint(names)
+}
+
+func formatFieldType(field *Field) string {
+	if field.Repeated {
+		return fmt.Sprintf("REPEATED %s", field.Type)
+	}
+	return field.Type
+}
@@ -0,0 +1,99 @@
+package zetasqlite
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+var _ driver.Connector = &ZetaSQLiteConnector{}
+
+// ConnectorOption is the setting applied to each connection opened by the connector.
+type ConnectorOption func(*ZetaSQLiteConn) error
+
+// WithReadOnly when enabled, the connection accepts only a single query statement.
+// DML, DDL and scripts are rejected at analysis time with ReadOnlyError before any statement is executed.
+// This includes the statements in BEGIN...END block and EXECUTE IMMEDIATE, whose SQL cannot be verified before running it.
+func WithReadOnly(enabled bool) ConnectorOption {
+	return func(conn *ZetaSQLiteConn) error {
+		conn.SetReadOnlyMode(enabled)
+		return nil
+	}
+}
+
+// WithErrorQueryText when disabled, the original and the translated SQL texts are not attached to SQLiteError.
+// Use it if the SQL texts may contain the sensitive literals and the errors are logged. Enabled by default.
+func WithErrorQueryText(enabled bool) ConnectorOption {
+	return func(conn *ZetaSQLiteConn) error {
+		conn.SetErrorQueryTextMode(enabled)
+		return nil
+	}
+}
+
+// WithLanguageFeatures enables the language features of ZetaSQL in addition to DefaultLanguageFeatures.
+//
+//	db := sql.OpenDB(zetasqlite.NewConnector(":memory:", zetasqlite.WithLanguageFeatures(zetasql.FeatureV13LikeAnySomeAll)))
+func WithLanguageFeatures(features ...LanguageFeature) ConnectorOption {
+	return func(conn *ZetaSQLiteConn) error {
+		return conn.analyzer.EnableLanguageFeatures(features...)
+	}
+}
+
+// WithoutLanguageFeatures disables the language features of ZetaSQL, then the syntax gated by them is rejected at analysis time.
+func WithoutLanguageFeatures(features ...LanguageFeature) ConnectorOption {
+	return func(conn *ZetaSQLiteConn) error {
+		return conn.analyzer.DisableLanguageFeatures(features...)
+	}
+}
+
+// WithMaxResultRows limits the number of the rows returned by a query. See SetMaxResultRows.
+func WithMaxResultRows(num int64) ConnectorOption {
+	return func(conn *ZetaSQLiteConn) error {
+		conn.SetMaxResultRows(num)
+		return nil
+	}
+}
+
+// WithMaxBufferedValues limits the number of the values buffered by each aggregate or analytic function call. See SetMaxBufferedValues.
+func WithMaxBufferedValues(num int64) ConnectorOption {
+	return func(conn *ZetaSQLiteConn) error {
+		conn.SetMaxBufferedValues(num)
+		return nil
+	}
+}
+
+// ZetaSQLiteConnector opens the connections with the options. Use it with sql.OpenDB.
+//
+//	db := sql.OpenDB(zetasqlite.NewConnector("file:test.db", zetasqlite.WithReadOnly(true)))
+type ZetaSQLiteConnector struct {
+	name   string
+	driver *ZetaSQLiteDriver
+	opts   []ConnectorOption
+}
+
+// NewConnector creates the connector to the database specified by name like sql.Open("zetasqlite", name).
+func NewConnector(name string, opts ...ConnectorOption) *ZetaSQLiteConnector {
+	return &ZetaSQLiteConnector{
+		name:   name,
+		driver: &ZetaSQLiteDriver{},
+		opts:   opts,
+	}
+}
+
+func (c *ZetaSQLiteConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.name)
+	if err != nil {
+		return nil, err
+	}
+	zetasqliteConn := conn.(*ZetaSQLiteConn)
+	for _, opt := range c.opts {
+		if err := opt(zetasqliteConn); err != nil {
+			_ = zetasqliteConn.Close()
+			return nil, err
+		}
+	}
+	return zetasqliteConn, nil
+}
+
+func (c *ZetaSQLiteConnector) Driver() driver.Driver {
+	return c.driver
+}
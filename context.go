@@ -18,3 +18,43 @@ func WithCurrentTime(ctx context.Context, now time.Time) context.Context {
 func CurrentTime(ctx context.Context) *time.Time {
 	return internal.CurrentTime(ctx)
 }
+
+// WithQueryParamTypes declares the types of the named query parameters ( e.g. map[string]string{"ids": "ARRAY<INT64>"} ).
+// To declare the types, you need to pass the returned context as an argument to PrepareContext, ExecContext or QueryContext.
+// The declared types are used to analyze the query instead of inferring them, so the parameter whose type cannot be inferred
+// ( e.g. `IN UNNEST(@ids)` with an empty slice ) can be used. The values passed at the execution are validated against the declared types.
+func WithQueryParamTypes(ctx context.Context, paramTypes map[string]string) context.Context {
+	return internal.WithQueryParameterTypes(ctx, paramTypes)
+}
+
+const (
+	QueryPriorityInteractive = internal.QueryPriorityInteractive
+	QueryPriorityBatch       = internal.QueryPriorityBatch
+)
+
+type (
+	QueryLog    = internal.QueryLog
+	QueryLogger = internal.QueryLogger
+)
+
+// WithQueryLabels specifies the labels of the query ( e.g. map[string]string{"team": "audit"} ).
+// To label the query, you need to pass the returned context as an argument to ExecContext, QueryContext or PrepareContext.
+// The labels are merged with the ones specified by the parent context, and overwrite the ones set by SET @@query_label with the same key.
+// For multiple statements, the labels are applied to all of them.
+// They are reported to the query logger ( see SetQueryLogger ), the JOBS view ( see SetJobRecordingMode ) and QueryStats.
+func WithQueryLabels(ctx context.Context, labels map[string]string) context.Context {
+	return internal.WithQueryLabels(ctx, labels)
+}
+
+// WithQueryPriority specifies the priority of the query. Either of QueryPriorityInteractive ( default ) or QueryPriorityBatch can be specified.
+// The priority doesn't change how the query runs, and is reported in the same way as the labels.
+func WithQueryPriority(ctx context.Context, priority string) context.Context {
+	return internal.WithQueryPriority(ctx, priority)
+}
+
+// WithQueryTimeout specifies the timeout of the Exec/Query call. The call fails with the context error if the timeout elapses.
+// For queries, the timeout includes the time spent reading the rows until Close.
+// For prepared statements, the timeout is applied to PrepareContext because the executions don't receive the context.
+func WithQueryTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return internal.WithQueryTimeout(ctx, timeout)
+}
@@ -0,0 +1,54 @@
+package zetasqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	internal "github.com/goccy/go-zetasqlite/internal"
+)
+
+type (
+	StatementDiagnostics = internal.StatementDiagnostics
+	DiagnosticsStatus    = internal.DiagnosticsStatus
+)
+
+const (
+	DiagnosticsStatusOK            = internal.DiagnosticsStatusOK
+	DiagnosticsStatusParseError    = internal.DiagnosticsStatusParseError
+	DiagnosticsStatusAnalysisError = internal.DiagnosticsStatusAnalysisError
+)
+
+// AnalyzeAll analyzes all statements in the script without executing them, and returns the diagnostics of each statement.
+// Unlike executing the script, the analysis continues after the failed statement,
+// so all errors in the script are reported at once. The tables and functions defined by the preceding statements
+// of the script are visible to the subsequent statements, but they are not created in the database.
+func AnalyzeAll(ctx context.Context, db *sql.DB, script string) []StatementDiagnostics {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return newFailedDiagnostics(fmt.Errorf("failed to get connection: %w", err))
+	}
+	defer conn.Close()
+
+	var diagnostics []StatementDiagnostics
+	if err := conn.Raw(func(c interface{}) error {
+		zetasqliteConn, ok := c.(*ZetaSQLiteConn)
+		if !ok {
+			return fmt.Errorf("zetasqlite: sql.DB must be an instance created using the zetasqlite database driver")
+		}
+		diagnostics = zetasqliteConn.AnalyzeAll(ctx, script)
+		return nil
+	}); err != nil {
+		return newFailedDiagnostics(err)
+	}
+	return diagnostics
+}
+
+func newFailedDiagnostics(err error) []StatementDiagnostics {
+	return []StatementDiagnostics{{
+		Line:   1,
+		Column: 1,
+		Status: DiagnosticsStatusAnalysisError,
+		Error:  err.Error(),
+	}}
+}
@@ -5,11 +5,13 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/mattn/go-sqlite3"
 
 	internal "github.com/goccy/go-zetasqlite/internal"
+	"github.com/goccy/go-zetasqlite/transpiler"
 )
 
 var (
@@ -18,6 +20,22 @@ var (
 	_ driver.Tx     = &ZetaSQLiteTx{}
 )
 
+// ErrConcurrentWrite is returned when the statement cannot be executed because the database is locked
+// by another connection even after waiting and retrying. It can be checked by errors.Is.
+var ErrConcurrentWrite = internal.ErrConcurrentWrite
+
+// ErrResourcesExceeded is matched with ResourcesExceededError by errors.Is.
+var ErrResourcesExceeded = internal.ErrResourcesExceeded
+
+// ResourcesExceededError is returned when the query exceeds the limit set by SetMaxResultRows or SetMaxBufferedValues.
+// It has the kind of the exceeded resource and the limit, and can be retrieved by errors.As.
+type ResourcesExceededError = internal.ResourcesExceededError
+
+// SQLiteError is returned when SQLite fails to execute the statement translated from the query.
+// It has the original statement, the translated statement of SQLite, the names of the bound parameters and the index of the statement in the script,
+// and can be retrieved by errors.As.
+type SQLiteError = internal.SQLiteError
+
 var (
 	nameToCatalogMap = map[string]*internal.Catalog{}
 	nameToDBMap      = map[string]*sql.DB{}
@@ -28,7 +46,7 @@ func init() {
 	sql.Register("zetasqlite", &ZetaSQLiteDriver{})
 	sql.Register("zetasqlite_sqlite3", &sqlite3.SQLiteDriver{
 		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
-			if err := internal.RegisterFunctions(conn); err != nil {
+			if err := transpiler.RegisterFunctions(conn); err != nil {
 				return err
 			}
 			conn.SetLimit(sqlite3.SQLITE_LIMIT_VARIABLE_NUMBER, -1)
@@ -44,7 +62,7 @@ func newDBAndCatalog(name string) (*sql.DB, *internal.Catalog, error) {
 	if exists {
 		return db, nameToCatalogMap[name], nil
 	}
-	db, err := sql.Open("zetasqlite_sqlite3", name)
+	db, err := sql.Open("zetasqlite_sqlite3", withDefaultDSNParams(name))
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to open database by %s: %w", name, err)
 	}
@@ -54,6 +72,55 @@ func newDBAndCatalog(name string) (*sql.DB, *internal.Catalog, error) {
 	return db, catalog, nil
 }
 
+// defaultDSNParams are the parameters of go-sqlite3 applied to the file-backed database unless specified.
+// WAL mode allows reading while another connection writes, the busy timeout makes the connection wait for the lock,
+// and the transaction acquires the write lock at the beginning so that concurrent transactions are serialized
+// instead of failing when they upgrade the read lock.
+var defaultDSNParams = []struct {
+	names []string
+	value string
+}{
+	{names: []string{"_journal_mode", "_journal"}, value: "WAL"},
+	{names: []string{"_busy_timeout", "_timeout"}, value: "5000"},
+	{names: []string{"_txlock"}, value: "immediate"},
+}
+
+func withDefaultDSNParams(name string) string {
+	if isMemoryDSN(name) {
+		return name
+	}
+	dsn, query, _ := strings.Cut(name, "?")
+	var params []string
+	if query != "" {
+		params = strings.Split(query, "&")
+	}
+	specified := map[string]struct{}{}
+	for _, param := range params {
+		key, _, _ := strings.Cut(param, "=")
+		specified[key] = struct{}{}
+	}
+	for _, param := range defaultDSNParams {
+		var exists bool
+		for _, name := range param.names {
+			if _, found := specified[name]; found {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			params = append(params, fmt.Sprintf("%s=%s", param.names[0], param.value))
+		}
+	}
+	return fmt.Sprintf("%s?%s", dsn, strings.Join(params, "&"))
+}
+
+func isMemoryDSN(name string) bool {
+	return name == "" ||
+		strings.HasPrefix(name, ":memory:") ||
+		strings.HasPrefix(name, "file::memory:") ||
+		strings.Contains(name, "mode=memory")
+}
+
 type ZetaSQLiteDriver struct {
 	ConnectHook func(*ZetaSQLiteConn) error
 }
@@ -104,6 +171,138 @@ func (c *ZetaSQLiteConn) SetExplainMode(enabled bool) {
 	c.analyzer.SetExplainMode(enabled)
 }
 
+// SetDeterministicOutputOrder when enabled, the rows of a query without ORDER BY clause are sorted by all output columns.
+// This is useful for tests that compare the query results because the order of the rows is not guaranteed otherwise.
+// Queries having ORDER BY clause are not affected. Disabled by default.
+func (c *ZetaSQLiteConn) SetDeterministicOutputOrder(enabled bool) {
+	c.analyzer.SetDeterministicOutputOrder(enabled)
+}
+
+// SetPrettyFormatMode when enabled, the SQLite queries translated from queries and DML statements are indented
+// and normalized ( see SetExplainMode to get them ). The results are the same as the disabled mode. Disabled by default.
+func (c *ZetaSQLiteConn) SetPrettyFormatMode(enabled bool) {
+	c.analyzer.SetPrettyFormatMode(enabled)
+}
+
+// SetAnalysisCacheMode when enabled, the results of the analysis are reused for the same query text
+// until the catalog is changed by any DDL. This reduces the cost of the analysis of the repeated queries.
+// The cache holds the recently used statements of the connection. Enabled by default.
+func (c *ZetaSQLiteConn) SetAnalysisCacheMode(enabled bool) {
+	c.analyzer.SetAnalysisCacheMode(enabled)
+}
+
+// SetAutoRegisterNativeTableMode when enabled, a table that is not found in the catalog is looked up from the SQLite database,
+// and if it exists, it is registered with types inferred from the column affinities
+// ( INTEGER: INT64, REAL and NUMERIC: FLOAT64, TEXT: STRING, BLOB: BYTES ).
+// This makes it possible to query tables created by other tools. The lookup uses the table name as is, without name path.
+// The setting is shared by all connections to the same database. Disabled by default.
+func (c *ZetaSQLiteConn) SetAutoRegisterNativeTableMode(enabled bool) {
+	c.analyzer.SetAutoRegisterNativeTableMode(enabled)
+}
+
+// SetTableExpirationMode when enabled, a table whose expiration_timestamp option has passed is treated as not found.
+// The setting is shared by all connections to the same database. Disabled by default.
+func (c *ZetaSQLiteConn) SetTableExpirationMode(enabled bool) {
+	c.analyzer.SetTableExpirationMode(enabled)
+}
+
+// SetRowAccessPolicyMode when enabled, the rows of the table having row access policies are filtered by
+// the predicates of the policies granted to the session user ( see SetSessionUser ).
+// If no policy is granted to the session user, no rows are visible. Disabled by default.
+func (c *ZetaSQLiteConn) SetRowAccessPolicyMode(enabled bool) {
+	c.analyzer.SetRowAccessPolicyMode(enabled)
+}
+
+// SetSessionUser specifies the user to check the grantees of row access policies.
+// Either of the grantee format ( e.g. "user:alice@example.com" ) or the email address can be specified.
+func (c *ZetaSQLiteConn) SetSessionUser(user string) {
+	c.analyzer.SetSessionUser(user)
+}
+
+// SetTimeZone specifies the default time zone of the connection ( e.g. "America/Los_Angeles" or "+09:00" ).
+// It is used by CURRENT_DATE/DATETIME/TIME, the casts between STRING and TIMESTAMP and the timestamp functions
+// such as FORMAT_TIMESTAMP and EXTRACT when the time zone is omitted. It can also be set by SET @@time_zone = "zone".
+// The empty zone resets the default time zone to UTC.
+func (c *ZetaSQLiteConn) SetTimeZone(zone string) error {
+	return c.analyzer.SetTimeZone(zone)
+}
+
+// SetErrorQueryTextMode when disabled, the original and the translated SQL texts are not attached to SQLiteError.
+// The names of the parameters and the index of the statement are still attached. Enabled by default. See also WithErrorQueryText.
+func (c *ZetaSQLiteConn) SetErrorQueryTextMode(enabled bool) {
+	c.analyzer.SetErrorQueryTextMode(enabled)
+}
+
+// SetLanguageFeatures replaces the language features of ZetaSQL enabled by the analysis of the connection ( DefaultLanguageFeatures by default ).
+// The syntax gated by the disabled features is rejected at analysis time. The analysis cache of the connection is cleared.
+// See also WithLanguageFeatures and WithoutLanguageFeatures.
+func (c *ZetaSQLiteConn) SetLanguageFeatures(features []LanguageFeature) error {
+	return c.analyzer.SetLanguageFeatures(features)
+}
+
+// LanguageFeatures returns the language features enabled by the analysis of the connection.
+func (c *ZetaSQLiteConn) LanguageFeatures() []LanguageFeature {
+	return c.analyzer.LanguageFeatures()
+}
+
+// SetMaxResultRows specifies the maximum number of the rows returned by a query.
+// Reading the next row beyond the limit fails with ResourcesExceededError. Zero or less means unlimited ( default ).
+// See also WithMaxResultRows.
+func (c *ZetaSQLiteConn) SetMaxResultRows(num int64) {
+	c.analyzer.SetMaxResultRows(num)
+}
+
+// SetMaxBufferedValues specifies the maximum number of the values buffered by each aggregate or analytic function call
+// ( e.g. the elements of ARRAY_AGG and the rows of the window partition ). The query exceeding the limit fails with ResourcesExceededError
+// instead of growing the memory. Zero or less means unlimited ( default ). See also WithMaxBufferedValues.
+func (c *ZetaSQLiteConn) SetMaxBufferedValues(num int64) {
+	c.analyzer.SetMaxBufferedValues(num)
+}
+
+// SetReadOnlyMode when enabled, only a single query statement is accepted and the other statements are rejected
+// by ReadOnlyError at analysis time. InsertRows and RestoreSnapshot are also rejected. See also WithReadOnly.
+func (c *ZetaSQLiteConn) SetReadOnlyMode(enabled bool) {
+	c.analyzer.SetReadOnlyMode(enabled)
+}
+
+// SetJobRecordingMode when enabled, the statements executed by each Exec/Query call are recorded as a query job,
+// and listed by INFORMATION_SCHEMA.JOBS and INFORMATION_SCHEMA.JOBS_BY_PROJECT views ( e.g. `region-us`.INFORMATION_SCHEMA.JOBS ).
+// The job has the query text, the statement type ( SCRIPT for multiple statements ), the referenced tables,
+// the session user ( see SetSessionUser ), the labels set by SET @@query_label = "key:value,..." and WithQueryLabels,
+// and the priority specified by WithQueryPriority.
+// The first element of the name path is used as the project of the job. The prepared statements are not recorded.
+// The setting is shared by all connections to the same database. Disabled by default.
+func (c *ZetaSQLiteConn) SetJobRecordingMode(enabled bool) {
+	c.analyzer.SetJobRecordingMode(enabled)
+}
+
+// SetQueryLogger specifies the hook called after each Exec/Query call with the query, the labels, the priority and the statistics.
+// The labels are the ones set by SET @@query_label = "key:value,..." overwritten by the ones specified by WithQueryLabels.
+// It is also called after each execution of the prepared statements with the labels and the priority specified by the context of PrepareContext.
+func (c *ZetaSQLiteConn) SetQueryLogger(logger QueryLogger) {
+	c.analyzer.SetQueryLogger(logger)
+}
+
+// SetMaxRecordedJobs specifies the maximum number of the recorded jobs ( default 1000 ). The oldest jobs are discarded first.
+// If zero or less is specified, all jobs are kept. The setting is shared by all connections to the same database.
+func (c *ZetaSQLiteConn) SetMaxRecordedJobs(num int) {
+	c.analyzer.SetMaxRecordedJobs(num)
+}
+
+// TableSpec returns the table spec including the values specified by OPTIONS(...) clause.
+// The name path set as prefix is applied to the specified path.
+func (c *ZetaSQLiteConn) TableSpec(path []string) (*TableSpec, error) {
+	return c.analyzer.TableSpec(path)
+}
+
+// SetModuleResolver set the resolver to get the source of the module imported by IMPORT MODULE statement.
+// The resolver receives the name path of the module ( e.g. []string{"my_lib"} for IMPORT MODULE my_lib )
+// and returns the source beginning with MODULE statement.
+// The public functions of the module are callable with the module name ( or alias ) as prefix until the end of the script.
+func (c *ZetaSQLiteConn) SetModuleResolver(resolver ModuleResolver) {
+	c.analyzer.SetModuleResolver(resolver)
+}
+
 // SetMaxNamePath specifies the maximum value of name path.
 // If the name path in the query is the maximum value, the name path set as prefix is not used.
 // Effective only when a value greater than zero is specified ( default zero ).
@@ -133,8 +332,66 @@ func (c *ZetaSQLiteConn) AddNamePath(path string) error {
 	return c.analyzer.AddNamePath(path)
 }
 
+// InsertRows inserts n rows generated by gen into the table in a single transaction.
+// gen returns the values of the i-th row ordered by the columns of the table.
+// The rows are inserted by batched prepared statements without analyzing INSERT statements,
+// so this is much faster than executing INSERT statements to prepare a large number of rows.
+// If the connection is in a transaction, the rows are inserted in it.
+func (c *ZetaSQLiteConn) InsertRows(ctx context.Context, table string, gen func(i int) []interface{}, n int) (e error) {
+	tx := c.tx
+	if tx == nil {
+		newTx, err := c.conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer func() {
+			if e != nil {
+				_ = newTx.Rollback()
+				return
+			}
+			if err := newTx.Commit(); err != nil {
+				e = fmt.Errorf("failed to commit: %w", err)
+			}
+		}()
+		tx = newTx
+	}
+	conn := internal.NewConn(c.conn, tx)
+	return c.analyzer.InsertRows(ctx, conn, strings.Split(table, "."), gen, n)
+}
+
+// AnalyzeAll analyzes all statements in the script with the settings of the connection without executing them.
+// See also zetasqlite.AnalyzeAll.
+func (c *ZetaSQLiteConn) AnalyzeAll(ctx context.Context, script string) []StatementDiagnostics {
+	return c.analyzer.AnalyzeAll(ctx, internal.NewConn(c.conn, c.tx), script)
+}
+
+// EvaluateExpr evaluates the standalone expression with the settings of the connection. See also zetasqlite.EvaluateExpr.
+func (c *ZetaSQLiteConn) EvaluateExpr(ctx context.Context, expr string, columns map[string]interface{}) (Value, error) {
+	return c.analyzer.EvaluateExpr(ctx, internal.NewConn(c.conn, c.tx), expr, columns)
+}
+
+// TakeSnapshot copies the database contents and the catalog. See also zetasqlite.Snapshot.
+func (c *ZetaSQLiteConn) TakeSnapshot(ctx context.Context) (*DatabaseSnapshot, error) {
+	return c.analyzer.TakeSnapshot(ctx, internal.NewConn(c.conn, c.tx))
+}
+
+// RestoreSnapshot replaces the database contents and the catalog with the snapshot. See also zetasqlite.RestoreSnapshot.
+func (c *ZetaSQLiteConn) RestoreSnapshot(ctx context.Context, snapshot *DatabaseSnapshot) error {
+	return c.analyzer.RestoreSnapshot(ctx, internal.NewConn(c.conn, c.tx), snapshot)
+}
+
+// Catalog returns the datasets, tables, views and functions visible from the connection. See also zetasqlite.ListCatalog.
+func (c *ZetaSQLiteConn) Catalog(ctx context.Context) (*CatalogListing, error) {
+	return c.analyzer.Catalog(ctx, internal.NewConn(c.conn, c.tx))
+}
+
+// RefreshCatalog reloads the catalog from the database. See also zetasqlite.RefreshCatalog.
+func (c *ZetaSQLiteConn) RefreshCatalog(ctx context.Context) error {
+	return c.analyzer.RefreshCatalog(ctx, internal.NewConn(c.conn, c.tx))
+}
+
 func (s *ZetaSQLiteConn) CheckNamedValue(value *driver.NamedValue) error {
-	return nil
+	return internal.CheckNamedValue(value)
 }
 
 func (c *ZetaSQLiteConn) Prepare(query string) (driver.Stmt, error) {
@@ -160,11 +417,25 @@ func (c *ZetaSQLiteConn) PrepareContext(ctx context.Context, query string) (driv
 		}
 		stmt = s
 	}
-	return stmt, nil
+	if stmt == nil {
+		return nil, nil
+	}
+	return &zetasqliteStmt{
+		Stmt:     stmt,
+		analyzer: c.analyzer,
+		query:    query,
+		metadata: internal.QueryMetadataFromContext(ctx),
+	}, nil
 }
 
 func (c *ZetaSQLiteConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (r driver.Result, e error) {
+	ctx, cancel := internal.WithQueryDeadline(ctx)
+	defer cancel()
 	conn := internal.NewConn(c.conn, c.tx)
+	defer conn.FinishStats()
+	defer func() {
+		c.analyzer.RecordJob(ctx, conn, query, e)
+	}()
 	actionFuncs, err := c.analyzer.Analyze(ctx, conn, query, args)
 	if err != nil {
 		return nil, err
@@ -198,7 +469,11 @@ func (c *ZetaSQLiteConn) ExecContext(ctx context.Context, query string, args []d
 }
 
 func (c *ZetaSQLiteConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (r driver.Rows, e error) {
+	ctx, cancel := internal.WithQueryDeadline(ctx)
 	conn := internal.NewConn(c.conn, c.tx)
+	defer func() {
+		c.analyzer.RecordJob(ctx, conn, query, e)
+	}()
 	actionFuncs, err := c.analyzer.Analyze(ctx, conn, query, args)
 	if err != nil {
 		return nil, err
@@ -207,6 +482,14 @@ func (c *ZetaSQLiteConn) QueryContext(ctx context.Context, query string, args []
 		actions []internal.StmtAction
 		rows    *internal.Rows
 	)
+	defer func() {
+		if rows == nil || e != nil {
+			cancel()
+			return
+		}
+		// the deadline of the query is kept until the rows are closed.
+		rows.SetCancel(cancel)
+	}()
 	defer func() {
 		if rows != nil {
 			// If we call cleanup action at the end of QueryContext function,
@@ -231,6 +514,40 @@ func (c *ZetaSQLiteConn) QueryContext(ctx context.Context, query string, args []
 	return rows, nil
 }
 
+// zetasqliteStmt passes the log of each execution of the prepared statement to the query logger.
+// Since the executions of the prepared statements don't receive the context,
+// the labels and the priority specified by the context of PrepareContext are used.
+type zetasqliteStmt struct {
+	driver.Stmt
+	analyzer *internal.Analyzer
+	query    string
+	metadata *internal.QueryMetadata
+}
+
+func (s *zetasqliteStmt) CheckNamedValue(value *driver.NamedValue) error {
+	return internal.CheckNamedValue(value)
+}
+
+func (s *zetasqliteStmt) Exec(args []driver.Value) (driver.Result, error) {
+	result, err := s.Stmt.Exec(args)
+	s.log(err)
+	return result, err
+}
+
+func (s *zetasqliteStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, err := s.Stmt.Query(args)
+	s.log(err)
+	return rows, err
+}
+
+func (s *zetasqliteStmt) log(err error) {
+	ctx := context.Background()
+	if s.metadata != nil {
+		ctx = internal.WithQueryMetadata(ctx, s.metadata)
+	}
+	s.analyzer.LogQuery(ctx, s.query, nil, err)
+}
+
 func (c *ZetaSQLiteConn) Close() error {
 	return c.conn.Close()
 }
@@ -241,7 +558,7 @@ func (c *ZetaSQLiteConn) BeginTx(ctx context.Context, opts driver.TxOptions) (dr
 		ReadOnly:  opts.ReadOnly,
 	})
 	if err != nil {
-		return nil, err
+		return nil, internal.TranslateLockError(err)
 	}
 	c.tx = tx
 	return &ZetaSQLiteTx{
@@ -253,7 +570,7 @@ func (c *ZetaSQLiteConn) BeginTx(ctx context.Context, opts driver.TxOptions) (dr
 func (c *ZetaSQLiteConn) Begin() (driver.Tx, error) {
 	tx, err := c.conn.BeginTx(context.Background(), nil)
 	if err != nil {
-		return nil, err
+		return nil, internal.TranslateLockError(err)
 	}
 	c.tx = tx
 	return &ZetaSQLiteTx{
@@ -271,7 +588,7 @@ func (tx *ZetaSQLiteTx) Commit() error {
 	defer func() {
 		tx.conn.tx = nil
 	}()
-	return tx.tx.Commit()
+	return internal.TranslateLockError(tx.tx.Commit())
 }
 
 func (tx *ZetaSQLiteTx) Rollback() error {
@@ -3,8 +3,20 @@ package zetasqlite_test
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/goccy/go-zetasql"
 	"github.com/google/go-cmp/cmp"
 
 	zetasqlite "github.com/goccy/go-zetasqlite"
@@ -186,6 +198,138 @@ CREATE TABLE IF NOT EXISTS Singers (
 	})
 }
 
+func TestQueryStats(t *testing.T) {
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`
+CREATE TABLE Singers (SingerId INT64, FirstName STRING, LastName STRING);
+CREATE TABLE Albums (SingerId INT64, AlbumTitle STRING);
+INSERT Singers (SingerId, FirstName, LastName) VALUES (1, 'John', 'Titor'), (2, 'Alice', 'Lee');
+INSERT Albums (SingerId, AlbumTitle) VALUES (1, 'Total Junk');
+`); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.Query(`
+SELECT FirstName, AlbumTitle FROM Singers JOIN Albums USING (SingerId)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := zetasqlite.QueryStatsFromRows(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.RowsReturned != 1 {
+		t.Fatalf("failed to get returned rows: %d", stats.RowsReturned)
+	}
+	if stats.Duration <= 0 {
+		t.Fatalf("failed to get duration: %s", stats.Duration)
+	}
+	if diff := cmp.Diff([]*zetasqlite.ReferencedTable{
+		{Name: "Singers", Columns: []string{"FirstName", "SingerId"}, RowsRead: 2},
+		{Name: "Albums", Columns: []string{"AlbumTitle", "SingerId"}, RowsRead: 1},
+	}, stats.ReferencedTables); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	result, err := db.Exec(`DELETE FROM Albums WHERE SingerId = 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resultStats, err := zetasqlite.QueryStatsFromResult(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resultStats.ReferencedTables) != 1 || resultStats.ReferencedTables[0].Name != "Albums" {
+		t.Fatalf("failed to get referenced tables: %v", resultStats.ReferencedTables)
+	}
+}
+
+func TestMergeStats(t *testing.T) {
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`
+CREATE TABLE MergeStatsInventory (product STRING, quantity INT64);
+CREATE TABLE MergeStatsArrivals (product STRING, quantity INT64);
+INSERT MergeStatsInventory (product, quantity) VALUES ('dishwasher', 10), ('dryer', 20), ('oven', 30);
+INSERT MergeStatsArrivals (product, quantity) VALUES ('dishwasher', 5), ('dryer', 7), ('microwave', 1);
+`); err != nil {
+		t.Fatal(err)
+	}
+	result, err := db.Exec(`
+MERGE MergeStatsInventory AS I
+USING MergeStatsArrivals AS A
+ON I.product = A.product
+WHEN NOT MATCHED BY TARGET THEN
+ INSERT (product, quantity) VALUES (product, quantity)
+WHEN MATCHED THEN
+ UPDATE SET quantity = I.quantity + A.quantity
+WHEN NOT MATCHED BY SOURCE THEN
+ DELETE`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected != 4 {
+		t.Fatalf("failed to get affected rows: %d", affected)
+	}
+	stats, err := zetasqlite.QueryStatsFromResult(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&zetasqlite.DMLStats{
+		InsertedRowCount: 1,
+		UpdatedRowCount:  2,
+		DeletedRowCount:  1,
+	}, stats.DMLStats); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	if stats.DMLStats.AffectedRowCount() != affected {
+		t.Fatalf("failed to get affected rows from stats: %d", stats.DMLStats.AffectedRowCount())
+	}
+
+	rows, err := db.Query(`SELECT product, quantity FROM MergeStatsInventory ORDER BY product`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var (
+			product  string
+			quantity int64
+		)
+		if err := rows.Scan(&product, &quantity); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%s:%d", product, quantity))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"dishwasher:15", "dryer:27", "microwave:1"}, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
 func TestPreparedStatements(t *testing.T) {
 	t.Run("prepared select", func(t *testing.T) {
 		db, err := sql.Open("zetasqlite", ":memory:")
@@ -261,4 +405,2761 @@ CREATE TABLE IF NOT EXISTS Singers (
 			t.Fatal("expected no rows; expected one row")
 		}
 	})
+	t.Run("prepared positional parameters", func(t *testing.T) {
+		db, err := sql.Open("zetasqlite", ":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		if _, err := db.Exec(`CREATE TABLE Items (ItemId INT64 NOT NULL, Name STRING)`); err != nil {
+			t.Fatal(err)
+		}
+		insertStmt, err := db.Prepare("INSERT `Items` (`ItemId`, `Name`) VALUES (?, ?)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer insertStmt.Close()
+		for _, item := range []struct {
+			id   int64
+			name string
+		}{{1, "apple"}, {2, "banana"}, {3, "cherry"}} {
+			if _, err := insertStmt.Exec(item.id, item.name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		queryStmt, err := db.Prepare("SELECT CONCAT(Name, ?) FROM Items WHERE ItemId > ? ORDER BY ItemId")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer queryStmt.Close()
+		for _, test := range []struct {
+			suffix   string
+			minID    int64
+			expected []string
+		}{
+			{suffix: "!", minID: 0, expected: []string{"apple!", "banana!", "cherry!"}},
+			{suffix: "?", minID: 1, expected: []string{"banana?", "cherry?"}},
+			{suffix: "", minID: 3, expected: nil},
+		} {
+			rows, err := queryStmt.Query(test.suffix, test.minID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for rows.Next() {
+				var name string
+				if err := rows.Scan(&name); err != nil {
+					t.Fatal(err)
+				}
+				names = append(names, name)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+			rows.Close()
+			if diff := cmp.Diff(test.expected, names); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		}
+	})
+}
+
+func TestDeterministicOutputOrder(t *testing.T) {
+	sql.Register("zetasqlite-deterministic", &zetasqlite.ZetaSQLiteDriver{
+		ConnectHook: func(conn *zetasqlite.ZetaSQLiteConn) error {
+			conn.SetDeterministicOutputOrder(true)
+			return nil
+		},
+	})
+	db, err := sql.Open("zetasqlite-deterministic", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, test := range []struct {
+		name     string
+		query    string
+		expected []int64
+	}{
+		{
+			name:     "without order by",
+			query:    "SELECT * FROM UNNEST([3, 1, 2])",
+			expected: []int64{1, 2, 3},
+		},
+		{
+			name:     "with order by",
+			query:    "SELECT * FROM UNNEST([3, 1, 2]) AS v ORDER BY v DESC",
+			expected: []int64{3, 2, 1},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			rows, err := db.Query(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			var got []int64
+			for rows.Next() {
+				var v int64
+				if err := rows.Scan(&v); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, v)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.expected, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAutoRegisterNativeTable(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "native.db")
+	nativeDB, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nativeDB.Close()
+	if _, err := nativeDB.Exec(`
+CREATE TABLE items (
+  id INTEGER NOT NULL,
+  name TEXT,
+  price REAL,
+  data BLOB
+)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nativeDB.Exec(
+		`INSERT INTO items (id, name, price, data) VALUES (1, 'apple', 1.5, x'0102'), (2, 'banana', 2.25, NULL)`,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	sql.Register("zetasqlite-native-table", &zetasqlite.ZetaSQLiteDriver{
+		ConnectHook: func(conn *zetasqlite.ZetaSQLiteConn) error {
+			conn.SetAutoRegisterNativeTableMode(true)
+			return nil
+		},
+	})
+	db, err := sql.Open("zetasqlite-native-table", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`INSERT INTO items (id, name, price) VALUES (3, 'cherry', 3.0)`); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.Query(`SELECT id, UPPER(name), price * 2, data FROM items WHERE price > 1.0 ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	type item struct {
+		ID    int64
+		Name  string
+		Price float64
+		Data  []byte
+	}
+	var got []item
+	for rows.Next() {
+		var v item
+		if err := rows.Scan(&v.ID, &v.Name, &v.Price, &v.Data); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []item{
+		{ID: 1, Name: "APPLE", Price: 3, Data: []byte{1, 2}},
+		{ID: 2, Name: "BANANA", Price: 4.5},
+		{ID: 3, Name: "CHERRY", Price: 6},
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestTableOptions(t *testing.T) {
+	sql.Register("zetasqlite-table-options", &zetasqlite.ZetaSQLiteDriver{
+		ConnectHook: func(conn *zetasqlite.ZetaSQLiteConn) error {
+			conn.SetTableExpirationMode(true)
+			return nil
+		},
+	})
+	db, err := sql.Open("zetasqlite-table-options", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, query := range []string{
+		`CREATE TABLE items (id INT64) OPTIONS(description='items table', labels=[('team', 'data')])`,
+		`CREATE TABLE expired_items (id INT64) OPTIONS(expiration_timestamp=TIMESTAMP '2000-01-01 00:00:00+00')`,
+		`ALTER TABLE items SET OPTIONS(description='updated items table')`,
+	} {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := conn.QueryContext(ctx, `SELECT * FROM expired_items`); err == nil {
+		t.Fatal("expected error for expired table")
+	}
+
+	rows, err := conn.QueryContext(
+		ctx,
+		`SELECT table_name, option_name, option_type, option_value FROM INFORMATION_SCHEMA.TABLE_OPTIONS ORDER BY table_name, option_name`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got [][]string
+	for rows.Next() {
+		var tableName, optionName, optionType, optionValue string
+		if err := rows.Scan(&tableName, &optionName, &optionType, &optionValue); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, []string{tableName, optionName, optionType, optionValue})
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{
+		{"expired_items", "expiration_timestamp", "TIMESTAMP", `TIMESTAMP "2000-01-01 00:00:00+00"`},
+		{"items", "description", "STRING", `"updated items table"`},
+		{"items", "labels", "ARRAY<STRUCT<STRING, STRING>>", `[("team", "data")]`},
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	if err := conn.Raw(func(c interface{}) error {
+		spec, err := c.(*zetasqlite.ZetaSQLiteConn).TableSpec([]string{"items"})
+		if err != nil {
+			return err
+		}
+		if spec.Description != "updated items table" {
+			t.Errorf("unexpected description %q", spec.Description)
+		}
+		if diff := cmp.Diff(map[string]string{"team": "data"}, spec.Labels); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportModule(t *testing.T) {
+	modules := map[string]string{
+		"my_lib": `
+MODULE my_lib;
+IMPORT MODULE math_lib AS m;
+CREATE PRIVATE FUNCTION add_tax(price FLOAT64) AS (price * 1.1);
+CREATE PUBLIC FUNCTION total(price FLOAT64, quantity INT64) AS (m.round2(add_tax(price) * quantity));
+`,
+		"math_lib": `
+MODULE math_lib;
+CREATE PUBLIC FUNCTION round2(v FLOAT64) AS (ROUND(v, 2));
+`,
+		"cycle_a": `
+MODULE cycle_a;
+IMPORT MODULE cycle_b;
+`,
+		"cycle_b": `
+MODULE cycle_b;
+IMPORT MODULE cycle_a;
+`,
+	}
+	sql.Register("zetasqlite-import-module", &zetasqlite.ZetaSQLiteDriver{
+		ConnectHook: func(conn *zetasqlite.ZetaSQLiteConn) error {
+			conn.SetModuleResolver(func(namePath []string) (string, error) {
+				source, exists := modules[strings.Join(namePath, ".")]
+				if !exists {
+					return "", fmt.Errorf("module %v is not found", namePath)
+				}
+				return source, nil
+			})
+			return nil
+		},
+	})
+	db, err := sql.Open("zetasqlite-import-module", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	t.Run("call public function", func(t *testing.T) {
+		var total float64
+		if err := db.QueryRow(`IMPORT MODULE my_lib; SELECT my_lib.total(10, 3)`).Scan(&total); err != nil {
+			t.Fatal(err)
+		}
+		if total != 33 {
+			t.Fatalf("unexpected total %v", total)
+		}
+	})
+	t.Run("private function is not exported", func(t *testing.T) {
+		if _, err := db.Query(`IMPORT MODULE my_lib; SELECT my_lib.add_tax(10)`); err == nil {
+			t.Fatal("expected error for private function")
+		}
+	})
+	t.Run("alias", func(t *testing.T) {
+		var v float64
+		if err := db.QueryRow(`IMPORT MODULE math_lib AS m; SELECT m.round2(1.234)`).Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		if v != 1.23 {
+			t.Fatalf("unexpected value %v", v)
+		}
+	})
+	t.Run("circular import", func(t *testing.T) {
+		_, err := db.Exec(`IMPORT MODULE cycle_a`)
+		if err == nil {
+			t.Fatal("expected error for circular import")
+		}
+		if !strings.Contains(err.Error(), "circular module import detected: cycle_a -> cycle_b -> cycle_a") {
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+}
+
+func TestAccessControl(t *testing.T) {
+	sql.Register("zetasqlite-access-control", &zetasqlite.ZetaSQLiteDriver{
+		ConnectHook: func(conn *zetasqlite.ZetaSQLiteConn) error {
+			conn.SetRowAccessPolicyMode(true)
+			conn.SetSessionUser("alice@example.com")
+			return nil
+		},
+	})
+	db, err := sql.Open("zetasqlite-access-control", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, query := range []string{
+		`CREATE TABLE sales (region STRING, amount INT64)`,
+		`INSERT INTO sales (region, amount) VALUES ('US', 10), ('EU', 20), ('JP', 30)`,
+		`GRANT SELECT, INSERT ON TABLE sales TO "user:alice@example.com", "user:bob@example.com"`,
+		`REVOKE INSERT ON TABLE sales FROM "user:bob@example.com"`,
+		`CREATE ROW ACCESS POLICY us_filter ON sales GRANT TO ("user:alice@example.com") FILTER USING (region = 'US')`,
+		`CREATE ROW ACCESS POLICY eu_filter ON sales GRANT TO ("user:bob@example.com") FILTER USING (region = 'EU')`,
+		`CREATE ROW ACCESS POLICY jp_filter ON sales GRANT TO ("user:bob@example.com") FILTER USING (region = 'JP')`,
+		`ALTER ROW ACCESS POLICY eu_filter ON sales GRANT TO ("user:alice@example.com")`,
+		`DROP ROW ACCESS POLICY jp_filter ON sales`,
+	} {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+
+	queryStrings := func(t *testing.T, query string) [][]string {
+		t.Helper()
+		rows, err := conn.QueryContext(ctx, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		columns, err := rows.Columns()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got [][]string
+		for rows.Next() {
+			values := make([]string, len(columns))
+			dest := make([]interface{}, len(columns))
+			for i := range values {
+				dest[i] = &values[i]
+			}
+			if err := rows.Scan(dest...); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, values)
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	t.Run("object privileges", func(t *testing.T) {
+		got := queryStrings(t, `SELECT object_name, object_type, privilege_type, grantee FROM INFORMATION_SCHEMA.OBJECT_PRIVILEGES ORDER BY privilege_type, grantee`)
+		expected := [][]string{
+			{"sales", "TABLE", "INSERT", "user:alice@example.com"},
+			{"sales", "TABLE", "SELECT", "user:alice@example.com"},
+			{"sales", "TABLE", "SELECT", "user:bob@example.com"},
+		}
+		if diff := cmp.Diff(expected, got); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+	})
+	t.Run("row access policies", func(t *testing.T) {
+		got := queryStrings(t, `SELECT table_name, row_access_policy_name, filter_predicate FROM INFORMATION_SCHEMA.ROW_ACCESS_POLICIES ORDER BY row_access_policy_name`)
+		expected := [][]string{
+			{"sales", "eu_filter", "region = 'EU'"},
+			{"sales", "us_filter", "region = 'US'"},
+		}
+		if diff := cmp.Diff(expected, got); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+	})
+	t.Run("enforce row access policies", func(t *testing.T) {
+		got := queryStrings(t, `SELECT region FROM sales ORDER BY region`)
+		expected := [][]string{{"EU"}, {"US"}}
+		if diff := cmp.Diff(expected, got); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+	})
+	t.Run("drop all row access policies", func(t *testing.T) {
+		if _, err := conn.ExecContext(ctx, `DROP ALL ROW ACCESS POLICIES ON sales`); err != nil {
+			t.Fatal(err)
+		}
+		got := queryStrings(t, `SELECT region FROM sales ORDER BY region`)
+		expected := [][]string{{"EU"}, {"JP"}, {"US"}}
+		if diff := cmp.Diff(expected, got); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestInsertValidation(t *testing.T) {
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE validated_items (id INT64 NOT NULL, name STRING, created_at TIMESTAMP)`); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name        string
+		query       string
+		args        []interface{}
+		expectedErr string
+	}{
+		{
+			name:        "null literal into not null column",
+			query:       `INSERT INTO validated_items (id, name) VALUES (NULL, 'a')`,
+			expectedErr: "required field id cannot be null",
+		},
+		{
+			name:        "null parameter into not null column",
+			query:       `INSERT INTO validated_items (id, name) VALUES (?, ?)`,
+			args:        []interface{}{nil, "a"},
+			expectedErr: "required field id cannot be null",
+		},
+		{
+			name:        "string parameter into int64 column",
+			query:       `INSERT INTO validated_items (id, name) VALUES (@id, @name)`,
+			args:        []interface{}{sql.Named("id", "1"), sql.Named("name", "a")},
+			expectedErr: "value has type STRING which cannot be inserted into column id, which has type INT64",
+		},
+		{
+			name:        "int64 parameter into string column",
+			query:       `INSERT INTO validated_items (id, name) VALUES (?, ?)`,
+			args:        []interface{}{1, 2},
+			expectedErr: "value has type INT64 which cannot be inserted into column name, which has type STRING",
+		},
+		{
+			name:  "coercible parameters",
+			query: `INSERT INTO validated_items (id, name, created_at) VALUES (?, ?, ?)`,
+			args:  []interface{}{1, "a", "2022-01-01 00:00:00"},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			_, err := db.Exec(test.query, test.args...)
+			if test.expectedErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error %q", test.expectedErr)
+			}
+			if !strings.Contains(err.Error(), test.expectedErr) {
+				t.Fatalf("expected error %q but got %q", test.expectedErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestInsertRows(t *testing.T) {
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE generated_rows (id INT64 NOT NULL, name STRING, score FLOAT64)`); err != nil {
+		t.Fatal(err)
+	}
+	const rowNum = 1234
+	if err := conn.Raw(func(c interface{}) error {
+		return c.(*zetasqlite.ZetaSQLiteConn).InsertRows(ctx, "generated_rows", func(i int) []interface{} {
+			return []interface{}{i, fmt.Sprintf("name%d", i), float64(i) / 2}
+		}, rowNum)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		count int64
+		sum   int64
+		name  string
+	)
+	if err := conn.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*), SUM(id), MAX(IF(id = 10, name, NULL)) FROM generated_rows`,
+	).Scan(&count, &sum, &name); err != nil {
+		t.Fatal(err)
+	}
+	if count != rowNum || sum != rowNum*(rowNum-1)/2 || name != "name10" {
+		t.Fatalf("unexpected result: count = %d, sum = %d, name = %s", count, sum, name)
+	}
+
+	if err := conn.Raw(func(c interface{}) error {
+		return c.(*zetasqlite.ZetaSQLiteConn).InsertRows(ctx, "generated_rows", func(i int) []interface{} {
+			return []interface{}{nil, "invalid", 0.0}
+		}, 1)
+	}); err == nil || !strings.Contains(err.Error(), "required field id cannot be null") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestAnalyzeAll(t *testing.T) {
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	script := `CREATE TABLE diagnostics_table (id INT64, name STRING);
+SELECT id, missing1 FROM diagnostics_table;
+SELECT FROM diagnostics_table;
+INSERT INTO diagnostics_table (id, missing2) VALUES (1, 'a');
+SELECT id, name FROM diagnostics_table WHERE id = 1`
+	diagnostics := zetasqlite.AnalyzeAll(context.Background(), db, script)
+	if len(diagnostics) != 5 {
+		t.Fatalf("unexpected diagnostics length: %d", len(diagnostics))
+	}
+	expected := []struct {
+		status      zetasqlite.DiagnosticsStatus
+		line        int
+		errorLine   int
+		errorColumn int
+	}{
+		{status: zetasqlite.DiagnosticsStatusOK, line: 1},
+		{status: zetasqlite.DiagnosticsStatusAnalysisError, line: 2, errorLine: 2, errorColumn: 12},
+		{status: zetasqlite.DiagnosticsStatusParseError, line: 3, errorLine: 3},
+		{status: zetasqlite.DiagnosticsStatusAnalysisError, line: 4, errorLine: 4},
+		{status: zetasqlite.DiagnosticsStatusOK, line: 5},
+	}
+	for idx, exp := range expected {
+		d := diagnostics[idx]
+		if d.Index != idx || d.Status != exp.status || d.Line != exp.line {
+			t.Fatalf("unexpected diagnostics at %d: %+v", idx, d)
+		}
+		if d.ErrorLine != exp.errorLine || (exp.errorColumn != 0 && d.ErrorColumn != exp.errorColumn) {
+			t.Fatalf("unexpected error location at %d: %d:%d (%s)", idx, d.ErrorLine, d.ErrorColumn, d.Error)
+		}
+	}
+	last := diagnostics[4]
+	if len(last.ReferencedTables) != 1 || last.ReferencedTables[0].Name != "diagnostics_table" {
+		t.Fatalf("unexpected referenced tables: %+v", last.ReferencedTables)
+	}
+	if len(last.OutputColumns) != 2 ||
+		last.OutputColumns[0].Name != "id" || last.OutputColumns[0].Type.FormatType() != "INT64" ||
+		last.OutputColumns[1].Name != "name" || last.OutputColumns[1].Type.FormatType() != "STRING" {
+		t.Fatalf("unexpected output columns: %+v", last.OutputColumns)
+	}
+
+	// the statements are not executed.
+	if _, err := db.Exec("SELECT * FROM diagnostics_table"); err == nil {
+		t.Fatal("expected error because diagnostics_table is not created")
+	}
+}
+
+func TestEvaluateExpr(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, "CREATE FUNCTION evaluate_expr_double(x INT64) AS (x * 2)"); err != nil {
+		t.Fatal(err)
+	}
+	columns := map[string]interface{}{
+		"cost":    int64(10),
+		"enabled": true,
+		"name":    "alice",
+		"tags":    []string{"a", "b"},
+		"missing": nil,
+	}
+	for _, test := range []struct {
+		name     string
+		expr     string
+		expected interface{}
+	}{
+		{name: "arithmetic", expr: "cost * 2 + 1", expected: int64(21)},
+		{name: "predicate", expr: "enabled AND name = 'alice'", expected: true},
+		{name: "function", expr: "UPPER(name)", expected: "ALICE"},
+		{name: "array column", expr: "ARRAY_LENGTH(tags)", expected: int64(2)},
+		{name: "null column", expr: "IFNULL(missing, 3)", expected: int64(3)},
+		{name: "user defined function", expr: "evaluate_expr_double(cost)", expected: int64(20)},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			value, err := zetasqlite.EvaluateExpr(ctx, db, test.expr, columns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.expected, value.Interface()); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+	t.Run("unknown column", func(t *testing.T) {
+		if _, err := zetasqlite.EvaluateExpr(ctx, db, "unknown_column + 1", columns); err == nil {
+			t.Fatal("expected error for unknown column")
+		}
+	})
+}
+
+func TestDuplicateOutputColumnNames(t *testing.T) {
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`
+CREATE TABLE duplicate_output_column_table (a INT64, b STRING);
+INSERT INTO duplicate_output_column_table (a, b) VALUES (1, 'x');
+`); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.Query("SELECT a, a AS a, CAST(a + 1 AS STRING) AS a, b AS a FROM duplicate_output_column_table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"a", "a", "a", "a"}, columns); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	if !rows.Next() {
+		t.Fatal("expected a row")
+	}
+	var (
+		a1, a2 int64
+		a3, a4 string
+	)
+	if err := rows.Scan(&a1, &a2, &a3, &a4); err != nil {
+		t.Fatal(err)
+	}
+	if a1 != 1 || a2 != 1 || a3 != "2" || a4 != "x" {
+		t.Fatalf("unexpected values: %d, %d, %s, %s", a1, a2, a3, a4)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDotStarExpansion(t *testing.T) {
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`
+CREATE TABLE dot_star_left (id INT64, name STRING);
+CREATE TABLE dot_star_right (id INT64, score INT64);
+INSERT INTO dot_star_left (id, name) VALUES (1, 'alice'), (2, 'bob');
+INSERT INTO dot_star_right (id, score) VALUES (1, 10), (2, 20);
+`); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name            string
+		query           string
+		expectedColumns []string
+		expectedRows    [][]interface{}
+	}{
+		{
+			name:            "table star with join",
+			query:           "SELECT l.*, r.score FROM dot_star_left AS l JOIN dot_star_right AS r ON l.id = r.id ORDER BY l.id",
+			expectedColumns: []string{"id", "name", "score"},
+			expectedRows:    [][]interface{}{{int64(1), "alice", int64(10)}, {int64(2), "bob", int64(20)}},
+		},
+		{
+			name:            "table star with self join",
+			query:           "SELECT a.*, b.* FROM dot_star_left AS a JOIN dot_star_left AS b ON a.id < b.id",
+			expectedColumns: []string{"id", "name", "id", "name"},
+			expectedRows:    [][]interface{}{{int64(1), "alice", int64(2), "bob"}},
+		},
+		{
+			name:            "table star with except and replace",
+			query:           "SELECT l.* EXCEPT (id), r.* REPLACE (score * 2 AS score) FROM dot_star_left AS l JOIN dot_star_right AS r USING (id) ORDER BY r.id",
+			expectedColumns: []string{"name", "id", "score"},
+			expectedRows:    [][]interface{}{{"alice", int64(1), int64(20)}, {"bob", int64(2), int64(40)}},
+		},
+		{
+			name:            "struct star",
+			query:           "SELECT s.* FROM (SELECT STRUCT(1 AS a, 'x' AS b) AS s)",
+			expectedColumns: []string{"a", "b"},
+			expectedRows:    [][]interface{}{{int64(1), "x"}},
+		},
+		{
+			name:            "struct star with except and replace",
+			query:           "SELECT s.* EXCEPT (b) REPLACE (s.a + 1 AS a), s.c FROM (SELECT STRUCT(1 AS a, 'x' AS b, true AS c) AS s)",
+			expectedColumns: []string{"a", "c", "c"},
+			expectedRows:    [][]interface{}{{int64(2), true, true}},
+		},
+		{
+			name:            "struct star of expression",
+			query:           "SELECT (SELECT AS STRUCT id, name FROM dot_star_left WHERE id = 2).*",
+			expectedColumns: []string{"id", "name"},
+			expectedRows:    [][]interface{}{{int64(2), "bob"}},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			rows, err := db.Query(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			columns, err := rows.Columns()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.expectedColumns, columns); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+			var gotRows [][]interface{}
+			for rows.Next() {
+				row := make([]interface{}, len(columns))
+				args := make([]interface{}, len(columns))
+				for i := range row {
+					args[i] = &row[i]
+				}
+				if err := rows.Scan(args...); err != nil {
+					t.Fatal(err)
+				}
+				gotRows = append(gotRows, row)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.expectedRows, gotRows); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCreateOrReplaceAtomicity(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, `
+CREATE TABLE create_or_replace_table (id INT64, name STRING);
+INSERT INTO create_or_replace_table (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c');
+CREATE FUNCTION create_or_replace_func(x INT64) AS (x + 1);
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("table", func(t *testing.T) {
+		if _, err := db.ExecContext(ctx, `
+CREATE OR REPLACE TABLE create_or_replace_table AS
+SELECT IF(x = 100, ERROR('failed at 100th row'), x) AS id FROM UNNEST(GENERATE_ARRAY(1, 200)) AS x`,
+		); err == nil {
+			t.Fatal("expected error")
+		}
+		var (
+			count int64
+			names string
+		)
+		if err := db.QueryRowContext(
+			ctx,
+			"SELECT COUNT(*), STRING_AGG(name, ',' ORDER BY id) FROM create_or_replace_table",
+		).Scan(&count, &names); err != nil {
+			t.Fatal(err)
+		}
+		if count != 3 || names != "a,b,c" {
+			t.Fatalf("unexpected table contents: %d rows (%s)", count, names)
+		}
+	})
+	t.Run("function", func(t *testing.T) {
+		if _, err := db.ExecContext(ctx, "CREATE OR REPLACE FUNCTION create_or_replace_func(x INT64) AS (x + missing_column)"); err == nil {
+			t.Fatal("expected error")
+		}
+		var v int64
+		if err := db.QueryRowContext(ctx, "SELECT create_or_replace_func(1)").Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		if v != 2 {
+			t.Fatalf("unexpected function result %d", v)
+		}
+	})
+	t.Run("replace after failure", func(t *testing.T) {
+		if _, err := db.ExecContext(ctx, "CREATE OR REPLACE TABLE create_or_replace_table AS SELECT 10 AS id"); err != nil {
+			t.Fatal(err)
+		}
+		var id int64
+		if err := db.QueryRowContext(ctx, "SELECT id FROM create_or_replace_table").Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		if id != 10 {
+			t.Fatalf("unexpected id %d", id)
+		}
+	})
+}
+
+func TestCheckConstraint(t *testing.T) {
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`
+CREATE TABLE check_constraint_periods (
+  id INT64 NOT NULL,
+  period STRUCT<start_date DATE, end_date DATE>,
+  name STRING,
+  PRIMARY KEY (id),
+  CONSTRAINT valid_period CHECK (period.end_date > period.start_date),
+  CHECK (LENGTH(name) > 0),
+  CONSTRAINT not_enforced CHECK (id < 0) NOT ENFORCED
+)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`
+INSERT INTO check_constraint_periods (id, period, name)
+VALUES (1, STRUCT(DATE '2022-01-01', DATE '2022-01-31'), 'january'), (2, NULL, NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name        string
+		query       string
+		expectedErr string
+	}{
+		{
+			name:        "insert violating named constraint",
+			query:       `INSERT INTO check_constraint_periods (id, period, name) VALUES (3, STRUCT(DATE '2022-02-01', DATE '2022-01-01'), 'invalid')`,
+			expectedErr: "check constraint valid_period of table check_constraint_periods is violated by the row with primary key (id = 3)",
+		},
+		{
+			name:        "insert violating unnamed constraint",
+			query:       `INSERT INTO check_constraint_periods (id, period, name) VALUES (4, NULL, '')`,
+			expectedErr: "check constraint CHECK(LENGTH(name) > 0) of table check_constraint_periods is violated by the row with primary key (id = 4)",
+		},
+		{
+			name:        "update violating constraint",
+			query:       `UPDATE check_constraint_periods SET period = STRUCT(DATE '2022-01-31', DATE '2022-01-01') WHERE id = 1`,
+			expectedErr: "check constraint valid_period of table check_constraint_periods is violated by the row with primary key (id = 1)",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := db.Exec(test.query)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), test.expectedErr) {
+				t.Fatalf("unexpected error message %q", err.Error())
+			}
+		})
+	}
+	var count int64
+	if err := db.QueryRow(`SELECT COUNT(*) FROM check_constraint_periods`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("unexpected row count %d", count)
+	}
+}
+
+func TestPartitionedTable(t *testing.T) {
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, query := range []string{
+		`CREATE TABLE partitioned_events (id INT64, ts TIMESTAMP, name STRING)
+PARTITION BY DATE(ts)
+CLUSTER BY name, id
+OPTIONS(require_partition_filter=true)`,
+		`CREATE TABLE partitioned_ranges (id INT64, label STRING) PARTITION BY RANGE_BUCKET(id, GENERATE_ARRAY(0, 100, 10))`,
+		`INSERT INTO partitioned_events (id, ts, name) VALUES
+  (1, TIMESTAMP '2023-01-01 10:00:00+00', 'a'),
+  (2, TIMESTAMP '2023-01-01 23:00:00+00', 'b'),
+  (3, TIMESTAMP '2023-01-02 00:00:00+00', 'c'),
+  (4, NULL, 'd')`,
+		`INSERT INTO partitioned_ranges (id, label) VALUES (1, 'a'), (5, 'b'), (15, 'c'), (100, 'd'), (NULL, 'e')`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := db.Query(`SELECT * FROM partitioned_events`); err == nil {
+		t.Fatal("expected error for the query without partition filter")
+	} else if !strings.Contains(err.Error(), "without a filter over column ts") {
+		t.Fatalf("unexpected error message %q", err.Error())
+	}
+	var count int64
+	if err := db.QueryRow(`SELECT COUNT(*) FROM partitioned_events WHERE ts >= TIMESTAMP '2023-01-02'`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("unexpected row count %d", count)
+	}
+
+	rows, err := db.Query(`
+SELECT table_name, partition_id, total_rows FROM INFORMATION_SCHEMA.PARTITIONS
+WHERE table_name LIKE 'partitioned_%' ORDER BY table_name, partition_id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var partitions []string
+	for rows.Next() {
+		var (
+			tableName, partitionID string
+			totalRows              int64
+		)
+		if err := rows.Scan(&tableName, &partitionID, &totalRows); err != nil {
+			t.Fatal(err)
+		}
+		partitions = append(partitions, fmt.Sprintf("%s:%s:%d", tableName, partitionID, totalRows))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{
+		"partitioned_events:20230101:2",
+		"partitioned_events:20230102:1",
+		"partitioned_events:__NULL__:1",
+		"partitioned_ranges:0:2",
+		"partitioned_ranges:10:1",
+		"partitioned_ranges:__NULL__:1",
+		"partitioned_ranges:__UNPARTITIONED__:1",
+	}, partitions); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	columnRows, err := db.Query(`
+SELECT column_name, is_partitioning_column, clustering_ordinal_position FROM INFORMATION_SCHEMA.COLUMNS
+WHERE table_name = 'partitioned_events' ORDER BY ordinal_position`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer columnRows.Close()
+	var columns []string
+	for columnRows.Next() {
+		var (
+			columnName, isPartitioningColumn string
+			clusteringPosition               sql.NullInt64
+		)
+		if err := columnRows.Scan(&columnName, &isPartitioningColumn, &clusteringPosition); err != nil {
+			t.Fatal(err)
+		}
+		columns = append(columns, fmt.Sprintf("%s:%s:%v", columnName, isPartitioningColumn, clusteringPosition.Int64))
+	}
+	if err := columnRows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"id:NO:2", "ts:YES:0", "name:NO:1"}, columns); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestSpecialCharacterNamePath(t *testing.T) {
+	sql.Register("zetasqlite-name-path", &zetasqlite.ZetaSQLiteDriver{
+		ConnectHook: func(conn *zetasqlite.ZetaSQLiteConn) error {
+			conn.SetAutoIndexMode(true)
+			return nil
+		},
+	})
+	db, err := sql.Open("zetasqlite-name-path", filepath.Join(t.TempDir(), "name_path.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, query := range []string{
+		"CREATE TABLE `my-project.name_path_dataset.my-table` (id INT64, `order` STRING, PRIMARY KEY (id, `order`))",
+		"CREATE TABLE `my-project`.name_path_dataset.`テーブル` (id INT64, name STRING)",
+		"CREATE TABLE `other-project.name_path_dataset.other-table` (id INT64)",
+		"INSERT INTO `my-project.name_path_dataset.my-table` (id, `order`) VALUES (1, 'a'), (2, 'b')",
+		"INSERT INTO `my-project`.name_path_dataset.`テーブル` (id, name) VALUES (1, 'x')",
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	queryStrings := func(t *testing.T, query string) []string {
+		t.Helper()
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var ret []string
+		for rows.Next() {
+			var v string
+			if err := rows.Scan(&v); err != nil {
+				t.Fatal(err)
+			}
+			ret = append(ret, v)
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return ret
+	}
+	t.Run("dashes and unicode", func(t *testing.T) {
+		got := queryStrings(
+			t,
+			"SELECT CONCAT(t.`order`, u.name) FROM `my-project`.name_path_dataset.`my-table` AS t "+
+				"JOIN `my-project.name_path_dataset.テーブル` AS u USING (id)",
+		)
+		if diff := cmp.Diff([]string{"ax"}, got); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+	})
+	t.Run("region qualified information schema", func(t *testing.T) {
+		got := queryStrings(t, `
+SELECT DISTINCT table_name FROM `+"`region-us`"+`.INFORMATION_SCHEMA.COLUMNS
+WHERE table_schema = 'name_path_dataset' ORDER BY table_name`)
+		if diff := cmp.Diff([]string{"my-table", "other-table", "テーブル"}, got); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+	})
+	t.Run("project and region qualified information schema", func(t *testing.T) {
+		got := queryStrings(t, `
+SELECT DISTINCT table_name FROM `+"`my-project.region-us`"+`.INFORMATION_SCHEMA.COLUMNS
+WHERE table_schema = 'name_path_dataset' ORDER BY table_name`)
+		if diff := cmp.Diff([]string{"my-table", "テーブル"}, got); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestJobs(t *testing.T) {
+	ctx := context.Background()
+	sql.Register("zetasqlite-jobs", &zetasqlite.ZetaSQLiteDriver{
+		ConnectHook: func(conn *zetasqlite.ZetaSQLiteConn) error {
+			conn.SetJobRecordingMode(true)
+			conn.SetSessionUser("alice@example.com")
+			return conn.SetNamePath([]string{"jobs-project", "jobs_dataset"})
+		},
+	})
+	db, err := sql.Open("zetasqlite-jobs", filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// @@query_label is kept by the connection, so use the same connection for all statements.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, query := range []string{
+		`CREATE TABLE jobs_items (id INT64)`,
+		`INSERT INTO jobs_items (id) VALUES (1), (2)`,
+	} {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rows, err := conn.QueryContext(ctx, `SET @@query_label = "team:audit,env:test"; SELECT COUNT(*) FROM jobs_items`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.QueryContext(ctx, `SELECT * FROM jobs_missing`); err == nil {
+		t.Fatal("expected error for the missing table")
+	}
+
+	queryStrings := func(t *testing.T, query string) []string {
+		t.Helper()
+		rows, err := conn.QueryContext(ctx, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var ret []string
+		for rows.Next() {
+			var v string
+			if err := rows.Scan(&v); err != nil {
+				t.Fatal(err)
+			}
+			ret = append(ret, v)
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return ret
+	}
+	t.Run("jobs by project", func(t *testing.T) {
+		got := queryStrings(t, `
+SELECT CONCAT(query, '|', IFNULL(statement_type, ''), '|', project_id, '|', user_email, '|', IFNULL(error_result.reason, ''))
+FROM `+"`region-us`"+`.INFORMATION_SCHEMA.JOBS_BY_PROJECT
+WHERE job_type = 'QUERY' AND state = 'DONE' AND end_time >= start_time
+ORDER BY creation_time, job_id`)
+		if diff := cmp.Diff([]string{
+			"CREATE TABLE jobs_items (id INT64)|CREATE_TABLE|jobs-project|alice@example.com|",
+			"INSERT INTO jobs_items (id) VALUES (1), (2)|INSERT|jobs-project|alice@example.com|",
+			`SET @@query_label = "team:audit,env:test"; SELECT COUNT(*) FROM jobs_items|SCRIPT|jobs-project|alice@example.com|`,
+			"SELECT * FROM jobs_missing||jobs-project|alice@example.com|invalidQuery",
+		}, got); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+	})
+	t.Run("labels and referenced tables", func(t *testing.T) {
+		labels := queryStrings(t, `
+SELECT CONCAT(l.key, ':', l.value) FROM `+"`region-us`"+`.INFORMATION_SCHEMA.JOBS AS j, UNNEST(j.labels) AS l
+WHERE j.statement_type = 'SCRIPT' ORDER BY l.key`)
+		if diff := cmp.Diff([]string{"env:test", "team:audit"}, labels); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+		tables := queryStrings(t, `
+SELECT CONCAT(r.project_id, '.', r.dataset_id, '.', r.table_id)
+FROM `+"`region-us`"+`.INFORMATION_SCHEMA.JOBS AS j, UNNEST(j.referenced_tables) AS r
+WHERE j.statement_type = 'SCRIPT'`)
+		if diff := cmp.Diff([]string{"jobs-project.jobs_dataset.jobs_items"}, tables); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+	})
+	t.Run("retention", func(t *testing.T) {
+		if err := conn.Raw(func(c interface{}) error {
+			c.(*zetasqlite.ZetaSQLiteConn).SetMaxRecordedJobs(2)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		got := queryStrings(t, "SELECT CAST(COUNT(*) AS STRING) FROM `region-us`.INFORMATION_SCHEMA.JOBS")
+		if diff := cmp.Diff([]string{"2"}, got); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestQueryLabels(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mu   sync.Mutex
+		logs []string
+	)
+	sql.Register("zetasqlite-query-labels", &zetasqlite.ZetaSQLiteDriver{
+		ConnectHook: func(conn *zetasqlite.ZetaSQLiteConn) error {
+			conn.SetJobRecordingMode(true)
+			conn.SetQueryLogger(func(ctx context.Context, log *zetasqlite.QueryLog) {
+				mu.Lock()
+				defer mu.Unlock()
+				keys := make([]string, 0, len(log.Labels))
+				for key := range log.Labels {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				labels := make([]string, 0, len(keys))
+				for _, key := range keys {
+					labels = append(labels, key+":"+log.Labels[key])
+				}
+				logs = append(logs, fmt.Sprintf("%s|%s|%s|%t", log.Query, strings.Join(labels, ","), log.Priority, log.Stats != nil))
+			})
+			return nil
+		},
+	})
+	db, err := sql.Open("zetasqlite-query-labels", filepath.Join(t.TempDir(), "labels.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// @@query_label is kept by the connection, so use the same connection for all statements.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE label_items (id INT64)`); err != nil {
+		t.Fatal(err)
+	}
+	labeledCtx := zetasqlite.WithQueryPriority(
+		zetasqlite.WithQueryLabels(ctx, map[string]string{"team": "audit"}),
+		zetasqlite.QueryPriorityBatch,
+	)
+	result, err := conn.ExecContext(labeledCtx, `INSERT label_items (id) VALUES (1); INSERT label_items (id) VALUES (2)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := zetasqlite.QueryStatsFromResult(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]string{"team": "audit"}, stats.Labels); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	if stats.Priority != zetasqlite.QueryPriorityBatch {
+		t.Errorf("unexpected priority %s", stats.Priority)
+	}
+
+	// the labels of the context overwrite the ones set by @@query_label with the same key.
+	if _, err := conn.ExecContext(ctx, `SET @@query_label = "team:base,env:test"`); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := conn.QueryContext(zetasqlite.WithQueryLabels(ctx, map[string]string{"team": "audit"}), `SELECT id FROM label_items`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the prepared statement is labeled by the context of PrepareContext.
+	stmt, err := conn.PrepareContext(labeledCtx, `INSERT label_items (id) VALUES (?)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stmt.ExecContext(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := stmt.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	got := append([]string{}, logs...)
+	mu.Unlock()
+	if diff := cmp.Diff([]string{
+		"CREATE TABLE label_items (id INT64)||INTERACTIVE|true",
+		"INSERT label_items (id) VALUES (1); INSERT label_items (id) VALUES (2)|team:audit|BATCH|true",
+		`SET @@query_label = "team:base,env:test"|env:test,team:base|INTERACTIVE|true`,
+		"SELECT id FROM label_items|env:test,team:audit|INTERACTIVE|true",
+		"INSERT label_items (id) VALUES (?)|env:test,team:audit|BATCH|false",
+	}, got); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+
+	t.Run("jobs", func(t *testing.T) {
+		rows, err := conn.QueryContext(ctx, `
+SELECT CONCAT(j.priority, '|', l.key, ':', l.value) FROM `+"`region-us`"+`.INFORMATION_SCHEMA.JOBS AS j, UNNEST(j.labels) AS l
+WHERE j.statement_type = 'SCRIPT' ORDER BY l.key`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var got []string
+		for rows.Next() {
+			var v string
+			if err := rows.Scan(&v); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, v)
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{"BATCH|team:audit"}, got); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+	})
+	t.Run("timeout", func(t *testing.T) {
+		timeoutCtx := zetasqlite.WithQueryTimeout(ctx, time.Nanosecond)
+		_, err := conn.ExecContext(timeoutCtx, `INSERT label_items (id) VALUES (4)`)
+		if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+			t.Fatalf("expected deadline exceeded error but got %v", err)
+		}
+	})
+	t.Run("invalid priority", func(t *testing.T) {
+		if _, err := conn.ExecContext(zetasqlite.WithQueryPriority(ctx, "URGENT"), `SELECT 1`); err == nil {
+			t.Fatal("expected error for the unsupported priority")
+		}
+	})
+}
+
+func TestRenameTable(t *testing.T) {
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, query := range []string{
+		`CREATE TABLE rename_items (id INT64, name STRING)`,
+		`INSERT INTO rename_items (id, name) VALUES (1, 'a'), (2, 'b')`,
+		`CREATE VIEW rename_items_view AS SELECT * FROM rename_items`,
+		`CREATE TABLE rename_others (id INT64)`,
+		`ALTER TABLE rename_items RENAME TO rename_goods`,
+		`ALTER TABLE IF EXISTS rename_missing RENAME TO rename_missing2`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	countRows := func(t *testing.T, table string) int64 {
+		t.Helper()
+		var count int64
+		if err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+	if count := countRows(t, "rename_goods"); count != 2 {
+		t.Fatalf("unexpected row count %d", count)
+	}
+	if _, err := db.Query(`SELECT * FROM rename_items`); err == nil {
+		t.Fatal("expected error for the old table name")
+	}
+	if _, err := db.Query(`SELECT * FROM rename_items_view`); err == nil {
+		t.Fatal("expected error for the view referring to the renamed table")
+	} else if !strings.Contains(err.Error(), "rename_items was renamed to rename_goods") {
+		t.Fatalf("unexpected error message %q", err.Error())
+	}
+	if _, err := db.Exec(`ALTER TABLE rename_missing RENAME TO rename_missing2`); err == nil {
+		t.Fatal("expected error for the missing table")
+	}
+	if _, err := db.Exec(`ALTER TABLE rename_goods RENAME TO rename_others`); err == nil {
+		t.Fatal("expected error for renaming onto the existing table")
+	} else if !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("unexpected error message %q", err.Error())
+	}
+	if count := countRows(t, "rename_goods"); count != 2 {
+		t.Fatalf("unexpected row count %d", count)
+	}
+
+	if _, err := db.Exec(`RENAME TABLE rename_goods TO rename_products`); err != nil {
+		t.Fatal(err)
+	}
+	if count := countRows(t, "rename_products"); count != 2 {
+		t.Fatalf("unexpected row count %d", count)
+	}
+}
+
+func TestAlterView(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, query := range []string{
+		`CREATE TABLE alter_items (id INT64, name STRING)`,
+		`INSERT INTO alter_items (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c')`,
+		`CREATE VIEW alter_items_view AS SELECT id FROM alter_items WHERE id > 1`,
+		`CREATE VIEW alter_items_count AS SELECT COUNT(*) AS cnt FROM alter_items_view`,
+		`ALTER VIEW alter_items_view SET OPTIONS (description = 'filtered items')`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	countView := func(t *testing.T, view string) int64 {
+		t.Helper()
+		var count int64
+		if err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", view)).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+	if count := countView(t, "alter_items_view"); count != 2 {
+		t.Fatalf("unexpected row count %d", count)
+	}
+
+	t.Run("set as", func(t *testing.T) {
+		if _, err := db.Exec(`ALTER VIEW alter_items_view SET AS 'SELECT id FROM alter_items WHERE id > 2'`); err != nil {
+			t.Fatal(err)
+		}
+		if count := countView(t, "alter_items_view"); count != 1 {
+			t.Fatalf("unexpected row count %d", count)
+		}
+		var cnt int64
+		if err := db.QueryRow(`SELECT cnt FROM alter_items_count`).Scan(&cnt); err != nil {
+			t.Fatal(err)
+		}
+		if cnt != 1 {
+			t.Fatalf("unexpected count of the dependent view %d", cnt)
+		}
+		listing, err := zetasqlite.ListCatalog(ctx, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, view := range listing.Views {
+			if view.ViewID != "alter_items_view" {
+				continue
+			}
+			if view.Query != "SELECT id FROM alter_items WHERE id > 2" {
+				t.Fatalf("unexpected view definition %q", view.Query)
+			}
+			if view.Description != "filtered items" {
+				t.Fatalf("the options must be kept but got description %q", view.Description)
+			}
+		}
+	})
+	t.Run("invalid query", func(t *testing.T) {
+		if _, err := db.Exec(`ALTER VIEW alter_items_view SET AS 'SELECT unknown FROM alter_items'`); err == nil {
+			t.Fatal("expected error for the invalid query")
+		}
+		if count := countView(t, "alter_items_view"); count != 1 {
+			t.Fatalf("the view must be kept but got row count %d", count)
+		}
+	})
+	t.Run("changed columns", func(t *testing.T) {
+		if _, err := db.Exec(`ALTER VIEW alter_items_view SET AS 'SELECT name FROM alter_items'`); err != nil {
+			t.Fatal(err)
+		}
+		if count := countView(t, "alter_items_view"); count != 3 {
+			t.Fatalf("unexpected row count %d", count)
+		}
+		if _, err := db.Query(`SELECT * FROM alter_items_count`); err == nil {
+			t.Fatal("expected error for the view referring to the altered view")
+		} else if !strings.Contains(err.Error(), "alter_items_view was altered") {
+			t.Fatalf("unexpected error message %q", err.Error())
+		}
+	})
+	t.Run("if exists", func(t *testing.T) {
+		if _, err := db.Exec(`ALTER VIEW IF EXISTS alter_missing SET AS 'SELECT 1 AS id'`); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(`ALTER VIEW IF EXISTS alter_missing SET OPTIONS (description = 'missing')`); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(`ALTER VIEW alter_missing SET AS 'SELECT 1 AS id'`); err == nil {
+			t.Fatal("expected error for the missing view")
+		}
+		if _, err := db.Exec(`ALTER VIEW alter_items SET AS 'SELECT 1 AS id'`); err == nil {
+			t.Fatal("expected error for the table")
+		}
+	})
+}
+
+func TestAnalysisCache(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	columnNum := func(t *testing.T) int {
+		t.Helper()
+		rows, err := conn.QueryContext(ctx, `SELECT * FROM cached_items WHERE id = @id`, sql.Named("id", 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		columns, err := rows.Columns()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(columns)
+	}
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE cached_items (id INT64)`); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if num := columnNum(t); num != 1 {
+			t.Fatalf("unexpected column number %d", num)
+		}
+	}
+	if _, err := conn.ExecContext(ctx, `CREATE OR REPLACE TABLE cached_items (id INT64, name STRING)`); err != nil {
+		t.Fatal(err)
+	}
+	if num := columnNum(t); num != 2 {
+		t.Fatalf("the cached analysis was used after the table was replaced: column number %d", num)
+	}
+	if _, err := conn.ExecContext(ctx, `DROP TABLE cached_items`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.QueryContext(ctx, `SELECT * FROM cached_items WHERE id = @id`, sql.Named("id", 1)); err == nil {
+		t.Fatal("expected error for the dropped table")
+	}
+
+	if err := conn.Raw(func(c interface{}) error {
+		c.(*zetasqlite.ZetaSQLiteConn).SetAnalysisCacheMode(false)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE cached_items (id INT64, name STRING, score FLOAT64)`); err != nil {
+		t.Fatal(err)
+	}
+	if num := columnNum(t); num != 3 {
+		t.Fatalf("unexpected column number %d", num)
+	}
+}
+
+func TestManyUniqueQueriesMemoryGrowth(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip soak test in short mode")
+	}
+	if runtime.GOOS != "linux" {
+		t.Skip("RSS is read from /proc")
+	}
+	const (
+		queryNum       = 50000
+		ddlInterval    = 1000
+		maxGrowthBytes = 1 << 30
+	)
+	readRSS := func(t *testing.T) int64 {
+		t.Helper()
+		statm, err := os.ReadFile("/proc/self/statm")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields := strings.Fields(string(statm))
+		if len(fields) < 2 {
+			t.Fatalf("unexpected statm format %q", statm)
+		}
+		pages, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pages * int64(os.Getpagesize())
+	}
+
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE soak_items (id INT64, name STRING)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(ctx, `INSERT INTO soak_items (id, name) VALUES (1, 'a'), (2, 'b')`); err != nil {
+		t.Fatal(err)
+	}
+	runQueries := func(t *testing.T, start, end int) {
+		t.Helper()
+		for i := start; i < end; i++ {
+			if i%ddlInterval == 0 {
+				// the catalog is re-created by deleting the table
+				table := fmt.Sprintf("soak_tmp_%d", i)
+				if _, err := conn.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE %s (id INT64)`, table)); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := conn.ExecContext(ctx, fmt.Sprintf(`DROP TABLE %s`, table)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var v int64
+			if err := conn.QueryRowContext(
+				ctx,
+				fmt.Sprintf(`SELECT id + %d FROM soak_items WHERE id = 1`, i),
+			).Scan(&v); err != nil {
+				t.Fatal(err)
+			}
+			if v != int64(i+1) {
+				t.Fatalf("unexpected value %d", v)
+			}
+		}
+	}
+	// warm up to exclude the memory allocated once
+	runQueries(t, 0, ddlInterval)
+	runtime.GC()
+	before := readRSS(t)
+	runQueries(t, ddlInterval, queryNum)
+	runtime.GC()
+	after := readRSS(t)
+	if growth := after - before; growth > maxGrowthBytes {
+		t.Fatalf("RSS grew by %d bytes after %d unique queries", growth, queryNum)
+	}
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	const (
+		workerNum = 8
+		loopNum   = 50
+	)
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", filepath.Join(t.TempDir(), "concurrent.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(workerNum)
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE counters (id INT64, value INT64)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, `INSERT counters (id, value) VALUES (0, 0)`); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, workerNum)
+	for i := 0; i < workerNum; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < loopNum; j++ {
+				if _, err := db.ExecContext(
+					ctx,
+					`INSERT counters (id, value) VALUES (?, ?)`,
+					int64(worker*loopNum+j+1), int64(j),
+				); err != nil {
+					errCh <- fmt.Errorf("worker %d failed to insert: %w", worker, err)
+					return
+				}
+				if _, err := db.ExecContext(ctx, `UPDATE counters SET value = value + 1 WHERE id = 0`); err != nil {
+					errCh <- fmt.Errorf("worker %d failed to update: %w", worker, err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		if errors.Is(err, zetasqlite.ErrConcurrentWrite) {
+			t.Errorf("unexpected lock error: %v", err)
+		} else {
+			t.Error(err)
+		}
+	}
+	if t.Failed() {
+		return
+	}
+
+	var (
+		rowNum  int64
+		counter int64
+	)
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM counters WHERE id > 0`).Scan(&rowNum); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRowContext(ctx, `SELECT value FROM counters WHERE id = 0`).Scan(&counter); err != nil {
+		t.Fatal(err)
+	}
+	if rowNum != workerNum*loopNum {
+		t.Fatalf("failed to insert rows: expected %d but got %d", workerNum*loopNum, rowNum)
+	}
+	if counter != workerNum*loopNum {
+		t.Fatalf("failed to update counter: expected %d but got %d", workerNum*loopNum, counter)
+	}
+}
+
+type testUserID int64
+
+func (id testUserID) Value() (driver.Value, error) {
+	return int64(id), nil
+}
+
+type testEventTime struct {
+	t time.Time
+}
+
+func (e testEventTime) Value() (driver.Value, error) {
+	return e.t, nil
+}
+
+type testMoney struct {
+	Currency string
+	Amount   int64
+}
+
+func (m *testMoney) Scan(src interface{}) error {
+	fields, ok := src.([]map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected source type %T", src)
+	}
+	for _, field := range fields {
+		for k, v := range field {
+			switch k {
+			case "currency":
+				m.Currency = v.(string)
+			case "amount":
+				m.Amount = v.(int64)
+			}
+		}
+	}
+	return nil
+}
+
+func TestValuerAndScanner(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, `
+CREATE TABLE payments (
+  user_id INT64,
+  paid_at TIMESTAMP,
+  price   STRUCT<currency STRING, amount INT64>
+)`); err != nil {
+		t.Fatal(err)
+	}
+	paidAt := time.Date(2022, 10, 1, 12, 30, 0, 0, time.UTC)
+	if _, err := db.ExecContext(
+		ctx,
+		`INSERT payments (user_id, paid_at, price) VALUES (@user_id, @paid_at, STRUCT('JPY' AS currency, 1000 AS amount))`,
+		sql.Named("user_id", testUserID(1)),
+		sql.Named("paid_at", testEventTime{t: paidAt}),
+	); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("valuer", func(t *testing.T) {
+		var matched bool
+		if err := db.QueryRowContext(
+			ctx,
+			`SELECT paid_at = @paid_at FROM payments WHERE user_id = @user_id`,
+			sql.Named("paid_at", testEventTime{t: paidAt}),
+			sql.Named("user_id", testUserID(1)),
+		).Scan(&matched); err != nil {
+			t.Fatal(err)
+		}
+		if !matched {
+			t.Fatal("failed to bind the value of driver.Valuer to TIMESTAMP parameter")
+		}
+	})
+	t.Run("scanner", func(t *testing.T) {
+		var price testMoney
+		if err := db.QueryRowContext(ctx, `SELECT price FROM payments WHERE user_id = ?`, testUserID(1)).Scan(&price); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(testMoney{Currency: "JPY", Amount: 1000}, price); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestNullValues(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, test := range []struct {
+		typ   string
+		value string
+	}{
+		{typ: "INT64", value: "1"},
+		{typ: "FLOAT64", value: "1.5"},
+		{typ: "BOOL", value: "TRUE"},
+		{typ: "STRING", value: "'a'"},
+		{typ: "BYTES", value: "b'a'"},
+		{typ: "NUMERIC", value: "NUMERIC '1.5'"},
+		{typ: "BIGNUMERIC", value: "BIGNUMERIC '1.5'"},
+		{typ: "DATE", value: "DATE '2022-01-01'"},
+		{typ: "DATETIME", value: "DATETIME '2022-01-01 00:00:00'"},
+		{typ: "TIME", value: "TIME '12:00:00'"},
+		{typ: "TIMESTAMP", value: "TIMESTAMP '2022-01-01 00:00:00 UTC'"},
+		{typ: "INTERVAL", value: "INTERVAL 1 DAY"},
+		{typ: "JSON", value: "JSON '{\"a\":1}'"},
+		{typ: "ARRAY<INT64>", value: "[1, 2]"},
+		{typ: "STRUCT<a INT64, b STRING>", value: "STRUCT(1 AS a, 'b' AS b)"},
+	} {
+		test := test
+		t.Run(test.typ, func(t *testing.T) {
+			var (
+				castIsNull   bool
+				ifNullIsNull bool
+			)
+			if err := db.QueryRowContext(
+				ctx,
+				fmt.Sprintf(
+					"SELECT CAST(NULL AS %[1]s) IS NULL, IFNULL(CAST(NULL AS %[1]s), %[2]s) IS NULL",
+					test.typ, test.value,
+				),
+			).Scan(&castIsNull, &ifNullIsNull); err != nil {
+				t.Fatal(err)
+			}
+			if !castIsNull {
+				t.Error("CAST(NULL) must be NULL")
+			}
+			if ifNullIsNull {
+				t.Error("IFNULL(NULL, value) must not be NULL")
+			}
+
+			table := fmt.Sprintf("null_values_%d", time.Now().UnixNano())
+			if _, err := db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s (id INT64, col %s)", table, test.typ)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.ExecContext(
+				ctx,
+				fmt.Sprintf("INSERT %s (id, col) VALUES (1, NULL), (2, %s), (3, CAST(NULL AS %s))", table, test.value, test.typ),
+			); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.ExecContext(ctx, fmt.Sprintf("INSERT %s (id) VALUES (4)", table)); err != nil {
+				t.Fatal(err)
+			}
+			var (
+				rowNum     int64
+				nonNullNum int64
+				nullNum    int64
+				notNullNum int64
+			)
+			if err := db.QueryRowContext(
+				ctx,
+				fmt.Sprintf(
+					"SELECT COUNT(*), COUNT(col), COUNTIF(col IS NULL), COUNTIF(col IS NOT NULL) FROM %s",
+					table,
+				),
+			).Scan(&rowNum, &nonNullNum, &nullNum, &notNullNum); err != nil {
+				t.Fatal(err)
+			}
+			if rowNum != 4 || nonNullNum != 1 || nullNum != 3 || notNullNum != 1 {
+				t.Fatalf(
+					"unexpected counts: COUNT(*) = %d, COUNT(col) = %d, IS NULL = %d, IS NOT NULL = %d",
+					rowNum, nonNullNum, nullNum, notNullNum,
+				)
+			}
+			var col interface{}
+			if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT col FROM %s WHERE id = 1", table)).Scan(&col); err != nil {
+				t.Fatal(err)
+			}
+			if col != nil {
+				t.Fatalf("expected nil but got %v", col)
+			}
+		})
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", filepath.Join(t.TempDir(), "snapshot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, `
+CREATE TABLE users (id INT64, name STRING);
+INSERT users (id, name) VALUES (1, 'alice'), (2, 'bob');
+CREATE FUNCTION add_one(x INT64) AS (x + 1);
+`); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := zetasqlite.Snapshot(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Close()
+
+	countUsers := func(t *testing.T) int64 {
+		t.Helper()
+		var count int64
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := db.ExecContext(ctx, `
+INSERT users (id, name) VALUES (3, 'carol');
+CREATE TABLE items (id INT64);
+DROP FUNCTION add_one;
+`); err != nil {
+			t.Fatal(err)
+		}
+		if count := countUsers(t); count != 3 {
+			t.Fatalf("unexpected number of users before restoring: %d", count)
+		}
+		if err := zetasqlite.RestoreSnapshot(ctx, db, snapshot); err != nil {
+			t.Fatal(err)
+		}
+		if count := countUsers(t); count != 2 {
+			t.Fatalf("unexpected number of users after restoring: %d", count)
+		}
+		var v int64
+		if err := db.QueryRowContext(ctx, `SELECT add_one(1)`).Scan(&v); err != nil {
+			t.Fatalf("function must be restored: %v", err)
+		}
+		if v != 2 {
+			t.Fatalf("unexpected result of function: %d", v)
+		}
+		if _, err := db.ExecContext(ctx, `SELECT * FROM items`); err == nil {
+			t.Fatal("table created after taking snapshot must be removed")
+		}
+	}
+}
+
+func TestTimeZone(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// the default time zone is kept by the connection, so use the dedicated connections.
+	laConn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer laConn.Close()
+	if _, err := laConn.ExecContext(ctx, `SET @@time_zone = "America/Los_Angeles"`); err != nil {
+		t.Fatal(err)
+	}
+	tokyoConn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tokyoConn.Close()
+	if err := tokyoConn.Raw(func(c interface{}) error {
+		return c.(*zetasqlite.ZetaSQLiteConn).SetTimeZone("Asia/Tokyo")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	queryString := func(t *testing.T, conn *sql.Conn, query string) string {
+		t.Helper()
+		var v string
+		if err := conn.QueryRowContext(ctx, query).Scan(&v); err != nil {
+			t.Fatalf("failed to query %s: %v", query, err)
+		}
+		return v
+	}
+	for _, test := range []struct {
+		name          string
+		query         string
+		expectedLA    string
+		expectedTokyo string
+	}{
+		{
+			name:          "cast string to timestamp before spring forward",
+			query:         `SELECT FORMAT_TIMESTAMP('%F %T', CAST('2021-03-14 01:30:00' AS TIMESTAMP), 'UTC')`,
+			expectedLA:    "2021-03-14 09:30:00",
+			expectedTokyo: "2021-03-13 16:30:00",
+		},
+		{
+			name:          "cast string to timestamp after spring forward",
+			query:         `SELECT FORMAT_TIMESTAMP('%F %T', CAST('2021-03-14 03:30:00' AS TIMESTAMP), 'UTC')`,
+			expectedLA:    "2021-03-14 10:30:00",
+			expectedTokyo: "2021-03-13 18:30:00",
+		},
+		{
+			name:          "cast timestamp to string in daylight saving time",
+			query:         `SELECT CAST(TIMESTAMP '2021-03-14 10:30:00+00' AS STRING)`,
+			expectedLA:    "2021-03-14 03:30:00-07",
+			expectedTokyo: "2021-03-14 19:30:00+09",
+		},
+		{
+			name:          "cast timestamp to string in standard time",
+			query:         `SELECT CAST(TIMESTAMP '2021-11-07 09:30:00+00' AS STRING)`,
+			expectedLA:    "2021-11-07 01:30:00-08",
+			expectedTokyo: "2021-11-07 18:30:00+09",
+		},
+		{
+			name:          "format timestamp before fall back",
+			query:         `SELECT FORMAT_TIMESTAMP('%F %T', TIMESTAMP '2021-11-07 08:30:00+00')`,
+			expectedLA:    "2021-11-07 01:30:00",
+			expectedTokyo: "2021-11-07 17:30:00",
+		},
+		{
+			name:          "format timestamp after fall back",
+			query:         `SELECT FORMAT_TIMESTAMP('%F %T', TIMESTAMP '2021-11-07 09:30:00+00')`,
+			expectedLA:    "2021-11-07 01:30:00",
+			expectedTokyo: "2021-11-07 18:30:00",
+		},
+		{
+			name:          "extract from timestamp",
+			query:         `SELECT CAST(EXTRACT(HOUR FROM TIMESTAMP '2021-11-07 10:30:00+00') AS STRING)`,
+			expectedLA:    "2",
+			expectedTokyo: "19",
+		},
+		{
+			name:          "date from timestamp",
+			query:         `SELECT CAST(DATE(TIMESTAMP '2021-03-14 07:30:00+00') AS STRING)`,
+			expectedLA:    "2021-03-13",
+			expectedTokyo: "2021-03-14",
+		},
+		{
+			name:          "explicit time zone takes precedence",
+			query:         `SELECT FORMAT_TIMESTAMP('%F %T', TIMESTAMP '2021-03-14 10:30:00+00', 'UTC')`,
+			expectedLA:    "2021-03-14 10:30:00",
+			expectedTokyo: "2021-03-14 10:30:00",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := queryString(t, laConn, test.query); got != test.expectedLA {
+				t.Errorf("unexpected result in America/Los_Angeles: expected %q but got %q", test.expectedLA, got)
+			}
+			if got := queryString(t, tokyoConn, test.query); got != test.expectedTokyo {
+				t.Errorf("unexpected result in Asia/Tokyo: expected %q but got %q", test.expectedTokyo, got)
+			}
+		})
+	}
+	t.Run("invalid time zone", func(t *testing.T) {
+		if _, err := laConn.ExecContext(ctx, `SET @@time_zone = "Invalid/Zone"`); err == nil {
+			t.Fatal("expected error for the invalid time zone")
+		}
+	})
+	t.Run("reset time zone", func(t *testing.T) {
+		if _, err := laConn.ExecContext(ctx, `SET @@time_zone = NULL`); err != nil {
+			t.Fatal(err)
+		}
+		if got := queryString(t, laConn, `SELECT CAST(EXTRACT(HOUR FROM TIMESTAMP '2021-11-07 10:30:00+00') AS STRING)`); got != "10" {
+			t.Fatalf("expected UTC after reset but got %q", got)
+		}
+	})
+}
+
+func TestQueryParamTypes(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, `
+CREATE TABLE query_param_types_items (id INT64, name STRING);
+INSERT query_param_types_items (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c');
+`); err != nil {
+		t.Fatal(err)
+	}
+	const query = `SELECT COUNT(*) FROM query_param_types_items WHERE id IN UNNEST(@ids)`
+	typedCtx := zetasqlite.WithQueryParamTypes(ctx, map[string]string{"ids": "ARRAY<INT64>"})
+	t.Run("empty slice", func(t *testing.T) {
+		var count int64
+		if err := db.QueryRowContext(typedCtx, query, sql.Named("ids", []int64{})).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Fatalf("unexpected count %d", count)
+		}
+	})
+	t.Run("values", func(t *testing.T) {
+		var count int64
+		if err := db.QueryRowContext(typedCtx, query, sql.Named("ids", []int64{1, 3})).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 2 {
+			t.Fatalf("unexpected count %d", count)
+		}
+	})
+	t.Run("prepared statement", func(t *testing.T) {
+		stmt, err := db.PrepareContext(typedCtx, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stmt.Close()
+		var count int64
+		if err := stmt.QueryRow([]int64{2}).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Fatalf("unexpected count %d", count)
+		}
+		if err := stmt.QueryRow("abc").Scan(&count); err == nil {
+			t.Fatal("expected error for the mismatched value")
+		}
+	})
+	t.Run("mismatched value", func(t *testing.T) {
+		var count int64
+		err := db.QueryRowContext(typedCtx, query, sql.Named("ids", "abc")).Scan(&count)
+		if err == nil {
+			t.Fatal("expected error for the mismatched value")
+		}
+		for _, expected := range []string{"@ids", "ARRAY<INT64>", "STRING"} {
+			if !strings.Contains(err.Error(), expected) {
+				t.Fatalf("expected error to contain %q but got %v", expected, err)
+			}
+		}
+	})
+	t.Run("invalid type name", func(t *testing.T) {
+		invalidCtx := zetasqlite.WithQueryParamTypes(ctx, map[string]string{"ids": "ARRAY<UNKNOWN>"})
+		var count int64
+		if err := db.QueryRowContext(invalidCtx, query, sql.Named("ids", []int64{1})).Scan(&count); err == nil {
+			t.Fatal("expected error for the invalid type name")
+		}
+	})
+}
+
+func TestArrayReturningFunctionsForEachElementType(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, test := range []struct {
+		typ          string
+		first        string
+		second       string
+		isComparable bool
+	}{
+		{typ: "INT64", first: "1", second: "2", isComparable: true},
+		{typ: "FLOAT64", first: "1.5", second: "2.5", isComparable: true},
+		{typ: "NUMERIC", first: "NUMERIC '1.1'", second: "NUMERIC '2.2'", isComparable: true},
+		{typ: "BIGNUMERIC", first: "BIGNUMERIC '1.1'", second: "BIGNUMERIC '2.2'", isComparable: true},
+		{typ: "BOOL", first: "false", second: "true", isComparable: true},
+		{typ: "STRING", first: "'a'", second: "'b'", isComparable: true},
+		{typ: "BYTES", first: "b'a'", second: "b'b'", isComparable: true},
+		{typ: "DATE", first: "DATE '2022-01-01'", second: "DATE '2022-01-02'", isComparable: true},
+		{typ: "DATETIME", first: "DATETIME '2022-01-01 00:00:00'", second: "DATETIME '2022-01-02 00:00:00'", isComparable: true},
+		{typ: "TIME", first: "TIME '01:00:00'", second: "TIME '02:00:00'", isComparable: true},
+		{typ: "TIMESTAMP", first: "TIMESTAMP '2022-01-01 00:00:00+00'", second: "TIMESTAMP '2022-01-02 00:00:00+00'", isComparable: true},
+		{typ: "INTERVAL", first: "INTERVAL 1 DAY", second: "INTERVAL 2 DAY", isComparable: true},
+		{typ: "STRUCT", first: "STRUCT(1 AS a, 'x' AS b)", second: "STRUCT(2 AS a, 'y' AS b)", isComparable: true},
+		{typ: "STRUCT<ARRAY>", first: "STRUCT([1] AS a)", second: "STRUCT([2, 3] AS a)"},
+		{typ: "JSON", first: "JSON '{\"a\": 1}'", second: "JSON '{\"a\": 2}'"},
+	} {
+		test := test
+		t.Run(test.typ, func(t *testing.T) {
+			for _, fn := range []struct {
+				name  string
+				query string
+			}{
+				{
+					name:  "function",
+					query: fmt.Sprintf("SELECT ARRAY_REVERSE(ARRAY_CONCAT([%s], [%s])) AS arr", test.second, test.first),
+				},
+				{
+					name:  "aggregate function",
+					query: fmt.Sprintf("SELECT ARRAY_AGG(x ORDER BY off) AS arr FROM UNNEST([%s, %s]) AS x WITH OFFSET AS off", test.first, test.second),
+				},
+				{
+					name:  "analytic function",
+					query: fmt.Sprintf("SELECT arr FROM (SELECT ARRAY_AGG(x) OVER (ORDER BY off ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING) AS arr, off FROM UNNEST([%s, %s]) AS x WITH OFFSET AS off) WHERE off = 0", test.first, test.second),
+				},
+			} {
+				check := "TRUE"
+				if test.isComparable {
+					check = fmt.Sprintf("arr[OFFSET(0)] = %s AND arr[OFFSET(1)] = %s", test.first, test.second)
+				}
+				query := fmt.Sprintf("SELECT ARRAY_LENGTH(arr), %s FROM (%s)", check, fn.query)
+				var (
+					length int64
+					equals bool
+				)
+				if err := db.QueryRowContext(ctx, query).Scan(&length, &equals); err != nil {
+					t.Fatalf("%s: failed to query %s: %v", fn.name, query, err)
+				}
+				if length != 2 || !equals {
+					t.Fatalf("%s: unexpected result of %s: length %d, equals %t", fn.name, query, length, equals)
+				}
+			}
+		})
+	}
+}
+
+func TestListCatalog(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "catalog.db")
+	db, err := sql.Open("zetasqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, `
+CREATE TABLE project1.dataset1.users (id INT64 NOT NULL, profile STRUCT<name STRING, tags ARRAY<STRING>>);
+CREATE VIEW project1.dataset1.user_ids AS SELECT id FROM project1.dataset1.users;
+CREATE FUNCTION project1.dataset2.add_one(x INT64) RETURNS INT64 AS (x + 1);
+CREATE FUNCTION project1.dataset2.js_add(x FLOAT64, y FLOAT64) RETURNS FLOAT64 LANGUAGE js AS "return x + y;";
+`); err != nil {
+		t.Fatal(err)
+	}
+	listing, err := zetasqlite.ListCatalog(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var datasets []string
+	for _, dataset := range listing.Datasets {
+		datasets = append(datasets, dataset.ProjectID+"."+dataset.DatasetID)
+	}
+	if diff := cmp.Diff([]string{"project1.dataset1", "project1.dataset2"}, datasets); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	if len(listing.Tables) != 1 {
+		t.Fatalf("unexpected number of tables: %d", len(listing.Tables))
+	}
+	table := listing.Tables[0]
+	if table.ProjectID != "project1" || table.DatasetID != "dataset1" || table.TableID != "users" {
+		t.Fatalf("unexpected table: %s.%s.%s", table.ProjectID, table.DatasetID, table.TableID)
+	}
+	rows, err := db.QueryContext(ctx, `
+SELECT column_name, is_nullable, data_type FROM project1.dataset1.INFORMATION_SCHEMA.COLUMNS
+WHERE table_name = 'users' ORDER BY ordinal_position`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expectedColumns [][]string
+	for rows.Next() {
+		var name, isNullable, dataType string
+		if err := rows.Scan(&name, &isNullable, &dataType); err != nil {
+			t.Fatal(err)
+		}
+		expectedColumns = append(expectedColumns, []string{name, isNullable, dataType})
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	var columns [][]string
+	for _, column := range table.Columns {
+		isNullable := "YES"
+		if column.IsNotNull {
+			isNullable = "NO"
+		}
+		columns = append(columns, []string{column.Name, isNullable, column.Type.FormatType()})
+	}
+	if diff := cmp.Diff(expectedColumns, columns); diff != "" {
+		t.Errorf("columns must be consistent with INFORMATION_SCHEMA (-want +got):\n%s", diff)
+	}
+	profile := table.Columns[1].Type
+	if len(profile.FieldTypes) != 2 || profile.FieldTypes[1].Type.ElementType == nil {
+		t.Fatalf("nested schema must be listed: %s", profile.FormatType())
+	}
+
+	if len(listing.Views) != 1 {
+		t.Fatalf("unexpected number of views: %d", len(listing.Views))
+	}
+	if view := listing.Views[0]; view.ViewID != "user_ids" || !strings.Contains(view.Query, "SELECT id FROM project1.dataset1.users") {
+		t.Fatalf("unexpected view %s: %q", view.ViewID, view.Query)
+	}
+	var functions []string
+	for _, function := range listing.Functions {
+		functions = append(functions, fmt.Sprintf("%s %s %s", function.Signature, function.Language, function.Body))
+	}
+	if len(functions) != 2 {
+		t.Fatalf("unexpected functions: %v", functions)
+	}
+	if !strings.HasPrefix(functions[0], "`project1.dataset2.add_one`(x INT64) RETURNS INT64") {
+		t.Errorf("unexpected function: %s", functions[0])
+	}
+	if functions[1] != "`project1.dataset2.js_add`(x DOUBLE, y DOUBLE) RETURNS DOUBLE js return x + y;" {
+		t.Errorf("unexpected function: %s", functions[1])
+	}
+
+	t.Run("other connection", func(t *testing.T) {
+		other, err := sql.Open("zetasqlite", "file:"+path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer other.Close()
+
+		if _, err := other.ExecContext(ctx, `
+CREATE TABLE project1.dataset3.items (id INT64);
+DROP VIEW project1.dataset1.user_ids;
+`); err != nil {
+			t.Fatal(err)
+		}
+		if err := zetasqlite.RefreshCatalog(ctx, db); err != nil {
+			t.Fatal(err)
+		}
+		listing, err := zetasqlite.ListCatalog(ctx, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tables []string
+		for _, table := range listing.Tables {
+			tables = append(tables, strings.Join(table.NamePath, "."))
+		}
+		if diff := cmp.Diff([]string{"project1.dataset1.users", "project1.dataset3.items"}, tables); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+		if len(listing.Views) != 0 {
+			t.Errorf("view dropped by other connection must be removed after refresh")
+		}
+	})
+}
+
+func TestStatementKind(t *testing.T) {
+	for _, test := range []struct {
+		query    string
+		expected zetasqlite.Kind
+	}{
+		{query: "SELECT * FROM not_found_table", expected: zetasqlite.KindSelect},
+		{query: "WITH t AS (SELECT 1 AS x) SELECT x FROM t", expected: zetasqlite.KindSelect},
+		{query: "INSERT INTO t (id) VALUES (1)", expected: zetasqlite.KindDML},
+		{query: "UPDATE t SET id = 2 WHERE true", expected: zetasqlite.KindDML},
+		{query: "DELETE FROM t WHERE true", expected: zetasqlite.KindDML},
+		{query: "MERGE t USING s ON t.id = s.id WHEN NOT MATCHED THEN INSERT ROW", expected: zetasqlite.KindDML},
+		{query: "TRUNCATE TABLE t", expected: zetasqlite.KindDML},
+		{query: "CREATE TEMP TABLE t (id INT64)", expected: zetasqlite.KindDDL},
+		{query: "CREATE FUNCTION f(x INT64) AS (x + 1)", expected: zetasqlite.KindDDL},
+		{query: "DROP TABLE t", expected: zetasqlite.KindDDL},
+		{query: "ALTER TABLE t ADD COLUMN name STRING", expected: zetasqlite.KindDDL},
+		{query: "SELECT 1; SELECT 2", expected: zetasqlite.KindScript},
+		{query: "BEGIN SELECT 1; END", expected: zetasqlite.KindScript},
+		{query: "DECLARE x INT64 DEFAULT 1", expected: zetasqlite.KindScript},
+		{query: "SET @@time_zone = 'Asia/Tokyo'", expected: zetasqlite.KindScript},
+		{query: "EXECUTE IMMEDIATE 'SELECT 1'", expected: zetasqlite.KindScript},
+		{query: "BEGIN TRANSACTION", expected: zetasqlite.KindScript},
+	} {
+		test := test
+		t.Run(test.query, func(t *testing.T) {
+			kind, err := zetasqlite.StatementKind(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kind != test.expected {
+				t.Fatalf("expected %s but got %s", test.expected, kind)
+			}
+		})
+	}
+	if _, err := zetasqlite.StatementKind("SELEC 1"); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "read_only.db")
+	db, err := sql.Open("zetasqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, `
+CREATE TABLE read_only_table (id INT64);
+INSERT read_only_table (id) VALUES (1);
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	readOnlyDB := sql.OpenDB(zetasqlite.NewConnector(path, zetasqlite.WithReadOnly(true)))
+	defer readOnlyDB.Close()
+
+	var count int64
+	if err := readOnlyDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM read_only_table").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("unexpected count: %d", count)
+	}
+	for _, test := range []struct {
+		query    string
+		expected zetasqlite.Kind
+	}{
+		{query: "INSERT read_only_table (id) VALUES (2)", expected: zetasqlite.KindDML},
+		{query: "DELETE FROM read_only_table WHERE true", expected: zetasqlite.KindDML},
+		{query: "CREATE TEMP TABLE read_only_temp (id INT64)", expected: zetasqlite.KindDDL},
+		{query: "DROP TABLE read_only_table", expected: zetasqlite.KindDDL},
+		{query: "SELECT 1; DELETE FROM read_only_table WHERE true", expected: zetasqlite.KindScript},
+		{query: "BEGIN CREATE TEMP TABLE read_only_temp (id INT64); END", expected: zetasqlite.KindScript},
+		{query: "EXECUTE IMMEDIATE 'DELETE FROM read_only_table WHERE true'", expected: zetasqlite.KindScript},
+	} {
+		test := test
+		t.Run(test.query, func(t *testing.T) {
+			_, err := readOnlyDB.ExecContext(ctx, test.query)
+			if !errors.Is(err, zetasqlite.ErrReadOnly) {
+				t.Fatalf("expected read-only error but got %v", err)
+			}
+			var readOnlyErr *zetasqlite.ReadOnlyError
+			if !errors.As(err, &readOnlyErr) {
+				t.Fatalf("expected ReadOnlyError but got %T", err)
+			}
+			if readOnlyErr.Kind != test.expected {
+				t.Fatalf("expected %s but got %s", test.expected, readOnlyErr.Kind)
+			}
+		})
+	}
+	conn, err := readOnlyDB.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := conn.Raw(func(c interface{}) error {
+		return c.(*zetasqlite.ZetaSQLiteConn).InsertRows(ctx, "read_only_table", func(i int) []interface{} {
+			return []interface{}{int64(i)}
+		}, 1)
+	}); !errors.Is(err, zetasqlite.ErrReadOnly) {
+		t.Fatalf("expected read-only error but got %v", err)
+	}
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM read_only_table").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("table must not be changed by read-only connection: %d", count)
+	}
+}
+
+func TestLanguageFeatures(t *testing.T) {
+	ctx := context.Background()
+	const arrayEqualityQuery = "SELECT [1, 2] = [1, 2]"
+
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var equal bool
+	if err := db.QueryRowContext(ctx, arrayEqualityQuery).Scan(&equal); err == nil {
+		t.Fatal("expected error of array equality disabled by default")
+	}
+
+	enabledDB := sql.OpenDB(zetasqlite.NewConnector(":memory:", zetasqlite.WithLanguageFeatures(zetasql.FeatureV11ArrayEquality)))
+	defer enabledDB.Close()
+	if err := enabledDB.QueryRowContext(ctx, arrayEqualityQuery).Scan(&equal); err != nil {
+		t.Fatal(err)
+	}
+	if !equal {
+		t.Fatal("expected equal arrays")
+	}
+
+	const qualifyQuery = "SELECT x FROM UNNEST([1, 2, 3]) AS x QUALIFY ROW_NUMBER() OVER (ORDER BY x DESC) = 1"
+	disabledDB := sql.OpenDB(zetasqlite.NewConnector(":memory:", zetasqlite.WithoutLanguageFeatures(zetasql.FeatureV13Qualify)))
+	defer disabledDB.Close()
+	var x int64
+	if err := disabledDB.QueryRowContext(ctx, qualifyQuery).Scan(&x); err == nil {
+		t.Fatal("expected error of disabled QUALIFY")
+	}
+	if err := db.QueryRowContext(ctx, qualifyQuery).Scan(&x); err != nil {
+		t.Fatal(err)
+	}
+	if x != 3 {
+		t.Fatalf("unexpected value %d", x)
+	}
+
+	t.Run("with recursive", func(t *testing.T) {
+		recursiveDB := sql.OpenDB(zetasqlite.NewConnector(":memory:", zetasqlite.WithLanguageFeatures(zetasql.FeatureV13WithRecursive)))
+		defer recursiveDB.Close()
+		// the traversal of the cyclic graph must fail instead of iterating forever.
+		rows, err := recursiveDB.QueryContext(ctx, `
+WITH RECURSIVE
+  edges AS (SELECT 1 AS src, 2 AS dst UNION ALL SELECT 2, 1),
+  paths AS (SELECT 1 AS node UNION ALL SELECT dst FROM paths JOIN edges ON paths.node = edges.src)
+SELECT node FROM paths`)
+		if err == nil {
+			for rows.Next() {
+			}
+			err = rows.Err()
+			rows.Close()
+		}
+		if err == nil {
+			t.Fatal("expected error of unsupported WITH RECURSIVE")
+		}
+		if !strings.Contains(err.Error(), "WITH RECURSIVE is unsupported") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	t.Run("analysis cache", func(t *testing.T) {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		setFeatures := func(features []zetasqlite.LanguageFeature) {
+			t.Helper()
+			if err := conn.Raw(func(c interface{}) error {
+				return c.(*zetasqlite.ZetaSQLiteConn).SetLanguageFeatures(features)
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		setFeatures(append(zetasqlite.DefaultLanguageFeatures, zetasql.FeatureV11ArrayEquality))
+		if err := conn.QueryRowContext(ctx, arrayEqualityQuery).Scan(&equal); err != nil {
+			t.Fatal(err)
+		}
+		// the result of the analysis with the previous features must not be reused.
+		setFeatures(zetasqlite.DefaultLanguageFeatures)
+		if err := conn.QueryRowContext(ctx, arrayEqualityQuery).Scan(&equal); err == nil {
+			t.Fatal("expected error of array equality after disabling it")
+		}
+	})
+}
+
+func TestResourceLimits(t *testing.T) {
+	ctx := context.Background()
+	assertResourcesExceeded := func(t *testing.T, err error, resource string, limit int64) {
+		t.Helper()
+		if !errors.Is(err, zetasqlite.ErrResourcesExceeded) {
+			t.Fatalf("expected resources exceeded error but got %v", err)
+		}
+		var resourcesErr *zetasqlite.ResourcesExceededError
+		if !errors.As(err, &resourcesErr) {
+			t.Fatalf("expected ResourcesExceededError but got %T", err)
+		}
+		if resourcesErr.Resource != resource || resourcesErr.Limit != limit {
+			t.Fatalf("unexpected error: %v", resourcesErr)
+		}
+	}
+	countRows := func(db *sql.DB, query string) (int, error) {
+		rows, err := db.QueryContext(ctx, query)
+		if err != nil {
+			return 0, err
+		}
+		defer rows.Close()
+		var count int
+		for rows.Next() {
+			count++
+		}
+		return count, rows.Err()
+	}
+	t.Run("max result rows", func(t *testing.T) {
+		db := sql.OpenDB(zetasqlite.NewConnector(":memory:", zetasqlite.WithMaxResultRows(10)))
+		defer db.Close()
+		if count, err := countRows(db, "SELECT x FROM UNNEST(GENERATE_ARRAY(1, 10)) AS x"); err != nil || count != 10 {
+			t.Fatalf("unexpected result: count = %d, err = %v", count, err)
+		}
+		// the cross join never finishes in time if the rows are not limited.
+		_, err := countRows(db, `
+SELECT a, b, c FROM UNNEST(GENERATE_ARRAY(1, 1000)) AS a, UNNEST(GENERATE_ARRAY(1, 1000)) AS b, UNNEST(GENERATE_ARRAY(1, 1000)) AS c`)
+		assertResourcesExceeded(t, err, "result rows", 10)
+	})
+	t.Run("max buffered values", func(t *testing.T) {
+		db := sql.OpenDB(zetasqlite.NewConnector(":memory:", zetasqlite.WithMaxBufferedValues(100)))
+		defer db.Close()
+		if count, err := countRows(db, "SELECT ARRAY_AGG(x), COUNT(x) OVER () FROM UNNEST(GENERATE_ARRAY(1, 100)) AS x GROUP BY x"); err != nil || count != 100 {
+			t.Fatalf("unexpected result: count = %d, err = %v", count, err)
+		}
+		for _, query := range []string{
+			"SELECT ARRAY_AGG(x) FROM UNNEST(GENERATE_ARRAY(1, 1000)) AS x",
+			"SELECT STRING_AGG(CAST(x AS STRING)) FROM UNNEST(GENERATE_ARRAY(1, 1000)) AS x",
+			"SELECT ARRAY(SELECT x FROM UNNEST(GENERATE_ARRAY(1, 1000)) AS x)",
+			"SELECT SUM(x) OVER (ORDER BY x) FROM UNNEST(GENERATE_ARRAY(1, 1000)) AS x",
+		} {
+			_, err := countRows(db, query)
+			assertResourcesExceeded(t, err, "buffered values", 100)
+		}
+	})
+	t.Run("unlimited by default", func(t *testing.T) {
+		db, err := sql.Open("zetasqlite", ":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		if count, err := countRows(db, "SELECT COUNT(x) OVER () FROM UNNEST(GENERATE_ARRAY(1, 1000)) AS x"); err != nil || count != 1000 {
+			t.Fatalf("unexpected result: count = %d, err = %v", count, err)
+		}
+	})
+}
+
+func TestSQLiteError(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "sqlite_error.db")
+	db, err := sql.Open("zetasqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, `
+CREATE TABLE sqlite_error_items (id INT64, name STRING);
+INSERT sqlite_error_items (id, name) VALUES (1, 'a');
+`); err != nil {
+		t.Fatal(err)
+	}
+	// the column is renamed behind the catalog, so the translated statements referring to it fail in SQLite.
+	sqliteDB, err := sql.Open("zetasqlite_sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqliteDB.Close()
+	if _, err := sqliteDB.ExecContext(ctx, "ALTER TABLE `sqlite_error_items` RENAME COLUMN `name` TO `renamed`"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("exec", func(t *testing.T) {
+		_, err := db.ExecContext(
+			ctx,
+			"SELECT 1; UPDATE sqlite_error_items SET name = @name WHERE id = @id",
+			sql.Named("name", "b"), sql.Named("id", int64(1)),
+		)
+		var sqliteErr *zetasqlite.SQLiteError
+		if !errors.As(err, &sqliteErr) {
+			t.Fatalf("expected SQLiteError but got %v", err)
+		}
+		if sqliteErr.StatementIndex != 1 {
+			t.Errorf("unexpected statement index %d", sqliteErr.StatementIndex)
+		}
+		if sqliteErr.Query != "UPDATE sqlite_error_items SET name = @name WHERE id = @id" {
+			t.Errorf("unexpected query %q", sqliteErr.Query)
+		}
+		if !strings.Contains(sqliteErr.SQLiteQuery, "sqlite_error_items") {
+			t.Errorf("unexpected sqlite query %q", sqliteErr.SQLiteQuery)
+		}
+		params := append([]string{}, sqliteErr.Params...)
+		sort.Strings(params)
+		if diff := cmp.Diff([]string{"@id", "@name"}, params); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+		if !strings.Contains(err.Error(), "no such column") || !strings.Contains(err.Error(), "\nsqlite query:\n") {
+			t.Errorf("unexpected error message %s", err)
+		}
+	})
+	t.Run("query", func(t *testing.T) {
+		_, err := db.QueryContext(ctx, "SELECT name FROM sqlite_error_items WHERE id = ?", int64(1))
+		var sqliteErr *zetasqlite.SQLiteError
+		if !errors.As(err, &sqliteErr) {
+			t.Fatalf("expected SQLiteError but got %v", err)
+		}
+		if sqliteErr.StatementIndex != 0 || sqliteErr.Query != "SELECT name FROM sqlite_error_items WHERE id = ?" {
+			t.Errorf("unexpected statement %d: %q", sqliteErr.StatementIndex, sqliteErr.Query)
+		}
+		if diff := cmp.Diff([]string{"?"}, sqliteErr.Params); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+	})
+	t.Run("without query text", func(t *testing.T) {
+		hiddenDB := sql.OpenDB(zetasqlite.NewConnector(path, zetasqlite.WithErrorQueryText(false)))
+		defer hiddenDB.Close()
+		_, err := hiddenDB.QueryContext(ctx, "SELECT name FROM sqlite_error_items WHERE name = 'secret'")
+		var sqliteErr *zetasqlite.SQLiteError
+		if !errors.As(err, &sqliteErr) {
+			t.Fatalf("expected SQLiteError but got %v", err)
+		}
+		if sqliteErr.Query != "" || sqliteErr.SQLiteQuery != "" || strings.Contains(err.Error(), "secret") {
+			t.Errorf("query text must be hidden: %s", err)
+		}
+	})
+}
+
+func TestInsertManyValues(t *testing.T) {
+	const rowNum = 50000
+
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE insert_many_values (id INT64, name STRING, score FLOAT64, PRIMARY KEY (id))`); err != nil {
+		t.Fatal(err)
+	}
+	insertQuery := func(ids ...int) string {
+		rows := make([]string, 0, len(ids))
+		for _, id := range ids {
+			rows = append(rows, fmt.Sprintf("(%d, 'name%d', %d.5)", id, id, id))
+		}
+		return fmt.Sprintf("INSERT insert_many_values (id, name, score) VALUES %s", strings.Join(rows, ","))
+	}
+	countRows := func(t *testing.T) int64 {
+		t.Helper()
+		var count int64
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM insert_many_values`).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+	ids := make([]int, 0, rowNum)
+	for i := 0; i < rowNum; i++ {
+		ids = append(ids, i)
+	}
+	t.Run("many rows", func(t *testing.T) {
+		result, err := db.ExecContext(ctx, insertQuery(ids...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if affected != rowNum {
+			t.Fatalf("unexpected affected rows: %d", affected)
+		}
+		var (
+			name  string
+			score float64
+		)
+		if err := db.QueryRowContext(ctx, `SELECT name, score FROM insert_many_values WHERE id = 43210`).Scan(&name, &score); err != nil {
+			t.Fatal(err)
+		}
+		if name != "name43210" || score != 43210.5 {
+			t.Fatalf("unexpected row: %s %f", name, score)
+		}
+	})
+	t.Run("atomic", func(t *testing.T) {
+		if _, err := db.ExecContext(ctx, insertQuery(rowNum, rowNum+1, rowNum+2, 0)); err == nil {
+			t.Fatal("expected error")
+		}
+		// the duplicated key is in the last batch, so the previous batches must be rolled back.
+		more := make([]int, 0, 1001)
+		for i := 0; i < 1000; i++ {
+			more = append(more, rowNum+i)
+		}
+		more = append(more, 0)
+		if _, err := db.ExecContext(ctx, insertQuery(more...)); err == nil {
+			t.Fatal("expected error")
+		}
+		if count := countRows(t); count != rowNum {
+			t.Fatalf("unexpected number of rows: %d", count)
+		}
+	})
+	t.Run("rollback", func(t *testing.T) {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tx.ExecContext(ctx, insertQuery(rowNum+1, rowNum+2)); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		if count := countRows(t); count != rowNum {
+			t.Fatalf("unexpected number of rows: %d", count)
+		}
+	})
 }
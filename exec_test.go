@@ -632,3 +632,223 @@ LANGUAGE js AS """
 		}
 	})
 }
+
+func TestCorrelatedSubqueryInDML(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, `
+CREATE TABLE users (id INT64, name STRING);
+CREATE TABLE orders (id INT64, user_id INT64);
+INSERT users (id, name) VALUES (1, 'alice'), (2, 'bob'), (3, 'carol');
+INSERT orders (id, user_id) VALUES (1, 3), (2, 1), (3, 1);
+`); err != nil {
+		t.Fatal(err)
+	}
+	// orders has the id column too, so the correlated reference must be bound to users.id.
+	if _, err := db.ExecContext(ctx, `DELETE users u WHERE NOT EXISTS (SELECT 1 FROM orders o WHERE o.user_id = u.id)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, `
+UPDATE users u SET name = CONCAT(name, '-', CAST((SELECT COUNT(*) FROM orders o WHERE o.user_id = u.id) AS STRING)) WHERE TRUE
+`); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.QueryContext(ctx, `SELECT id, name FROM users ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var results []string
+	for rows.Next() {
+		var (
+			id   int64
+			name string
+		)
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, fmt.Sprintf("%d:%s", id, name))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"1:alice-2", "3:carol-1"}, results); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestWithClauseInDML(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, `
+CREATE TABLE users (id INT64, name STRING);
+CREATE TABLE archived_users (id INT64, name STRING);
+INSERT users (id, name) VALUES (1, 'alice'), (2, 'bob'), (3, 'carol'), (4, 'dave');
+`); err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{
+		`WITH inactive AS (SELECT id, name FROM users WHERE id > 2)
+INSERT archived_users (id, name) SELECT id, name FROM inactive`,
+		`WITH inactive AS (SELECT id FROM users WHERE id > 2), -- the entries can refer to the preceding ones
+last_user AS (SELECT MAX(id) AS id FROM inactive)
+DELETE FROM users WHERE id IN (SELECT id FROM inactive) AND id NOT IN (SELECT id FROM last_user)`,
+		`WITH renamed AS (SELECT 2 AS id, 'bobby' AS name)
+UPDATE users u SET name = (SELECT name FROM renamed r WHERE r.id = u.id) WHERE id IN (SELECT id FROM renamed)`,
+	} {
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			t.Fatalf("failed to exec %s: %v", query, err)
+		}
+	}
+	for _, test := range []struct {
+		query    string
+		expected []string
+	}{
+		{query: `SELECT id, name FROM users ORDER BY id`, expected: []string{"1:alice", "2:bobby", "4:dave"}},
+		{query: `SELECT id, name FROM archived_users ORDER BY id`, expected: []string{"3:carol", "4:dave"}},
+	} {
+		rows, err := db.QueryContext(ctx, test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var results []string
+		for rows.Next() {
+			var (
+				id   int64
+				name string
+			)
+			if err := rows.Scan(&id, &name); err != nil {
+				t.Fatal(err)
+			}
+			results = append(results, fmt.Sprintf("%d:%s", id, name))
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+		if diff := cmp.Diff(test.expected, results); diff != "" {
+			t.Errorf("%s: (-want +got):\n%s", test.query, diff)
+		}
+	}
+	if _, err := db.ExecContext(ctx, `WITH RECURSIVE r AS (SELECT 1 AS id) DELETE FROM users WHERE id IN (SELECT id FROM r)`); err == nil {
+		t.Fatal("expected error for WITH RECURSIVE clause")
+	}
+}
+
+func TestForInLoop(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, `
+CREATE TABLE t1 (id INT64);
+CREATE TABLE t2 (id INT64);
+INSERT t1 (id) VALUES (1), (2), (3);
+INSERT t2 (id) VALUES (10);
+`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, `
+CREATE TABLE counts (table_name STRING, cnt INT64);
+CREATE TABLE visited (id INT64);
+CREATE TABLE pairs (a INT64, b INT64);
+`); err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{
+		`FOR rec IN (SELECT table_name FROM INFORMATION_SCHEMA.TABLES WHERE table_name IN ('t1', 't2') ORDER BY table_name) DO
+  EXECUTE IMMEDIATE FORMAT('INSERT counts (table_name, cnt) SELECT "%s", COUNT(*) FROM ` + "`%s`" + `', rec.table_name, rec.table_name);
+END FOR`,
+		`FOR rec IN (SELECT id FROM t1 ORDER BY id) DO
+  INSERT visited (id) SELECT rec.id FROM UNNEST([1]) WHERE rec.id <> 2;
+  CONTINUE;
+  INSERT visited (id) VALUES (100);
+END FOR`,
+		`outer_loop: FOR x IN (SELECT id FROM t1 ORDER BY id) DO
+  FOR y IN (SELECT id FROM t1 ORDER BY id) DO
+    INSERT pairs (a, b) VALUES (x.id, y.id);
+    BREAK;
+  END FOR;
+END FOR`,
+		`EXECUTE IMMEDIATE 'INSERT visited (id) VALUES (@v), (?)' USING 200 AS v, 300`,
+	} {
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			t.Fatalf("failed to exec %s: %v", query, err)
+		}
+	}
+	for _, test := range []struct {
+		query    string
+		expected []string
+	}{
+		{query: `SELECT table_name, cnt FROM counts ORDER BY table_name`, expected: []string{"t1:3", "t2:1"}},
+		{query: `SELECT 'visited', id FROM visited ORDER BY id`, expected: []string{"visited:1", "visited:3", "visited:200", "visited:300"}},
+		{query: `SELECT CAST(a AS STRING), b FROM pairs ORDER BY a`, expected: []string{"1:1", "2:1", "3:1"}},
+	} {
+		rows, err := db.QueryContext(ctx, test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var results []string
+		for rows.Next() {
+			var (
+				name string
+				v    int64
+			)
+			if err := rows.Scan(&name, &v); err != nil {
+				t.Fatal(err)
+			}
+			results = append(results, fmt.Sprintf("%s:%d", name, v))
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+		if diff := cmp.Diff(test.expected, results); diff != "" {
+			t.Errorf("%s: (-want +got):\n%s", test.query, diff)
+		}
+	}
+	if _, err := db.ExecContext(ctx, `BREAK`); err == nil {
+		t.Fatal("expected error for BREAK outside of loop")
+	}
+}
+
+func TestUDFArgumentCoercion(t *testing.T) {
+	ctx := zetasqlite.WithQueryParamTypes(context.Background(), map[string]string{"start_date": "STRING"})
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, `
+CREATE TABLE events (event_date DATE);
+INSERT events (event_date) VALUES (DATE '2024-01-01'), (DATE '2024-02-01'), (DATE '2024-03-01');
+CREATE FUNCTION count_events_since(start_date DATE) AS ((SELECT COUNT(*) FROM events WHERE event_date >= start_date));
+`); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{query: `SELECT count_events_since('2024-02-01')`},
+		{query: `SELECT count_events_since(@start_date)`, args: []interface{}{sql.Named("start_date", "2024-02-01")}},
+	} {
+		var count int64
+		if err := db.QueryRowContext(ctx, test.query, test.args...).Scan(&count); err != nil {
+			t.Fatalf("failed to query %s: %v", test.query, err)
+		}
+		if count != 2 {
+			t.Errorf("%s: expected 2 but got %d", test.query, count)
+		}
+	}
+}
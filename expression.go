@@ -0,0 +1,29 @@
+package zetasqlite
+
+import (
+	"context"
+	"database/sql"
+
+	internal "github.com/goccy/go-zetasqlite/internal"
+)
+
+// Value is the value of the evaluated expression. It can be converted to the Go value by Interface().
+type Value = internal.Value
+
+// EvaluateExpr evaluates the standalone expression without building tables.
+// The columns are referred by name from the expression, and their types are inferred from the Go values
+// ( NULL is typed as INT64 ). The tables and functions of the database are also visible from the expression.
+func EvaluateExpr(ctx context.Context, db *sql.DB, expr string, columns map[string]interface{}) (Value, error) {
+	var value Value
+	if err := withZetaSQLiteConn(ctx, db, func(conn *ZetaSQLiteConn) error {
+		v, err := conn.EvaluateExpr(ctx, expr, columns)
+		if err != nil {
+			return err
+		}
+		value = v
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
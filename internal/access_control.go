@@ -0,0 +1,401 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+)
+
+const allPrivileges = "ALL PRIVILEGES"
+
+// GrantSpec is the privilege granted to the grantee by GRANT statement.
+type GrantSpec struct {
+	Privilege string `json:"privilege"`
+	Grantee   string `json:"grantee"`
+}
+
+// RowAccessPolicySpec is the row access policy created by CREATE ROW ACCESS POLICY statement.
+type RowAccessPolicySpec struct {
+	Name     string   `json:"name"`
+	Grantees []string `json:"grantees"`
+	// FilterPredicate is the predicate written in FILTER USING clause.
+	FilterPredicate string `json:"filterPredicate"`
+	// Filter is the predicate formatted to SQLite expression.
+	Filter    string    `json:"filter"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// accessControlUpdater updates the grants or row access policies of the copied table spec.
+type accessControlUpdater func(spec *TableSpec, now time.Time) error
+
+func (s *TableSpec) grant(privileges, grantees []string) {
+	for _, privilege := range privileges {
+		for _, grantee := range grantees {
+			if s.hasGrant(privilege, grantee) {
+				continue
+			}
+			s.Grants = append(s.Grants, &GrantSpec{Privilege: privilege, Grantee: grantee})
+		}
+	}
+}
+
+func (s *TableSpec) revoke(privileges, grantees []string) {
+	grants := make([]*GrantSpec, 0, len(s.Grants))
+	for _, grant := range s.Grants {
+		if containsFold(grantees, grant.Grantee) &&
+			(containsFold(privileges, grant.Privilege) || containsFold(privileges, allPrivileges)) {
+			continue
+		}
+		grants = append(grants, grant)
+	}
+	s.Grants = grants
+}
+
+func (s *TableSpec) hasGrant(privilege, grantee string) bool {
+	for _, grant := range s.Grants {
+		if strings.EqualFold(grant.Privilege, privilege) && grant.Grantee == grantee {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *TableSpec) rowAccessPolicy(name string) *RowAccessPolicySpec {
+	for _, policy := range s.RowAccessPolicies {
+		if strings.EqualFold(policy.Name, name) {
+			return policy
+		}
+	}
+	return nil
+}
+
+func (s *TableSpec) removeRowAccessPolicy(name string) {
+	policies := make([]*RowAccessPolicySpec, 0, len(s.RowAccessPolicies))
+	for _, policy := range s.RowAccessPolicies {
+		if strings.EqualFold(policy.Name, name) {
+			continue
+		}
+		policies = append(policies, policy)
+	}
+	s.RowAccessPolicies = policies
+}
+
+// rowAccessFilter returns the predicate to filter the rows visible to the user.
+// If the table has no row access policies, returns empty string.
+// If the table has row access policies but no policy is granted to the user, no rows are visible.
+func (s *TableSpec) rowAccessFilter(user string) string {
+	if len(s.RowAccessPolicies) == 0 {
+		return ""
+	}
+	var filters []string
+	for _, policy := range s.RowAccessPolicies {
+		if !policy.isGrantedTo(user) {
+			continue
+		}
+		filters = append(filters, fmt.Sprintf("(%s)", policy.Filter))
+	}
+	if len(filters) == 0 {
+		return "0"
+	}
+	return strings.Join(filters, " OR ")
+}
+
+func (p *RowAccessPolicySpec) isGrantedTo(user string) bool {
+	for _, grantee := range p.Grantees {
+		if strings.EqualFold(grantee, "allUsers") || strings.EqualFold(grantee, "allAuthenticatedUsers") {
+			return true
+		}
+		if user == "" {
+			continue
+		}
+		if strings.EqualFold(grantee, user) || strings.EqualFold(strings.TrimPrefix(grantee, "user:"), user) {
+			return true
+		}
+	}
+	return false
+}
+
+// rowAccessPolicyUpdater applies the action of ALTER ROW ACCESS POLICY statement to the policy.
+type rowAccessPolicyUpdater func(policy *RowAccessPolicySpec)
+
+func newRowAccessPolicyUpdaters(ctx context.Context, actions []ast.AlterActionNode) ([]rowAccessPolicyUpdater, error) {
+	updaters := make([]rowAccessPolicyUpdater, 0, len(actions))
+	for _, action := range actions {
+		switch act := action.(type) {
+		case *ast.GrantToActionNode:
+			grantees, err := granteesFromNodes(nil, act.GranteeExprList())
+			if err != nil {
+				return nil, err
+			}
+			updaters = append(updaters, func(policy *RowAccessPolicySpec) {
+				for _, grantee := range grantees {
+					if !containsFold(policy.Grantees, grantee) {
+						policy.Grantees = append(policy.Grantees, grantee)
+					}
+				}
+			})
+		case *ast.RevokeFromActionNode:
+			if act.IsRevokeFromAll() {
+				updaters = append(updaters, func(policy *RowAccessPolicySpec) {
+					policy.Grantees = nil
+				})
+				continue
+			}
+			revokees, err := granteesFromNodes(nil, act.RevokeeExprList())
+			if err != nil {
+				return nil, err
+			}
+			updaters = append(updaters, func(policy *RowAccessPolicySpec) {
+				grantees := make([]string, 0, len(policy.Grantees))
+				for _, grantee := range policy.Grantees {
+					if containsFold(revokees, grantee) {
+						continue
+					}
+					grantees = append(grantees, grantee)
+				}
+				policy.Grantees = grantees
+			})
+		case *ast.FilterUsingActionNode:
+			filter, err := newNode(act.Predicate()).FormatSQL(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to format filter predicate: %w", err)
+			}
+			predicate := act.PredicateStr()
+			updaters = append(updaters, func(policy *RowAccessPolicySpec) {
+				policy.FilterPredicate = predicate
+				policy.Filter = filter
+			})
+		case *ast.RenameToActionNode:
+			newPath := act.NewPath()
+			newName := newPath[len(newPath)-1]
+			updaters = append(updaters, func(policy *RowAccessPolicySpec) {
+				policy.Name = newName
+			})
+		default:
+			return nil, fmt.Errorf("unsupported action for row access policy %s", action.DebugString())
+		}
+	}
+	return updaters, nil
+}
+
+// updatedRowAccessPolicy returns the copy of the policy applied updaters.
+func updatedRowAccessPolicy(policy *RowAccessPolicySpec, updaters []rowAccessPolicyUpdater, now time.Time) *RowAccessPolicySpec {
+	updated := *policy
+	updated.Grantees = append([]string{}, policy.Grantees...)
+	for _, updater := range updaters {
+		updater(&updated)
+	}
+	updated.UpdatedAt = now
+	return &updated
+}
+
+// granteesFromNodes returns grantees specified as string list or string literals.
+func granteesFromNodes(grantees []string, exprs []ast.ExprNode) ([]string, error) {
+	ret := append([]string{}, grantees...)
+	for _, expr := range exprs {
+		literal, ok := expr.(*ast.LiteralNode)
+		if !ok {
+			return nil, fmt.Errorf("grantee must be string literal: %s", expr.DebugString())
+		}
+		ret = append(ret, literal.Value().StringValue())
+	}
+	return ret, nil
+}
+
+func privilegesFromNodes(nodes []*ast.PrivilegeNode) []string {
+	if len(nodes) == 0 {
+		return []string{allPrivileges}
+	}
+	privileges := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		privilege := node.ActionType()
+		if units := node.UnitList(); len(units) != 0 {
+			columns := make([]string, 0, len(units))
+			for _, unit := range units {
+				columns = append(columns, strings.Join(unit.NamePath(), "."))
+			}
+			privilege = fmt.Sprintf("%s(%s)", privilege, strings.Join(columns, ", "))
+		}
+		privileges = append(privileges, privilege)
+	}
+	return privileges
+}
+
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Analyzer) SetRowAccessPolicyMode(enabled bool) {
+	a.isRowAccessPolicyMode = enabled
+}
+
+func (a *Analyzer) SetSessionUser(user string) {
+	a.sessionUser = user
+}
+
+func (a *Analyzer) newGrantOrRevokeStmtAction(_ context.Context, _ string, node *ast.GrantOrRevokeStmtNode, isGrant bool) (*AccessControlStmtAction, error) {
+	switch strings.ToUpper(node.ObjectType()) {
+	case "", informationSchemaObjectTypeTable, informationSchemaObjectTypeView:
+	default:
+		return nil, fmt.Errorf("currently GRANT and REVOKE statements support TABLE and VIEW only: %s", node.ObjectType())
+	}
+	grantees, err := granteesFromNodes(node.GranteeList(), node.GranteeExprList())
+	if err != nil {
+		return nil, err
+	}
+	privileges := privilegesFromNodes(node.PrivilegeList())
+	return &AccessControlStmtAction{
+		name:    a.namePath.format(node.NamePath()),
+		catalog: a.catalog,
+		update: func(spec *TableSpec, _ time.Time) error {
+			if isGrant {
+				spec.grant(privileges, grantees)
+			} else {
+				spec.revoke(privileges, grantees)
+			}
+			return nil
+		},
+	}, nil
+}
+
+func (a *Analyzer) newCreateRowAccessPolicyStmtAction(ctx context.Context, _ string, node *ast.CreateRowAccessPolicyStmtNode) (*AccessControlStmtAction, error) {
+	grantees, err := granteesFromNodes(node.GranteeList(), node.GranteeExprList())
+	if err != nil {
+		return nil, err
+	}
+	filter := "1"
+	if predicate := node.Predicate(); predicate != nil {
+		formatted, err := newNode(predicate).FormatSQL(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to format filter predicate: %w", err)
+		}
+		filter = formatted
+	}
+	name := node.Name()
+	predicate := node.PredicateStr()
+	createMode := node.CreateMode()
+	return &AccessControlStmtAction{
+		name:    a.namePath.format(node.TargetNamePath()),
+		catalog: a.catalog,
+		update: func(spec *TableSpec, now time.Time) error {
+			createdAt := now
+			if current := spec.rowAccessPolicy(name); current != nil {
+				switch createMode {
+				case ast.CreateIfNotExistsMode:
+					return nil
+				case ast.CreateOrReplaceMode:
+					createdAt = current.CreatedAt
+					spec.removeRowAccessPolicy(name)
+				default:
+					return fmt.Errorf("row access policy %s already exists", name)
+				}
+			}
+			spec.RowAccessPolicies = append(spec.RowAccessPolicies, &RowAccessPolicySpec{
+				Name:            name,
+				Grantees:        grantees,
+				FilterPredicate: predicate,
+				Filter:          filter,
+				UpdatedAt:       now,
+				CreatedAt:       createdAt,
+			})
+			return nil
+		},
+	}, nil
+}
+
+func (a *Analyzer) newDropRowAccessPolicyStmtAction(_ context.Context, _ string, node *ast.DropRowAccessPolicyStmtNode) (*AccessControlStmtAction, error) {
+	name := node.Name()
+	isDropAll := node.IsDropAll()
+	isIfExists := node.IsIfExists()
+	return &AccessControlStmtAction{
+		name:    a.namePath.format(node.TargetNamePath()),
+		catalog: a.catalog,
+		update: func(spec *TableSpec, _ time.Time) error {
+			if isDropAll {
+				spec.RowAccessPolicies = nil
+				return nil
+			}
+			if spec.rowAccessPolicy(name) == nil {
+				if isIfExists {
+					return nil
+				}
+				return fmt.Errorf("row access policy %s is not found", name)
+			}
+			spec.removeRowAccessPolicy(name)
+			return nil
+		},
+	}, nil
+}
+
+func (a *Analyzer) newAlterRowAccessPolicyStmtAction(ctx context.Context, _ string, node *ast.AlterRowAccessPolicyStmtNode) (*AccessControlStmtAction, error) {
+	updaters, err := newRowAccessPolicyUpdaters(ctx, node.AlterActionList())
+	if err != nil {
+		return nil, err
+	}
+	name := node.Name()
+	isIfExists := node.IsIfExists()
+	return &AccessControlStmtAction{
+		name:       a.namePath.format(node.NamePath()),
+		isIfExists: isIfExists,
+		catalog:    a.catalog,
+		update: func(spec *TableSpec, now time.Time) error {
+			current := spec.rowAccessPolicy(name)
+			if current == nil {
+				if isIfExists {
+					return nil
+				}
+				return fmt.Errorf("row access policy %s is not found", name)
+			}
+			policy := updatedRowAccessPolicy(current, updaters, now)
+			if !strings.EqualFold(policy.Name, name) && spec.rowAccessPolicy(policy.Name) != nil {
+				return fmt.Errorf("row access policy %s already exists", policy.Name)
+			}
+			spec.removeRowAccessPolicy(name)
+			spec.RowAccessPolicies = append(spec.RowAccessPolicies, policy)
+			return nil
+		},
+	}, nil
+}
+
+func (a *Analyzer) newAlterAllRowAccessPoliciesStmtAction(ctx context.Context, _ string, node *ast.AlterAllRowAccessPoliciesStmtNode) (*AccessControlStmtAction, error) {
+	updaters, err := newRowAccessPolicyUpdaters(ctx, node.AlterActionList())
+	if err != nil {
+		return nil, err
+	}
+	return &AccessControlStmtAction{
+		name:       a.namePath.format(node.NamePath()),
+		isIfExists: node.IsIfExists(),
+		catalog:    a.catalog,
+		update: func(spec *TableSpec, now time.Time) error {
+			policies := make([]*RowAccessPolicySpec, 0, len(spec.RowAccessPolicies))
+			for _, policy := range spec.RowAccessPolicies {
+				policies = append(policies, updatedRowAccessPolicy(policy, updaters, now))
+			}
+			spec.RowAccessPolicies = policies
+			return nil
+		},
+	}, nil
+}
+
+// rowAccessPolicyFilter returns the predicate of row access policies to inject to the table scan.
+// Returns empty string if the row access policy mode is disabled or the table has no policies.
+func rowAccessPolicyFilter(ctx context.Context, tableName string) string {
+	analyzer := analyzerFromContext(ctx)
+	if analyzer == nil || !analyzer.isRowAccessPolicyMode {
+		return ""
+	}
+	spec := analyzer.catalog.tableSpec(tableName)
+	if spec == nil {
+		return ""
+	}
+	return spec.rowAccessFilter(analyzer.sessionUser)
+}
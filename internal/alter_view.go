@@ -0,0 +1,200 @@
+package internal
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	parsed_ast "github.com/goccy/go-zetasql/ast"
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+)
+
+// alterViewSavepointName is the savepoint to roll back ALTER VIEW SET AS statement.
+const alterViewSavepointName = "zetasqlite_alter_view"
+
+// alterViewSetAsStatement returns the ALTER VIEW or ALTER MATERIALIZED VIEW statement having SET AS action.
+// The action is written as ALTER VIEW name SET AS 'query' because ZetaSQL parses the body of SET AS as a literal.
+func alterViewSetAsStatement(stmt parsed_ast.StatementNode) *parsed_ast.AlterStatementBaseNode {
+	var node *parsed_ast.AlterStatementBaseNode
+	switch s := stmt.(type) {
+	case *parsed_ast.AlterViewStatementNode:
+		node = s.AlterStatementBaseNode
+	case *parsed_ast.AlterMaterializedViewStatementNode:
+		node = s.AlterStatementBaseNode
+	default:
+		return nil
+	}
+	if node.ActionList() == nil {
+		return nil
+	}
+	for _, action := range node.ActionList().Actions() {
+		if _, ok := action.(*parsed_ast.SetAsActionNode); ok {
+			return node
+		}
+	}
+	return nil
+}
+
+// newAlterViewSetAsStmtAction analyzes the new query of the view as CREATE OR REPLACE VIEW statement,
+// so that the query is validated against the current catalog in the same way as creating the view.
+func (a *Analyzer) newAlterViewSetAsStmtAction(ctx context.Context, query string, funcMap map[string]*FunctionSpec, node *parsed_ast.AlterStatementBaseNode) (*AlterViewSetAsStmtAction, error) {
+	actions := node.ActionList().Actions()
+	if len(actions) != 1 {
+		return nil, fmt.Errorf("SET AS action cannot be combined with other actions: %s", query)
+	}
+	body := actions[0].(*parsed_ast.SetAsActionNode).TextBody()
+	if body == nil {
+		return nil, fmt.Errorf("SET AS action of ALTER VIEW requires the query as a string literal: %s", query)
+	}
+	var path []string
+	for _, name := range node.Path().Names() {
+		path = append(path, name.Name())
+	}
+	name := a.namePath.format(path)
+	spec := a.catalog.tableSpec(name)
+	if spec == nil {
+		if node.IsIfExists() {
+			return &AlterViewSetAsStmtAction{name: name, isIfExists: true, catalog: a.catalog}, nil
+		}
+		return nil, fmt.Errorf("failed to find view %s", name)
+	}
+	if !spec.IsView {
+		return nil, fmt.Errorf("failed to alter view %s: %s is not a view", name, name)
+	}
+	var scope string
+	if spec.IsTemp {
+		scope = "TEMP "
+	}
+	createQuery := fmt.Sprintf("CREATE OR REPLACE %sVIEW `%s` AS %s", scope, strings.Join(path, "`.`"), body.Value())
+	stmts, err := parseScriptStatements(createQuery, a.opt.ParserOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the query of view %s: %w", name, err)
+	}
+	if len(stmts) != 1 {
+		return nil, fmt.Errorf("the query of view %s must be a single query: %s", name, body.Value())
+	}
+	stmtNode, _, err := a.analyzeStmt(createQuery, stmts[0], nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query of view %s: %w", name, err)
+	}
+	createView, ok := stmtNode.(*ast.CreateViewStmtNode)
+	if !ok {
+		return nil, fmt.Errorf("unexpected create view query %s", createQuery)
+	}
+	ctx = a.context(a.stmtContext(ctx, createView), funcMap, createView, stmts[0])
+	if err := a.validatePartitionFilter(ctx, createView); err != nil {
+		return nil, err
+	}
+	createAction, err := a.newCreateViewStmtAction(ctx, createQuery, nil, createView)
+	if err != nil {
+		return nil, err
+	}
+	return &AlterViewSetAsStmtAction{
+		name:       name,
+		spec:       createAction.spec,
+		isIfExists: node.IsIfExists(),
+		catalog:    a.catalog,
+	}, nil
+}
+
+// AlterViewSetAsStmtAction replaces the query of the view by ALTER VIEW SET AS statement.
+// The options and the creation time of the view are kept.
+type AlterViewSetAsStmtAction struct {
+	name       string
+	spec       *TableSpec
+	isIfExists bool
+	catalog    *Catalog
+}
+
+func (a *AlterViewSetAsStmtAction) exec(ctx context.Context, conn *Conn) error {
+	spec := a.catalog.tableSpec(a.name)
+	if spec == nil || a.spec == nil {
+		if a.isIfExists {
+			return nil
+		}
+		return fmt.Errorf("failed to find view %s", a.name)
+	}
+	newSpec := new(TableSpec)
+	*newSpec = *spec
+	newSpec.Columns = a.spec.Columns
+	newSpec.Query = a.spec.Query
+	newSpec.Definition = a.spec.Definition
+	newSpec.InvalidReason = ""
+	newSpec.UpdatedAt = time.Now()
+
+	var invalidated []*TableSpec
+	if err := conn.withSavepoint(ctx, alterViewSavepointName, func() error {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("DROP VIEW IF EXISTS `%s`", newSpec.TableName())); err != nil {
+			return fmt.Errorf("failed to drop view %s: %w", a.name, err)
+		}
+		if _, err := conn.ExecContext(ctx, newSpec.SQLiteSchema()); err != nil {
+			return fmt.Errorf("failed to exec %s: %w", newSpec.SQLiteSchema(), err)
+		}
+		if err := a.catalog.AddNewTableSpec(ctx, conn, newSpec); err != nil {
+			return fmt.Errorf("failed to update view spec: %w", err)
+		}
+		if isSameColumns(spec.Columns, newSpec.Columns) {
+			return nil
+		}
+		// the views referring to the columns of the view cannot be used if the columns are changed.
+		views, err := a.catalog.InvalidateDependentViews(
+			ctx, conn, newSpec.TableName(), fmt.Sprintf("%s was altered to have the different columns", a.name),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to invalidate views: %w", err)
+		}
+		invalidated = views
+		return nil
+	}); err != nil {
+		return err
+	}
+	if !newSpec.IsTemp {
+		conn.updateTable(newSpec)
+	}
+	for _, view := range invalidated {
+		if !view.IsTemp {
+			conn.updateTable(view)
+		}
+	}
+	return nil
+}
+
+func isSameColumns(a, b []*ColumnSpec) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx].Name != b[idx].Name || a[idx].Type.FormatType() != b[idx].Type.FormatType() {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *AlterViewSetAsStmtAction) Prepare(ctx context.Context, conn *Conn) (driver.Stmt, error) {
+	return nil, nil
+}
+
+func (a *AlterViewSetAsStmtAction) ExecContext(ctx context.Context, conn *Conn) (driver.Result, error) {
+	if err := a.exec(ctx, conn); err != nil {
+		return nil, err
+	}
+	return &Result{conn: conn}, nil
+}
+
+func (a *AlterViewSetAsStmtAction) QueryContext(ctx context.Context, conn *Conn) (*Rows, error) {
+	if err := a.exec(ctx, conn); err != nil {
+		return nil, err
+	}
+	return &Rows{conn: conn}, nil
+}
+
+func (a *AlterViewSetAsStmtAction) Args() []interface{} {
+	return nil
+}
+
+func (a *AlterViewSetAsStmtAction) Cleanup(ctx context.Context, conn *Conn) error {
+	return nil
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/goccy/go-zetasql"
+	parsed_ast "github.com/goccy/go-zetasql/ast"
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+)
+
+const defaultAnalysisCacheSize = 256
+
+// analysisCacheKey identifies the result of the analysis.
+// The query text is used as is without normalization because the resolved nodes refer to the byte offsets of it
+// ( e.g. the source text of PARTITION BY expression ).
+// The types of the undeclared parameters are not included because they are typed by the analysis itself,
+// so the result depends only on the parameter mode and the types declared by WithQueryParameterTypes.
+type analysisCacheKey struct {
+	query             string
+	start             int
+	end               int
+	mode              zetasql.ParameterMode
+	paramTypes        string
+	catalogGeneration uint64
+}
+
+type analysisCacheEntry struct {
+	key    analysisCacheKey
+	output *zetasql.AnalyzerOutput
+}
+
+// analysisCache is the LRU cache of the analyzed statements.
+// The analysis crosses into C++ via cgo, so reusing the result makes the repeated queries much faster.
+// The entries analyzed with the older catalog generation are never hit because the generation is bumped by any catalog change.
+type analysisCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[analysisCacheKey]*list.Element
+	lru     *list.List
+}
+
+func newAnalysisCache(size int) *analysisCache {
+	return &analysisCache{
+		size:    size,
+		entries: map[analysisCacheKey]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+func newAnalysisCacheKey(query string, stmt parsed_ast.StatementNode, mode zetasql.ParameterMode, paramTypes queryParameterTypes, generation uint64) analysisCacheKey {
+	key := analysisCacheKey{
+		query:             query,
+		mode:              mode,
+		paramTypes:        paramTypes.cacheKey(),
+		catalogGeneration: generation,
+	}
+	if loc := stmt.ParseLocationRange(); loc != nil {
+		key.start = loc.Start().ByteOffset()
+		key.end = loc.End().ByteOffset()
+	}
+	return key
+}
+
+func (c *analysisCache) get(key analysisCacheKey) (ast.StatementNode, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, exists := c.entries[key]
+	if !exists {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*analysisCacheEntry).output.Statement(), true
+}
+
+func (c *analysisCache) add(key analysisCacheKey, output *zetasql.AnalyzerOutput) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, exists := c.entries[key]; exists {
+		elem.Value.(*analysisCacheEntry).output = output
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&analysisCacheEntry{key: key, output: output})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*analysisCacheEntry).key)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql/driver"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/goccy/go-zetasql"
@@ -13,69 +14,48 @@ import (
 )
 
 type Analyzer struct {
-	namePath        *NamePath
-	isAutoIndexMode bool
-	isExplainMode   bool
-	catalog         *Catalog
-	opt             *zetasql.AnalyzerOptions
+	namePath                   *NamePath
+	isAutoIndexMode            bool
+	isExplainMode              bool
+	isDeterministicOutputOrder bool
+	isPrettyFormatMode         bool
+	catalog                    *Catalog
+	opt                        *zetasql.AnalyzerOptions
+	moduleResolver             ModuleResolver
+	importStack                []string
+	isRowAccessPolicyMode      bool
+	sessionUser                string
+	queryLabels                []*jobLabel
+	timeZone                   string
+	isReadOnlyMode             bool
+	analysisCache              *analysisCache
+	withClauseCatalog          *withClauseCatalog
+	queryLogger                QueryLogger
+	isErrorQueryTextHidden     bool
+	languageFeatures           []zetasql.LanguageFeature
+	maxResultRows              int64
+	maxBufferedValues          int64
 }
 
 func NewAnalyzer(catalog *Catalog) (*Analyzer, error) {
-	opt, err := newAnalyzerOptions()
+	opt, err := newAnalyzerOptions(defaultLanguageFeatures)
 	if err != nil {
 		return nil, err
 	}
 	return &Analyzer{
-		catalog:  catalog,
-		opt:      opt,
-		namePath: &NamePath{},
+		catalog:          catalog,
+		opt:              opt,
+		languageFeatures: defaultLanguageFeatures,
+		namePath:         &NamePath{},
+		analysisCache:    newAnalysisCache(defaultAnalysisCacheSize),
 	}, nil
 }
 
-func newAnalyzerOptions() (*zetasql.AnalyzerOptions, error) {
+func newAnalyzerOptions(features []zetasql.LanguageFeature) (*zetasql.AnalyzerOptions, error) {
 	langOpt := zetasql.NewLanguageOptions()
 	langOpt.SetNameResolutionMode(zetasql.NameResolutionDefault)
 	langOpt.SetProductMode(types.ProductInternal)
-	langOpt.SetEnabledLanguageFeatures([]zetasql.LanguageFeature{
-		zetasql.FeatureAnalyticFunctions,
-		zetasql.FeatureNamedArguments,
-		zetasql.FeatureNumericType,
-		zetasql.FeatureBignumericType,
-		zetasql.FeatureV13DecimalAlias,
-		zetasql.FeatureCreateTableNotNull,
-		zetasql.FeatureParameterizedTypes,
-		zetasql.FeatureTablesample,
-		zetasql.FeatureTimestampNanos,
-		zetasql.FeatureV11HavingInAggregate,
-		zetasql.FeatureV11NullHandlingModifierInAggregate,
-		zetasql.FeatureV11NullHandlingModifierInAnalytic,
-		zetasql.FeatureV11OrderByCollate,
-		zetasql.FeatureV11SelectStarExceptReplace,
-		zetasql.FeatureV12SafeFunctionCall,
-		zetasql.FeatureJsonType,
-		zetasql.FeatureJsonArrayFunctions,
-		zetasql.FeatureJsonStrictNumberParsing,
-		zetasql.FeatureV13IsDistinct,
-		zetasql.FeatureV13FormatInCast,
-		zetasql.FeatureV13DateArithmetics,
-		zetasql.FeatureV11OrderByInAggregate,
-		zetasql.FeatureV11LimitInAggregate,
-		zetasql.FeatureV13DateTimeConstructors,
-		zetasql.FeatureV13ExtendedDateTimeSignatures,
-		zetasql.FeatureV12CivilTime,
-		zetasql.FeatureV12WeekWithWeekday,
-		zetasql.FeatureIntervalType,
-		zetasql.FeatureGroupByRollup,
-		zetasql.FeatureV13NullsFirstLastInOrderBy,
-		zetasql.FeatureV13Qualify,
-		zetasql.FeatureV13AllowDashesInTableName,
-		zetasql.FeatureGeography,
-		zetasql.FeatureV13ExtendedGeographyParsers,
-		zetasql.FeatureTemplateFunctions,
-		zetasql.FeatureV11WithOnSubquery,
-		zetasql.FeatureV13Pivot,
-		zetasql.FeatureV13Unpivot,
-	})
+	langOpt.SetEnabledLanguageFeatures(features)
 	langOpt.SetSupportedStatementKinds([]ast.Kind{
 		ast.BeginStmt,
 		ast.CommitStmt,
@@ -93,6 +73,19 @@ func newAnalyzerOptions() (*zetasql.AnalyzerOptions, error) {
 		ast.CreateTableFunctionStmt,
 		ast.CreateViewStmt,
 		ast.DropFunctionStmt,
+		ast.AlterTableStmt,
+		ast.AlterTableSetOptionsStmt,
+		ast.RenameStmt,
+		ast.AlterViewStmt,
+		ast.AlterMaterializedViewStmt,
+		ast.ImportStmt,
+		ast.ModuleStmt,
+		ast.GrantStmt,
+		ast.RevokeStmt,
+		ast.CreateRowAccessPolicyStmt,
+		ast.DropRowAccessPolicyStmt,
+		ast.AlterRowAccessPolicyStmt,
+		ast.AlterAllRowAccessPoliciesStmt,
 	})
 	// Enable QUALIFY without WHERE
 	// https://github.com/google/zetasql/issues/124
@@ -114,6 +107,73 @@ func (a *Analyzer) SetExplainMode(enabled bool) {
 	a.isExplainMode = enabled
 }
 
+func (a *Analyzer) SetDeterministicOutputOrder(enabled bool) {
+	a.isDeterministicOutputOrder = enabled
+}
+
+func (a *Analyzer) SetPrettyFormatMode(enabled bool) {
+	a.isPrettyFormatMode = enabled
+}
+
+// formatQuery returns the query pretty-printed by FormatPrettySQL when the pretty format mode is enabled.
+func (a *Analyzer) formatQuery(query string) string {
+	if !a.isPrettyFormatMode {
+		return query
+	}
+	return FormatPrettySQL(query)
+}
+
+// SetAnalysisCacheMode enables or disables the cache of the analyzed statements.
+func (a *Analyzer) SetAnalysisCacheMode(enabled bool) {
+	if !enabled {
+		a.analysisCache = nil
+		return
+	}
+	if a.analysisCache == nil {
+		a.analysisCache = newAnalysisCache(defaultAnalysisCacheSize)
+	}
+}
+
+func (a *Analyzer) SetAutoRegisterNativeTableMode(enabled bool) {
+	a.catalog.SetAutoRegisterNativeTableMode(enabled)
+}
+
+func (a *Analyzer) SetTableExpirationMode(enabled bool) {
+	a.catalog.SetTableExpirationMode(enabled)
+}
+
+func (a *Analyzer) TableSpec(path []string) (*TableSpec, error) {
+	name := a.namePath.format(path)
+	spec := a.catalog.tableSpec(name)
+	if spec == nil {
+		return nil, fmt.Errorf("failed to find table spec %s", name)
+	}
+	return spec, nil
+}
+
+func (a *Analyzer) TakeSnapshot(ctx context.Context, conn *Conn) (*DatabaseSnapshot, error) {
+	return a.catalog.TakeSnapshot(ctx, conn)
+}
+
+func (a *Analyzer) RestoreSnapshot(ctx context.Context, conn *Conn, snapshot *DatabaseSnapshot) error {
+	if err := a.validateReadOnlyOperation(KindDDL); err != nil {
+		return err
+	}
+	return a.catalog.RestoreSnapshot(ctx, conn, snapshot)
+}
+
+// Catalog synchronizes the catalog with the database and returns the objects in it.
+func (a *Analyzer) Catalog(ctx context.Context, conn *Conn) (*CatalogListing, error) {
+	if err := a.catalog.Sync(ctx, conn); err != nil {
+		return nil, fmt.Errorf("failed to sync catalog: %w", err)
+	}
+	return a.catalog.Listing(), nil
+}
+
+func (a *Analyzer) RefreshCatalog(ctx context.Context, conn *Conn) error {
+	return a.catalog.Refresh(ctx, conn)
+}
+
 func (a *Analyzer) NamePath() []string {
 	return a.namePath.path
 }
@@ -135,24 +195,25 @@ func (a *Analyzer) AddNamePath(path string) error {
 }
 
 func (a *Analyzer) parseScript(query string) ([]parsed_ast.StatementNode, error) {
-	loc := zetasql.NewParseResumeLocation(query)
+	scriptStmts, err := parseScriptStatements(query, a.opt.ParserOptions())
+	if err != nil {
+		return nil, err
+	}
+	return flattenScript(scriptStmts), nil
+}
+
+// flattenScript expands the statements of BEGIN...END blocks to run them in order.
+func flattenScript(scriptStmts []parsed_ast.StatementNode) []parsed_ast.StatementNode {
 	var stmts []parsed_ast.StatementNode
-	for {
-		stmt, isEnd, err := zetasql.ParseNextScriptStatement(loc, a.opt.ParserOptions())
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse statement: %w", err)
-		}
+	for _, stmt := range scriptStmts {
 		switch s := stmt.(type) {
 		case *parsed_ast.BeginEndBlockNode:
 			stmts = append(stmts, s.StatementList()...)
 		default:
 			stmts = append(stmts, s)
 		}
-		if isEnd {
-			break
-		}
 	}
-	return stmts, nil
+	return stmts
 }
 
 func (a *Analyzer) getParameterMode(stmt parsed_ast.StatementNode) (zetasql.ParameterMode, error) {
@@ -184,38 +245,133 @@ func (a *Analyzer) getParameterMode(stmt parsed_ast.StatementNode) (zetasql.Para
 type StmtActionFunc func() (StmtAction, error)
 
 func (a *Analyzer) Analyze(ctx context.Context, conn *Conn, query string, args []driver.NamedValue) ([]StmtActionFunc, error) {
+	if err := validateQueryMetadata(ctx); err != nil {
+		return nil, err
+	}
 	if err := a.catalog.Sync(ctx, conn); err != nil {
 		return nil, fmt.Errorf("failed to sync catalog: %w", err)
 	}
-	stmts, err := a.parseScript(query)
+	paramTypes, err := a.resolveQueryParameterTypes(queryParameterTypeNames(ctx))
+	if err != nil {
+		return nil, err
+	}
+	actionFuncs, err := a.analyzeScript(ctx, conn, query, args, paramTypes)
+	if err != nil {
+		return nil, err
+	}
+	conn.job = newJob(query, len(actionFuncs))
+	return actionFuncs, nil
+}
+
+// analyzeScript returns the actions of the statements in the script.
+// It is also used to run the statements in the body of FOR ... IN loop and the SQL of EXECUTE IMMEDIATE statement
+// with the query parameters declared by the script.
+func (a *Analyzer) analyzeScript(ctx context.Context, conn *Conn, query string, args []driver.NamedValue, paramTypes queryParameterTypes) ([]StmtActionFunc, error) {
+	query, err := rewriteGroupByAll(query, a.opt.ParserOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse statements: %w", err)
+	}
+	// ZetaSQL cannot parse WITH clause preceding DML statement, so the clause is split from the statement.
+	withClause, dmlQuery, err := splitDMLWithClause(query)
+	if err != nil {
+		return nil, err
+	}
+	stmtQuery := query
+	if withClause != nil {
+		stmtQuery = dmlQuery
+	}
+	scriptStmts, err := parseScriptStatements(stmtQuery, a.opt.ParserOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse statements: %w", err)
 	}
-	funcMap := map[string]*FunctionSpec{}
-	for _, spec := range a.catalog.getFunctions(a.namePath) {
-		funcMap[spec.FuncName()] = spec
+	if withClause != nil && len(scriptStmts) != 1 {
+		return nil, fmt.Errorf("WITH clause must be followed by a single DML statement")
+	}
+	if err := a.validateReadOnlyScript(scriptStmts); err != nil {
+		return nil, err
+	}
+	stmts := flattenScript(scriptStmts)
+	if err := paramTypes.validateNamedValues(args); err != nil {
+		return nil, err
 	}
+	ctx = withQueryParameterTypes(ctx, paramTypes)
+	funcMap := a.funcMap()
 	actionFuncs := make([]StmtActionFunc, 0, len(stmts))
-	for _, stmt := range stmts {
+	for idx, stmt := range stmts {
 		stmt := stmt
+		stmtText, err := nodeText(stmtQuery, stmt)
+		if err != nil {
+			stmtText = query
+		}
+		stmtCtx := withStatementLocation(ctx, idx, stmtText)
+		switch s := stmt.(type) {
+		case *parsed_ast.SystemVariableAssignmentNode:
+			// SET @@name = value is a script statement, so it cannot be analyzed as a statement.
+			actionFuncs = append(actionFuncs, func() (StmtAction, error) {
+				return a.newSystemVariableAssignmentStmtAction(s)
+			})
+			continue
+		case *parsed_ast.ForInStatementNode:
+			actionFuncs = append(actionFuncs, func() (StmtAction, error) {
+				conn.job.setScript()
+				return a.newForInStmtAction(query, args, paramTypes, s)
+			})
+			continue
+		case *parsed_ast.ExecuteImmediateStatementNode:
+			// the resolved EXECUTE IMMEDIATE statement doesn't provide the SQL expression, so it is evaluated by the query.
+			actionFuncs = append(actionFuncs, func() (StmtAction, error) {
+				conn.job.setScript()
+				return a.newExecuteImmediateStmtAction(query, args, paramTypes, s)
+			})
+			continue
+		case *parsed_ast.BreakStatementNode:
+			actionFuncs = append(actionFuncs, func() (StmtAction, error) {
+				return newLoopControlStmtAction(true, s.Label()), nil
+			})
+			continue
+		case *parsed_ast.ContinueStatementNode:
+			actionFuncs = append(actionFuncs, func() (StmtAction, error) {
+				return newLoopControlStmtAction(false, s.Label()), nil
+			})
+			continue
+		}
+		if alterView := alterViewSetAsStatement(stmt); alterView != nil {
+			// ZetaSQL resolves SET AS action for ALTER ENTITY statement only, so the view query is analyzed by itself.
+			actionFuncs = append(actionFuncs, func() (StmtAction, error) {
+				return a.newAlterViewSetAsStmtAction(ctx, query, funcMap, alterView)
+			})
+			continue
+		}
+		if withClause != nil {
+			actionFuncs = append(actionFuncs, func() (StmtAction, error) {
+				stmtNode, err := a.analyzeDMLWithClause(ctx, stmtQuery, funcMap, withClause, stmt, paramTypes)
+				if err != nil {
+					return nil, err
+				}
+				if err := a.validateReadOnlyStmt(stmtNode); err != nil {
+					return nil, err
+				}
+				conn.job.setStatementType(stmtNode)
+				// the statement is preceded by WITH clause, so the whole query is reported as the statement.
+				stmtCtx := withStatementLocation(ctx, idx, query)
+				action, err := a.newStmtAction(a.context(stmtCtx, funcMap, stmtNode, stmt), stmtQuery, args, stmtNode)
+				if err != nil {
+					return nil, err
+				}
+				return withClause.apply(action)
+			})
+			continue
+		}
 		actionFuncs = append(actionFuncs, func() (StmtAction, error) {
-			mode, err := a.getParameterMode(stmt)
+			stmtNode, mode, err := a.analyzeStmt(query, stmt, paramTypes)
 			if err != nil {
 				return nil, err
 			}
-			a.opt.SetParameterMode(mode)
-			out, err := zetasql.AnalyzeStatementFromParserAST(
-				query,
-				stmt,
-				a.catalog,
-				a.opt,
-			)
-			if err != nil {
-				return nil, fmt.Errorf("failed to analyze: %w", err)
+			if err := a.validateReadOnlyStmt(stmtNode); err != nil {
+				return nil, err
 			}
-			stmtNode := out.Statement()
-			ctx = a.context(ctx, funcMap, stmtNode, stmt)
-			action, err := a.newStmtAction(ctx, query, args, stmtNode)
+			conn.job.setStatementType(stmtNode)
+			action, err := a.newStmtAction(a.context(stmtCtx, funcMap, stmtNode, stmt), query, args, stmtNode)
 			if err != nil {
 				return nil, err
 			}
@@ -228,11 +384,70 @@ func (a *Analyzer) Analyze(ctx context.Context, conn *Conn, query string, args [
 	return actionFuncs, nil
 }
 
+func (a *Analyzer) analyzeStmt(query string, stmt parsed_ast.StatementNode, paramTypes queryParameterTypes) (ast.StatementNode, zetasql.ParameterMode, error) {
+	mode, err := a.getParameterMode(stmt)
+	if err != nil {
+		return nil, mode, err
+	}
+	if mode != zetasql.ParameterNamed {
+		// the declared types are only for the named parameters.
+		paramTypes = nil
+	}
+	a.opt.SetParameterMode(mode)
+	cache := a.usableAnalysisCache()
+	var key analysisCacheKey
+	if cache != nil {
+		key = newAnalysisCacheKey(query, stmt, mode, paramTypes, a.catalog.Generation())
+		if node, exists := cache.get(key); exists {
+			return node, mode, nil
+		}
+	}
+	if len(paramTypes) != 0 {
+		if err := paramTypes.addTo(a.opt); err != nil {
+			return nil, mode, err
+		}
+		defer a.opt.ClearQueryParameters()
+	}
+	out, err := zetasql.AnalyzeStatementFromParserAST(
+		query,
+		stmt,
+		a.analysisCatalog(),
+		a.opt,
+	)
+	if err != nil {
+		return nil, mode, fmt.Errorf("failed to analyze: %w", err)
+	}
+	if cache != nil {
+		cache.add(key, out)
+	}
+	return out.Statement(), mode, nil
+}
+
+// usableAnalysisCache returns nil if the results of the analysis must not be reused.
+func (a *Analyzer) usableAnalysisCache() *analysisCache {
+	if a.analysisCache == nil || a.withClauseCatalog != nil {
+		return nil
+	}
+	if a.catalog.isTableExpirationModeEnabled() {
+		// the expiration of the tables is checked while analyzing.
+		return nil
+	}
+	return a.analysisCache
+}
+
+func (a *Analyzer) funcMap() map[string]*FunctionSpec {
+	funcMap := map[string]*FunctionSpec{}
+	for _, spec := range a.catalog.getFunctions(a.namePath) {
+		funcMap[spec.FuncName()] = spec
+	}
+	return funcMap
+}
+
 func (a *Analyzer) context(
 	ctx context.Context,
 	funcMap map[string]*FunctionSpec,
-	stmtNode ast.StatementNode,
-	stmt parsed_ast.StatementNode) context.Context {
+	stmtNode ast.Node,
+	stmt parsed_ast.Node) context.Context {
 	ctx = withAnalyzer(ctx, a)
 	ctx = withNamePath(ctx, a.namePath)
 	ctx = withColumnRefMap(ctx, map[string]string{})
@@ -243,12 +458,27 @@ func (a *Analyzer) context(
 	return ctx
 }
 
+// analyzeTemplatedFunctionWithRuntimeArgument analyzes the function defined by the argument types of the call.
+// It is called whenever the templated function is called, so the result of the analysis is cached.
 func (a *Analyzer) analyzeTemplatedFunctionWithRuntimeArgument(ctx context.Context, query string) (*FunctionSpec, error) {
-	out, err := zetasql.AnalyzeStatement(query, a.catalog, a.opt)
-	if err != nil {
-		return nil, fmt.Errorf("failed to analyze: %w", err)
+	var (
+		node  ast.StatementNode
+		cache = a.usableAnalysisCache()
+		key   = analysisCacheKey{query: query, end: len(query), catalogGeneration: a.catalog.Generation()}
+	)
+	if cache != nil {
+		node, _ = cache.get(key)
+	}
+	if node == nil {
+		out, err := zetasql.AnalyzeStatement(query, a.catalog, a.opt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to analyze: %w", err)
+		}
+		if cache != nil {
+			cache.add(key, out)
+		}
+		node = out.Statement()
 	}
-	node := out.Statement()
 	stmt, ok := node.(*ast.CreateFunctionStmtNode)
 	if !ok {
 		return nil, fmt.Errorf("unexpected create function query %s", query)
@@ -260,17 +490,29 @@ func (a *Analyzer) analyzeTemplatedFunctionWithRuntimeArgument(ctx context.Conte
 	return spec, nil
 }
 
+// stmtContext returns the context to format the statement.
+// The statements that refer to the columns of sub queries use the column id to identify the column.
+func (a *Analyzer) stmtContext(ctx context.Context, node ast.StatementNode) context.Context {
+	switch node.Kind() {
+	case ast.CreateTableAsSelectStmt, ast.CreateViewStmt, ast.MergeStmt, ast.QueryStmt:
+		return withUseColumnID(ctx)
+	}
+	return ctx
+}
+
 func (a *Analyzer) newStmtAction(ctx context.Context, query string, args []driver.NamedValue, node ast.StatementNode) (StmtAction, error) {
+	ctx = a.stmtContext(ctx, node)
+	if err := a.validatePartitionFilter(ctx, node); err != nil {
+		return nil, err
+	}
 	switch node.Kind() {
 	case ast.CreateTableStmt:
 		return a.newCreateTableStmtAction(ctx, query, args, node.(*ast.CreateTableStmtNode))
 	case ast.CreateTableAsSelectStmt:
-		ctx = withUseColumnID(ctx)
 		return a.newCreateTableAsSelectStmtAction(ctx, query, args, node.(*ast.CreateTableAsSelectStmtNode))
 	case ast.CreateFunctionStmt:
 		return a.newCreateFunctionStmtAction(ctx, query, args, node.(*ast.CreateFunctionStmtNode))
 	case ast.CreateViewStmt:
-		ctx = withUseColumnID(ctx)
 		return a.newCreateViewStmtAction(ctx, query, args, node.(*ast.CreateViewStmtNode))
 	case ast.DropStmt:
 		return a.newDropStmtAction(ctx, query, args, node.(*ast.DropStmtNode))
@@ -280,11 +522,35 @@ func (a *Analyzer) newStmtAction(ctx context.Context, query string, args []drive
 		return a.newDMLStmtAction(ctx, query, args, node)
 	case ast.TruncateStmt:
 		return a.newTruncateStmtAction(ctx, query, args, node.(*ast.TruncateStmtNode))
+	case ast.AlterTableStmt:
+		return a.newAlterObjectStmtAction(ctx, query, node.(*ast.AlterTableStmtNode).AlterObjectStmtNode)
+	case ast.AlterViewStmt:
+		return a.newAlterObjectStmtAction(ctx, query, node.(*ast.AlterViewStmtNode).AlterObjectStmtNode)
+	case ast.AlterMaterializedViewStmt:
+		return a.newAlterObjectStmtAction(ctx, query, node.(*ast.AlterMaterializedViewStmtNode).AlterObjectStmtNode)
+	case ast.AlterTableSetOptionsStmt:
+		return a.newAlterTableSetOptionsStmtAction(ctx, query, node.(*ast.AlterTableSetOptionsStmtNode))
+	case ast.RenameStmt:
+		return a.newRenameStmtAction(query, node.(*ast.RenameStmtNode))
+	case ast.GrantStmt:
+		return a.newGrantOrRevokeStmtAction(ctx, query, node.(*ast.GrantStmtNode).GrantOrRevokeStmtNode, true)
+	case ast.RevokeStmt:
+		return a.newGrantOrRevokeStmtAction(ctx, query, node.(*ast.RevokeStmtNode).GrantOrRevokeStmtNode, false)
+	case ast.CreateRowAccessPolicyStmt:
+		return a.newCreateRowAccessPolicyStmtAction(ctx, query, node.(*ast.CreateRowAccessPolicyStmtNode))
+	case ast.DropRowAccessPolicyStmt:
+		return a.newDropRowAccessPolicyStmtAction(ctx, query, node.(*ast.DropRowAccessPolicyStmtNode))
+	case ast.AlterRowAccessPolicyStmt:
+		return a.newAlterRowAccessPolicyStmtAction(ctx, query, node.(*ast.AlterRowAccessPolicyStmtNode))
+	case ast.AlterAllRowAccessPoliciesStmt:
+		return a.newAlterAllRowAccessPoliciesStmtAction(ctx, query, node.(*ast.AlterAllRowAccessPoliciesStmtNode))
+	case ast.ImportStmt:
+		return a.newImportStmtAction(ctx, query, node.(*ast.ImportStmtNode))
+	case ast.ModuleStmt:
+		return nil, fmt.Errorf("MODULE statement is allowed only in the module imported by IMPORT MODULE statement")
 	case ast.MergeStmt:
-		ctx = withUseColumnID(ctx)
 		return a.newMergeStmtAction(ctx, query, args, node.(*ast.MergeStmtNode))
 	case ast.QueryStmt:
-		ctx = withUseColumnID(ctx)
 		return a.newQueryStmtAction(ctx, query, args, node.(*ast.QueryStmtNode))
 	case ast.BeginStmt:
 		return a.newBeginStmtAction(ctx, query, args, node)
@@ -294,36 +560,65 @@ func (a *Analyzer) newStmtAction(ctx context.Context, query string, args []drive
 	return nil, fmt.Errorf("unsupported stmt %s", node.DebugString())
 }
 
-func (a *Analyzer) newCreateTableStmtAction(_ context.Context, query string, args []driver.NamedValue, node *ast.CreateTableStmtNode) (*CreateTableStmtAction, error) {
+func (a *Analyzer) newCreateTableStmtAction(ctx context.Context, query string, args []driver.NamedValue, node *ast.CreateTableStmtNode) (*CreateTableStmtAction, error) {
 	spec := newTableSpec(a.namePath, node)
+	checkConstraints, err := newCheckConstraintSpecs(ctx, query, node.CheckConstraintList())
+	if err != nil {
+		return nil, err
+	}
+	spec.CheckConstraints = checkConstraints
+	columnNames := map[int]string{}
+	for _, def := range node.ColumnDefinitionList() {
+		columnNames[def.Column().ColumnID()] = def.Name()
+	}
+	if err := spec.setPartitioning(query, node.PartitionByList(), node.ClusterByList(), columnNames); err != nil {
+		return nil, err
+	}
 	params := getParamsFromNode(node)
 	queryArgs, err := getArgsFromParams(args, params)
 	if err != nil {
 		return nil, err
 	}
+	options, err := newOptionExprs(ctx, node.OptionList())
+	if err != nil {
+		return nil, err
+	}
 	return &CreateTableStmtAction{
 		query:           query,
 		spec:            spec,
+		options:         options,
 		args:            queryArgs,
 		catalog:         a.catalog,
 		isAutoIndexMode: a.isAutoIndexMode,
 	}, nil
 }
 
-func (a *Analyzer) newCreateTableAsSelectStmtAction(ctx context.Context, _ string, args []driver.NamedValue, node *ast.CreateTableAsSelectStmtNode) (*CreateTableStmtAction, error) {
+func (a *Analyzer) newCreateTableAsSelectStmtAction(ctx context.Context, stmt string, args []driver.NamedValue, node *ast.CreateTableAsSelectStmtNode) (*CreateTableStmtAction, error) {
 	query, err := newNode(node.Query()).FormatSQL(ctx)
 	if err != nil {
 		return nil, err
 	}
 	spec := newTableAsSelectSpec(a.namePath, query, node)
+	columnNames := map[int]string{}
+	for _, column := range node.OutputColumnList() {
+		columnNames[column.Column().ColumnID()] = column.Name()
+	}
+	if err := spec.setPartitioning(stmt, node.PartitionByList(), node.ClusterByList(), columnNames); err != nil {
+		return nil, err
+	}
 	params := getParamsFromNode(node)
 	queryArgs, err := getArgsFromParams(args, params)
 	if err != nil {
 		return nil, err
 	}
+	options, err := newOptionExprs(ctx, node.OptionList())
+	if err != nil {
+		return nil, err
+	}
 	return &CreateTableStmtAction{
 		query:           query,
 		spec:            spec,
+		options:         options,
 		args:            queryArgs,
 		catalog:         a.catalog,
 		isAutoIndexMode: a.isAutoIndexMode,
@@ -331,40 +626,51 @@ func (a *Analyzer) newCreateTableAsSelectStmtAction(ctx context.Context, _ strin
 }
 
 func (a *Analyzer) newCreateFunctionStmtAction(ctx context.Context, query string, _ []driver.NamedValue, node *ast.CreateFunctionStmtNode) (*CreateFunctionStmtAction, error) {
-	var spec *FunctionSpec
-	if a.resultTypeIsTemplatedType(node.Signature()) {
-		realStmts, err := a.inferTemplatedTypeByRealType(query, node)
-		if err != nil {
-			return nil, err
-		}
-		templatedFuncSpec, err := newTemplatedFunctionSpec(ctx, a.namePath, node, realStmts)
-		if err != nil {
-			return nil, err
-		}
-		spec = templatedFuncSpec
-	} else {
-		funcSpec, err := newFunctionSpec(ctx, a.namePath, node)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create function spec: %w", err)
-		}
-		spec = funcSpec
+	spec, err := a.newFunctionSpecFromStmt(ctx, query, node)
+	if err != nil {
+		return nil, err
+	}
+	options, err := newOptionExprs(ctx, node.OptionList())
+	if err != nil {
+		return nil, err
 	}
 	return &CreateFunctionStmtAction{
 		spec:    spec,
+		options: options,
 		catalog: a.catalog,
 		funcMap: funcMapFromContext(ctx),
 	}, nil
 }
 
+func (a *Analyzer) newFunctionSpecFromStmt(ctx context.Context, query string, node *ast.CreateFunctionStmtNode) (*FunctionSpec, error) {
+	if a.resultTypeIsTemplatedType(node.Signature()) {
+		realStmts, err := a.inferTemplatedTypeByRealType(query, node)
+		if err != nil {
+			return nil, err
+		}
+		return newTemplatedFunctionSpec(ctx, a.namePath, node, realStmts)
+	}
+	spec, err := newFunctionSpec(ctx, a.namePath, node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create function spec: %w", err)
+	}
+	return spec, nil
+}
+
 func (a *Analyzer) newCreateViewStmtAction(ctx context.Context, _ string, _ []driver.NamedValue, node *ast.CreateViewStmtNode) (*CreateViewStmtAction, error) {
 	query, err := newNode(node.Query()).FormatSQL(ctx)
 	if err != nil {
 		return nil, err
 	}
 	spec := newTableAsViewSpec(a.namePath, query, node)
+	options, err := newOptionExprs(ctx, node.OptionList())
+	if err != nil {
+		return nil, err
+	}
 	return &CreateViewStmtAction{
 		query:   query,
 		spec:    spec,
+		options: options,
 		catalog: a.catalog,
 	}, nil
 }
@@ -482,23 +788,67 @@ func (a *Analyzer) newDropFunctionStmtAction(ctx context.Context, query string,
 }
 
 func (a *Analyzer) newDMLStmtAction(ctx context.Context, query string, args []driver.NamedValue, node ast.Node) (*DMLStmtAction, error) {
-	formattedQuery, err := newNode(node).FormatSQL(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to format query %s: %w", query, err)
+	var values *insertValues
+	if insertNode, ok := node.(*ast.InsertStmtNode); ok {
+		v, err := newInsertValues(ctx, insertNode)
+		if err != nil {
+			return nil, err
+		}
+		values = v
 	}
-	if formattedQuery == "" {
-		return nil, fmt.Errorf("failed to format query %s", query)
+	var formattedQuery string
+	if values == nil {
+		// the rows of INSERT ... VALUES are bound as parameters, so the statement is formatted only when it is prepared.
+		q, err := newNode(node).FormatSQL(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to format query %s: %w", query, err)
+		}
+		if q == "" {
+			return nil, fmt.Errorf("failed to format query %s", query)
+		}
+		formattedQuery = a.formatQuery(q)
 	}
 	params := getParamsFromNode(node)
+	var validator *insertValidator
+	if insertNode, ok := node.(*ast.InsertStmtNode); ok {
+		v, err := newInsertValidator(ctx, insertNode, params)
+		if err != nil {
+			return nil, err
+		}
+		validator = v
+	}
+	if validator != nil && args != nil {
+		namedValues, err := getNamedValuesFromParams(args, params)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, 0, len(namedValues))
+		for _, namedValue := range namedValues {
+			values = append(values, namedValue.Value)
+		}
+		if err := validator.validate(values); err != nil {
+			return nil, err
+		}
+	}
 	queryArgs, err := getArgsFromParams(args, params)
 	if err != nil {
 		return nil, err
 	}
+	referencedTables, err := getReferencedTablesFromNode(ctx, node)
+	if err != nil {
+		return nil, err
+	}
 	return &DMLStmtAction{
-		query:          query,
-		params:         params,
-		args:           queryArgs,
-		formattedQuery: formattedQuery,
+		kind:             dmlKindFromNode(node),
+		query:            query,
+		params:           params,
+		args:             queryArgs,
+		formattedQuery:   formattedQuery,
+		referencedTables: referencedTables,
+		validator:        validator,
+		paramTypes:       queryParameterTypesFromContext(ctx),
+		insertValues:     values,
+		errorContext:     a.newSQLiteErrorContext(ctx, params),
 	}, nil
 }
 
@@ -517,18 +867,30 @@ func (a *Analyzer) newQueryStmtAction(ctx context.Context, query string, args []
 	if formattedQuery == "" {
 		return nil, fmt.Errorf("failed to format query %s", query)
 	}
+	formattedQuery = a.formatQuery(formattedQuery)
 	params := getParamsFromNode(node)
 	queryArgs, err := getArgsFromParams(args, params)
 	if err != nil {
 		return nil, err
 	}
+	referencedTables, err := getReferencedTablesFromNode(ctx, node)
+	if err != nil {
+		return nil, err
+	}
 	return &QueryStmtAction{
-		query:          query,
-		params:         params,
-		args:           queryArgs,
-		formattedQuery: formattedQuery,
-		outputColumns:  outputColumns,
-		isExplainMode:  a.isExplainMode,
+		query:            query,
+		params:           params,
+		args:             queryArgs,
+		formattedQuery:   formattedQuery,
+		outputColumns:    outputColumns,
+		isExplainMode:    a.isExplainMode,
+		referencedTables: referencedTables,
+		paramTypes:       queryParameterTypesFromContext(ctx),
+		errorContext:     a.newSQLiteErrorContext(ctx, params),
+		resourceLimit: &resourceLimit{
+			maxResultRows:     a.maxResultRows,
+			maxBufferedValues: a.maxBufferedValues,
+		},
 	}, nil
 }
 
@@ -546,6 +908,77 @@ func (a *Analyzer) newTruncateStmtAction(_ context.Context, _ string, _ []driver
 	return &TruncateStmtAction{query: fmt.Sprintf("DELETE FROM `%s`", table)}, nil
 }
 
+func (a *Analyzer) newAlterObjectStmtAction(ctx context.Context, query string, node *ast.AlterObjectStmtNode) (StmtAction, error) {
+	var optionNodes []*ast.OptionNode
+	for _, action := range node.AlterActionList() {
+		if renameTo, ok := action.(*ast.RenameToActionNode); ok {
+			if len(node.AlterActionList()) != 1 {
+				return nil, fmt.Errorf("RENAME TO action cannot be combined with other actions: %s", query)
+			}
+			return a.newRenameTableStmtAction(node.NamePath(), renameTo.NewPath(), node.IsIfExists()), nil
+		}
+		setOptions, ok := action.(*ast.SetOptionsActionNode)
+		if !ok {
+			return nil, fmt.Errorf("currently ALTER statement supports SET OPTIONS and RENAME TO actions only: %s", query)
+		}
+		optionNodes = append(optionNodes, setOptions.OptionList()...)
+	}
+	options, err := newOptionExprs(ctx, optionNodes)
+	if err != nil {
+		return nil, err
+	}
+	return &AlterTableStmtAction{
+		name:       a.namePath.format(node.NamePath()),
+		options:    options,
+		isIfExists: node.IsIfExists(),
+		catalog:    a.catalog,
+	}, nil
+}
+
+func (a *Analyzer) newRenameStmtAction(query string, node *ast.RenameStmtNode) (*RenameTableStmtAction, error) {
+	switch node.ObjectType() {
+	case "TABLE", "VIEW":
+	default:
+		return nil, fmt.Errorf("currently unsupported RENAME %s statement: %s", node.ObjectType(), query)
+	}
+	return a.newRenameTableStmtAction(node.OldNamePath(), node.NewNamePath(), false), nil
+}
+
+func (a *Analyzer) newRenameTableStmtAction(path, newPath []string, isIfExists bool) *RenameTableStmtAction {
+	return &RenameTableStmtAction{
+		name:        a.namePath.format(path),
+		newName:     a.namePath.format(newPath),
+		newNamePath: a.namePath.mergePath(newPath),
+		isIfExists:  isIfExists,
+		catalog:     a.catalog,
+	}
+}
+
+func (a *Analyzer) newAlterTableSetOptionsStmtAction(ctx context.Context, _ string, node *ast.AlterTableSetOptionsStmtNode) (*AlterTableStmtAction, error) {
+	options, err := newOptionExprs(ctx, node.OptionList())
+	if err != nil {
+		return nil, err
+	}
+	return &AlterTableStmtAction{
+		name:       a.namePath.format(node.NamePath()),
+		options:    options,
+		isIfExists: node.IsIfExists(),
+		catalog:    a.catalog,
+	}, nil
+}
+
+func (a *Analyzer) newImportStmtAction(ctx context.Context, _ string, node *ast.ImportStmtNode) (*ImportStmtAction, error) {
+	specs, err := a.importModule(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	return &ImportStmtAction{
+		specs:   specs,
+		catalog: a.catalog,
+		funcMap: funcMapFromContext(ctx),
+	}, nil
+}
+
 func (a *Analyzer) newMergeStmtAction(ctx context.Context, _ string, args []driver.NamedValue, node *ast.MergeStmtNode) (*MergeStmtAction, error) {
 	targetTable, err := newNode(node.TableScan()).FormatSQL(ctx)
 	if err != nil {
@@ -595,11 +1028,11 @@ func (a *Analyzer) newMergeStmtAction(ctx context.Context, _ string, args []driv
 		mergedTableTargetColumnName,
 		mergedTableSourceColumnName,
 	}
-	var stmts []string
-	stmts = append(stmts, fmt.Sprintf(
+	var stmts []*mergeStmt
+	stmts = append(stmts, &mergeStmt{query: fmt.Sprintf(
 		"CREATE TABLE zetasqlite_merged_table AS SELECT DISTINCT * FROM (SELECT * FROM %[1]s LEFT JOIN %[2]s ON %[3]s UNION ALL SELECT * FROM %[2]s LEFT JOIN %[1]s ON %[3]s)",
 		sourceTable, targetTable, expr,
-	))
+	)})
 
 	// exists target table and source table
 	matchedFromStmt := fmt.Sprintf(
@@ -649,14 +1082,14 @@ func (a *Analyzer) newMergeStmtAction(ctx context.Context, _ string, args []driv
 			if err != nil {
 				return nil, err
 			}
-			stmts = append(stmts, fmt.Sprintf(
+			stmts = append(stmts, &mergeStmt{kind: dmlKindInsert, query: fmt.Sprintf(
 				"INSERT INTO `%[1]s`(%[2]s) SELECT %[3]s FROM (SELECT * FROM `%[4]s` %[5]s)",
 				targetColumn.TableName(),
 				strings.Join(columns, ","),
 				row,
 				sourceColumn.TableName(),
 				whereStmt,
-			))
+			)})
 		case ast.ActionTypeUpdate:
 			var items []string
 			for _, item := range when.UpdateItemList() {
@@ -666,28 +1099,31 @@ func (a *Analyzer) newMergeStmtAction(ctx context.Context, _ string, args []driv
 				}
 				items = append(items, sql)
 			}
-			stmts = append(stmts, fmt.Sprintf(
+			stmts = append(stmts, &mergeStmt{kind: dmlKindUpdate, query: fmt.Sprintf(
 				"UPDATE `%s` SET %s %s",
 				targetColumn.TableName(),
 				strings.Join(items, ","),
 				fromStmt,
-			))
+			)})
 		case ast.ActionTypeDelete:
-			stmts = append(stmts, fmt.Sprintf(
+			stmts = append(stmts, &mergeStmt{kind: dmlKindDelete, query: fmt.Sprintf(
 				"DELETE FROM `%s` %s",
 				targetColumn.TableName(),
 				whereStmt,
-			))
+			)})
 		}
 	}
-	stmts = append(stmts, "DROP TABLE zetasqlite_merged_table")
+	stmts = append(stmts, &mergeStmt{query: "DROP TABLE zetasqlite_merged_table"})
 	return &MergeStmtAction{stmts: stmts}, nil
 }
 
+// getParamsFromNode returns the parameters referenced by the node.
+// Positional parameters are ordered by the position because they are formatted as numbered placeholders.
 func getParamsFromNode(node ast.Node) []*ast.ParameterNode {
 	var (
-		params       []*ast.ParameterNode
-		paramNameMap = map[string]struct{}{}
+		params           []*ast.ParameterNode
+		paramNameMap     = map[string]struct{}{}
+		paramPositionMap = map[int]struct{}{}
 	)
 	_ = ast.Walk(node, func(n ast.Node) error {
 		param, ok := n.(*ast.ParameterNode)
@@ -699,11 +1135,19 @@ func getParamsFromNode(node ast.Node) []*ast.ParameterNode {
 					paramNameMap[name] = struct{}{}
 				}
 			} else {
-				params = append(params, param)
+				if _, exists := paramPositionMap[param.Position()]; !exists {
+					params = append(params, param)
+					paramPositionMap[param.Position()] = struct{}{}
+				}
 			}
 		}
 		return nil
 	})
+	if len(paramPositionMap) != 0 {
+		sort.SliceStable(params, func(i, j int) bool {
+			return params[i].Position() < params[j].Position()
+		})
+	}
 	return params
 }
 
@@ -711,6 +1155,23 @@ func getArgsFromParams(values []driver.NamedValue, params []*ast.ParameterNode)
 	if values == nil {
 		return nil, nil
 	}
+	namedValues, err := getNamedValuesFromParams(values, params)
+	if err != nil {
+		return nil, err
+	}
+	newNamedValues, err := EncodeNamedValues(namedValues, params)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, 0, len(params))
+	for _, newNamedValue := range newNamedValues {
+		args = append(args, newNamedValue)
+	}
+	return args, nil
+}
+
+// getNamedValuesFromParams returns the values ordered by the parameters.
+func getNamedValuesFromParams(values []driver.NamedValue, params []*ast.ParameterNode) ([]driver.NamedValue, error) {
 	argNum := len(params)
 	if len(values) < argNum {
 		return nil, fmt.Errorf("not enough query arguments")
@@ -734,13 +1195,5 @@ func getArgsFromParams(values []driver.NamedValue, params []*ast.ParameterNode)
 			namedValues = append(namedValues, values[idx])
 		}
 	}
-	newNamedValues, err := EncodeNamedValues(namedValues, params)
-	if err != nil {
-		return nil, err
-	}
-	args := make([]interface{}, 0, argNum)
-	for _, newNamedValue := range newNamedValues {
-		args = append(args, newNamedValue)
-	}
-	return args, nil
+	return namedValues, nil
 }
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-zetasql/types"
+)
+
+const (
+	// sqliteMaxVariableNumber is the default upper bound of the number of host parameters in a single statement.
+	sqliteMaxVariableNumber = 32766
+	maxBulkInsertBatchRows  = 500
+)
+
+// RowGenerator returns the values of the i-th row ordered by the columns of the table.
+type RowGenerator func(i int) []interface{}
+
+// InsertRows inserts n rows generated by gen into the table.
+// The values are validated and encoded without analyzing the INSERT statement,
+// and they are inserted by the batched prepared statements.
+func (a *Analyzer) InsertRows(ctx context.Context, conn *Conn, path []string, gen RowGenerator, n int) error {
+	if err := a.validateReadOnlyOperation(KindDML); err != nil {
+		return err
+	}
+	if err := a.catalog.Sync(ctx, conn); err != nil {
+		return fmt.Errorf("failed to sync catalog: %w", err)
+	}
+	spec, err := a.TableSpec(path)
+	if err != nil {
+		return err
+	}
+	if spec.IsView {
+		return fmt.Errorf("failed to insert rows: %s is a view", strings.Join(path, "."))
+	}
+	validator := &insertValidator{}
+	columnTypes := make([]types.Type, 0, len(spec.Columns))
+	columnNames := make([]string, 0, len(spec.Columns))
+	for idx, column := range spec.Columns {
+		typ, err := column.Type.ToZetaSQLType()
+		if err != nil {
+			return err
+		}
+		validator.columns = append(validator.columns, &insertParamColumn{
+			paramIndex: idx,
+			name:       column.Name,
+			typ:        typ,
+			isNotNull:  column.IsNotNull,
+		})
+		columnTypes = append(columnTypes, typ)
+		columnNames = append(columnNames, fmt.Sprintf("`%s`", column.Name))
+	}
+	if len(columnTypes) == 0 {
+		return fmt.Errorf("failed to insert rows: %s has no columns", strings.Join(path, "."))
+	}
+	batchRows := bulkInsertBatchRows(len(columnTypes))
+	insertQuery := func(rowNum int) string {
+		return bulkInsertQuery(spec.TableName(), columnNames, rowNum)
+	}
+	var batchStmt *sql.Stmt
+	defer func() {
+		if batchStmt != nil {
+			batchStmt.Close()
+		}
+	}()
+	args := make([]interface{}, 0, batchRows*len(columnTypes))
+	rowNum := 0
+	for i := 0; i < n; i++ {
+		row := gen(i)
+		if len(row) != len(columnTypes) {
+			return fmt.Errorf(
+				"failed to insert row %d: %d values are specified but %s has %d columns",
+				i, len(row), spec.TableName(), len(columnTypes),
+			)
+		}
+		if err := validator.validate(row); err != nil {
+			return fmt.Errorf("failed to insert row %d: %w", i, err)
+		}
+		for idx, value := range row {
+			encoded, err := EncodeGoValue(columnTypes[idx], value)
+			if err != nil {
+				return fmt.Errorf("failed to insert row %d: %w", i, err)
+			}
+			args = append(args, encoded)
+		}
+		rowNum++
+		if rowNum < batchRows {
+			continue
+		}
+		if batchStmt == nil {
+			stmt, err := conn.PrepareContext(ctx, insertQuery(batchRows))
+			if err != nil {
+				return fmt.Errorf("failed to prepare bulk insert: %w", err)
+			}
+			batchStmt = stmt
+		}
+		if _, err := batchStmt.ExecContext(ctx, args...); err != nil {
+			return fmt.Errorf("failed to insert rows: %w", translateConstraintError(err))
+		}
+		args = args[:0]
+		rowNum = 0
+	}
+	if rowNum == 0 {
+		return nil
+	}
+	if _, err := conn.ExecContext(ctx, insertQuery(rowNum), args...); err != nil {
+		return fmt.Errorf("failed to insert rows: %w", translateConstraintError(err))
+	}
+	return nil
+}
+
+// bulkInsertBatchRows returns the number of rows inserted by a statement, so that the number of parameters never exceeds the limit.
+func bulkInsertBatchRows(columnNum int) int {
+	batchRows := sqliteMaxVariableNumber / columnNum
+	if batchRows > maxBulkInsertBatchRows {
+		batchRows = maxBulkInsertBatchRows
+	}
+	if batchRows < 1 {
+		batchRows = 1
+	}
+	return batchRows
+}
+
+// bulkInsertQuery returns INSERT statement to bind the values of rowNum rows as parameters.
+func bulkInsertQuery(table string, columnNames []string, rowNum int) string {
+	rowPlaceholder := fmt.Sprintf("(%s)", strings.TrimSuffix(strings.Repeat("?,", len(columnNames)), ","))
+	return fmt.Sprintf(
+		"INSERT INTO `%s` (%s) VALUES %s",
+		table,
+		strings.Join(columnNames, ","),
+		strings.TrimSuffix(strings.Repeat(rowPlaceholder+",", rowNum), ","),
+	)
+}
@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/goccy/go-json"
@@ -55,20 +56,25 @@ const (
 )
 
 type Catalog struct {
-	db           *sql.DB
-	lastSyncedAt time.Time
-	mu           sync.Mutex
-	tables       []*TableSpec
-	functions    []*FunctionSpec
-	catalog      *types.SimpleCatalog
-	tableMap     map[string]*TableSpec
-	funcMap      map[string]*FunctionSpec
-}
-
+	db                            *sql.DB
+	lastSyncedAt                  time.Time
+	mu                            sync.Mutex
+	isAutoRegisterNativeTableMode bool
+	isTableExpirationMode         bool
+	tables                        []*TableSpec
+	functions                     []*FunctionSpec
+	catalog                       *types.SimpleCatalog
+	tableMap                      map[string]*TableSpec
+	funcMap                       map[string]*FunctionSpec
+	generation                    atomic.Uint64
+	pool                          *schemaObjectPool
+	jobs                          *jobLog
+}
+
+// newSimpleCatalog creates the catalog without builtin functions.
+// The builtin functions are looked up from the shared catalog ( see getBuiltinCatalog ).
 func newSimpleCatalog(name string) *types.SimpleCatalog {
-	catalog := types.NewSimpleCatalog(name)
-	catalog.AddZetaSQLBuiltinFunctions(nil)
-	return catalog
+	return types.NewSimpleCatalog(name)
 }
 
 func NewCatalog(db *sql.DB) *Catalog {
@@ -77,6 +83,8 @@ func NewCatalog(db *sql.DB) *Catalog {
 		catalog:  newSimpleCatalog(catalogName),
 		tableMap: map[string]*TableSpec{},
 		funcMap:  map[string]*FunctionSpec{},
+		pool:     newSchemaObjectPool(),
+		jobs:     newJobLog(),
 	}
 }
 
@@ -88,7 +96,27 @@ func (c *Catalog) FindTable(path []string) (types.Table, error) {
 	if c.isWildcardTable(path) {
 		return c.createWildcardTable(path)
 	}
-	return c.catalog.FindTable(path)
+	if c.isInformationSchemaTable(path) {
+		return c.createInformationSchemaTable(path)
+	}
+	table, err := c.catalog.FindTable(path)
+	if err == nil && !c.isNilTable(table) {
+		if err := c.validateTableExpiration(path); err != nil {
+			return nil, err
+		}
+		if err := c.validateView(path); err != nil {
+			return nil, err
+		}
+		return table, nil
+	}
+	registered, registerErr := c.registerNativeTable(path)
+	if registerErr != nil {
+		return nil, registerErr
+	}
+	if registered {
+		return c.catalog.FindTable(path)
+	}
+	return table, err
 }
 
 func (c *Catalog) FindModel(path []string) (types.Model, error) {
@@ -100,11 +128,19 @@ func (c *Catalog) FindConnection(path []string) (types.Connection, error) {
 }
 
 func (c *Catalog) FindFunction(path []string) (*types.Function, error) {
-	return c.catalog.FindFunction(path)
+	fn, err := c.catalog.FindFunction(path)
+	if err == nil && fn != nil {
+		return fn, nil
+	}
+	return getBuiltinCatalog().FindFunction(path)
 }
 
 func (c *Catalog) FindTableValuedFunction(path []string) (types.TableValuedFunction, error) {
-	return c.catalog.FindTableValuedFunction(path)
+	fn, err := c.catalog.FindTableValuedFunction(path)
+	if err == nil && fn != nil {
+		return fn, nil
+	}
+	return getBuiltinCatalog().FindTableValuedFunction(path)
 }
 
 func (c *Catalog) FindProcedure(path []string) (*types.Procedure, error) {
@@ -247,6 +283,18 @@ func (c *Catalog) AddNewFunctionSpec(ctx context.Context, conn *Conn, spec *Func
 	return nil
 }
 
+func (c *Catalog) addTableSpecWithLock(spec *TableSpec) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.addTableSpec(spec)
+}
+
+func (c *Catalog) addFunctionSpecWithLock(spec *FunctionSpec) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.addFunctionSpec(spec)
+}
+
 func (c *Catalog) DeleteTableSpec(ctx context.Context, conn *Conn, name string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -260,6 +308,50 @@ func (c *Catalog) DeleteTableSpec(ctx context.Context, conn *Conn, name string)
 	return nil
 }
 
+// InvalidateDependentViews marks the views referring to the table as invalid and returns them.
+// Like BigQuery, the views are not rewritten to refer to the renamed table, so they fail on the next use.
+func (c *Catalog) InvalidateDependentViews(ctx context.Context, conn *Conn, tableName, reason string) ([]*TableSpec, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var views []*TableSpec
+	for _, spec := range c.tables {
+		if !spec.IsView || spec.InvalidReason != "" {
+			continue
+		}
+		if !strings.Contains(spec.Query, fmt.Sprintf("`%s`", tableName)) {
+			continue
+		}
+		view := new(TableSpec)
+		*view = *spec
+		view.InvalidReason = reason
+		views = append(views, view)
+	}
+	for _, view := range views {
+		if err := c.addTableSpec(view); err != nil {
+			return nil, err
+		}
+		if !view.IsTemp {
+			if err := c.saveTableSpec(ctx, conn, view); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return views, nil
+}
+
+func (c *Catalog) validateView(path []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, spec := range c.findTableSpecsByPath(path) {
+		if spec.IsView && spec.InvalidReason != "" {
+			return fmt.Errorf("view %s is invalid because the referenced table %s", strings.Join(path, "."), spec.InvalidReason)
+		}
+	}
+	return nil
+}
+
 func (c *Catalog) DeleteFunctionSpec(ctx context.Context, conn *Conn, name string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -311,7 +403,14 @@ func (c *Catalog) deleteFunctionSpecByName(name string) error {
 	return nil
 }
 
+// Generation returns the counter incremented by any change of the tables or the functions.
+// The results of the analysis are reusable while the generation is not changed.
+func (c *Catalog) Generation() uint64 {
+	return c.generation.Load()
+}
+
 func (c *Catalog) resetCatalog(tables []*TableSpec, functions []*FunctionSpec) error {
+	c.generation.Add(1)
 	c.catalog = newSimpleCatalog(catalogName)
 	c.tables = []*TableSpec{}
 	c.functions = []*FunctionSpec{}
@@ -411,6 +510,7 @@ func (c *Catalog) trimmedLastPath(path []string) []string {
 }
 
 func (c *Catalog) addFunctionSpec(spec *FunctionSpec) error {
+	c.generation.Add(1)
 	funcName := spec.FuncName()
 	if _, exists := c.funcMap[funcName]; exists {
 		c.funcMap[funcName] = spec // update current spec
@@ -425,9 +525,15 @@ func (c *Catalog) addFunctionSpec(spec *FunctionSpec) error {
 }
 
 func (c *Catalog) addTableSpec(spec *TableSpec) error {
+	c.generation.Add(1)
 	tableName := spec.TableName()
 	if _, exists := c.tableMap[tableName]; exists {
 		c.tableMap[tableName] = spec // update current spec
+		for idx, table := range c.tables {
+			if table.TableName() == tableName {
+				c.tables[idx] = spec
+			}
+		}
 		return nil
 	}
 	c.tables = append(c.tables, spec)
@@ -482,17 +588,19 @@ func (c *Catalog) addTableSpecRecursive(cat *types.SimpleCatalog, spec *TableSpe
 }
 
 func (c *Catalog) createSimpleTable(tableName string, spec *TableSpec) (*types.SimpleTable, error) {
-	columns := []types.Column{}
-	for _, column := range spec.Columns {
-		typ, err := column.Type.ToZetaSQLType()
-		if err != nil {
-			return nil, err
+	return c.pool.table(tableName, spec.Columns, func() (*types.SimpleTable, error) {
+		columns := []types.Column{}
+		for _, column := range spec.Columns {
+			typ, err := column.Type.ToZetaSQLType()
+			if err != nil {
+				return nil, err
+			}
+			columns = append(columns, types.NewSimpleColumn(
+				tableName, column.Name, typ,
+			))
 		}
-		columns = append(columns, types.NewSimpleColumn(
-			tableName, column.Name, typ,
-		))
-	}
-	return types.NewSimpleTable(tableName, columns), nil
+		return types.NewSimpleTable(tableName, columns), nil
+	})
 }
 
 func (c *Catalog) addFunctionSpecRecursive(cat *types.SimpleCatalog, spec *FunctionSpec) error {
@@ -522,20 +630,25 @@ func (c *Catalog) addFunctionSpecRecursive(cat *types.SimpleCatalog, spec *Funct
 	if c.existsFunction(cat, funcName) {
 		return nil
 	}
-	argTypes := []*types.FunctionArgumentType{}
-	for _, arg := range spec.Args {
-		argType, err := arg.FunctionArgumentType()
+	newFunc, err := c.pool.function(funcName, spec.Args, spec.Return, func() (*types.Function, error) {
+		argTypes := []*types.FunctionArgumentType{}
+		for _, arg := range spec.Args {
+			argType, err := arg.FunctionArgumentType()
+			if err != nil {
+				return nil, err
+			}
+			argTypes = append(argTypes, argType)
+		}
+		retType, err := spec.Return.FunctionArgumentType()
 		if err != nil {
-			return err
+			return nil, err
 		}
-		argTypes = append(argTypes, argType)
-	}
-	retType, err := spec.Return.FunctionArgumentType()
+		sig := types.NewFunctionSignature(retType, argTypes)
+		return types.NewFunction([]string{funcName}, "", types.ScalarMode, []*types.FunctionSignature{sig}), nil
+	})
 	if err != nil {
 		return err
 	}
-	sig := types.NewFunctionSignature(retType, argTypes)
-	newFunc := types.NewFunction([]string{funcName}, "", types.ScalarMode, []*types.FunctionSignature{sig})
 	cat.AddFunction(newFunc)
 	return nil
 }
@@ -547,7 +660,12 @@ func (c *Catalog) existsTable(cat *types.SimpleCatalog, name string) bool {
 
 func (c *Catalog) existsFunction(cat *types.SimpleCatalog, name string) bool {
 	foundFunc, _ := cat.FindFunction([]string{name})
-	return foundFunc != nil
+	if foundFunc != nil {
+		return true
+	}
+	// the function having the same name as the builtin function cannot be added
+	builtinFunc, _ := getBuiltinCatalog().FindFunction([]string{name})
+	return builtinFunc != nil
 }
 
 func (c *Catalog) isNilTable(t types.Table) bool {
@@ -558,6 +676,26 @@ func (c *Catalog) isNilTable(t types.Table) bool {
 	return v.IsNil()
 }
 
+// newSnapshotCatalog creates the catalog having the copy of the current table and function specs.
+// The changes to the created catalog are not reflected to the original one.
+func (c *Catalog) newSnapshotCatalog() (*Catalog, error) {
+	c.mu.Lock()
+	tables := append([]*TableSpec{}, c.tables...)
+	functions := append([]*FunctionSpec{}, c.functions...)
+	isAutoRegisterNativeTableMode := c.isAutoRegisterNativeTableMode
+	isTableExpirationMode := c.isTableExpirationMode
+	c.mu.Unlock()
+
+	catalog := NewCatalog(c.db)
+	catalog.pool = c.pool
+	catalog.isAutoRegisterNativeTableMode = isAutoRegisterNativeTableMode
+	catalog.isTableExpirationMode = isTableExpirationMode
+	if err := catalog.resetCatalog(tables, functions); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot catalog: %w", err)
+	}
+	return catalog, nil
+}
+
 func (c *Catalog) copyTableSpec(spec *TableSpec, newNamePath []string) *TableSpec {
 	return &TableSpec{
 		NamePath:   newNamePath,
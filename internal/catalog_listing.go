@@ -0,0 +1,267 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/goccy/go-zetasql/types"
+)
+
+// CatalogListing is the list of the datasets, tables, views and functions registered in the catalog.
+// The project and the dataset of each object are derived from its name path in the same way as INFORMATION_SCHEMA.
+type CatalogListing struct {
+	Datasets  []*DatasetListing
+	Tables    []*TableListing
+	Views     []*ViewListing
+	Functions []*FunctionListing
+}
+
+// DatasetListing is the dataset that has at least one table, view or function.
+type DatasetListing struct {
+	ProjectID string
+	DatasetID string
+}
+
+// TableListing is the table with its schema. The type of the column has the nested fields of STRUCT and ARRAY types.
+type TableListing struct {
+	ProjectID   string
+	DatasetID   string
+	TableID     string
+	NamePath    []string
+	Columns     []*ColumnSpec
+	IsTemp      bool
+	Description string
+	Options     []*OptionSpec
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// ViewListing is the view with its output columns.
+// Query is the query text of CREATE VIEW statement. It is empty for the views created by the old version.
+type ViewListing struct {
+	ProjectID     string
+	DatasetID     string
+	ViewID        string
+	NamePath      []string
+	Columns       []*ColumnSpec
+	Query         string
+	IsTemp        bool
+	Description   string
+	Options       []*OptionSpec
+	InvalidReason string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// FunctionListing is the user defined function.
+// Signature is the formatted arguments and return type ( e.g. `add`(x INT64, y INT64) RETURNS INT64 ).
+// Body is the source of the function: the code for JavaScript functions and the expression for SQL functions.
+type FunctionListing struct {
+	ProjectID  string
+	DatasetID  string
+	FunctionID string
+	NamePath   []string
+	Language   string
+	Args       []*NameWithType
+	Return     *Type
+	Signature  string
+	Body       string
+	IsTemp     bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Listing returns the objects in the catalog ordered by name.
+// The catalog should be synchronized before calling this to include the objects created by other connections.
+func (c *Catalog) Listing() *CatalogListing {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	listing := &CatalogListing{}
+	datasets := map[string]*DatasetListing{}
+	addDataset := func(projectID, datasetID string) {
+		if datasetID == "" {
+			return
+		}
+		key := projectID + "." + datasetID
+		if _, exists := datasets[key]; exists {
+			return
+		}
+		dataset := &DatasetListing{ProjectID: projectID, DatasetID: datasetID}
+		datasets[key] = dataset
+		listing.Datasets = append(listing.Datasets, dataset)
+	}
+	for _, spec := range c.tableMap {
+		projectID, datasetID, name := splitObjectPath(spec.NamePath)
+		addDataset(projectID, datasetID)
+		if spec.IsView {
+			listing.Views = append(listing.Views, &ViewListing{
+				ProjectID:     projectID,
+				DatasetID:     datasetID,
+				ViewID:        name,
+				NamePath:      spec.NamePath,
+				Columns:       spec.Columns,
+				Query:         spec.Definition,
+				IsTemp:        spec.IsTemp,
+				Description:   spec.Description,
+				Options:       spec.Options,
+				InvalidReason: spec.InvalidReason,
+				CreatedAt:     spec.CreatedAt,
+				UpdatedAt:     spec.UpdatedAt,
+			})
+			continue
+		}
+		listing.Tables = append(listing.Tables, &TableListing{
+			ProjectID:   projectID,
+			DatasetID:   datasetID,
+			TableID:     name,
+			NamePath:    spec.NamePath,
+			Columns:     spec.Columns,
+			IsTemp:      spec.IsTemp,
+			Description: spec.Description,
+			Options:     spec.Options,
+			CreatedAt:   spec.CreatedAt,
+			UpdatedAt:   spec.UpdatedAt,
+		})
+	}
+	for _, spec := range c.funcMap {
+		projectID, datasetID, name := splitObjectPath(spec.NamePath)
+		addDataset(projectID, datasetID)
+		body := spec.Body
+		if spec.Code != "" {
+			body = spec.Code
+		}
+		listing.Functions = append(listing.Functions, &FunctionListing{
+			ProjectID:  projectID,
+			DatasetID:  datasetID,
+			FunctionID: name,
+			NamePath:   spec.NamePath,
+			Language:   spec.Language,
+			Args:       spec.Args,
+			Return:     spec.Return,
+			Signature:  functionSignature(spec),
+			Body:       body,
+			IsTemp:     spec.IsTemp,
+			CreatedAt:  spec.CreatedAt,
+			UpdatedAt:  spec.UpdatedAt,
+		})
+	}
+	sort.Slice(listing.Datasets, func(i, j int) bool {
+		return listing.Datasets[i].ProjectID+"."+listing.Datasets[i].DatasetID <
+			listing.Datasets[j].ProjectID+"."+listing.Datasets[j].DatasetID
+	})
+	sort.Slice(listing.Tables, func(i, j int) bool {
+		return formatPath(listing.Tables[i].NamePath) < formatPath(listing.Tables[j].NamePath)
+	})
+	sort.Slice(listing.Views, func(i, j int) bool {
+		return formatPath(listing.Views[i].NamePath) < formatPath(listing.Views[j].NamePath)
+	})
+	sort.Slice(listing.Functions, func(i, j int) bool {
+		return formatPath(listing.Functions[i].NamePath) < formatPath(listing.Functions[j].NamePath)
+	})
+	return listing
+}
+
+// splitObjectPath returns the project, the dataset and the name of the object like INFORMATION_SCHEMA views.
+func splitObjectPath(path []string) (string, string, string) {
+	namePath := splitPath(path)
+	var projectID, datasetID string
+	if len(namePath) >= 3 {
+		projectID = namePath[len(namePath)-3]
+	}
+	if len(namePath) >= 2 {
+		datasetID = namePath[len(namePath)-2]
+	}
+	return projectID, datasetID, namePath[len(namePath)-1]
+}
+
+func functionSignature(spec *FunctionSpec) string {
+	args := make([]string, 0, len(spec.Args))
+	for _, arg := range spec.Args {
+		args = append(args, fmt.Sprintf("%s %s", arg.Name, formatSignatureType(arg.Type)))
+	}
+	signature := fmt.Sprintf("`%s`(%s)", strings.Join(splitPath(spec.NamePath), "."), strings.Join(args, ", "))
+	if spec.Return == nil {
+		return signature
+	}
+	return fmt.Sprintf("%s RETURNS %s", signature, formatSignatureType(spec.Return))
+}
+
+func formatSignatureType(t *Type) string {
+	switch t.SignatureKind {
+	case types.ArgTypeFixed:
+		return t.FormatType()
+	case types.ArgArrayTypeAny1, types.ArgArrayTypeAny2:
+		return "ARRAY<ANY TYPE>"
+	}
+	return "ANY TYPE"
+}
+
+// Refresh reloads all specs from the database.
+// Unlike Sync, the tables and the functions dropped by other connections are also removed.
+// The temporary specs exist only in this catalog, so they are kept.
+func (c *Catalog) Refresh(ctx context.Context, conn *Conn) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.createCatalogTablesIfNotExists(ctx, conn); err != nil {
+		return fmt.Errorf("failed to create catalog tables: %w", err)
+	}
+	var (
+		tables    []*TableSpec
+		functions []*FunctionSpec
+	)
+	for _, spec := range c.tables {
+		if spec.IsTemp {
+			tables = append(tables, spec)
+		}
+	}
+	for _, spec := range c.functions {
+		if spec.IsTemp {
+			functions = append(functions, spec)
+		}
+	}
+	now := time.Now()
+	rows, err := conn.QueryContext(ctx, `SELECT kind, spec FROM zetasqlite_catalog`)
+	if err != nil {
+		return fmt.Errorf("failed to query load catalog: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			kind CatalogSpecKind
+			spec string
+		)
+		if err := rows.Scan(&kind, &spec); err != nil {
+			return fmt.Errorf("failed to scan catalog values: %w", err)
+		}
+		switch kind {
+		case TableSpecKind, ViewSpecKind:
+			var v TableSpec
+			if err := json.Unmarshal([]byte(spec), &v); err != nil {
+				return fmt.Errorf("failed to decode table spec: %w", err)
+			}
+			tables = append(tables, &v)
+		case FunctionSpecKind:
+			var v FunctionSpec
+			if err := json.Unmarshal([]byte(spec), &v); err != nil {
+				return fmt.Errorf("failed to decode function spec: %w", err)
+			}
+			functions = append(functions, &v)
+		default:
+			return fmt.Errorf("unknown catalog spec kind %s", kind)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := c.resetCatalog(tables, functions); err != nil {
+		return fmt.Errorf("failed to reload catalog: %w", err)
+	}
+	c.lastSyncedAt = now
+	return nil
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/goccy/go-json"
+	"github.com/goccy/go-zetasql/types"
+)
+
+// The objects created on the zetasql side are never released because the binding has no API to free them.
+// So the objects that are the same between catalogs are created once and shared.
+
+var (
+	builtinCatalog     *types.SimpleCatalog
+	builtinCatalogOnce sync.Once
+)
+
+// getBuiltinCatalog returns the catalog having only the builtin functions of ZetaSQL.
+// The builtin functions are looked up from this catalog instead of being added to each catalog,
+// because they are numerous and the catalog is re-created whenever a table or a function is deleted.
+func getBuiltinCatalog() *types.SimpleCatalog {
+	builtinCatalogOnce.Do(func() {
+		builtinCatalog = types.NewSimpleCatalog(catalogName)
+		builtinCatalog.AddZetaSQLBuiltinFunctions(nil)
+	})
+	return builtinCatalog
+}
+
+// schemaObjectPool keeps the tables and the functions added to the catalog to reuse them
+// when the catalog is re-created with the same specs.
+// The pool is shared by the catalog and its snapshots.
+type schemaObjectPool struct {
+	mu        sync.Mutex
+	tables    map[string]*types.SimpleTable
+	functions map[string]*types.Function
+}
+
+func newSchemaObjectPool() *schemaObjectPool {
+	return &schemaObjectPool{
+		tables:    map[string]*types.SimpleTable{},
+		functions: map[string]*types.Function{},
+	}
+}
+
+func (p *schemaObjectPool) table(name string, columns []*ColumnSpec, create func() (*types.SimpleTable, error)) (*types.SimpleTable, error) {
+	encoded, err := json.Marshal(columns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode columns of %s: %w", name, err)
+	}
+	key := fmt.Sprintf("%s:%s", name, encoded)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if table, exists := p.tables[key]; exists {
+		return table, nil
+	}
+	table, err := create()
+	if err != nil {
+		return nil, err
+	}
+	p.tables[key] = table
+	return table, nil
+}
+
+func (p *schemaObjectPool) function(name string, args []*NameWithType, ret *Type, create func() (*types.Function, error)) (*types.Function, error) {
+	encodedArgs, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode arguments of %s: %w", name, err)
+	}
+	encodedRet, err := json.Marshal(ret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode return type of %s: %w", name, err)
+	}
+	key := fmt.Sprintf("%s:%s:%s", name, encodedArgs, encodedRet)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if fn, exists := p.functions[key]; exists {
+		return fn, nil
+	}
+	fn, err := create()
+	if err != nil {
+		return nil, err
+	}
+	p.functions[key] = fn
+	return fn, nil
+}
@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+)
+
+const checkConstraintViolationFuncName = "zetasqlite_check_constraint_violation"
+
+var checkConstraintTriggerEvents = []string{"INSERT", "UPDATE"}
+
+// CheckConstraintSpec is the CHECK constraint of the table.
+// Expression is the original expression text, and FormattedExpression is the expression translated for SQLite
+// that refers to the columns of the table by their names.
+type CheckConstraintSpec struct {
+	Name                string `json:"name"`
+	Expression          string `json:"expression"`
+	FormattedExpression string `json:"formattedExpression"`
+	Enforced            bool   `json:"enforced"`
+}
+
+func newCheckConstraintSpecs(ctx context.Context, query string, constraints []*ast.CheckConstraintNode) ([]*CheckConstraintSpec, error) {
+	specs := make([]*CheckConstraintSpec, 0, len(constraints))
+	for _, constraint := range constraints {
+		expr := constraint.Expression()
+		formatted, err := newNode(expr).FormatSQL(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to format check constraint expression: %w", err)
+		}
+		specs = append(specs, &CheckConstraintSpec{
+			Name:                constraint.ConstraintName(),
+			Expression:          sourceText(query, expr),
+			FormattedExpression: formatted,
+			Enforced:            constraint.Enforced(),
+		})
+	}
+	return specs, nil
+}
+
+// displayName returns the name to identify the constraint in the error message.
+func (s *CheckConstraintSpec) displayName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return fmt.Sprintf("CHECK(%s)", s.Expression)
+}
+
+// checkConstraintTriggers returns the queries to create the triggers enforcing the CHECK constraints.
+// SQLite's CHECK constraint cannot call the functions registered to the connection reliably
+// because it is evaluated by any connection opening the database,
+// so the constraints are evaluated by the triggers executed before INSERT and UPDATE instead.
+func (s *TableSpec) checkConstraintTriggers() []string {
+	var (
+		queries     []string
+		tableName   = s.TableName()
+		newColumns  = make([]string, 0, len(s.Columns))
+		primaryKeys = make([]string, 0, len(s.PrimaryKey)*2)
+	)
+	for _, column := range s.Columns {
+		newColumns = append(newColumns, fmt.Sprintf("NEW.`%[1]s` AS `%[1]s`", column.Name))
+	}
+	for _, key := range s.PrimaryKey {
+		primaryKeys = append(primaryKeys, quoteSQLiteString(key), fmt.Sprintf("NEW.`%s`", key))
+	}
+	for idx, constraint := range s.CheckConstraints {
+		if !constraint.Enforced {
+			continue
+		}
+		args := append([]string{quoteSQLiteString(constraint.displayName()), quoteSQLiteString(strings.Join(s.NamePath, "."))}, primaryKeys...)
+		body := fmt.Sprintf(
+			"SELECT %s(%s) FROM (SELECT %s) WHERE NOT (%s);",
+			checkConstraintViolationFuncName,
+			strings.Join(args, ","),
+			strings.Join(newColumns, ","),
+			constraint.FormattedExpression,
+		)
+		for _, event := range checkConstraintTriggerEvents {
+			queries = append(queries, fmt.Sprintf(
+				"CREATE TRIGGER IF NOT EXISTS `%s` BEFORE %s ON `%s` BEGIN %s END",
+				checkConstraintTriggerName(tableName, idx, event), event, tableName, body,
+			))
+		}
+	}
+	return queries
+}
+
+func checkConstraintTriggerName(tableName string, idx int, event string) string {
+	return fmt.Sprintf("zetasqlite_check_%s_%d_%s", tableName, idx, strings.ToLower(event))
+}
+
+func (s *TableSpec) createCheckConstraintTriggers(ctx context.Context, conn *Conn) error {
+	for _, query := range s.checkConstraintTriggers() {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to create trigger for check constraint: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *TableSpec) dropCheckConstraintTriggers(ctx context.Context, conn *Conn) error {
+	for idx := range s.CheckConstraints {
+		for _, event := range checkConstraintTriggerEvents {
+			if _, err := conn.ExecContext(
+				ctx,
+				fmt.Sprintf("DROP TRIGGER IF EXISTS `%s`", checkConstraintTriggerName(s.TableName(), idx, event)),
+			); err != nil {
+				return fmt.Errorf("failed to drop trigger for check constraint: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkConstraintViolation is called by the trigger when the row violates the CHECK constraint.
+// keyValues is the pairs of the primary key column name and the encoded value of it.
+func checkConstraintViolation(constraint, table string, keyValues ...interface{}) (interface{}, error) {
+	if len(keyValues) == 0 {
+		return nil, fmt.Errorf("check constraint %s of table %s is violated", constraint, table)
+	}
+	keys := make([]string, 0, len(keyValues)/2)
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		value, err := DecodeValue(keyValues[i+1])
+		if err != nil {
+			return nil, err
+		}
+		formatted := "NULL"
+		if value != nil {
+			s, err := value.ToString()
+			if err != nil {
+				return nil, err
+			}
+			formatted = s
+		}
+		keys = append(keys, fmt.Sprintf("%v = %s", keyValues[i], formatted))
+	}
+	return nil, fmt.Errorf(
+		"check constraint %s of table %s is violated by the row with primary key (%s)",
+		constraint, table, strings.Join(keys, ", "),
+	)
+}
+
+func quoteSQLiteString(s string) string {
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(s, "'", "''"))
+}
@@ -3,6 +3,9 @@ package internal
 import (
 	"context"
 	"database/sql"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
 )
 
 type ChangedCatalog struct {
@@ -41,17 +44,65 @@ func (f *ChangedFunction) Changed() bool {
 }
 
 type Conn struct {
-	conn *sql.Conn
-	tx   *sql.Tx
-	cc   *ChangedCatalog
+	conn  *sql.Conn
+	tx    *sql.Tx
+	cc    *ChangedCatalog
+	stats *QueryStats
+	job   *job
 }
 
 func NewConn(conn *sql.Conn, tx *sql.Tx) *Conn {
 	return &Conn{
-		conn: conn,
-		tx:   tx,
-		cc:   newChangedCatalog(),
+		conn:  conn,
+		tx:    tx,
+		cc:    newChangedCatalog(),
+		stats: newQueryStats(),
+	}
+}
+
+// Stats returns the statistics of the statements executed by this connection.
+func (c *Conn) Stats() *QueryStats {
+	return c.stats
+}
+
+// FinishStats records the time taken to execute the statements.
+func (c *Conn) FinishStats() {
+	c.stats.finish()
+}
+
+// rawConn calls fn with the SQLite connection to use the APIs not provided by database/sql ( e.g. backup ).
+func (c *Conn) rawConn(fn func(*sqlite3.SQLiteConn) error) error {
+	return c.conn.Raw(func(driverConn interface{}) error {
+		conn, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection type %T", driverConn)
+		}
+		return fn(conn)
+	})
+}
+
+// withSavepoint calls fn in the savepoint, so all changes made by fn are rolled back if fn fails.
+// The savepoint behaves as a transaction outside of the transaction, and as a nested transaction in the transaction.
+func (c *Conn) withSavepoint(ctx context.Context, name string, fn func() error) (e error) {
+	if _, err := c.ExecContext(ctx, fmt.Sprintf("SAVEPOINT `%s`", name)); err != nil {
+		return fmt.Errorf("failed to create savepoint %s: %w", name, err)
 	}
+	defer func() {
+		eg := new(ErrorGroup)
+		eg.Add(e)
+		if e != nil {
+			if _, err := c.ExecContext(ctx, fmt.Sprintf("ROLLBACK TO `%s`", name)); err != nil {
+				eg.Add(fmt.Errorf("failed to rollback to savepoint %s: %w", name, err))
+			}
+		}
+		if _, err := c.ExecContext(ctx, fmt.Sprintf("RELEASE `%s`", name)); err != nil {
+			eg.Add(fmt.Errorf("failed to release savepoint %s: %w", name, err))
+		}
+		if eg.HasError() && e == nil {
+			e = eg
+		}
+	}()
+	return fn()
 }
 
 func (c *Conn) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
@@ -63,16 +114,41 @@ func (c *Conn) PrepareContext(ctx context.Context, query string) (*sql.Stmt, err
 
 func (c *Conn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	if c.tx != nil {
-		return c.tx.ExecContext(ctx, query, args...)
+		result, err := c.tx.ExecContext(ctx, query, args...)
+		return result, TranslateLockError(err)
+	}
+	var result sql.Result
+	if err := retryOnLockError(ctx, func() error {
+		r, err := c.conn.ExecContext(ctx, query, args...)
+		result = r
+		return err
+	}); err != nil {
+		return nil, err
 	}
-	return c.conn.ExecContext(ctx, query, args...)
+	return result, nil
 }
 
 func (c *Conn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
 	if c.tx != nil {
-		return c.tx.QueryContext(ctx, query, args...)
+		rows, err := c.tx.QueryContext(ctx, query, args...)
+		return rows, TranslateLockError(err)
+	}
+	var rows *sql.Rows
+	if err := retryOnLockError(ctx, func() error {
+		r, err := c.conn.QueryContext(ctx, query, args...)
+		rows = r
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (c *Conn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if c.tx != nil {
+		return c.tx.QueryRowContext(ctx, query, args...)
 	}
-	return c.conn.QueryContext(ctx, query, args...)
+	return c.conn.QueryRowContext(ctx, query, args...)
 }
 
 func (c *Conn) addTable(spec *TableSpec) {
@@ -80,7 +156,6 @@ func (c *Conn) addTable(spec *TableSpec) {
 	c.cc.Table.Added = append(c.cc.Table.Added, spec)
 }
 
-//nolint:unused
 func (c *Conn) updateTable(spec *TableSpec) {
 	c.cc.Table.Updated = append(c.cc.Table.Updated, spec)
 }
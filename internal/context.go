@@ -17,11 +17,18 @@ type (
 	analyticOrderColumnNamesKey     struct{}
 	analyticPartitionColumnNamesKey struct{}
 	analyticInputScanKey            struct{}
+	analyticRowIDColumnNameKey      struct{}
 	arraySubqueryColumnNameKey      struct{}
 	currentTimeKey                  struct{}
 	tableNameToColumnListMapKey     struct{}
 	useColumnIDKey                  struct{}
 	useTableNameForColumnKey        struct{}
+	correlatedColumnNameMapKey      struct{}
+	dmlTargetTableNameKey           struct{}
+	queryParameterTypeNamesKey      struct{}
+	queryParameterTypesKey          struct{}
+	queryMetadataKey                struct{}
+	statementLocationKey            struct{}
 )
 
 func analyzerFromContext(ctx context.Context) *Analyzer {
@@ -129,6 +136,18 @@ func analyticInputScanFromContext(ctx context.Context) string {
 	return value.(string)
 }
 
+func withAnalyticRowIDColumnName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, analyticRowIDColumnNameKey{}, name)
+}
+
+func analyticRowIDColumnNameFromContext(ctx context.Context) string {
+	value := ctx.Value(analyticRowIDColumnNameKey{})
+	if value == nil {
+		return ""
+	}
+	return value.(string)
+}
+
 type arraySubqueryColumnNames struct {
 	names []string
 }
@@ -185,6 +204,30 @@ func tableNameToColumnListMap(ctx context.Context) map[string][]*ast.Column {
 	return value.(map[string][]*ast.Column)
 }
 
+func withCorrelatedColumnNameMap(ctx context.Context, v map[int]string) context.Context {
+	return context.WithValue(ctx, correlatedColumnNameMapKey{}, v)
+}
+
+func correlatedColumnNameMap(ctx context.Context) map[int]string {
+	value := ctx.Value(correlatedColumnNameMapKey{})
+	if value == nil {
+		return nil
+	}
+	return value.(map[int]string)
+}
+
+func withDMLTargetTableName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, dmlTargetTableNameKey{}, name)
+}
+
+func dmlTargetTableName(ctx context.Context) string {
+	value := ctx.Value(dmlTargetTableNameKey{})
+	if value == nil {
+		return ""
+	}
+	return value.(string)
+}
+
 func WithCurrentTime(ctx context.Context, now time.Time) context.Context {
 	return context.WithValue(ctx, currentTimeKey{}, &now)
 }
@@ -196,3 +239,45 @@ func CurrentTime(ctx context.Context) *time.Time {
 	}
 	return value.(*time.Time)
 }
+
+func WithQueryParameterTypes(ctx context.Context, typeNames map[string]string) context.Context {
+	return context.WithValue(ctx, queryParameterTypeNamesKey{}, typeNames)
+}
+
+func queryParameterTypeNames(ctx context.Context) map[string]string {
+	value := ctx.Value(queryParameterTypeNamesKey{})
+	if value == nil {
+		return nil
+	}
+	return value.(map[string]string)
+}
+
+func withQueryParameterTypes(ctx context.Context, paramTypes queryParameterTypes) context.Context {
+	return context.WithValue(ctx, queryParameterTypesKey{}, paramTypes)
+}
+
+func queryParameterTypesFromContext(ctx context.Context) queryParameterTypes {
+	value := ctx.Value(queryParameterTypesKey{})
+	if value == nil {
+		return nil
+	}
+	return value.(queryParameterTypes)
+}
+
+// statementLocation is the position and the text of the statement in the script attached to SQLiteError.
+type statementLocation struct {
+	index int
+	text  string
+}
+
+func withStatementLocation(ctx context.Context, index int, text string) context.Context {
+	return context.WithValue(ctx, statementLocationKey{}, &statementLocation{index: index, text: text})
+}
+
+func statementLocationFromContext(ctx context.Context) *statementLocation {
+	value := ctx.Value(statementLocationKey{})
+	if value == nil {
+		return nil
+	}
+	return value.(*statementLocation)
+}
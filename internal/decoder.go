@@ -47,6 +47,12 @@ func decodeFromValueLayout(layout *ValueLayout) (Value, error) {
 			return nil, err
 		}
 		return BytesValue(decoded), nil
+	case FloatValueType:
+		f64, err := strconv.ParseFloat(layout.Body, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse float value %s: %w", layout.Body, err)
+		}
+		return FloatValue(f64), nil
 	case NumericValueType:
 		r := new(big.Rat)
 		r.SetString(layout.Body)
@@ -0,0 +1,344 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-zetasql"
+	parsed_ast "github.com/goccy/go-zetasql/ast"
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+)
+
+type DiagnosticsStatus string
+
+const (
+	DiagnosticsStatusOK            DiagnosticsStatus = "OK"
+	DiagnosticsStatusParseError    DiagnosticsStatus = "PARSE_ERROR"
+	DiagnosticsStatusAnalysisError DiagnosticsStatus = "ANALYSIS_ERROR"
+)
+
+// StatementDiagnostics is the result of analyzing a statement in the script.
+// Line and Column are 1-based positions in the original script.
+// RowsRead of ReferencedTables is always zero because the statement is not executed.
+type StatementDiagnostics struct {
+	Index            int                `json:"index"`
+	Query            string             `json:"query"`
+	Line             int                `json:"line"`
+	Column           int                `json:"column"`
+	Status           DiagnosticsStatus  `json:"status"`
+	Error            string             `json:"error,omitempty"`
+	ErrorLine        int                `json:"errorLine,omitempty"`
+	ErrorColumn      int                `json:"errorColumn,omitempty"`
+	ReferencedTables []*ReferencedTable `json:"referencedTables,omitempty"`
+	OutputColumns    []*NameWithType    `json:"outputColumns,omitempty"`
+}
+
+var errorLocationPattern = regexp.MustCompile(`\[at (\d+):(\d+)\]`)
+
+// scriptSegment is the part of the script to resume parsing after the parse error.
+type scriptSegment struct {
+	source string
+	offset int
+}
+
+// AnalyzeAll analyzes all statements in the script without executing them.
+// Unlike Analyze, the analysis continues after the failed statement.
+// The statements are analyzed on the snapshot of the catalog, and the table and function definitions of
+// the succeeded statements are applied to the snapshot so that the subsequent statements can refer to them.
+func (a *Analyzer) AnalyzeAll(ctx context.Context, conn *Conn, script string) []StatementDiagnostics {
+	if err := a.catalog.Sync(ctx, conn); err != nil {
+		return []StatementDiagnostics{
+			newScriptDiagnostics(0, script, 0, DiagnosticsStatusAnalysisError, fmt.Errorf("failed to sync catalog: %w", err)),
+		}
+	}
+	analyzer, err := a.newDiagnosticsAnalyzer()
+	if err != nil {
+		return []StatementDiagnostics{
+			newScriptDiagnostics(0, script, 0, DiagnosticsStatusAnalysisError, err),
+		}
+	}
+	var diagnostics []StatementDiagnostics
+	segment := &scriptSegment{source: script}
+	for segment != nil {
+		segment = analyzer.analyzeSegment(ctx, script, segment, &diagnostics)
+	}
+	return diagnostics
+}
+
+func (a *Analyzer) newDiagnosticsAnalyzer() (*Analyzer, error) {
+	catalog, err := a.catalog.newSnapshotCatalog()
+	if err != nil {
+		return nil, err
+	}
+	analyzer, err := NewAnalyzer(catalog)
+	if err != nil {
+		return nil, err
+	}
+	if err := analyzer.SetLanguageFeatures(a.languageFeatures); err != nil {
+		return nil, err
+	}
+	analyzer.namePath = a.namePath
+	analyzer.moduleResolver = a.moduleResolver
+	analyzer.isRowAccessPolicyMode = a.isRowAccessPolicyMode
+	analyzer.sessionUser = a.sessionUser
+	analyzer.timeZone = a.timeZone
+	return analyzer, nil
+}
+
+// analyzeSegment analyzes the statements in the segment and appends the results to diagnostics.
+// If the segment has a parse error, the segment following the failed statement is returned.
+func (a *Analyzer) analyzeSegment(ctx context.Context, script string, segment *scriptSegment, diagnostics *[]StatementDiagnostics) *scriptSegment {
+	loc := zetasql.NewParseResumeLocation(segment.source)
+	lastEnd := 0
+	for {
+		stmt, isEnd, err := zetasql.ParseNextScriptStatement(loc, a.opt.ParserOptions())
+		if err != nil {
+			errOffset := len(segment.source)
+			if line, column, found := errorLocation(err); found {
+				errOffset = byteOffsetFromPosition(segment.source, line, column)
+			}
+			end := strings.Index(segment.source[errOffset:], ";")
+			if end < 0 {
+				end = len(segment.source)
+			} else {
+				end += errOffset + 1
+			}
+			start := lastEnd + leadingSeparatorLen(segment.source[lastEnd:])
+			if start > end {
+				start = end
+			}
+			*diagnostics = append(*diagnostics, newScriptDiagnostics(
+				len(*diagnostics),
+				script,
+				segment.offset+start,
+				DiagnosticsStatusParseError,
+				mapErrorLocation(script, segment.offset, err),
+			).withQuery(strings.TrimSpace(segment.source[start:end])))
+			if strings.TrimSpace(segment.source[end:]) == "" {
+				return nil
+			}
+			return &scriptSegment{source: segment.source[end:], offset: segment.offset + end}
+		}
+		stmts := []parsed_ast.StatementNode{stmt}
+		if block, ok := stmt.(*parsed_ast.BeginEndBlockNode); ok {
+			stmts = block.StatementList()
+		}
+		for _, s := range stmts {
+			*diagnostics = append(*diagnostics, a.analyzeStmtForDiagnostics(ctx, script, segment, s, len(*diagnostics)))
+		}
+		lastEnd = stmt.ParseLocationRange().End().ByteOffset()
+		if isEnd {
+			return nil
+		}
+	}
+}
+
+func (a *Analyzer) analyzeStmtForDiagnostics(ctx context.Context, script string, segment *scriptSegment, stmt parsed_ast.StatementNode, index int) StatementDiagnostics {
+	locRange := stmt.ParseLocationRange()
+	start := locRange.Start().ByteOffset()
+	end := locRange.End().ByteOffset()
+	query := segment.source[start:end]
+	node, _, err := a.analyzeStmt(segment.source, stmt, nil)
+	if err != nil {
+		return newScriptDiagnostics(
+			index, script, segment.offset+start, DiagnosticsStatusAnalysisError, mapErrorLocation(script, segment.offset, err),
+		).withQuery(query)
+	}
+	diagnostics := newScriptDiagnostics(index, script, segment.offset+start, DiagnosticsStatusOK, nil).withQuery(query)
+	stmtCtx := a.stmtContext(a.context(ctx, a.funcMap(), node, stmt), node)
+	referencedTables, err := getReferencedTablesFromNode(stmtCtx, node)
+	if err != nil {
+		return newScriptDiagnostics(index, script, segment.offset+start, DiagnosticsStatusAnalysisError, err).withQuery(query)
+	}
+	diagnostics.ReferencedTables = referencedTables
+	if query, ok := node.(*ast.QueryStmtNode); ok {
+		for _, column := range query.OutputColumnList() {
+			diagnostics.OutputColumns = append(diagnostics.OutputColumns, &NameWithType{
+				Name: column.Name(),
+				Type: newType(column.Column().Type()),
+			})
+		}
+	}
+	if err := a.applyCatalogChange(stmtCtx, segment.source, node); err != nil {
+		return newScriptDiagnostics(index, script, segment.offset+start, DiagnosticsStatusAnalysisError, err).withQuery(query)
+	}
+	return diagnostics
+}
+
+// applyCatalogChange reflects the table and function definitions of the statement to the catalog
+// without executing the statement.
+func (a *Analyzer) applyCatalogChange(ctx context.Context, query string, node ast.StatementNode) error {
+	switch n := node.(type) {
+	case *ast.CreateTableStmtNode:
+		return a.catalog.addTableSpecWithLock(newTableSpec(a.namePath, n))
+	case *ast.CreateTableAsSelectStmtNode:
+		return a.catalog.addTableSpecWithLock(newTableAsSelectSpec(a.namePath, "", n))
+	case *ast.CreateViewStmtNode:
+		return a.catalog.addTableSpecWithLock(newTableAsViewSpec(a.namePath, "", n))
+	case *ast.CreateFunctionStmtNode:
+		spec, err := a.newFunctionSpecFromStmt(ctx, query, n)
+		if err != nil {
+			return err
+		}
+		return a.catalog.addFunctionSpecWithLock(spec)
+	case *ast.ImportStmtNode:
+		specs, err := a.importModule(ctx, n)
+		if err != nil {
+			return err
+		}
+		for _, spec := range specs {
+			if err := a.catalog.addFunctionSpecWithLock(spec); err != nil {
+				return err
+			}
+		}
+	case *ast.DropStmtNode:
+		switch n.ObjectType() {
+		case "TABLE", "VIEW":
+		default:
+			return nil
+		}
+		return a.dropSpecForDiagnostics(a.namePath.format(n.NamePath()), n.IsIfExists(), false)
+	case *ast.DropFunctionStmtNode:
+		return a.dropSpecForDiagnostics(a.namePath.format(n.NamePath()), n.IsIfExists(), true)
+	case *ast.RenameStmtNode:
+		return a.renameSpecForDiagnostics(n.OldNamePath(), n.NewNamePath(), false)
+	case *ast.AlterTableStmtNode:
+		for _, action := range n.AlterActionList() {
+			if renameTo, ok := action.(*ast.RenameToActionNode); ok {
+				return a.renameSpecForDiagnostics(n.NamePath(), renameTo.NewPath(), n.IsIfExists())
+			}
+		}
+	}
+	return nil
+}
+
+func (a *Analyzer) renameSpecForDiagnostics(path, newPath []string, isIfExists bool) error {
+	a.catalog.mu.Lock()
+	defer a.catalog.mu.Unlock()
+	name := a.namePath.format(path)
+	spec, exists := a.catalog.tableMap[name]
+	if !exists {
+		if isIfExists {
+			return nil
+		}
+		return fmt.Errorf("table %s is not found", name)
+	}
+	newName := a.namePath.format(newPath)
+	if _, exists := a.catalog.tableMap[newName]; exists {
+		return fmt.Errorf("table %s already exists", newName)
+	}
+	newSpec := new(TableSpec)
+	*newSpec = *spec
+	newSpec.NamePath = a.namePath.mergePath(newPath)
+	if err := a.catalog.deleteTableSpecByName(name); err != nil {
+		return err
+	}
+	return a.catalog.addTableSpec(newSpec)
+}
+
+func (a *Analyzer) dropSpecForDiagnostics(name string, isIfExists, isFunction bool) error {
+	a.catalog.mu.Lock()
+	defer a.catalog.mu.Unlock()
+	if isFunction {
+		if _, exists := a.catalog.funcMap[name]; !exists {
+			if isIfExists {
+				return nil
+			}
+			return fmt.Errorf("function %s is not found", name)
+		}
+		return a.catalog.deleteFunctionSpecByName(name)
+	}
+	if _, exists := a.catalog.tableMap[name]; !exists {
+		if isIfExists {
+			return nil
+		}
+		return fmt.Errorf("table %s is not found", name)
+	}
+	return a.catalog.deleteTableSpecByName(name)
+}
+
+func newScriptDiagnostics(index int, script string, offset int, status DiagnosticsStatus, err error) StatementDiagnostics {
+	line, column := positionFromByteOffset(script, offset)
+	diagnostics := StatementDiagnostics{
+		Index:  index,
+		Line:   line,
+		Column: column,
+		Status: status,
+	}
+	if err != nil {
+		diagnostics.Error = err.Error()
+		if errLine, errColumn, found := errorLocation(err); found {
+			diagnostics.ErrorLine = errLine
+			diagnostics.ErrorColumn = errColumn
+		} else {
+			diagnostics.ErrorLine = line
+			diagnostics.ErrorColumn = column
+		}
+	}
+	return diagnostics
+}
+
+func (d StatementDiagnostics) withQuery(query string) StatementDiagnostics {
+	d.Query = query
+	return d
+}
+
+func errorLocation(err error) (int, int, bool) {
+	matched := errorLocationPattern.FindStringSubmatch(err.Error())
+	if len(matched) != 3 {
+		return 0, 0, false
+	}
+	line, _ := strconv.Atoi(matched[1])
+	column, _ := strconv.Atoi(matched[2])
+	return line, column, true
+}
+
+// mapErrorLocation converts the error location in the segment to the location in the original script.
+func mapErrorLocation(script string, offset int, err error) error {
+	if offset == 0 {
+		return err
+	}
+	line, column, found := errorLocation(err)
+	if !found {
+		return err
+	}
+	segmentLine, segmentColumn := positionFromByteOffset(script, offset)
+	if line == 1 {
+		column += segmentColumn - 1
+	}
+	line += segmentLine - 1
+	msg := errorLocationPattern.ReplaceAllString(err.Error(), fmt.Sprintf("[at %d:%d]", line, column))
+	return fmt.Errorf("%s", msg)
+}
+
+func positionFromByteOffset(src string, offset int) (int, int) {
+	if offset > len(src) {
+		offset = len(src)
+	}
+	line := strings.Count(src[:offset], "\n") + 1
+	column := offset - (strings.LastIndex(src[:offset], "\n") + 1) + 1
+	return line, column
+}
+
+func byteOffsetFromPosition(src string, line, column int) int {
+	offset := 0
+	for i := 1; i < line; i++ {
+		idx := strings.Index(src[offset:], "\n")
+		if idx < 0 {
+			return len(src)
+		}
+		offset += idx + 1
+	}
+	offset += column - 1
+	if offset > len(src) {
+		return len(src)
+	}
+	return offset
+}
+
+func leadingSeparatorLen(src string) int {
+	return len(src) - len(strings.TrimLeft(src, " \t\r\n;"))
+}
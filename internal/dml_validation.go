@@ -0,0 +1,232 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+	"github.com/goccy/go-zetasql/types"
+)
+
+// insertParamColumn is the column that the query parameter in INSERT VALUES is inserted into.
+type insertParamColumn struct {
+	paramIndex int
+	name       string
+	typ        types.Type
+	isNotNull  bool
+}
+
+// insertValidator validates the query parameters of INSERT VALUES against the schema of the target table
+// before they are encoded, because the encoded values are written to SQLite without type checking.
+type insertValidator struct {
+	columns []*insertParamColumn
+}
+
+func newInsertValidator(ctx context.Context, node *ast.InsertStmtNode, params []*ast.ParameterNode) (*insertValidator, error) {
+	if len(params) == 0 || len(node.RowList()) == 0 {
+		return nil, nil
+	}
+	var spec *TableSpec
+	if analyzer := analyzerFromContext(ctx); analyzer != nil {
+		tableName, err := getTableName(ctx, node.TableScan())
+		if err != nil {
+			return nil, err
+		}
+		spec = analyzer.catalog.tableSpec(tableName)
+	}
+	insertColumns := node.InsertColumnList()
+	validator := &insertValidator{}
+	for _, row := range node.RowList() {
+		for idx, value := range row.ValueList() {
+			param, ok := value.Value().(*ast.ParameterNode)
+			if !ok || idx >= len(insertColumns) {
+				continue
+			}
+			paramIndex := paramIndexOf(params, param)
+			if paramIndex < 0 {
+				continue
+			}
+			column := insertColumns[idx]
+			var isNotNull bool
+			if spec != nil {
+				if colSpec := spec.Column(column.Name()); colSpec != nil {
+					isNotNull = colSpec.IsNotNull
+				}
+			}
+			validator.columns = append(validator.columns, &insertParamColumn{
+				paramIndex: paramIndex,
+				name:       column.Name(),
+				typ:        column.Type(),
+				isNotNull:  isNotNull,
+			})
+		}
+	}
+	if len(validator.columns) == 0 {
+		return nil, nil
+	}
+	return validator, nil
+}
+
+func paramIndexOf(params []*ast.ParameterNode, param *ast.ParameterNode) int {
+	for idx, p := range params {
+		if param.Name() != "" {
+			if p.Name() == param.Name() {
+				return idx
+			}
+			continue
+		}
+		if p.Name() == "" && p.Position() == param.Position() {
+			return idx
+		}
+	}
+	return -1
+}
+
+// validate checks the values ordered by the parameters of the statement.
+func (v *insertValidator) validate(values []interface{}) error {
+	if v == nil {
+		return nil
+	}
+	for _, column := range v.columns {
+		if column.paramIndex >= len(values) {
+			continue
+		}
+		value, err := ValueFromGoValue(values[column.paramIndex])
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			if column.isNotNull {
+				return newRequiredFieldError(column.name)
+			}
+			continue
+		}
+		if !isCoercibleValue(value, column.typ) {
+			return fmt.Errorf(
+				"value has type %s which cannot be inserted into column %s, which has type %s",
+				valueTypeName(value),
+				column.name,
+				column.typ.TypeName(types.ProductExternal),
+			)
+		}
+	}
+	return nil
+}
+
+// isCoercibleValue reports whether the value converted from Go value can be coerced to the column type.
+// In addition to the coercion rules of ZetaSQL, STRING values are accepted as NUMERIC and BIGNUMERIC
+// because Go applications usually pass them as string to keep the precision.
+func isCoercibleValue(value Value, typ types.Type) bool {
+	switch v := value.(type) {
+	case *SafeValue:
+		return isCoercibleValue(v.value, typ)
+	case IntValue:
+		switch typ.Kind() {
+		case types.INT64, types.INT32, types.UINT32, types.UINT64,
+			types.FLOAT, types.DOUBLE, types.NUMERIC, types.BIG_NUMERIC, types.JSON:
+			return true
+		}
+		return false
+	case FloatValue:
+		switch typ.Kind() {
+		case types.FLOAT, types.DOUBLE, types.NUMERIC, types.BIG_NUMERIC, types.JSON:
+			return true
+		}
+		return false
+	case BoolValue:
+		switch typ.Kind() {
+		case types.BOOL, types.JSON:
+			return true
+		}
+		return false
+	case StringValue:
+		switch typ.Kind() {
+		case types.STRING, types.NUMERIC, types.BIG_NUMERIC, types.JSON, types.GEOGRAPHY, types.INTERVAL,
+			types.DATE, types.DATETIME, types.TIME, types.TIMESTAMP, types.ENUM:
+			return true
+		}
+		return false
+	case BytesValue:
+		switch typ.Kind() {
+		case types.BYTES, types.PROTO:
+			return true
+		}
+		return false
+	case TimestampValue:
+		switch typ.Kind() {
+		case types.TIMESTAMP, types.DATETIME, types.DATE, types.TIME:
+			return true
+		}
+		return false
+	case *ArrayValue:
+		if typ.Kind() != types.ARRAY {
+			return false
+		}
+		elemType := typ.AsArray().ElementType()
+		for _, elem := range v.values {
+			if elem == nil {
+				continue
+			}
+			if !isCoercibleValue(elem, elemType) {
+				return false
+			}
+		}
+		return true
+	case *StructValue:
+		switch typ.Kind() {
+		case types.STRUCT, types.JSON, types.NUMERIC, types.BIG_NUMERIC:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+func valueTypeName(value Value) string {
+	switch value.(type) {
+	case IntValue:
+		return "INT64"
+	case FloatValue:
+		return "FLOAT64"
+	case BoolValue:
+		return "BOOL"
+	case StringValue:
+		return "STRING"
+	case BytesValue:
+		return "BYTES"
+	case TimestampValue:
+		return "TIMESTAMP"
+	case *ArrayValue:
+		return "ARRAY"
+	case *StructValue:
+		return "STRUCT"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func newRequiredFieldError(column string) error {
+	return fmt.Errorf("required field %s cannot be null", column)
+}
+
+const notNullConstraintErrorPrefix = "NOT NULL constraint failed: "
+
+// translateConstraintError converts the NOT NULL constraint error of SQLite to the error identifying the column.
+func translateConstraintError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	idx := strings.Index(msg, notNullConstraintErrorPrefix)
+	if idx < 0 {
+		return err
+	}
+	column := msg[idx+len(notNullConstraintErrorPrefix):]
+	if end := strings.IndexAny(column, " \n"); end >= 0 {
+		column = column[:end]
+	}
+	if dot := strings.LastIndex(column, "."); dot >= 0 {
+		column = column[dot+1:]
+	}
+	return fmt.Errorf("%s: %w", newRequiredFieldError(column), err)
+}
@@ -0,0 +1,394 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	parsed_ast "github.com/goccy/go-zetasql/ast"
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+	"github.com/goccy/go-zetasql/types"
+)
+
+// dmlWithClause is the WITH clause preceding INSERT, UPDATE or DELETE statement.
+// ZetaSQL parses the WITH clause only for the query, so the clause is split from the statement by splitDMLWithClause
+// and each entry is analyzed as the query. The entries are referred from the statement as the tables of withClauseCatalog,
+// and the clause is emitted ahead of the formatted statement because SQLite supports the common table expressions on DML.
+type dmlWithClause struct {
+	entries []*dmlWithEntry
+	catalog *withClauseCatalog
+	prefix  string
+}
+
+type dmlWithEntry struct {
+	name  string
+	query string
+}
+
+var dmlKeywordsAfterWithClause = []string{"INSERT", "UPDATE", "DELETE"}
+
+// splitDMLWithClause returns the WITH clause and the rest of the query if the query is a DML statement preceded by WITH clause.
+// It returns nil if the query is not the case, then the query is parsed as it is.
+func splitDMLWithClause(query string) (*dmlWithClause, string, error) {
+	s := &withClauseScanner{src: query}
+	s.skipSpaces()
+	if !s.consumeKeyword("WITH") {
+		return nil, "", nil
+	}
+	s.skipSpaces()
+	if s.consumeKeyword("RECURSIVE") {
+		if !s.isFollowedByDML() {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("WITH RECURSIVE clause is unsupported for DML statement")
+	}
+	var entries []*dmlWithEntry
+	for {
+		s.skipSpaces()
+		name, ok := s.identifier()
+		if !ok {
+			return nil, "", nil
+		}
+		s.skipSpaces()
+		if !s.consumeKeyword("AS") {
+			return nil, "", nil
+		}
+		s.skipSpaces()
+		body, ok := s.parenthesized()
+		if !ok {
+			return nil, "", nil
+		}
+		entries = append(entries, &dmlWithEntry{name: name, query: body})
+		s.skipSpaces()
+		if !s.consume(",") {
+			break
+		}
+	}
+	if !s.isFollowedByDML() {
+		return nil, "", nil
+	}
+	return &dmlWithClause{entries: entries}, s.src[s.pos:], nil
+}
+
+// withClauseScanner scans the WITH clause by skipping the comments and the string literals in the entries.
+type withClauseScanner struct {
+	src string
+	pos int
+}
+
+func (s *withClauseScanner) skipSpaces() {
+	for s.pos < len(s.src) {
+		switch {
+		case unicode.IsSpace(rune(s.src[s.pos])):
+			s.pos++
+		case strings.HasPrefix(s.src[s.pos:], "--"), s.src[s.pos] == '#':
+			end := strings.IndexByte(s.src[s.pos:], '\n')
+			if end < 0 {
+				s.pos = len(s.src)
+				return
+			}
+			s.pos += end + 1
+		case strings.HasPrefix(s.src[s.pos:], "/*"):
+			end := strings.Index(s.src[s.pos+2:], "*/")
+			if end < 0 {
+				s.pos = len(s.src)
+				return
+			}
+			s.pos += end + 4
+		default:
+			return
+		}
+	}
+}
+
+func (s *withClauseScanner) consume(token string) bool {
+	if !strings.HasPrefix(s.src[s.pos:], token) {
+		return false
+	}
+	s.pos += len(token)
+	return true
+}
+
+func (s *withClauseScanner) hasKeyword(keyword string) bool {
+	end := s.pos + len(keyword)
+	if end > len(s.src) || !strings.EqualFold(s.src[s.pos:end], keyword) {
+		return false
+	}
+	return end == len(s.src) || !isIdentifierChar(s.src[end])
+}
+
+func (s *withClauseScanner) consumeKeyword(keyword string) bool {
+	if !s.hasKeyword(keyword) {
+		return false
+	}
+	s.pos += len(keyword)
+	return true
+}
+
+func (s *withClauseScanner) isFollowedByDML() bool {
+	s.skipSpaces()
+	for _, keyword := range dmlKeywordsAfterWithClause {
+		if s.hasKeyword(keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *withClauseScanner) identifier() (string, bool) {
+	if s.pos < len(s.src) && s.src[s.pos] == '`' {
+		end := strings.IndexByte(s.src[s.pos+1:], '`')
+		if end < 0 {
+			return "", false
+		}
+		name := s.src[s.pos+1 : s.pos+1+end]
+		s.pos += end + 2
+		return name, name != ""
+	}
+	start := s.pos
+	for s.pos < len(s.src) && isIdentifierChar(s.src[s.pos]) {
+		s.pos++
+	}
+	return s.src[start:s.pos], s.pos > start
+}
+
+// parenthesized returns the text between the parentheses and the matching closing parenthesis.
+func (s *withClauseScanner) parenthesized() (string, bool) {
+	if !s.consume("(") {
+		return "", false
+	}
+	start := s.pos
+	depth := 1
+	for s.pos < len(s.src) {
+		switch c := s.src[s.pos]; c {
+		case '(':
+			depth++
+			s.pos++
+		case ')':
+			depth--
+			if depth == 0 {
+				body := s.src[start:s.pos]
+				s.pos++
+				return body, true
+			}
+			s.pos++
+		case '\'', '"', '`':
+			if !s.skipQuoted(c) {
+				return "", false
+			}
+		case '-', '#', '/':
+			prev := s.pos
+			s.skipSpaces()
+			if s.pos == prev {
+				s.pos++
+			}
+		default:
+			s.pos++
+		}
+	}
+	return "", false
+}
+
+func (s *withClauseScanner) skipQuoted(quote byte) bool {
+	delim := string(quote)
+	if quote != '`' && strings.HasPrefix(s.src[s.pos:], strings.Repeat(delim, 3)) {
+		delim = strings.Repeat(delim, 3)
+	}
+	s.pos += len(delim)
+	for s.pos < len(s.src) {
+		if s.src[s.pos] == '\\' {
+			s.pos += 2
+			continue
+		}
+		if strings.HasPrefix(s.src[s.pos:], delim) {
+			s.pos += len(delim)
+			return true
+		}
+		s.pos++
+	}
+	return false
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// analyze analyzes the entries in order, so that each entry can refer to the preceding entries,
+// and formats the WITH clause of SQLite. The names of the entries are formatted by the name path
+// in the same way as the tables referred from the statement.
+func (w *dmlWithClause) analyze(ctx context.Context, a *Analyzer, funcMap map[string]*FunctionSpec) error {
+	formattedEntries := make([]string, 0, len(w.entries))
+	for _, entry := range w.entries {
+		stmts, err := parseScriptStatements(entry.query, a.opt.ParserOptions())
+		if err != nil {
+			return fmt.Errorf("failed to parse WITH clause entry %s: %w", entry.name, err)
+		}
+		if len(stmts) != 1 {
+			return fmt.Errorf("WITH clause entry %s must be a single query", entry.name)
+		}
+		if _, ok := stmts[0].(*parsed_ast.QueryStatementNode); !ok {
+			return fmt.Errorf("WITH clause entry %s must be a query", entry.name)
+		}
+		stmtNode, _, err := a.analyzeStmt(entry.query, stmts[0], nil)
+		if err != nil {
+			return fmt.Errorf("failed to analyze WITH clause entry %s: %w", entry.name, err)
+		}
+		queryNode, ok := stmtNode.(*ast.QueryStmtNode)
+		if !ok {
+			return fmt.Errorf("WITH clause entry %s must be a query", entry.name)
+		}
+		if len(getParamsFromNode(queryNode)) != 0 {
+			return fmt.Errorf("query parameters are unsupported in WITH clause entry %s of DML statement", entry.name)
+		}
+		entryCtx := a.context(a.stmtContext(ctx, queryNode), funcMap, queryNode, stmts[0])
+		formatted, err := newNode(queryNode).FormatSQL(entryCtx)
+		if err != nil {
+			return fmt.Errorf("failed to format WITH clause entry %s: %w", entry.name, err)
+		}
+		table := newWithClauseTable(a.namePath.format([]string{entry.name}), entry.name, queryNode.OutputColumnList())
+		w.catalog.tables[strings.ToLower(entry.name)] = table
+		formattedEntries = append(formattedEntries, fmt.Sprintf("`%s` AS (%s)", table.sqliteName, formatted))
+	}
+	w.prefix = fmt.Sprintf("WITH %s", strings.Join(formattedEntries, ","))
+	return nil
+}
+
+// analyzeDMLWithClause analyzes the statement by the catalog including the entries of the WITH clause.
+func (a *Analyzer) analyzeDMLWithClause(ctx context.Context, query string, funcMap map[string]*FunctionSpec, withClause *dmlWithClause, stmt parsed_ast.StatementNode, paramTypes queryParameterTypes) (ast.StatementNode, error) {
+	withClause.catalog = &withClauseCatalog{Catalog: a.catalog, tables: map[string]*withClauseTable{}}
+	a.withClauseCatalog = withClause.catalog
+	defer func() {
+		a.withClauseCatalog = nil
+	}()
+	if err := withClause.analyze(ctx, a, funcMap); err != nil {
+		return nil, err
+	}
+	stmtNode, _, err := a.analyzeStmt(query, stmt, paramTypes)
+	if err != nil {
+		return nil, err
+	}
+	switch stmtNode.Kind() {
+	case ast.InsertStmt, ast.UpdateStmt, ast.DeleteStmt:
+	default:
+		return nil, fmt.Errorf("WITH clause is unsupported for %s statement", stmtNode.Kind())
+	}
+	return stmtNode, nil
+}
+
+// apply emits the WITH clause ahead of the formatted statement.
+func (w *dmlWithClause) apply(action StmtAction) (StmtAction, error) {
+	dml, ok := action.(*DMLStmtAction)
+	if !ok {
+		return nil, fmt.Errorf("WITH clause is unsupported for %T", action)
+	}
+	if dml.insertValues != nil {
+		// the literal rows never refer to the entries of the WITH clause.
+		return dml, nil
+	}
+	dml.formattedQuery = fmt.Sprintf("%s %s", w.prefix, dml.formattedQuery)
+	return dml, nil
+}
+
+// analysisCatalog returns the catalog to analyze the statement.
+func (a *Analyzer) analysisCatalog() types.Catalog {
+	if a.withClauseCatalog != nil {
+		return a.withClauseCatalog
+	}
+	return a.catalog
+}
+
+// withClauseCatalog is the catalog to find the entries of the WITH clause before the tables of the catalog.
+type withClauseCatalog struct {
+	*Catalog
+	tables map[string]*withClauseTable
+}
+
+func (c *withClauseCatalog) FindTable(path []string) (types.Table, error) {
+	if len(path) == 1 {
+		if table, exists := c.tables[strings.ToLower(path[0])]; exists {
+			return table, nil
+		}
+	}
+	return c.Catalog.FindTable(path)
+}
+
+// withClauseTable is the entry of the WITH clause. It is formatted as the subquery selecting the common table expression.
+type withClauseTable struct {
+	sqliteName string
+	name       string
+	columns    []*ColumnSpec
+}
+
+func newWithClauseTable(sqliteName, name string, outputColumns []*ast.OutputColumnNode) *withClauseTable {
+	return &withClauseTable{
+		sqliteName: sqliteName,
+		name:       name,
+		columns:    newColumnsFromOutputColumns(outputColumns),
+	}
+}
+
+func (t *withClauseTable) FormatSQL(ctx context.Context) (string, error) {
+	return fmt.Sprintf("SELECT * FROM `%s`", t.sqliteName), nil
+}
+
+func (t *withClauseTable) Name() string {
+	return t.name
+}
+
+func (t *withClauseTable) FullName() string {
+	return t.name
+}
+
+func (t *withClauseTable) NumColumns() int {
+	return len(t.columns)
+}
+
+func (t *withClauseTable) Column(idx int) types.Column {
+	return t.newColumn(t.columns[idx])
+}
+
+func (t *withClauseTable) newColumn(column *ColumnSpec) types.Column {
+	typ, err := column.Type.ToZetaSQLType()
+	if err != nil {
+		return nil
+	}
+	return types.NewSimpleColumn(t.name, column.Name, typ)
+}
+
+func (t *withClauseTable) PrimaryKey() []int {
+	return nil
+}
+
+func (t *withClauseTable) FindColumnByName(name string) types.Column {
+	for _, column := range t.columns {
+		if strings.EqualFold(column.Name, name) {
+			return t.newColumn(column)
+		}
+	}
+	return nil
+}
+
+func (t *withClauseTable) IsValueTable() bool {
+	return false
+}
+
+func (t *withClauseTable) SerializationID() int64 {
+	return 0
+}
+
+func (t *withClauseTable) CreateEvaluatorTableIterator(columnIdxs []int) (*types.EvaluatorTableIterator, error) {
+	return nil, nil
+}
+
+func (t *withClauseTable) AnonymizationInfo() *types.AnonymizationInfo {
+	return nil
+}
+
+func (t *withClauseTable) SupportsAnonymization() bool {
+	return false
+}
+
+func (t *withClauseTable) TableTypeName(mode types.ProductMode) string {
+	return ""
+}
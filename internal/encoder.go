@@ -5,6 +5,7 @@ import (
 	"database/sql/driver"
 	"encoding/base64"
 	"fmt"
+	"math"
 	"math/big"
 	"reflect"
 	"regexp"
@@ -12,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"cloud.google.com/go/civil"
 	"github.com/goccy/go-json"
 	ast "github.com/goccy/go-zetasql/resolved_ast"
 	"github.com/goccy/go-zetasql/types"
@@ -66,14 +68,16 @@ func EncodeGoValue(t types.Type, v interface{}) (interface{}, error) {
 }
 
 func EncodeValue(v Value) (interface{}, error) {
-	if v == nil {
+	if isNull(v) {
 		return nil, nil
 	}
 	switch vv := v.(type) {
 	case IntValue:
 		return v.ToInt64()
 	case FloatValue:
-		return v.ToFloat64()
+		if !isSpecialFloat(float64(vv)) {
+			return v.ToFloat64()
+		}
 	case BoolValue:
 		return v.ToBool()
 	case *SafeValue:
@@ -91,7 +95,7 @@ func EncodeValue(v Value) (interface{}, error) {
 }
 
 func LiteralFromValue(v Value) (string, error) {
-	if v == nil {
+	if isNull(v) {
 		return "null", nil
 	}
 	switch vv := v.(type) {
@@ -106,6 +110,9 @@ func LiteralFromValue(v Value) (string, error) {
 		if err != nil {
 			return "", err
 		}
+		if isSpecialFloat(f64) {
+			break
+		}
 		value := strconv.FormatFloat(f64, 'g', -1, 64)
 		if !strings.Contains(value, ".") && !strings.Contains(value, "e") {
 			// append x.0 suffix to keep float value context
@@ -145,12 +152,16 @@ func ValueFromZetaSQLValue(v types.Value) (Value, error) {
 		return nil, nil
 	}
 	switch v.Type().Kind() {
-	case types.INT32, types.INT64, types.UINT32, types.UINT64:
-		return intValueFromLiteral(v.SQLLiteral(0))
+	// the values are taken directly instead of parsing SQL literal,
+	// because the literal of some values is an expression ( e.g. CAST("nan" AS FLOAT64) ).
+	case types.INT32, types.INT64, types.UINT32:
+		return IntValue(v.ToInt64()), nil
+	case types.UINT64:
+		return IntValue(int64(v.Uint64Value())), nil
 	case types.BOOL:
-		return boolValueFromLiteral(v.SQLLiteral(0))
+		return BoolValue(v.BoolValue()), nil
 	case types.FLOAT, types.DOUBLE:
-		return floatValueFromLiteral(v.SQLLiteral(0))
+		return FloatValue(v.ToDouble()), nil
 	case types.STRING:
 		return StringValue(v.StringValue()), nil
 	case types.ENUM:
@@ -183,30 +194,6 @@ func ValueFromZetaSQLValue(v types.Value) (Value, error) {
 	return nil, fmt.Errorf("unsupported literal type: %s", v.Type().Kind())
 }
 
-func intValueFromLiteral(lit string) (IntValue, error) {
-	v, err := strconv.ParseInt(lit, 10, 64)
-	if err != nil {
-		return 0, err
-	}
-	return IntValue(v), nil
-}
-
-func boolValueFromLiteral(lit string) (BoolValue, error) {
-	v, err := strconv.ParseBool(lit)
-	if err != nil {
-		return false, err
-	}
-	return BoolValue(v), nil
-}
-
-func floatValueFromLiteral(lit string) (FloatValue, error) {
-	v, err := strconv.ParseFloat(lit, 64)
-	if err != nil {
-		return 0, err
-	}
-	return FloatValue(v), nil
-}
-
 func stringValueFromLiteral(lit string) (StringValue, error) {
 	v, err := strconv.Unquote(lit)
 	if err != nil {
@@ -225,7 +212,7 @@ func bytesValueFromLiteral(lit string) BytesValue {
 }
 
 func dateValueFromLiteral(days int64) DateValue {
-	t := time.Unix(int64(time.Duration(days)*24*(time.Hour/time.Second)), 0)
+	t := time.Unix(int64(time.Duration(days)*24*(time.Hour/time.Second)), 0).UTC()
 	return DateValue(t)
 }
 
@@ -277,7 +264,7 @@ func timeValueFromLiteral(bit int64) TimeValue {
 }
 
 func timestampValueFromLiteral(t time.Time) (TimestampValue, error) {
-	return TimestampValue(t), nil
+	return TimestampValue(timestampKey(t)), nil
 }
 
 var (
@@ -354,7 +341,7 @@ func structValueFromLiteral(v types.Value) (*StructValue, error) {
 }
 
 func CastValue(t types.Type, v Value) (Value, error) {
-	if v == nil {
+	if isNull(v) {
 		return nil, nil
 	}
 	switch t.Kind() {
@@ -411,7 +398,7 @@ func CastValue(t types.Type, v Value) (Value, error) {
 		if err != nil {
 			return nil, err
 		}
-		return TimestampValue(t), nil
+		return TimestampValue(timestampKey(t)), nil
 	case types.INTERVAL:
 		s, err := v.ToString()
 		if err != nil {
@@ -454,6 +441,23 @@ func CastValue(t types.Type, v Value) (Value, error) {
 			return nil, err
 		}
 		typ := t.AsStruct()
+		if len(s.values) == typ.NumFields() && !hasAllStructFields(s, typ) {
+			// STRUCT having the different field names is converted by the field position like ZetaSQL.
+			// This makes the encoded values of the same STRUCT type comparable ( e.g. the inputs of UNION DISTINCT ).
+			// The value created from the Go map or the JavaScript object has no field order, so it is converted by name below.
+			ret := &StructValue{m: map[string]Value{}}
+			for i := 0; i < typ.NumFields(); i++ {
+				field := typ.Field(i)
+				casted, err := CastValue(field.Type(), s.values[i])
+				if err != nil {
+					return nil, err
+				}
+				ret.keys = append(ret.keys, field.Name())
+				ret.values = append(ret.values, casted)
+				ret.m[field.Name()] = casted
+			}
+			return ret, nil
+		}
 		anonymousStruct := true
 		for _, key := range s.keys {
 			if key != "" {
@@ -509,6 +513,10 @@ func ValueFromGoValue(v interface{}) (Value, error) {
 	if isNullValue(v) {
 		return nil, nil
 	}
+	if value, ok := v.(Value); ok {
+		// the value bound by the script ( e.g. the row of FOR ... IN loop ) is passed as it is.
+		return value, nil
+	}
 	return valueFromGoReflectValue(reflect.ValueOf(v))
 }
 
@@ -560,9 +568,16 @@ func valueFromGoReflectValue(v reflect.Value) (Value, error) {
 		}
 		return ret, nil
 	case reflect.Struct:
-		t, ok := v.Interface().(time.Time)
-		if ok {
-			return TimestampValue(t), nil
+		switch t := v.Interface().(type) {
+		case time.Time:
+			// TIMESTAMP has microsecond precision, so the nanoseconds of time.Time are truncated.
+			return TimestampValue(timestampKey(t)), nil
+		case civil.Date:
+			return DateValue(t.In(time.UTC)), nil
+		case civil.DateTime:
+			return DatetimeValue(civilDatetime(t.In(time.UTC))), nil
+		case civil.Time:
+			return TimeValue(civilTime(time.Date(1970, 1, 1, t.Hour, t.Minute, t.Second, t.Nanosecond, time.UTC))), nil
 		}
 		ret := &StructValue{m: map[string]Value{}}
 		typ := v.Type()
@@ -578,6 +593,9 @@ func valueFromGoReflectValue(v reflect.Value) (Value, error) {
 		}
 		return ret, nil
 	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
 		return valueFromGoReflectValue(v.Elem())
 	case reflect.Interface:
 		vv := v.Interface()
@@ -600,8 +618,29 @@ func encodeNamedValue(v driver.NamedValue, param *ast.ParameterNode) (sql.NamedA
 	}, nil
 }
 
+func hasAllStructFields(s *StructValue, typ *types.StructType) bool {
+	for i := 0; i < typ.NumFields(); i++ {
+		if _, exists := s.m[typ.Field(i).Name()]; !exists {
+			return false
+		}
+	}
+	return true
+}
+
+// isSpecialFloat reports whether f is NaN, infinity or negative zero.
+// SQLite stores NaN as NULL and the whole number of REAL column as integer, and JSON cannot represent NaN and infinity,
+// so these values are encoded with the value layout instead of the native float.
+func isSpecialFloat(f float64) bool {
+	return math.IsNaN(f) || math.IsInf(f, 0) || (f == 0 && math.Signbit(f))
+}
+
 func valueLayoutFromValue(v Value) (*ValueLayout, error) {
 	switch vv := v.(type) {
+	case FloatValue:
+		return &ValueLayout{
+			Header: FloatValueType,
+			Body:   strconv.FormatFloat(float64(vv), 'g', -1, 64),
+		}, nil
 	case StringValue:
 		return &ValueLayout{
 			Header: StringValueType,
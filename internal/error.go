@@ -1,6 +1,169 @@
 package internal
 
-import "strings"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrConcurrentWrite is the error returned when the statement cannot be executed
+// because the database is locked by another connection even after waiting and retrying.
+var ErrConcurrentWrite = errors.New("database is locked by another connection")
+
+const (
+	maxLockRetries      = 5
+	initialRetryBackoff = 10 * time.Millisecond
+)
+
+type concurrentWriteError struct {
+	err error
+}
+
+func (e *concurrentWriteError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrConcurrentWrite, e.err)
+}
+
+func (e *concurrentWriteError) Is(target error) bool {
+	return target == ErrConcurrentWrite
+}
+
+func (e *concurrentWriteError) Unwrap() error {
+	return e.err
+}
+
+func isLockError(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
+
+// TranslateLockError converts the lock error of SQLite to the error matched with ErrConcurrentWrite.
+func TranslateLockError(err error) error {
+	if err == nil || !isLockError(err) {
+		return err
+	}
+	return &concurrentWriteError{err: err}
+}
+
+// retryOnLockError calls fn again while it fails by the lock error.
+// SQLite rolls back the statement failed by the lock error, so retrying it is safe outside of the explicit transaction.
+func retryOnLockError(ctx context.Context, fn func() error) error {
+	backoff := initialRetryBackoff
+	for i := 0; ; i++ {
+		err := fn()
+		if err == nil || !isLockError(err) {
+			return err
+		}
+		if i >= maxLockRetries {
+			return TranslateLockError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return TranslateLockError(err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// SQLiteError is the error of SQLite executing the statement translated from the query.
+// The original statement and the translated one are attached to find out which part of the query is translated incorrectly.
+// The SQL texts are empty if they are hidden by SetErrorQueryTextMode.
+type SQLiteError struct {
+	// Query is the original statement of the query.
+	Query string
+	// SQLiteQuery is the statement of SQLite translated from Query.
+	SQLiteQuery string
+	// Params is the names of the parameters bound to SQLiteQuery ( "?" for the positional parameters ).
+	Params []string
+	// StatementIndex is the zero-based index of the statement in the script.
+	// For the statements in FOR ... IN loop or EXECUTE IMMEDIATE, it is the index in the body or the dynamic SQL.
+	StatementIndex int
+	Err            error
+}
+
+func (e *SQLiteError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "failed to execute statement %d: %s", e.StatementIndex, e.Err)
+	if e.Query != "" {
+		fmt.Fprintf(&b, "\nquery:\n%s", indentText(e.Query))
+	}
+	if e.SQLiteQuery != "" {
+		fmt.Fprintf(&b, "\nsqlite query:\n%s", indentText(e.SQLiteQuery))
+	}
+	if len(e.Params) != 0 {
+		fmt.Fprintf(&b, "\nparameters: %s", strings.Join(e.Params, ", "))
+	}
+	return b.String()
+}
+
+func (e *SQLiteError) Unwrap() error {
+	return e.Err
+}
+
+func indentText(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = "  " + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sqliteErrorContext is the information of the translated statement attached to SQLiteError.
+type sqliteErrorContext struct {
+	location      *statementLocation
+	params        []string
+	hideQueryText bool
+}
+
+func (a *Analyzer) newSQLiteErrorContext(ctx context.Context, params []*ast.ParameterNode) *sqliteErrorContext {
+	names := make([]string, 0, len(params))
+	for _, param := range params {
+		if param.Name() == "" {
+			names = append(names, "?")
+		} else {
+			names = append(names, "@"+param.Name())
+		}
+	}
+	return &sqliteErrorContext{
+		location:      statementLocationFromContext(ctx),
+		params:        names,
+		hideQueryText: a.isErrorQueryTextHidden,
+	}
+}
+
+// wrap wraps the error by SQLiteError. The error already wrapped by the nested statement is returned as it is.
+func (c *sqliteErrorContext) wrap(sqliteQuery string, err error) error {
+	if c == nil || err == nil {
+		return err
+	}
+	var sqliteErr *SQLiteError
+	if errors.As(err, &sqliteErr) {
+		return err
+	}
+	wrapped := &SQLiteError{Params: c.params, Err: err}
+	if c.location != nil {
+		wrapped.StatementIndex = c.location.index
+		if !c.hideQueryText {
+			wrapped.Query = c.location.text
+		}
+	}
+	if !c.hideQueryText {
+		wrapped.SQLiteQuery = sqliteQuery
+	}
+	return wrapped
+}
+
+func (a *Analyzer) SetErrorQueryTextMode(enabled bool) {
+	a.isErrorQueryTextHidden = !enabled
+}
 
 type ErrorGroup struct {
 	errs []error
@@ -27,3 +190,21 @@ func (eg *ErrorGroup) Error() string {
 	}
 	return ""
 }
+
+func (eg *ErrorGroup) Is(target error) bool {
+	for _, err := range eg.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (eg *ErrorGroup) As(target interface{}) bool {
+	for _, err := range eg.errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
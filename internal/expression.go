@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-zetasql"
+	"github.com/goccy/go-zetasql/types"
+)
+
+// expressionColumnParamPrefix is the prefix of the parameters bound to the expression columns.
+// It separates the expression columns from the query parameters referenced by the expression.
+const expressionColumnParamPrefix = "zetasqlite_expr_"
+
+func expressionColumnParamName(name string) string {
+	return expressionColumnParamPrefix + name
+}
+
+// EvaluateExpr evaluates the standalone expression.
+// The columns are visible from the expression by name, and their types are inferred from the values.
+func (a *Analyzer) EvaluateExpr(ctx context.Context, conn *Conn, expr string, columns map[string]interface{}) (Value, error) {
+	if err := a.catalog.Sync(ctx, conn); err != nil {
+		return nil, fmt.Errorf("failed to sync catalog: %w", err)
+	}
+	opt, err := newAnalyzerOptions(a.languageFeatures)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	args := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		value, err := ValueFromGoValue(columns[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert column %s: %w", name, err)
+		}
+		typ, err := typeFromValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to infer type of column %s: %w", name, err)
+		}
+		if err := opt.AddExpressionColumn(name, typ); err != nil {
+			return nil, fmt.Errorf("failed to add expression column %s: %w", name, err)
+		}
+		encoded, err := EncodeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode column %s: %w", name, err)
+		}
+		// Name() value of ast.ExpressionColumnNode always returns lowercase name.
+		args = append(args, sql.Named(expressionColumnParamName(strings.ToLower(name)), encoded))
+	}
+	parsedExpr, err := zetasql.ParseExpression(expr, opt.ParserOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse expression: %w", err)
+	}
+	out, err := zetasql.AnalyzeExpression(expr, a.catalog, opt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze expression: %w", err)
+	}
+	exprNode := out.Expr()
+	exprCtx := a.context(ctx, a.funcMap(), exprNode, parsedExpr)
+	formattedExpr, err := newNode(exprNode).FormatSQL(exprCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format expression %s: %w", expr, err)
+	}
+	if formattedExpr == "" {
+		return nil, fmt.Errorf("failed to format expression %s", expr)
+	}
+	var result interface{}
+	if err := conn.QueryRowContext(ctx, fmt.Sprintf("SELECT %s", formattedExpr), args...).Scan(&result); err != nil {
+		return nil, fmt.Errorf("failed to evaluate expression %s: %w", expr, err)
+	}
+	decoded, err := DecodeValue(result)
+	if err != nil {
+		return nil, err
+	}
+	return CastValue(exprNode.Type(), decoded)
+}
+
+// typeFromValue infers the type of the value converted from the Go value.
+// NULL is typed as INT64 like the untyped NULL literal.
+func typeFromValue(v Value) (types.Type, error) {
+	if isNull(v) {
+		return types.Int64Type(), nil
+	}
+	switch vv := v.(type) {
+	case IntValue:
+		return types.Int64Type(), nil
+	case FloatValue:
+		return types.DoubleType(), nil
+	case BoolValue:
+		return types.BoolType(), nil
+	case StringValue:
+		return types.StringType(), nil
+	case BytesValue:
+		return types.BytesType(), nil
+	case TimestampValue:
+		return types.TimestampType(), nil
+	case *ArrayValue:
+		var elem Value
+		for _, value := range vv.values {
+			if !isNull(value) {
+				elem = value
+				break
+			}
+		}
+		elemType, err := typeFromValue(elem)
+		if err != nil {
+			return nil, err
+		}
+		arrayType, err := types.NewArrayType(elemType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create array type: %w", err)
+		}
+		return arrayType, nil
+	case *StructValue:
+		fields := make([]*types.StructField, 0, len(vv.keys))
+		for idx, key := range vv.keys {
+			fieldType, err := typeFromValue(vv.values[idx])
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, types.NewStructField(key, fieldType))
+		}
+		structType, err := types.NewStructType(fields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create struct type: %w", err)
+		}
+		return structType, nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T", v)
+}
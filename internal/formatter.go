@@ -3,7 +3,7 @@ package internal
 import (
 	"context"
 	"fmt"
-	"regexp"
+	"math"
 	"strings"
 
 	"github.com/goccy/go-json"
@@ -131,6 +131,9 @@ func formatInput(input string) (string, error) {
 	return "", fmt.Errorf("unexpected input pattern: %s", input)
 }
 
+// getFuncNameAndArgs returns the name of the SQLite function registered for the call and the formatted arguments.
+// The name doesn't depend on the result type, so each builtin function is registered once for all types
+// ( e.g. ARRAY_AGG for every element type ), because the encoded value carries its type including the element type of ARRAY.
 func getFuncNameAndArgs(ctx context.Context, node *ast.BaseFunctionCallNode, isWindowFunc bool) (string, []string, error) {
 	args := []string{}
 	for _, a := range node.ArgumentList() {
@@ -143,6 +146,14 @@ func getFuncNameAndArgs(ctx context.Context, node *ast.BaseFunctionCallNode, isW
 	funcName := node.Function().FullName(false)
 	funcName = strings.Replace(funcName, ".", "_", -1)
 
+	zoneArg, err := defaultTimeZoneArg(ctx, funcName, node)
+	if err != nil {
+		return "", nil, err
+	}
+	if zoneArg != "" {
+		args = append(args, zoneArg)
+	}
+
 	_, existsCurrentTimeFunc := currentTimeFuncMap[funcName]
 	_, existsNormalFunc := normalFuncMap[funcName]
 	_, existsAggregateFunc := aggregateFuncMap[funcName]
@@ -165,9 +176,10 @@ func getFuncNameAndArgs(ctx context.Context, node *ast.BaseFunctionCallNode, isW
 		}
 	} else if existsCurrentTimeFunc {
 		if currentTime != nil {
+			// the current time precedes the time zone argument.
 			args = append(
-				args,
-				fmt.Sprint(currentTime.UnixNano()),
+				[]string{fmt.Sprint(currentTime.UnixNano())},
+				args...,
 			)
 		}
 		funcName = fmt.Sprintf("%s_%s", funcPrefix, funcName)
@@ -199,21 +211,32 @@ func (n *LiteralNode) FormatSQL(ctx context.Context) (string, error) {
 
 func (n *ParameterNode) FormatSQL(ctx context.Context) (string, error) {
 	if n.node.Name() == "" {
-		return "?", nil
+		// positional parameter is numbered so that the value is bound by the position in the original query
+		// regardless of the order of appearance in the formatted query.
+		return fmt.Sprintf("?%d", n.node.Position()), nil
 	}
 	return fmt.Sprintf("@%s", n.node.Name()), nil
 }
 
 func (n *ExpressionColumnNode) FormatSQL(ctx context.Context) (string, error) {
-	return "", nil
+	if n.node == nil {
+		return "", nil
+	}
+	// the value of the expression column is bound to the named parameter by EvaluateExpr.
+	return fmt.Sprintf("@%s", expressionColumnParamName(n.node.Name())), nil
 }
 
 func (n *ColumnRefNode) FormatSQL(ctx context.Context) (string, error) {
 	if n.node == nil {
 		return "", nil
 	}
-	columnMap := columnRefMap(ctx)
 	col := n.node.Column()
+	if n.node.IsCorrelated() {
+		if name, exists := correlatedColumnNameMap(ctx)[col.ColumnID()]; exists {
+			return name, nil
+		}
+	}
+	columnMap := columnRefMap(ctx)
 	colName := uniqueColumnName(ctx, col)
 	if ref, exists := columnMap[colName]; exists {
 		delete(columnMap, colName)
@@ -222,8 +245,13 @@ func (n *ColumnRefNode) FormatSQL(ctx context.Context) (string, error) {
 	return fmt.Sprintf("`%s`", colName), nil
 }
 
+// FormatSQL named constants cannot be resolved yet because go-zetasql doesn't provide a way to create types.Constant,
+// so the catalog is unable to return them from FindConstant.
 func (n *ConstantNode) FormatSQL(ctx context.Context) (string, error) {
-	return "", nil
+	if n.node == nil {
+		return "", nil
+	}
+	return "", fmt.Errorf("unsupported constant reference %s", n.node.Constant().FullName())
 }
 
 func (n *SystemVariableNode) FormatSQL(ctx context.Context) (string, error) {
@@ -234,18 +262,85 @@ func (n *InlineLambdaNode) FormatSQL(ctx context.Context) (string, error) {
 	return "", nil
 }
 
+// FILTER_FIELDS accepts only the proto value, and the analyzer rejects the other types ( including STRUCT ) by itself.
+// The proto type is never registered to the catalog and the field paths of the arguments are not exposed by go-zetasql,
+// so the resolved nodes are reported as unsupported instead of being formatted to an empty expression.
+
 func (n *FilterFieldArgNode) FormatSQL(ctx context.Context) (string, error) {
-	return "", nil
+	return "", fmt.Errorf("FILTER_FIELDS is unsupported: field path argument cannot be formatted")
 }
 
 func (n *FilterFieldNode) FormatSQL(ctx context.Context) (string, error) {
-	return "", nil
+	return "", fmt.Errorf("FILTER_FIELDS is unsupported: proto type is not supported")
+}
+
+// nativeComparisonOperatorMap is the comparison functions whose BigQuery semantics coincide with SQLite operators
+// when both operands are stored as native SQLite values.
+var nativeComparisonOperatorMap = map[string]string{
+	"$equal":            "=",
+	"$not_equal":        "!=",
+	"$less":             "<",
+	"$less_or_equal":    "<=",
+	"$greater":          ">",
+	"$greater_or_equal": ">=",
+}
+
+// formatNativeComparison formats the comparison as SQLite operator instead of calling the function
+// so that SQLite can use the indexes of the compared columns.
+// Only INT64 column references, literals and parameters are pushed down because they are always stored as SQLite integers.
+// The other types are encoded ( e.g. STRING ) or have special values that SQLite cannot store ( e.g. NaN of FLOAT64 ),
+// and LIKE is not pushed down because SQLite compares ASCII characters case-insensitively.
+// Returns false if the comparison cannot be pushed down.
+func formatNativeComparison(ctx context.Context, node *ast.BaseFunctionCallNode) (string, bool, error) {
+	if !node.Function().IsZetaSQLBuiltin() || node.ErrorMode() == ast.SafeErrorMode {
+		return "", false, nil
+	}
+	op, exists := nativeComparisonOperatorMap[node.Function().FullName(false)]
+	if !exists {
+		return "", false, nil
+	}
+	argList := node.ArgumentList()
+	if len(argList) != 2 {
+		return "", false, nil
+	}
+	for _, arg := range argList {
+		if !isNativeComparableExpr(arg) {
+			return "", false, nil
+		}
+	}
+	args := make([]string, 0, len(argList))
+	for _, arg := range argList {
+		formatted, err := newNode(arg).FormatSQL(ctx)
+		if err != nil {
+			return "", false, err
+		}
+		args = append(args, formatted)
+	}
+	return fmt.Sprintf("(%s %s %s)", args[0], op, args[1]), true, nil
+}
+
+func isNativeComparableExpr(expr ast.ExprNode) bool {
+	if expr.Type().Kind() != types.INT64 {
+		return false
+	}
+	switch expr.(type) {
+	case *ast.ColumnRefNode, *ast.LiteralNode, *ast.ParameterNode:
+		return true
+	}
+	return false
 }
 
 func (n *FunctionCallNode) FormatSQL(ctx context.Context) (string, error) {
 	if n.node == nil {
 		return "", nil
 	}
+	expr, pushedDown, err := formatNativeComparison(ctx, n.node.BaseFunctionCallNode)
+	if err != nil {
+		return "", err
+	}
+	if pushedDown {
+		return expr, nil
+	}
 	funcName, args, err := getFuncNameAndArgs(ctx, n.node.BaseFunctionCallNode, false)
 	if err != nil {
 		return "", err
@@ -329,6 +424,9 @@ func (n *AggregateFunctionCallNode) FormatSQL(ctx context.Context) (string, erro
 		opts = append(opts, "zetasqlite_ignore_nulls()")
 	case ast.RespectNulls:
 	}
+	if opt := maxBufferedValuesOptionFuncSQL(ctx); opt != "" {
+		opts = append(opts, opt)
+	}
 	args = append(args, opts...)
 	return fmt.Sprintf(
 		"%s(%s)",
@@ -357,6 +455,9 @@ func (n *AnalyticFunctionCallNode) FormatSQL(ctx context.Context) (string, error
 	default:
 		opts = append(opts, "zetasqlite_ignore_nulls()")
 	}
+	if opt := maxBufferedValuesOptionFuncSQL(ctx); opt != "" {
+		opts = append(opts, opt)
+	}
 	args = append(args, opts...)
 	for _, column := range analyticPartitionColumnNamesFromContext(ctx) {
 		args = append(args, getWindowPartitionOptionFuncSQL(column))
@@ -365,8 +466,17 @@ func (n *AnalyticFunctionCallNode) FormatSQL(ctx context.Context) (string, error
 		args = append(args, getWindowOrderByOptionFuncSQL(col.column, col.isAsc))
 	}
 	windowFrame := n.node.WindowFrame()
+	if n.node.Distinct() && windowFrame != nil {
+		// the distinct values cannot be computed for each frame,
+		// so it must be rejected instead of computing the values over the whole partition.
+		return "", fmt.Errorf("DISTINCT is not allowed for analytic function with window ORDER BY or window frame")
+	}
 	if windowFrame != nil {
-		args = append(args, getWindowFrameUnitOptionFuncSQL(windowFrame.FrameUnit()))
+		frameUnitSQL, err := getWindowFrameUnitOptionFuncSQL(windowFrame.FrameUnit())
+		if err != nil {
+			return "", err
+		}
+		args = append(args, frameUnitSQL)
 		startSQL, err := n.getWindowBoundaryOptionFuncSQL(ctx, windowFrame.StartExpr(), true)
 		if err != nil {
 			return "", err
@@ -377,7 +487,7 @@ func (n *AnalyticFunctionCallNode) FormatSQL(ctx context.Context) (string, error
 		}
 		args = append(args, startSQL, endSQL)
 	}
-	args = append(args, getWindowRowIDOptionFuncSQL())
+	args = append(args, getWindowRowIDOptionFuncSQL(analyticRowIDColumnNameFromContext(ctx)))
 	input := analyticInputScanFromContext(ctx)
 	funcMap := funcMapFromContext(ctx)
 	if spec, exists := funcMap[funcName]; exists {
@@ -424,12 +534,19 @@ func (n *CastNode) FormatSQL(ctx context.Context) (string, error) {
 	if n.node == nil {
 		return "", nil
 	}
-	fromType := newType(n.node.Expr().Type())
+	expr, err := newNode(n.node.Expr()).FormatSQL(ctx)
+	if err != nil {
+		return "", err
+	}
+	return formatCast(ctx, expr, newType(n.node.Expr().Type()), newType(n.node.Type()), n.node.ReturnNullOnError())
+}
+
+// formatCast returns the call of zetasqlite_cast converting the formatted expression from fromType to toType.
+func formatCast(ctx context.Context, expr string, fromType, toType *Type, returnNullOnError bool) (string, error) {
 	jsonEncodedFromType, err := json.Marshal(fromType)
 	if err != nil {
 		return "", err
 	}
-	toType := newType(n.node.Type())
 	jsonEncodedToType, err := json.Marshal(toType)
 	if err != nil {
 		return "", err
@@ -442,13 +559,19 @@ func (n *CastNode) FormatSQL(ctx context.Context) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	expr, err := newNode(n.node.Expr()).FormatSQL(ctx)
-	if err != nil {
-		return "", err
+	if zone := defaultTimeZone(ctx); zone != "" && isTimeZoneDependentCast(fromType, toType) {
+		zoneArg, err := LiteralFromValue(StringValue(zone))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(
+			"zetasqlite_cast(%s, '%s', '%s', %t, %s)",
+			expr, encodedFromType, encodedToType, returnNullOnError, zoneArg,
+		), nil
 	}
 	return fmt.Sprintf(
 		"zetasqlite_cast(%s, '%s', '%s', %t)",
-		expr, encodedFromType, encodedToType, n.node.ReturnNullOnError(),
+		expr, encodedFromType, encodedToType, returnNullOnError,
 	), nil
 }
 
@@ -536,9 +659,13 @@ func (n *SubqueryExprNode) FormatSQL(ctx context.Context) (string, error) {
 	if n.node == nil {
 		return "", nil
 	}
+	subqueryCtx := ctx
+	if !useColumnID(ctx) {
+		subqueryCtx = withUseColumnID(withCorrelatedColumnNameMap(ctx, n.correlatedColumnNameMap(ctx)))
+	}
 	columnNames := &arraySubqueryColumnNames{}
-	ctx = withArraySubqueryColumnName(ctx, columnNames)
-	sql, err := newNode(n.node.Subquery()).FormatSQL(ctx)
+	subqueryCtx = withArraySubqueryColumnName(subqueryCtx, columnNames)
+	sql, err := newNode(n.node.Subquery()).FormatSQL(subqueryCtx)
 	if err != nil {
 		return "", err
 	}
@@ -548,8 +675,17 @@ func (n *SubqueryExprNode) FormatSQL(ctx context.Context) (string, error) {
 		if len(n.node.Subquery().ColumnList()) == 0 {
 			return "", fmt.Errorf("failed to find computed column names for array subquery")
 		}
-		colName := uniqueColumnName(ctx, n.node.Subquery().ColumnList()[0])
-		return fmt.Sprintf("(SELECT zetasqlite_array(`%s`) FROM (%s))", colName, sql), nil
+		arrayArgs := fmt.Sprintf("`%s`", uniqueColumnName(subqueryCtx, n.node.Subquery().ColumnList()[0]))
+		if opt := maxBufferedValuesOptionFuncSQL(ctx); opt != "" {
+			arrayArgs += ", " + opt
+		}
+		if n.node.Subquery().IsOrdered() {
+			// zetasqlite_array appends the elements in the order of the input rows.
+			// SQLite always honors ORDER BY of the subquery with LIMIT clause,
+			// so add LIMIT -1 ( no limit ) to keep the order of the subquery.
+			return fmt.Sprintf("(SELECT zetasqlite_array(%s) FROM (SELECT * FROM (%s) LIMIT -1))", arrayArgs, sql), nil
+		}
+		return fmt.Sprintf("(SELECT zetasqlite_array(%s) FROM (%s))", arrayArgs, sql), nil
 	case ast.SubqueryTypeExists:
 		return fmt.Sprintf("EXISTS (%s)", sql), nil
 	case ast.SubqueryTypeIn:
@@ -564,6 +700,28 @@ func (n *SubqueryExprNode) FormatSQL(ctx context.Context) (string, error) {
 	return fmt.Sprintf("(%s)", sql), nil
 }
 
+// correlatedColumnNameMap returns the names of the outer columns referenced by the subquery.
+// The columns outside the subquery are referred to by their names when the column id is not used,
+// and they may conflict with the columns of the same name in the subquery.
+// So the subquery identifies its own columns by the column id, and the outer columns of the DML target table
+// are qualified by the table name.
+func (n *SubqueryExprNode) correlatedColumnNameMap(ctx context.Context) map[int]string {
+	names := map[int]string{}
+	for id, name := range correlatedColumnNameMap(ctx) {
+		names[id] = name
+	}
+	tableName := dmlTargetTableName(ctx)
+	for _, param := range n.node.ParameterList() {
+		col := param.Column()
+		if tableName != "" {
+			names[col.ColumnID()] = fmt.Sprintf("`%s`.`%s`", tableName, col.Name())
+		} else {
+			names[col.ColumnID()] = fmt.Sprintf("`%s`", uniqueColumnName(ctx, col))
+		}
+	}
+	return names
+}
+
 func (n *LetExprNode) FormatSQL(ctx context.Context) (string, error) {
 	return "", nil
 }
@@ -588,28 +746,51 @@ func (n *TableScanNode) FormatSQL(ctx context.Context) (string, error) {
 	if n.node == nil {
 		return "", nil
 	}
-	var columns []string
-	for _, col := range n.node.ColumnList() {
-		columns = append(
-			columns,
-			fmt.Sprintf("`%s` AS `%s`", col.Name(), uniqueColumnName(ctx, col)),
-		)
-	}
-
 	table := n.node.Table()
-	wildcardTable, ok := table.(*WildcardTable)
+	// wildcard tables and INFORMATION_SCHEMA views are not real tables on SQLite, so they format the query by themselves.
+	virtualTable, ok := table.(interface {
+		FormatSQL(context.Context) (string, error)
+	})
 	if ok {
-		query, err := wildcardTable.FormatSQL(ctx)
+		query, err := virtualTable.FormatSQL(ctx)
 		if err != nil {
 			return "", err
 		}
-		return fmt.Sprintf("(SELECT %s FROM (%s))", strings.Join(columns, ","), query), nil
+		return fmt.Sprintf("(SELECT %s FROM (%s))", strings.Join(n.formatColumns(ctx, false), ","), query), nil
 	}
 	tableName, err := getTableName(ctx, n.node)
 	if err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("(SELECT %s FROM `%s`)", strings.Join(columns, ","), tableName), nil
+	var isNative bool
+	if analyzer := analyzerFromContext(ctx); analyzer != nil {
+		isNative = analyzer.catalog.isNativeTable(tableName)
+	}
+	columns := strings.Join(n.formatColumns(ctx, isNative), ",")
+	if filter := rowAccessPolicyFilter(ctx, tableName); filter != "" {
+		return fmt.Sprintf("(SELECT %s FROM `%s` WHERE %s)", columns, tableName, filter), nil
+	}
+	return fmt.Sprintf("(SELECT %s FROM `%s`)", columns, tableName), nil
+}
+
+func (n *TableScanNode) formatColumns(ctx context.Context, isNative bool) []string {
+	var columns []string
+	for _, col := range n.node.ColumnList() {
+		if isNative {
+			// values of the table created without zetasqlite are stored as native SQLite values,
+			// so convert them to the encoded values.
+			columns = append(
+				columns,
+				fmt.Sprintf("zetasqlite_decode_native(`%s`, %d) AS `%s`", col.Name(), col.Type().Kind(), uniqueColumnName(ctx, col)),
+			)
+			continue
+		}
+		columns = append(
+			columns,
+			fmt.Sprintf("`%s` AS `%s`", col.Name(), uniqueColumnName(ctx, col)),
+		)
+	}
+	return columns
 }
 
 func (n *JoinScanNode) FormatSQL(ctx context.Context) (string, error) {
@@ -654,16 +835,29 @@ func (n *ArrayScanNode) FormatSQL(ctx context.Context) (string, error) {
 	if n.node == nil {
 		return "", nil
 	}
-	arrayExpr, err := newNode(n.node.ArrayExpr()).FormatSQL(ctx)
-	if err != nil {
-		return "", err
-	}
 	colName := uniqueColumnName(ctx, n.node.ElementColumn())
-	columns := []string{fmt.Sprintf("json_each.value AS `%s`", colName)}
+	// The element and the offset are always taken from the same row of the table-valued function of this scan.
+	// The table-valued function is aliased uniquely, so that they never refer to the function of the other UNNEST
+	// ( e.g. the input scan or the joined scan ) regardless of how the rows are reordered later.
+	alias := fmt.Sprintf("zetasqlite_unnest_%d", n.node.ElementColumn().ColumnID())
+	columns := []string{fmt.Sprintf("`%s`.value AS `%s`", alias, colName)}
 
 	if offsetColumn := n.node.ArrayOffsetColumn(); offsetColumn != nil {
 		offsetColName := uniqueColumnName(ctx, offsetColumn.Column())
-		columns = append(columns, fmt.Sprintf("json_each.key AS `%s`", offsetColName))
+		columns = append(columns, fmt.Sprintf("`%s`.key AS `%s`", alias, offsetColName))
+	}
+	if n.node.InputScan() == nil {
+		series, err := formatGenerateArraySeries(ctx, n.node.ArrayExpr(), alias)
+		if err != nil {
+			return "", err
+		}
+		if series != "" {
+			return fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ","), series), nil
+		}
+	}
+	arrayExpr, err := newNode(n.node.ArrayExpr()).FormatSQL(ctx)
+	if err != nil {
+		return "", err
 	}
 	if n.node.InputScan() != nil {
 		input, err := newNode(n.node.InputScan()).FormatSQL(ctx)
@@ -675,7 +869,7 @@ func (n *ArrayScanNode) FormatSQL(ctx context.Context) (string, error) {
 			return "", err
 		}
 
-		array := fmt.Sprintf("json_each(zetasqlite_decode_array(%s))", arrayExpr)
+		array := formatUnnestSource(arrayExpr, alias)
 		var arrayJoinExpr string
 		if n.node.JoinExpr() != nil {
 			arrayJoinExpr, err = newNode(n.node.JoinExpr()).FormatSQL(ctx)
@@ -707,9 +901,56 @@ func (n *ArrayScanNode) FormatSQL(ctx context.Context) (string, error) {
 		), nil
 	}
 	return fmt.Sprintf(
-		"SELECT %s FROM json_each(zetasqlite_decode_array(%s))",
+		"SELECT %s FROM %s",
 		strings.Join(columns, ","),
-		arrayExpr,
+		formatUnnestSource(arrayExpr, alias),
+	), nil
+}
+
+// formatGenerateArraySeries formats GENERATE_ARRAY of INT64 values to the recursive query that generates
+// the elements one by one instead of materializing the whole array, so that UNNEST(GENERATE_ARRAY(1, n)) can produce many rows.
+// The columns are named as same as formatUnnestSource and the query is aliased by alias. Returns empty string if the expression is not the target.
+// The step must be a non-zero literal because the recursive query never stops otherwise.
+func formatGenerateArraySeries(ctx context.Context, expr ast.ExprNode, alias string) (string, error) {
+	call, ok := expr.(*ast.FunctionCallNode)
+	if !ok || call.Function().Name() != "generate_array" {
+		return "", nil
+	}
+	if call.Type().AsArray().ElementType().Kind() != types.INT64 {
+		return "", nil
+	}
+	args := call.ArgumentList()
+	if len(args) != 2 && len(args) != 3 {
+		return "", nil
+	}
+	step := int64(1)
+	if len(args) == 3 {
+		literal, ok := args[2].(*ast.LiteralNode)
+		if !ok || literal.Value().IsNull() || literal.Value().Int64Value() == 0 {
+			return "", nil
+		}
+		step = literal.Value().Int64Value()
+	}
+	start, err := newNode(args[0]).FormatSQL(ctx)
+	if err != nil {
+		return "", err
+	}
+	end, err := newNode(args[1]).FormatSQL(ctx)
+	if err != nil {
+		return "", err
+	}
+	op := "<="
+	if step < 0 {
+		op = ">="
+	}
+	return fmt.Sprintf(
+		"(WITH RECURSIVE zetasqlite_series_range(series_start, series_end) AS (SELECT %[1]s, %[2]s), "+
+			"zetasqlite_series(key, value) AS ("+
+			"SELECT 0, series_start FROM zetasqlite_series_range WHERE series_start %[3]s series_end "+
+			"UNION ALL "+
+			"SELECT key + 1, value + %[4]d FROM zetasqlite_series, zetasqlite_series_range WHERE value + %[4]d %[3]s series_end"+
+			") SELECT key, value FROM zetasqlite_series) AS `%[5]s`",
+		start, end, op, step, alias,
 	), nil
 }
 
@@ -718,7 +959,40 @@ func (n *ColumnHolderNode) FormatSQL(ctx context.Context) (string, error) {
 }
 
 var tokensAfterFromClause = [...]string{"WHERE", "GROUP BY", "HAVING", "QUALIFY", "WINDOW", "ORDER BY", "COLLATE"}
-var removeExpressions = regexp.MustCompile(`\(.+?\)`)
+
+// topLevelQuery removes the parenthesized expressions including nested ones and the quoted texts from the query,
+// so that only the tokens of the outermost query remain.
+// The analytic functions are formatted to correlated subqueries, and they can be nested in any depth of expressions.
+func topLevelQuery(query string) string {
+	var (
+		b     strings.Builder
+		depth int
+		quote rune
+	)
+	for _, c := range query {
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '`', '\'', '"':
+			quote = c
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		default:
+			if depth == 0 {
+				b.WriteRune(c)
+			}
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
 
 func (n *FilterScanNode) FormatSQL(ctx context.Context) (string, error) {
 	if n.node == nil {
@@ -732,7 +1006,7 @@ func (n *FilterScanNode) FormatSQL(ctx context.Context) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	currentQuery := removeExpressions.ReplaceAllString(input, "")
+	currentQuery := topLevelQuery(input)
 
 	// Qualify the statement if the input is not wrapped in parens
 	queryWrappedInParens := currentQuery == ""
@@ -762,7 +1036,7 @@ func (n *AggregateScanNode) FormatSQL(ctx context.Context) (string, error) {
 			return "", err
 		}
 	}
-	input, err := newNode(n.node.InputScan()).FormatSQL(ctx)
+	input, err := newNode(unwrapPassThroughProjectScan(ctx, n.node.InputScan())).FormatSQL(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -919,9 +1193,16 @@ func (n *SetOperationScanNode) FormatSQL(ctx context.Context) (string, error) {
 			return "", err
 		}
 
-		formattedInput, err := formatInput(query)
-		if err != nil {
-			return "", err
+		var formattedInput string
+		if _, isSetOperation := item.Scan().(*ast.SetOperationScanNode); isSetOperation {
+			// SQLite evaluates the compound operators from left to right without regard to the grouping in the query,
+			// so the nested set operation is always evaluated as the subquery to keep the grouping of the resolved tree.
+			formattedInput = fmt.Sprintf("FROM (%s)", query)
+		} else {
+			formattedInput, err = formatInput(query)
+			if err != nil {
+				return "", err
+			}
 		}
 
 		queries = append(
@@ -952,6 +1233,30 @@ func (n *SetOperationScanNode) FormatSQL(ctx context.Context) (string, error) {
 	), nil
 }
 
+// formatFloatOrderBy formats the sort keys of FLOAT64 column.
+// The special values are encoded as text that SQLite sorts after all numbers,
+// so NaN is sorted after NULL and before the other values by the first key, and the others are decoded to the native floats by the second key.
+func formatFloatOrderBy(column string) (string, string, error) {
+	specialValues := []float64{math.NaN(), math.Inf(1), math.Inf(-1), math.Copysign(0, -1)}
+	encoded := make([]interface{}, 0, len(specialValues))
+	for _, f := range specialValues {
+		v, err := EncodeValue(FloatValue(f))
+		if err != nil {
+			return "", "", err
+		}
+		encoded = append(encoded, v)
+	}
+	nanOrder := fmt.Sprintf(
+		"(CASE WHEN %[1]s IS NULL THEN 0 WHEN %[1]s = '%[2]s' THEN 1 ELSE 2 END)",
+		column, encoded[0],
+	)
+	valueOrder := fmt.Sprintf(
+		"(CASE %[1]s WHEN '%[2]s' THEN 9e999 WHEN '%[3]s' THEN -9e999 WHEN '%[4]s' THEN 0.0 ELSE %[1]s END)",
+		column, encoded[1], encoded[2], encoded[3],
+	)
+	return nanOrder, valueOrder, nil
+}
+
 func (n *OrderByScanNode) FormatSQL(ctx context.Context) (string, error) {
 	if n.node == nil {
 		return "", nil
@@ -989,10 +1294,22 @@ func (n *OrderByScanNode) FormatSQL(ctx context.Context) (string, error) {
 				fmt.Sprintf("(`%s` IS NULL)", colName),
 			)
 		}
+		orderByColumn := fmt.Sprintf("`%s` COLLATE zetasqlite_collate", colName)
+		if kind := item.ColumnRef().Column().Type().Kind(); kind == types.DOUBLE || kind == types.FLOAT {
+			nanOrder, valueOrder, err := formatFloatOrderBy(fmt.Sprintf("`%s`", colName))
+			if err != nil {
+				return "", err
+			}
+			if item.IsDescending() {
+				nanOrder += " DESC"
+			}
+			orderByColumns = append(orderByColumns, nanOrder)
+			orderByColumn = valueOrder
+		}
 		if item.IsDescending() {
-			orderByColumns = append(orderByColumns, fmt.Sprintf("`%s` COLLATE zetasqlite_collate DESC", colName))
+			orderByColumns = append(orderByColumns, fmt.Sprintf("%s DESC", orderByColumn))
 		} else {
-			orderByColumns = append(orderByColumns, fmt.Sprintf("`%s` COLLATE zetasqlite_collate", colName))
+			orderByColumns = append(orderByColumns, orderByColumn)
 		}
 	}
 	formattedInput, err := formatInput(input)
@@ -1095,6 +1412,8 @@ func (n *AnalyticScanNode) FormatSQL(ctx context.Context) (string, error) {
 		return "", err
 	}
 	ctx = withAnalyticInputScan(ctx, formattedInput)
+	rowIDColumnName := n.rowIDColumnName()
+	ctx = withAnalyticRowIDColumnName(ctx, rowIDColumnName)
 	orderColumnNames := analyticOrderColumnNamesFromContext(ctx)
 	var scanOrderBy []*analyticOrderBy
 	for _, group := range n.node.FunctionGroupList() {
@@ -1151,6 +1470,13 @@ func (n *AnalyticScanNode) FormatSQL(ctx context.Context) (string, error) {
 			)
 		}
 	}
+	// The analytic function calls are correlated subqueries to the input of this scan.
+	// They must be referred by the column name from the outer expressions, so never leave them in the column map.
+	for _, group := range n.node.FunctionGroupList() {
+		for _, column := range group.AnalyticFunctionList() {
+			delete(columnMap, uniqueColumnName(ctx, column.Column()))
+		}
+	}
 	var orderColumnFormattedNames []string
 	for _, col := range scanOrderBy {
 		if col.isAsc {
@@ -1171,13 +1497,26 @@ func (n *AnalyticScanNode) FormatSQL(ctx context.Context) (string, error) {
 	}
 	orderColumnNames.values = []*analyticOrderBy{}
 	return fmt.Sprintf(
-		"SELECT %s FROM (SELECT *, ROW_NUMBER() OVER() AS `row_id` %s) %s",
+		"SELECT %s FROM (SELECT *, ROW_NUMBER() OVER() AS `%s` %s) %s",
 		strings.Join(columns, ","),
+		rowIDColumnName,
 		formattedInput,
 		orderBy,
 	), nil
 }
 
+// rowIDColumnName returns the name of the column numbering the rows of the input.
+// The analytic function calls refer to it from the correlated subqueries, so the name must be unique per scan
+// to never be resolved to the column of the input such as the user column or the column of the nested analytic scan.
+func (n *AnalyticScanNode) rowIDColumnName() string {
+	for _, group := range n.node.FunctionGroupList() {
+		for _, column := range group.AnalyticFunctionList() {
+			return fmt.Sprintf("zetasqlite_row_id#%d", column.Column().ColumnID())
+		}
+	}
+	return "zetasqlite_row_id"
+}
+
 func (n *SampleScanNode) FormatSQL(ctx context.Context) (string, error) {
 	return "", nil
 }
@@ -1251,26 +1590,41 @@ func (n *ProjectScanNode) FormatSQL(ctx context.Context) (string, error) {
 	if n.node == nil {
 		return "", nil
 	}
+	return n.formatProjection(ctx, n.node.ColumnList(), nil)
+}
+
+// formatProjection formats the projection that selects columns from the input scan.
+// If aliases is not nil, each column is named by the alias of the same index instead of its unique name.
+func (n *ProjectScanNode) formatProjection(ctx context.Context, columns []*ast.Column, aliases []string) (string, error) {
 	for _, col := range n.node.ExprList() {
 		// assign expr to columnRefMap
 		if _, err := newNode(col).FormatSQL(ctx); err != nil {
 			return "", err
 		}
 	}
-	input, err := newNode(n.node.InputScan()).FormatSQL(ctx)
+	input, err := newNode(unwrapPassThroughProjectScan(ctx, n.node.InputScan())).FormatSQL(ctx)
 	if err != nil {
 		return "", err
 	}
-	columns := []string{}
+	formattedColumns := make([]string, 0, len(columns))
 	columnMap := columnRefMap(ctx)
-	for _, col := range n.node.ColumnList() {
+	for idx, col := range columns {
 		colName := uniqueColumnName(ctx, col)
+		alias := colName
+		if aliases != nil {
+			alias = aliases[idx]
+		}
 		if ref, exists := columnMap[colName]; exists {
-			columns = append(columns, ref)
+			if alias != colName {
+				ref = fmt.Sprintf("%s AS `%s`", strings.TrimSuffix(ref, fmt.Sprintf(" AS `%s`", colName)), alias)
+			}
+			formattedColumns = append(formattedColumns, ref)
 			delete(columnMap, colName)
+		} else if alias != colName {
+			formattedColumns = append(formattedColumns, fmt.Sprintf("`%s` AS `%s`", colName, alias))
 		} else {
-			columns = append(
-				columns,
+			formattedColumns = append(
+				formattedColumns,
 				fmt.Sprintf("`%s`", colName),
 			)
 		}
@@ -1279,12 +1633,37 @@ func (n *ProjectScanNode) FormatSQL(ctx context.Context) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	formattedColumns := strings.Join(columns, ",")
-	return fmt.Sprintf("SELECT %s %s", formattedColumns, formattedInput), nil
+	return fmt.Sprintf("SELECT %s %s", strings.Join(formattedColumns, ","), formattedInput), nil
 }
 
+// unwrapPassThroughProjectScan skips the projections that only select the columns of their input scan.
+// The scans referring to the input columns by name don't need such projections,
+// so skipping them avoids nesting a subquery for every projection.
+// This is only applied when the columns are identified by the column id, because otherwise
+// the column names of the projection may conflict with the columns computed by the outer scans.
+func unwrapPassThroughProjectScan(ctx context.Context, scan ast.ScanNode) ast.ScanNode {
+	if !useColumnID(ctx) {
+		return scan
+	}
+	for {
+		project, ok := scan.(*ast.ProjectScanNode)
+		if !ok || len(project.ExprList()) != 0 {
+			return scan
+		}
+		scan = project.InputScan()
+	}
+}
+
+// go-zetasql only constructs table-valued functions from a resolved CREATE TABLE FUNCTION statement,
+// and the TableValuedFunction interface cannot be implemented outside of the package,
+// so neither SQL nor Go-implemented TVFs can be registered to the catalog.
+// The resolved scan is reported as unsupported instead of being formatted to an empty FROM clause.
+
 func (n *TVFScanNode) FormatSQL(ctx context.Context) (string, error) {
-	return "", nil
+	if n.node == nil {
+		return "", nil
+	}
+	return "", fmt.Errorf("table-valued function %s is unsupported: table-valued functions cannot be registered to the catalog", n.node.TVF().FullName())
 }
 
 func (n *GroupRowsScanNode) FormatSQL(ctx context.Context) (string, error) {
@@ -1307,7 +1686,20 @@ func (n *QueryStmtNode) FormatSQL(ctx context.Context) (string, error) {
 	if n.node == nil {
 		return "", nil
 	}
-	input, err := newNode(n.node.Query()).FormatSQL(ctx)
+	analyzer := analyzerFromContext(ctx)
+	isDeterministicOutputOrder := analyzer != nil && analyzer.isDeterministicOutputOrder && !n.node.Query().IsOrdered()
+	query := unwrapPassThroughProjectScan(ctx, n.node.Query())
+	if project, ok := query.(*ast.ProjectScanNode); ok && !isDeterministicOutputOrder {
+		if columns, aliases, ok := n.composableOutputColumns(ctx, project); ok {
+			// name the output columns in the projection directly instead of selecting them from the subquery.
+			return newProjectScanNode(project).formatProjection(ctx, columns, aliases)
+		}
+	}
+	input, err := newNode(query).FormatSQL(ctx)
+	if err != nil {
+		return "", err
+	}
+	formattedInput, err := formatInput(input)
 	if err != nil {
 		return "", err
 	}
@@ -1323,13 +1715,54 @@ func (n *QueryStmtNode) FormatSQL(ctx context.Context) (string, error) {
 		)
 	}
 
+	if isDeterministicOutputOrder {
+		// sort by all output columns to get a stable order for the query without ORDER BY clause.
+		orderBy := make([]string, 0, len(columns))
+		for i := range columns {
+			orderBy = append(orderBy, fmt.Sprint(i+1))
+		}
+		return fmt.Sprintf(
+			"SELECT %s %s ORDER BY %s",
+			strings.Join(columns, ", "),
+			formattedInput,
+			strings.Join(orderBy, ", "),
+		), nil
+	}
 	return fmt.Sprintf(
-		"SELECT %s FROM (%s)",
+		"SELECT %s %s",
 		strings.Join(columns, ", "),
-		input,
+		formattedInput,
 	), nil
 }
 
+// composableOutputColumns returns the columns and the names of the output columns
+// if they can be named in the projection of the query directly.
+// The output columns are resolved by position, so the same input column can be selected by multiple output columns
+// even if they have the same name. However, if the same computed column is referenced by multiple output columns,
+// the expression cannot be shared between them, so the output columns must be selected from the subquery.
+func (n *QueryStmtNode) composableOutputColumns(ctx context.Context, project *ast.ProjectScanNode) ([]*ast.Column, []string, bool) {
+	computedColumnIDMap := map[int]struct{}{}
+	for _, expr := range project.ExprList() {
+		computedColumnIDMap[expr.Column().ColumnID()] = struct{}{}
+	}
+	outputColumns := n.node.OutputColumnList()
+	columns := make([]*ast.Column, 0, len(outputColumns))
+	aliases := make([]string, 0, len(outputColumns))
+	columnIDMap := map[int]struct{}{}
+	for _, outputColumn := range outputColumns {
+		colID := outputColumn.Column().ColumnID()
+		if _, computed := computedColumnIDMap[colID]; computed {
+			if _, exists := columnIDMap[colID]; exists {
+				return nil, nil, false
+			}
+		}
+		columnIDMap[colID] = struct{}{}
+		columns = append(columns, outputColumn.Column())
+		aliases = append(aliases, outputColumn.Name())
+	}
+	return columns, aliases, true
+}
+
 func (n *CreateDatabaseStmtNode) FormatSQL(ctx context.Context) (string, error) {
 	return "", nil
 }
@@ -1451,8 +1884,16 @@ func (n *RecursiveRefScanNode) FormatSQL(ctx context.Context) (string, error) {
 	return "", nil
 }
 
+// SQLite only allows the reference to the recursive table in the top-level FROM clause of the recursive term,
+// but the formatted scans are nested as subqueries, so the recursive term cannot be translated to WITH RECURSIVE of SQLite.
+// The resolved scan is reported as unsupported instead of being formatted to an empty subquery,
+// because the iterations cannot be bounded to the limit of BigQuery without the translation.
+
 func (n *RecursiveScanNode) FormatSQL(ctx context.Context) (string, error) {
-	return "", nil
+	if n.node == nil {
+		return "", nil
+	}
+	return "", fmt.Errorf("WITH RECURSIVE is unsupported: the recursive term cannot be translated to SQLite")
 }
 
 func (n *WithScanNode) FormatSQL(ctx context.Context) (string, error) {
@@ -1493,7 +1934,10 @@ func (n *WithEntryNode) FormatSQL(ctx context.Context) (string, error) {
 }
 
 func (n *OptionNode) FormatSQL(ctx context.Context) (string, error) {
-	return "", nil
+	if n.node == nil {
+		return "", nil
+	}
+	return newNode(n.node.Value()).FormatSQL(ctx)
 }
 
 func (n *WindowPartitioningNode) FormatSQL(ctx context.Context) (string, error) {
@@ -1609,6 +2053,7 @@ func (n *DeleteStmtNode) FormatSQL(ctx context.Context) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	ctx = withDMLTargetTableName(ctx, table)
 	where, err := newNode(n.node.WhereExpr()).FormatSQL(ctx)
 	if err != nil {
 		return "", err
@@ -1647,6 +2092,7 @@ func (n *UpdateStmtNode) FormatSQL(ctx context.Context) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	ctx = withDMLTargetTableName(ctx, table)
 	updateItems := []string{}
 	for _, item := range n.node.UpdateItemList() {
 		sql, err := newNode(item).FormatSQL(ctx)
@@ -1840,7 +2286,10 @@ func (n *AlterAllRowAccessPoliciesStmtNode) FormatSQL(ctx context.Context) (stri
 }
 
 func (n *CreateConstantStmtNode) FormatSQL(ctx context.Context) (string, error) {
-	return "", nil
+	if n.node == nil {
+		return "", nil
+	}
+	return "", fmt.Errorf("CREATE CONSTANT is unsupported: constants cannot be registered to the catalog")
 }
 
 func (n *CreateFunctionStmtNode) FormatSQL(ctx context.Context) (string, error) {
@@ -1855,7 +2304,7 @@ func (n *ArgumentRefNode) FormatSQL(ctx context.Context) (string, error) {
 	if n.node == nil {
 		return "", nil
 	}
-	return fmt.Sprintf("@%s", n.node.Name()), nil
+	return argumentPlaceholder(n.node.Name()), nil
 }
 
 func (n *CreateTableFunctionStmtNode) FormatSQL(ctx context.Context) (string, error) {
@@ -2,10 +2,13 @@ package internal
 
 import (
 	"fmt"
+	"math"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/goccy/go-zetasql/types"
 	"github.com/google/uuid"
 )
 
@@ -205,35 +208,91 @@ func ARRAY_SAFE_ORDINAL(v Value, idx int) (Value, error) {
 	return array.values[idx-1], nil
 }
 
+// LIKE matches the value with the pattern. For STRING, the `_` wildcard matches a single code point,
+// and for BYTES, it matches a single byte. The backslash escapes `%`, `_` and itself.
 func LIKE(a, b Value) (Value, error) {
-	va, err := a.ToString()
+	va, err := likeOperand(a)
 	if err != nil {
 		return nil, err
 	}
-	vb, err := b.ToString()
+	vb, err := likeOperand(b)
 	if err != nil {
 		return nil, err
 	}
-	wildcard := strings.Replace(regexp.QuoteMeta(vb), "%", ".*", -1)
-	matchLimits := fmt.Sprintf("^%s$", wildcard)
-	re, err := regexp.Compile(matchLimits)
+	expr, err := likePatternToRegexp(vb)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(expr)
 	if err != nil {
 		return nil, err
 	}
 	return BoolValue(re.MatchString(va)), nil
 }
 
-func BETWEEN(target, start, end Value) (Value, error) {
-	greaterThanStart, err := target.GTE(start)
-	if err != nil {
-		return nil, err
+func likePatternToRegexp(pattern string) (string, error) {
+	runes := []rune(pattern)
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '\\':
+			i++
+			if i >= len(runes) {
+				return "", fmt.Errorf("LIKE pattern ends with a backslash: %s", pattern)
+			}
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String(), nil
+}
+
+// likeOperand returns the string to match by the regular expression.
+// Each byte of BYTES value is mapped to the code point of the same value, so that the wildcard matches a single byte.
+func likeOperand(v Value) (string, error) {
+	if _, ok := v.(BytesValue); !ok {
+		return v.ToString()
 	}
-	lessThanEnd, err := target.LTE(end)
+	b, err := v.ToBytes()
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	runes := make([]rune, 0, len(b))
+	for _, c := range b {
+		runes = append(runes, rune(c))
 	}
+	return string(runes), nil
+}
 
-	return BoolValue(greaterThanStart && lessThanEnd), nil
+// BETWEEN evaluates `target >= start AND target <= end` with three-valued logic.
+// If either comparison is unknown because of NULL, returns NULL unless the other comparison is false.
+func BETWEEN(target, start, end Value) (Value, error) {
+	if target == nil {
+		return nil, nil
+	}
+	var greaterThanStart, lessThanEnd Value
+	if start != nil {
+		cond, err := target.GTE(start)
+		if err != nil {
+			return nil, err
+		}
+		greaterThanStart = BoolValue(cond)
+	}
+	if end != nil {
+		cond, err := target.LTE(end)
+		if err != nil {
+			return nil, err
+		}
+		lessThanEnd = BoolValue(cond)
+	}
+	return AND(greaterThanStart, lessThanEnd)
 }
 
 func IN(a Value, values ...Value) (Value, error) {
@@ -256,7 +315,7 @@ func IN(a Value, values ...Value) (Value, error) {
 }
 
 func IS_NULL(a Value) (Value, error) {
-	return BoolValue(a == nil), nil
+	return BoolValue(isNull(a)), nil
 }
 
 func IS_TRUE(a Value) (Value, error) {
@@ -371,7 +430,7 @@ func IF(cond, trueV, falseV Value) (Value, error) {
 }
 
 func IFNULL(expr, nullResult Value) (Value, error) {
-	if expr == nil {
+	if isNull(expr) {
 		return nullResult, nil
 	}
 	return expr, nil
@@ -510,7 +569,20 @@ func GENERATE_UUID() (Value, error) {
 	return StringValue(id), nil
 }
 
-func CAST(expr Value, fromType, toType *Type, isSafeCast bool) (Value, error) {
+// CAST converts the value from fromType to toType.
+// If zone is not empty, it is used as the default time zone to convert TIMESTAMP from and to STRING, DATE and DATETIME, and to TIME.
+// The conversions between TIMESTAMP and the civil time types use UTC if zone is empty.
+func CAST(expr Value, fromType, toType *Type, isSafeCast bool, zone string) (Value, error) {
+	if (zone != "" || isCivilTimestampCast(fromType, toType)) && isTimeZoneDependentCast(fromType, toType) {
+		casted, err := castWithTimeZone(expr, toType, zone)
+		if err != nil {
+			if isSafeCast {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return casted, nil
+	}
 	from, err := fromType.ToZetaSQLType()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get zetasql type from cast base type: %w", err)
@@ -526,6 +598,17 @@ func CAST(expr Value, fromType, toType *Type, isSafeCast bool) (Value, error) {
 		}
 		return nil, err
 	}
+	if from.Kind() == types.STRING && fromValue != nil {
+		if casted, ok, err := castStringToScalar(fromValue, to.Kind()); ok {
+			if err != nil {
+				if isSafeCast {
+					return nil, nil
+				}
+				return nil, err
+			}
+			return casted, nil
+		}
+	}
 	casted, err := CastValue(to, fromValue)
 	if err != nil {
 		if isSafeCast {
@@ -535,3 +618,142 @@ func CAST(expr Value, fromType, toType *Type, isSafeCast bool) (Value, error) {
 	}
 	return casted, nil
 }
+
+var (
+	castIntRe   = regexp.MustCompile(`^[+-]?[0-9]+$`)
+	castHexRe   = regexp.MustCompile(`^([+-]?)0[xX]([0-9a-fA-F]+)$`)
+	castFloatRe = regexp.MustCompile(`^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?$`)
+)
+
+// castStringToScalar converts STRING to BOOL, INT64 or FLOAT64 by the literal grammar of BigQuery
+// instead of strconv that accepts the other forms ( e.g. "t" as BOOL or "1_000" as INT64 ).
+// It returns false if the type is not the case.
+func castStringToScalar(v Value, kind types.TypeKind) (Value, bool, error) {
+	s, err := v.ToString()
+	if err != nil {
+		return nil, true, err
+	}
+	switch kind {
+	case types.BOOL:
+		switch strings.ToLower(s) {
+		case "true":
+			return BoolValue(true), true, nil
+		case "false":
+			return BoolValue(false), true, nil
+		}
+		return nil, true, fmt.Errorf("bad bool value: %s", s)
+	case types.INT64:
+		trimmed := strings.TrimSpace(s)
+		if matched := castHexRe.FindStringSubmatch(trimmed); matched != nil {
+			i64, err := strconv.ParseInt(matched[1]+matched[2], 16, 64)
+			if err != nil {
+				return nil, true, fmt.Errorf("bad int64 value: %s", s)
+			}
+			return IntValue(i64), true, nil
+		}
+		if !castIntRe.MatchString(trimmed) {
+			return nil, true, fmt.Errorf("bad int64 value: %s", s)
+		}
+		i64, err := strconv.ParseInt(trimmed, 10, 64)
+		if err != nil {
+			return nil, true, fmt.Errorf("bad int64 value: %s", s)
+		}
+		return IntValue(i64), true, nil
+	case types.DOUBLE:
+		trimmed := strings.TrimSpace(s)
+		unsigned := strings.TrimLeft(trimmed, "+-")
+		if len(trimmed)-len(unsigned) <= 1 {
+			switch strings.ToLower(unsigned) {
+			case "inf", "infinity":
+				if strings.HasPrefix(trimmed, "-") {
+					return FloatValue(math.Inf(-1)), true, nil
+				}
+				return FloatValue(math.Inf(1)), true, nil
+			case "nan":
+				return FloatValue(math.NaN()), true, nil
+			}
+		}
+		if !castFloatRe.MatchString(trimmed) {
+			return nil, true, fmt.Errorf("bad double value: %s", s)
+		}
+		f64, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			return nil, true, fmt.Errorf("bad double value: %s", s)
+		}
+		return FloatValue(f64), true, nil
+	}
+	return nil, false, nil
+}
+
+func isTimeZoneDependentCast(fromType, toType *Type) bool {
+	from := types.TypeKind(fromType.Kind)
+	to := types.TypeKind(toType.Kind)
+	return (from == types.STRING && to == types.TIMESTAMP) || (from == types.TIMESTAMP && to == types.STRING) ||
+		isCivilTimestampCast(fromType, toType)
+}
+
+// isCivilTimestampCast reports whether the cast is between TIMESTAMP and DATE, DATETIME or TIME.
+func isCivilTimestampCast(fromType, toType *Type) bool {
+	from := types.TypeKind(fromType.Kind)
+	to := types.TypeKind(toType.Kind)
+	switch {
+	case from == types.TIMESTAMP:
+		return to == types.DATE || to == types.DATETIME || to == types.TIME
+	case to == types.TIMESTAMP:
+		return from == types.DATE || from == types.DATETIME
+	}
+	return false
+}
+
+func castWithTimeZone(expr Value, toType *Type, zone string) (Value, error) {
+	if isNull(expr) {
+		return nil, nil
+	}
+	switch types.TypeKind(toType.Kind) {
+	case types.TIMESTAMP:
+		switch expr.(type) {
+		case DateValue, DatetimeValue:
+			return TIMESTAMP(expr, zone)
+		}
+		s, err := expr.ToString()
+		if err != nil {
+			return nil, err
+		}
+		return TIMESTAMP(StringValue(s), zone)
+	case types.DATE:
+		t, err := timeInZone(expr, zone)
+		if err != nil {
+			return nil, err
+		}
+		return DateValue(civilDate(t)), nil
+	case types.DATETIME:
+		t, err := timeInZone(expr, zone)
+		if err != nil {
+			return nil, err
+		}
+		return DatetimeValue(civilDatetime(t)), nil
+	case types.TIME:
+		t, err := timeInZone(expr, zone)
+		if err != nil {
+			return nil, err
+		}
+		return TimeValue(civilTime(t)), nil
+	}
+	t, err := expr.ToTime()
+	if err != nil {
+		return nil, err
+	}
+	return STRING(t, zone)
+}
+
+func timeInZone(v Value, zone string) (time.Time, error) {
+	t, err := v.ToTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := toLocation(zone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(loc), nil
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"fmt"
+	"math/big"
 	"sort"
 	"strings"
 	"sync"
@@ -27,7 +28,7 @@ func (f *ARRAY) Step(v Value, opt *AggregatorOption) error {
 	f.values = append(f.values, &OrderedValue{
 		Value: v,
 	})
-	return nil
+	return opt.checkBufferedValues(len(f.values))
 }
 
 func (f *ARRAY) Done() (Value, error) {
@@ -76,7 +77,7 @@ func (f *ARRAY_AGG) Step(v Value, opt *AggregatorOption) error {
 		OrderBy: opt.OrderBy,
 		Value:   v,
 	})
-	return nil
+	return opt.checkBufferedValues(len(f.values))
 }
 
 func (f *ARRAY_AGG) Done() (Value, error) {
@@ -112,7 +113,7 @@ func (f *ARRAY_CONCAT_AGG) Step(v *ArrayValue, opt *AggregatorOption) error {
 		OrderBy: opt.OrderBy,
 		Value:   v,
 	})
-	return nil
+	return opt.checkBufferedValues(len(f.values))
 }
 
 func (f *ARRAY_CONCAT_AGG) Done() (Value, error) {
@@ -140,37 +141,151 @@ func (f *ARRAY_CONCAT_AGG) Done() (Value, error) {
 	}, nil
 }
 
-type AVG struct {
-	sum Value
-	num int64
-}
+type sumKind int
 
-func (f *AVG) Step(v Value, opt *AggregatorOption) error {
+const (
+	sumKindNone sumKind = iota
+	sumKindInt
+	sumKindNumeric
+	sumKindFloat
+	sumKindOther
+)
+
+var (
+	maxNumericValue, _    = new(big.Rat).SetString("99999999999999999999999999999.999999999")
+	maxBigNumericValue, _ = new(big.Rat).SetString("578960446186580977117854925043439539266.34992332820282019728792003956564819967")
+)
+
+// sumAccumulator adds the values of SUM and AVG by the type of the argument like BigQuery.
+// INT64 values are added exactly and SUM reports the overflow of INT64 as error,
+// NUMERIC and BIGNUMERIC values are added as fixed-point values, and FLOAT64 values are added as float64.
+// The values are not modified because they may be shared by the frames of window functions.
+type sumAccumulator struct {
+	kind         sumKind
+	intSum       *big.Int
+	ratSum       *big.Rat
+	floatSum     float64
+	otherSum     Value
+	isBigNumeric bool
+	num          int64
+}
+
+func (s *sumAccumulator) add(v Value) error {
 	if v == nil {
 		return nil
 	}
-	if f.sum == nil {
-		f.sum = v
-	} else {
-		added, err := f.sum.Add(v)
+	if s.kind == sumKindNone {
+		switch vv := v.(type) {
+		case IntValue:
+			s.kind = sumKindInt
+			s.intSum = new(big.Int)
+		case *NumericValue:
+			s.kind = sumKindNumeric
+			s.ratSum = new(big.Rat)
+			s.isBigNumeric = vv.isBigNumeric
+		case FloatValue:
+			s.kind = sumKindFloat
+		default:
+			s.kind = sumKindOther
+		}
+	}
+	if s.kind == sumKindInt {
+		if _, ok := v.(IntValue); !ok {
+			// the integral FLOAT64 value may be read as INT64 value, so switch to FLOAT64 by the other value.
+			f, _ := new(big.Float).SetInt(s.intSum).Float64()
+			s.kind = sumKindFloat
+			s.floatSum = f
+		}
+	}
+	switch s.kind {
+	case sumKindInt:
+		s.intSum.Add(s.intSum, big.NewInt(int64(v.(IntValue))))
+	case sumKindNumeric:
+		r, err := v.ToRat()
 		if err != nil {
 			return err
 		}
-		f.sum = added
+		s.ratSum.Add(s.ratSum, r)
+	case sumKindFloat:
+		f, err := v.ToFloat64()
+		if err != nil {
+			return err
+		}
+		s.floatSum += f
+	default:
+		if s.otherSum == nil {
+			s.otherSum = v
+		} else {
+			added, err := s.otherSum.Add(v)
+			if err != nil {
+				return err
+			}
+			s.otherSum = added
+		}
 	}
-	f.num++
+	s.num++
 	return nil
 }
 
-func (f *AVG) Done() (Value, error) {
-	if f.sum == nil {
+func (s *sumAccumulator) numericValue(r *big.Rat) (Value, error) {
+	maxValue, typeName := maxNumericValue, "NUMERIC"
+	if s.isBigNumeric {
+		maxValue, typeName = maxBigNumericValue, "BIGNUMERIC"
+	}
+	if new(big.Rat).Abs(r).Cmp(maxValue) > 0 {
+		return nil, fmt.Errorf("%s overflow", typeName)
+	}
+	return &NumericValue{Rat: r, isBigNumeric: s.isBigNumeric}, nil
+}
+
+// sum returns INT64 for INT64 values, NUMERIC ( BIGNUMERIC ) for NUMERIC ( BIGNUMERIC ) values and FLOAT64 for FLOAT64 values.
+func (s *sumAccumulator) sum() (Value, error) {
+	switch s.kind {
+	case sumKindNone:
 		return nil, nil
+	case sumKindInt:
+		if !s.intSum.IsInt64() {
+			return nil, fmt.Errorf("INT64 overflow")
+		}
+		return IntValue(s.intSum.Int64()), nil
+	case sumKindNumeric:
+		return s.numericValue(new(big.Rat).Set(s.ratSum))
+	case sumKindFloat:
+		return FloatValue(s.floatSum), nil
 	}
-	base, err := f.sum.ToFloat64()
+	return s.otherSum, nil
+}
+
+// avg returns FLOAT64 for INT64 and FLOAT64 values and NUMERIC ( BIGNUMERIC ) for NUMERIC ( BIGNUMERIC ) values.
+func (s *sumAccumulator) avg() (Value, error) {
+	switch s.kind {
+	case sumKindNone:
+		return nil, nil
+	case sumKindInt:
+		f, _ := new(big.Rat).SetFrac(s.intSum, big.NewInt(s.num)).Float64()
+		return FloatValue(f), nil
+	case sumKindNumeric:
+		return s.numericValue(new(big.Rat).Quo(s.ratSum, new(big.Rat).SetInt64(s.num)))
+	case sumKindFloat:
+		return FloatValue(s.floatSum / float64(s.num)), nil
+	}
+	base, err := s.otherSum.ToFloat64()
 	if err != nil {
 		return nil, err
 	}
-	return FloatValue(base / float64(f.num)), nil
+	return FloatValue(base / float64(s.num)), nil
+}
+
+type AVG struct {
+	acc sumAccumulator
+}
+
+func (f *AVG) Step(v Value, opt *AggregatorOption) error {
+	return f.acc.add(v)
+}
+
+func (f *AVG) Done() (Value, error) {
+	return f.acc.avg()
 }
 
 type BIT_AND_AGG struct {
@@ -444,7 +559,7 @@ func (f *STRING_AGG) Step(v Value, delim string, opt *AggregatorOption) error {
 		OrderBy: opt.OrderBy,
 		Value:   v,
 	})
-	return nil
+	return opt.checkBufferedValues(len(f.values))
 }
 
 func sortAggregatedValues(values []*OrderedValue, opt *AggregatorOption) []*OrderedValue {
@@ -509,27 +624,15 @@ func (f *STRING_AGG) Done() (Value, error) {
 }
 
 type SUM struct {
-	sum Value
+	acc sumAccumulator
 }
 
 func (f *SUM) Step(v Value, opt *AggregatorOption) error {
-	if v == nil {
-		return nil
-	}
-	if f.sum == nil {
-		f.sum = v
-	} else {
-		added, err := f.sum.Add(v)
-		if err != nil {
-			return err
-		}
-		f.sum = added
-	}
-	return nil
+	return f.acc.add(v)
 }
 
 func (f *SUM) Done() (Value, error) {
-	return f.sum, nil
+	return f.acc.sum()
 }
 
 type CORR struct {
@@ -551,7 +654,7 @@ func (f *CORR) Step(x, y Value, opt *AggregatorOption) error {
 	}
 	f.x = append(f.x, vx)
 	f.y = append(f.y, vy)
-	return nil
+	return opt.checkBufferedValues(len(f.x))
 }
 
 func (f *CORR) Done() (Value, error) {
@@ -580,7 +683,7 @@ func (f *COVAR_POP) Step(x, y Value, opt *AggregatorOption) error {
 	}
 	f.x = append(f.x, vx)
 	f.y = append(f.y, vy)
-	return nil
+	return opt.checkBufferedValues(len(f.x))
 }
 
 func (f *COVAR_POP) Done() (Value, error) {
@@ -609,7 +712,7 @@ func (f *COVAR_SAMP) Step(x, y Value, opt *AggregatorOption) error {
 	}
 	f.x = append(f.x, vx)
 	f.y = append(f.y, vy)
-	return nil
+	return opt.checkBufferedValues(len(f.x))
 }
 
 func (f *COVAR_SAMP) Done() (Value, error) {
@@ -632,7 +735,7 @@ func (f *STDDEV_POP) Step(v Value, opt *AggregatorOption) error {
 		return err
 	}
 	f.v = append(f.v, f64)
-	return nil
+	return opt.checkBufferedValues(len(f.v))
 }
 
 func (f *STDDEV_POP) Done() (Value, error) {
@@ -656,7 +759,7 @@ func (f *STDDEV_SAMP) Step(v Value, opt *AggregatorOption) error {
 		return err
 	}
 	f.v = append(f.v, f64)
-	return nil
+	return opt.checkBufferedValues(len(f.v))
 }
 
 func (f *STDDEV_SAMP) Done() (Value, error) {
@@ -681,7 +784,7 @@ func (f *VAR_POP) Step(v Value, opt *AggregatorOption) error {
 		return err
 	}
 	f.v = append(f.v, f64)
-	return nil
+	return opt.checkBufferedValues(len(f.v))
 }
 
 func (f *VAR_POP) Done() (Value, error) {
@@ -705,7 +808,7 @@ func (f *VAR_SAMP) Step(v Value, opt *AggregatorOption) error {
 		return err
 	}
 	f.v = append(f.v, f64)
-	return nil
+	return opt.checkBufferedValues(len(f.v))
 }
 
 func (f *VAR_SAMP) Done() (Value, error) {
@@ -750,7 +853,7 @@ func (f *APPROX_QUANTILES) Step(v Value, num int64, opt *AggregatorOption) error
 		f.num = num
 	})
 	f.values = append(f.values, v)
-	return nil
+	return opt.checkBufferedValues(len(f.values))
 }
 
 func (f *APPROX_QUANTILES) Done() (Value, error) {
@@ -20,7 +20,7 @@ func (o *AggregatorFuncOption) UnmarshalJSON(b []byte) error {
 	switch v.Type {
 	case AggregatorFuncOptionDistinct:
 	case AggregatorFuncOptionIgnoreNulls:
-	case AggregatorFuncOptionLimit:
+	case AggregatorFuncOptionLimit, AggregatorFuncOptionMaxBufferedValues:
 		var value struct {
 			Value int64 `json:"value"`
 		}
@@ -48,6 +48,8 @@ const (
 	AggregatorFuncOptionLimit       AggregatorFuncOptionType = "aggregate_limit"
 	AggregatorFuncOptionOrderBy     AggregatorFuncOptionType = "aggregate_order_by"
 	AggregatorFuncOptionIgnoreNulls AggregatorFuncOptionType = "aggregate_ignore_nulls"
+
+	AggregatorFuncOptionMaxBufferedValues AggregatorFuncOptionType = "aggregate_max_buffered_values"
 )
 
 func DISTINCT() (Value, error) {
@@ -65,6 +67,14 @@ func LIMIT(limit int64) (Value, error) {
 	return StringValue(string(b)), nil
 }
 
+func MAX_BUFFERED_VALUES(num int64) (Value, error) {
+	b, _ := json.Marshal(&AggregatorFuncOption{
+		Type:  AggregatorFuncOptionMaxBufferedValues,
+		Value: num,
+	})
+	return StringValue(string(b)), nil
+}
+
 func IGNORE_NULLS() (Value, error) {
 	b, _ := json.Marshal(&AggregatorFuncOption{
 		Type: AggregatorFuncOptionIgnoreNulls,
@@ -106,10 +116,11 @@ func ORDER_BY(value Value, isAsc bool) (Value, error) {
 }
 
 type AggregatorOption struct {
-	Distinct    bool
-	IgnoreNulls bool
-	Limit       *int64
-	OrderBy     []*AggregateOrderBy
+	Distinct          bool
+	IgnoreNulls       bool
+	Limit             *int64
+	OrderBy           []*AggregateOrderBy
+	MaxBufferedValues int64
 }
 
 func parseAggregateOptions(args ...Value) ([]Value, *AggregatorOption) {
@@ -142,6 +153,8 @@ func parseAggregateOptions(args ...Value) ([]Value, *AggregatorOption) {
 			opt.Limit = &i64
 		case AggregatorFuncOptionOrderBy:
 			opt.OrderBy = append(opt.OrderBy, v.Value.(*AggregateOrderBy))
+		case AggregatorFuncOptionMaxBufferedValues:
+			opt.MaxBufferedValues = v.Value.(int64)
 		default:
 			filteredArgs = append(filteredArgs, arg)
 			continue
@@ -30,7 +30,7 @@ type WindowFuncInfo struct {
 
 func existsNull(args []Value) bool {
 	for _, v := range args {
-		if v == nil {
+		if isNull(v) {
 			return true
 		}
 	}
@@ -432,9 +432,6 @@ func bindLike(args ...Value) (Value, error) {
 }
 
 func bindBetween(args ...Value) (Value, error) {
-	if existsNull(args) {
-		return BoolValue(false), nil
-	}
 	return BETWEEN(args[0], args[1], args[2])
 }
 
@@ -504,7 +501,7 @@ func bindNullIf(args ...Value) (Value, error) {
 }
 
 func bindCast(args ...Value) (Value, error) {
-	if len(args) != 4 {
+	if len(args) != 4 && len(args) != 5 {
 		return nil, fmt.Errorf("CAST: invalid argument num %d", len(args))
 	}
 	jsonEncodedFromType, err := args[1].ToString()
@@ -527,7 +524,15 @@ func bindCast(args ...Value) (Value, error) {
 	if err != nil {
 		return nil, err
 	}
-	return CAST(args[0], &fromType, &toType, isSafeCast)
+	var zone string
+	if len(args) == 5 {
+		z, err := args[4].ToString()
+		if err != nil {
+			return nil, err
+		}
+		zone = z
+	}
+	return CAST(args[0], &fromType, &toType, isSafeCast, zone)
 }
 
 func bindInterval(args ...Value) (Value, error) {
@@ -687,14 +692,7 @@ func bindAscii(args ...Value) (Value, error) {
 	if args[0] == nil {
 		return nil, nil
 	}
-	ascii, err := args[0].ToString()
-	if err != nil {
-		return nil, err
-	}
-	if ascii == "" {
-		return IntValue(0), nil
-	}
-	return ASCII(ascii)
+	return ASCII(args[0])
 }
 
 func bindByteLength(args ...Value) (Value, error) {
@@ -995,11 +993,11 @@ func bindLtrim(args ...Value) (Value, error) {
 	}
 	cutset := " "
 	if len(args) == 2 {
-		v, err := args[1].ToString()
+		v, err := args[1].ToBytes()
 		if err != nil {
 			return nil, err
 		}
-		cutset = v
+		cutset = string(v)
 	}
 	return LTRIM(args[0], cutset)
 }
@@ -1198,11 +1196,11 @@ func bindRtrim(args ...Value) (Value, error) {
 	}
 	var cutset string = " "
 	if len(args) > 1 {
-		v, err := args[1].ToString()
+		v, err := args[1].ToBytes()
 		if err != nil {
 			return nil, err
 		}
-		cutset = v
+		cutset = string(v)
 	}
 	return RTRIM(args[0], cutset)
 }
@@ -2849,6 +2847,17 @@ func bindIgnoreNulls(args ...Value) (Value, error) {
 	return IGNORE_NULLS()
 }
 
+func bindMaxBufferedValues(args ...Value) (Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("MAX_BUFFERED_VALUES: invalid argument num %d", len(args))
+	}
+	i64, err := args[0].ToInt64()
+	if err != nil {
+		return nil, err
+	}
+	return MAX_BUFFERED_VALUES(i64)
+}
+
 func bindOrderBy(args ...Value) (Value, error) {
 	if len(args) != 2 {
 		return nil, fmt.Errorf("ORDER_BY: invalid argument num %d", len(args))
@@ -17,46 +17,24 @@ func CURRENT_DATETIME_WITH_TIME(v time.Time) (Value, error) {
 	return DatetimeValue(v), nil
 }
 
+// DATETIME constructs DATETIME from the parts, DATE and TIME, or TIMESTAMP with the optional time zone ( UTC by default ).
+// The result is the wall clock in UTC that is the canonical representation of DATETIME.
 func DATETIME(args ...Value) (Value, error) {
 	if len(args) == 6 {
-		year, err := args[0].ToInt64()
-		if err != nil {
-			return nil, err
-		}
-		month, err := args[1].ToInt64()
-		if err != nil {
-			return nil, err
-		}
-		day, err := args[2].ToInt64()
-		if err != nil {
-			return nil, err
-		}
-		hour, err := args[3].ToInt64()
-		if err != nil {
-			return nil, err
-		}
-		minute, err := args[4].ToInt64()
-		if err != nil {
-			return nil, err
-		}
-		second, err := args[5].ToInt64()
-		if err != nil {
-			return nil, err
+		parts := make([]int, 0, len(args))
+		for _, arg := range args {
+			part, err := arg.ToInt64()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, int(part))
 		}
-		location, err := toLocation("")
-		if err != nil {
-			return nil, err
+		t := time.Date(parts[0], time.Month(parts[1]), parts[2], parts[3], parts[4], parts[5], 0, time.UTC)
+		if t.Year() != parts[0] || int(t.Month()) != parts[1] || t.Day() != parts[2] ||
+			t.Hour() != parts[3] || t.Minute() != parts[4] || t.Second() != parts[5] {
+			return nil, fmt.Errorf("DATETIME: invalid datetime %d-%d-%d %d:%d:%d", parts[0], parts[1], parts[2], parts[3], parts[4], parts[5])
 		}
-		return DatetimeValue(time.Date(
-			int(year),
-			time.Month(month),
-			int(day),
-			int(hour),
-			int(minute),
-			int(second),
-			0,
-			location,
-		)), nil
+		return DatetimeValue(t), nil
 	}
 	if len(args) != 1 && len(args) != 2 {
 		return nil, fmt.Errorf("DATETIME: invalid argument num %d", len(args))
@@ -80,27 +58,29 @@ func DATETIME(args ...Value) (Value, error) {
 				t2.Minute(),
 				t2.Second(),
 				t2.Nanosecond(),
-				t2.Location(),
+				time.UTC,
 			)), nil
 		}
-		return DatetimeValue(t), nil
+		return DatetimeValue(civilDate(t)), nil
+	case DatetimeValue:
+		return v, nil
 	case TimestampValue:
 		t, err := v.ToTime()
 		if err != nil {
 			return nil, err
 		}
+		var zone string
 		if len(args) == 2 {
-			zone, err := args[1].ToString()
+			zone, err = args[1].ToString()
 			if err != nil {
 				return nil, fmt.Errorf("DATETIME: second argument must be string type: %w", err)
 			}
-			loc, err := toLocation(zone)
-			if err != nil {
-				return nil, err
-			}
-			return DatetimeValue(t.In(loc)), nil
 		}
-		return DatetimeValue(t), nil
+		loc, err := toLocation(zone)
+		if err != nil {
+			return nil, err
+		}
+		return DatetimeValue(civilDatetime(t.In(loc))), nil
 	}
 	return nil, fmt.Errorf("DATETIME: first argument must be DATE or TIMESTAMP type")
 }
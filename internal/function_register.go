@@ -2,9 +2,11 @@ package internal
 
 import (
 	"fmt"
+	"math"
 	"sync"
 
 	"github.com/goccy/go-json"
+	"github.com/goccy/go-zetasql/types"
 	"github.com/mattn/go-sqlite3"
 )
 
@@ -263,6 +265,7 @@ var normalFuncs = []*FuncInfo{
 	{Name: "limit", BindFunc: bindLimit},
 	{Name: "order_by", BindFunc: bindOrderBy},
 	{Name: "ignore_nulls", BindFunc: bindIgnoreNulls},
+	{Name: "max_buffered_values", BindFunc: bindMaxBufferedValues},
 
 	// window option funcs
 	{Name: "window_frame_unit", BindFunc: bindWindowFrameUnit},
@@ -379,6 +382,72 @@ type NameAndFunc struct {
 	Func interface{}
 }
 
+// builtinFuncs are the functions used by the formatter to handle the encoded values.
+var builtinFuncs = []*NameAndFunc{
+	{
+		Name: "zetasqlite_decode_array",
+		Func: func(v interface{}) (string, error) {
+			decoded, err := DecodeValue(v)
+			if err != nil {
+				return "", err
+			}
+			if decoded == nil {
+				return "[]", nil
+			}
+			array, err := decoded.ToArray()
+			if err != nil {
+				return "", err
+			}
+			encodedValues := make([]interface{}, 0, len(array.values))
+			for _, value := range array.values {
+				v, err := EncodeValue(value)
+				if err != nil {
+					return "", err
+				}
+				encodedValues = append(encodedValues, v)
+			}
+			b, err := json.Marshal(encodedValues)
+			if err != nil {
+				return "", err
+			}
+			return string(b), err
+		},
+	},
+	{
+		Name: "zetasqlite_decode_native",
+		Func: func(v interface{}, kind int64) (interface{}, error) {
+			return decodeNativeValue(v, types.TypeKind(kind))
+		},
+	},
+	{
+		Name: "zetasqlite_group_by",
+		Func: func(v interface{}) (interface{}, error) {
+			decoded, err := DecodeValue(v)
+			if err != nil {
+				return "", err
+			}
+			if decoded == nil {
+				return nil, nil
+			}
+			if f, ok := decoded.(FloatValue); ok && math.IsNaN(float64(f)) {
+				// SQLite treats NaN as NULL, so NaN values are grouped together by the encoded value.
+				return v, nil
+			}
+			return decoded.Interface(), nil
+		},
+	},
+	{
+		Name: checkConstraintViolationFuncName,
+		Func: checkConstraintViolation,
+	},
+	{
+		Name: partitionIDFuncName,
+		Func: partitionID,
+	},
+}
+
+const collationName = "zetasqlite_collate"
+
 var (
 	funcMapMu          sync.RWMutex
 	registerFuncOnce   sync.Once
@@ -397,67 +466,20 @@ func RegisterFunctions(conn *sqlite3.SQLiteConn) error {
 	funcMapMu.RLock()
 	defer funcMapMu.RUnlock()
 
-	var onceErr error
-	registerFuncOnce.Do(func() {
-		for _, info := range normalFuncs {
-			setupNormalFuncMap(info)
-		}
-		for _, info := range aggregateFuncs {
-			setupAggregateFuncMap(info)
-		}
-		for _, info := range windowFuncs {
-			setupWindowFuncMap(info)
-		}
-	})
-	if onceErr != nil {
-		return onceErr
-	}
+	setupFuncMap()
 
-	if err := conn.RegisterFunc("zetasqlite_decode_array", func(v interface{}) (string, error) {
-		decoded, err := DecodeValue(v)
-		if err != nil {
-			return "", err
-		}
-		if decoded == nil {
-			return "[]", nil
-		}
-		array, err := decoded.ToArray()
-		if err != nil {
-			return "", err
-		}
-		encodedValues := make([]interface{}, 0, len(array.values))
-		for _, value := range array.values {
-			v, err := EncodeValue(value)
-			if err != nil {
-				return "", err
-			}
-			encodedValues = append(encodedValues, v)
-		}
-		b, err := json.Marshal(encodedValues)
-		if err != nil {
-			return "", err
-		}
-		return string(b), err
-	}, true); err != nil {
-		return fmt.Errorf("failed to register decode_array function: %w", err)
-	}
-
-	if err := conn.RegisterFunc("zetasqlite_group_by", func(v interface{}) (interface{}, error) {
-		decoded, err := DecodeValue(v)
-		if err != nil {
-			return "", err
-		}
-		if decoded == nil {
-			return nil, nil
+	for _, v := range builtinFuncs {
+		if err := conn.RegisterFunc(v.Name, v.Func, true); err != nil {
+			return fmt.Errorf("failed to register function %s: %w", v.Name, err)
 		}
-		return decoded.Interface(), nil
-	}, true); err != nil {
-		return fmt.Errorf("failed to register group_by function: %w", err)
 	}
 
-	if err := conn.RegisterCollation("zetasqlite_collate", func(a, b string) int {
+	if err := conn.RegisterCollation(collationName, func(a, b string) int {
 		va, _ := DecodeValue(a)
 		vb, _ := DecodeValue(b)
+		if cmp, ok := compareNaN(va, vb); ok {
+			return cmp
+		}
 		eq, _ := va.EQ(vb)
 		if eq {
 			return 0
@@ -492,7 +514,24 @@ func RegisterFunctions(conn *sqlite3.SQLiteConn) error {
 			}
 		}
 	}
-	return nil
+	if err := registerUnnestModule(conn); err != nil {
+		return err
+	}
+	return checkSQLiteCapabilities(conn)
+}
+
+func setupFuncMap() {
+	registerFuncOnce.Do(func() {
+		for _, info := range normalFuncs {
+			setupNormalFuncMap(info)
+		}
+		for _, info := range aggregateFuncs {
+			setupAggregateFuncMap(info)
+		}
+		for _, info := range windowFuncs {
+			setupWindowFuncMap(info)
+		}
+	})
 }
 
 func setupNormalFuncMap(info *FuncInfo) {
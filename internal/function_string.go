@@ -17,8 +17,32 @@ import (
 	"golang.org/x/text/unicode/norm"
 )
 
-func ASCII(v string) (Value, error) {
-	return IntValue(v[0]), nil
+func ASCII(v Value) (Value, error) {
+	switch v.(type) {
+	case StringValue:
+		s, err := v.ToString()
+		if err != nil {
+			return nil, err
+		}
+		if s == "" {
+			return IntValue(0), nil
+		}
+		r, _ := utf8.DecodeRuneInString(s)
+		if r > unicode.MaxASCII {
+			return nil, fmt.Errorf("ASCII: first character %q is not ASCII character", r)
+		}
+		return IntValue(r), nil
+	case BytesValue:
+		b, err := v.ToBytes()
+		if err != nil {
+			return nil, err
+		}
+		if len(b) == 0 {
+			return IntValue(0), nil
+		}
+		return IntValue(b[0]), nil
+	}
+	return nil, fmt.Errorf("ASCII: value type is must be STRING or BYTES type")
 }
 
 func BYTE_LENGTH(v []byte) (Value, error) {
@@ -233,7 +257,6 @@ func INSTR(source, search Value, position, occurrence int64) (Value, error) {
 	if occurrence <= 0 {
 		return nil, fmt.Errorf("INSTR: invalid occurrence number. occurrence is must be large than zero value. but specified %d", occurrence)
 	}
-	pos := int(math.Abs(float64(position)))
 	if _, ok := source.(StringValue); ok {
 		if _, ok := search.(StringValue); !ok {
 			return nil, fmt.Errorf("INSTR: source and search are must be same type")
@@ -246,30 +269,12 @@ func INSTR(source, search Value, position, occurrence int64) (Value, error) {
 		if err != nil {
 			return nil, err
 		}
-		if pos >= len(src) {
-			return nil, fmt.Errorf("INSTR: invalid position number. position %d is larger than source value length %d", pos, len(src))
-		}
-		length := len(src)
-		if position < 0 {
-			src = src[:len(src)-pos+1]
-		} else {
-			src = src[pos-1:]
-		}
-		var found int64
-		for i := 0; i < len(src); i++ {
-			idx := strings.Index(src[i:], search)
-			if idx >= 0 {
-				found++
-				i += idx
-			}
-			if found == occurrence {
-				if position < 0 {
-					return IntValue(length - i - 1), nil
-				}
-				return IntValue(pos + i), nil
-			}
-		}
-		return IntValue(0), nil
+		// the position and the result are counted by code points.
+		srcRunes := []rune(src)
+		searchRunes := []rune(search)
+		return instr(len(srcRunes), len(searchRunes), position, occurrence, func(i int) bool {
+			return string(srcRunes[i:i+len(searchRunes)]) == search
+		})
 	}
 	if _, ok := source.(BytesValue); ok {
 		if _, ok := search.(BytesValue); !ok {
@@ -283,32 +288,45 @@ func INSTR(source, search Value, position, occurrence int64) (Value, error) {
 		if err != nil {
 			return nil, err
 		}
-		if pos >= len(src) {
-			return nil, fmt.Errorf("INSTR: invalid position number. position %d is larger than source value length %d", pos, len(src))
-		}
-		length := len(src)
-		if position < 0 {
-			src = src[:len(src)-pos+1]
-		} else {
-			src = src[pos-1:]
-		}
-		var found int64
-		for i := 0; i < len(src); i++ {
-			idx := bytes.Index(src[i:], search)
-			if idx >= 0 {
+		return instr(len(src), len(search), position, occurrence, func(i int) bool {
+			return bytes.Equal(src[i:i+len(search)], search)
+		})
+	}
+	return nil, fmt.Errorf("INSTR: source and search type are must be STRING or BYTES type")
+}
+
+// instr finds the 1-based index of the occurrence-th match including the overlapping matches.
+// If position is negative, it searches backward from the position counted from the end.
+// matchAt reports whether search matches at the 0-based index of the source.
+func instr(srcLen, searchLen int, position, occurrence int64, matchAt func(i int) bool) (Value, error) {
+	pos := int(math.Abs(float64(position)))
+	if pos > srcLen {
+		return nil, fmt.Errorf("INSTR: invalid position number. position %d is larger than source value length %d", pos, srcLen)
+	}
+	var found int64
+	if position > 0 {
+		for i := pos - 1; i+searchLen <= srcLen; i++ {
+			if matchAt(i) {
 				found++
-				i += idx
-			}
-			if found == occurrence {
-				if position < 0 {
-					return IntValue(length - i - 1), nil
+				if found == occurrence {
+					return IntValue(i + 1), nil
 				}
-				return IntValue(pos + i), nil
 			}
 		}
 		return IntValue(0), nil
 	}
-	return nil, fmt.Errorf("INSTR: source and search type are must be STRING or BYTES type")
+	for i := srcLen - pos; i >= 0; i-- {
+		if i+searchLen > srcLen {
+			continue
+		}
+		if matchAt(i) {
+			found++
+			if found == occurrence {
+				return IntValue(i + 1), nil
+			}
+		}
+	}
+	return IntValue(0), nil
 }
 
 func LEFT(v Value, length int64) (Value, error) {
@@ -403,13 +421,14 @@ func LPAD(originalValue Value, returnLength int64, pattern Value) (Value, error)
 			if err != nil {
 				return nil, err
 			}
+			pat = p
 			if remainLen-len(p) > 0 {
 				// needs to repeat pattern
 				repeatNum := ((remainLen - len(p)) / len(p)) + 2
 				pat = bytes.Repeat(p, repeatNum)
 			}
 		}
-		return BytesValue(append(pat[:remainLen], b...)), nil
+		return BytesValue(append(append([]byte{}, pat[:remainLen]...), b...)), nil
 	}
 	return nil, fmt.Errorf("LPAD: original value type is must be STRING or BYTES type")
 }
@@ -448,7 +467,7 @@ func LTRIM(v Value, cutset string) (Value, error) {
 		if err != nil {
 			return nil, err
 		}
-		return BytesValue(bytes.TrimLeft(b, cutset)), nil
+		return BytesValue(trimBytes(b, []byte(cutset), true, false)), nil
 	}
 	return nil, fmt.Errorf("LTRIM: value type is must be STRING or BYTES type")
 }
@@ -508,10 +527,12 @@ func REGEXP_EXTRACT(value Value, expr string, position, occurrence int64) (Value
 		if err != nil {
 			return nil, err
 		}
-		if pos >= len([]rune(v)) {
+		runes := []rune(v)
+		if pos >= len(runes) {
 			return nil, nil
 		}
-		matches := re.FindAllStringSubmatch(v[pos:], int(occurrence))
+		// the position is counted by code points.
+		matches := re.FindAllStringSubmatch(string(runes[pos:]), int(occurrence))
 		if len(matches) < int(occurrence) {
 			return nil, nil
 		}
@@ -589,10 +610,13 @@ func REGEXP_INSTR(sourceValue, exprValue Value, position, occurrence, occurrence
 		if err != nil {
 			return nil, err
 		}
-		if pos >= len([]rune(source)) {
+		runes := []rune(source)
+		if pos >= len(runes) {
 			return IntValue(0), nil
 		}
-		matches := re.FindAllStringSubmatchIndex(source[pos:], int(occurrence))
+		// the position and the result are counted by code points.
+		offset := len(string(runes[:pos]))
+		matches := re.FindAllStringSubmatchIndex(source[offset:], int(occurrence))
 		if len(matches) < int(occurrence) {
 			return IntValue(0), nil
 		}
@@ -600,7 +624,7 @@ func REGEXP_INSTR(sourceValue, exprValue Value, position, occurrence, occurrence
 		if len(match) <= int(occurrencePos) {
 			return IntValue(0), nil
 		}
-		return IntValue(pos + match[occurrencePos] + 1), nil
+		return IntValue(utf8.RuneCountInString(source[:offset+match[occurrencePos]]) + 1), nil
 	case BytesValue:
 		source, err := sourceValue.ToBytes()
 		if err != nil {
@@ -857,6 +881,7 @@ func RPAD(originalValue Value, returnLength int64, pattern Value) (Value, error)
 			if err != nil {
 				return nil, err
 			}
+			pat = p
 			if remainLen-len(p) > 0 {
 				// needs to repeat pattern
 				repeatNum := ((remainLen - len(p)) / len(p)) + 2
@@ -881,7 +906,7 @@ func RTRIM(value Value, cutset string) (Value, error) {
 		if err != nil {
 			return nil, err
 		}
-		return BytesValue(bytes.TrimRight(v, cutset)), nil
+		return BytesValue(trimBytes(v, []byte(cutset), false, true)), nil
 	}
 	return nil, fmt.Errorf("RTRIM: value1 must be STRING or BYTES")
 }
@@ -979,6 +1004,13 @@ func SPLIT(value, delimValue Value) (Value, error) {
 			return nil, err
 		}
 		ret := &ArrayValue{}
+		if len(delim) == 0 {
+			// bytes.Split splits into UTF-8 sequences, but BYTES value is split into each byte.
+			for i := range v {
+				ret.values = append(ret.values, BytesValue(v[i:i+1]))
+			}
+			return ret, nil
+		}
 		for _, splitted := range bytes.Split(v, delim) {
 			ret.values = append(ret.values, BytesValue(splitted))
 		}
@@ -1024,7 +1056,11 @@ func STRPOS(value, search Value) (Value, error) {
 		if err != nil {
 			return nil, err
 		}
-		return IntValue(strings.Index(v, s) + 1), nil
+		idx := strings.Index(v, s)
+		if idx < 0 {
+			return IntValue(0), nil
+		}
+		return IntValue(utf8.RuneCountInString(v[:idx]) + 1), nil
 	case BytesValue:
 		v, err := value.ToBytes()
 		if err != nil {
@@ -1085,7 +1121,7 @@ func SUBSTR(value Value, pos int64, length *int64) (Value, error) {
 		if endIdx > runesLen {
 			endIdx = runesLen
 		}
-		return StringValue(v[startIdx:endIdx]), nil
+		return StringValue(string(runes[startIdx:endIdx])), nil
 	case BytesValue:
 		v, err := value.ToBytes()
 		if err != nil {
@@ -1166,19 +1202,27 @@ func TRANSLATE(expr, source, target Value) (Value, error) {
 		if err != nil {
 			return nil, err
 		}
-		evaluatedByte := map[byte]struct{}{}
-		for i := 0; i < len(s); i++ {
-			if _, exists := evaluatedByte[s[i]]; exists {
-				return nil, fmt.Errorf("TRANSLATE: found duplicated source character: %c", s[i])
+		// the characters are translated by code points at once, so the translated character is not translated again.
+		targetRunes := []rune(t)
+		sourceIdx := map[rune]int{}
+		for idx, r := range []rune(s) {
+			if _, exists := sourceIdx[r]; exists {
+				return nil, fmt.Errorf("TRANSLATE: found duplicated source character: %c", r)
 			}
-			if len(t) > i {
-				e = strings.ReplaceAll(e, string(s[i]), string(t[i]))
-			} else {
-				e = strings.ReplaceAll(e, string(s[i]), "")
+			sourceIdx[r] = idx
+		}
+		var b strings.Builder
+		for _, r := range e {
+			idx, exists := sourceIdx[r]
+			if !exists {
+				b.WriteRune(r)
+				continue
+			}
+			if idx < len(targetRunes) {
+				b.WriteRune(targetRunes[idx])
 			}
-			evaluatedByte[s[i]] = struct{}{}
 		}
-		return StringValue(e), nil
+		return StringValue(b.String()), nil
 	case BytesValue:
 		if _, ok := source.(BytesValue); !ok {
 			return nil, fmt.Errorf("TRANSLATE: source characters must be BYTES type")
@@ -1198,19 +1242,25 @@ func TRANSLATE(expr, source, target Value) (Value, error) {
 		if err != nil {
 			return nil, err
 		}
-		evaluatedByte := map[byte]struct{}{}
-		for i := 0; i < len(s); i++ {
-			if _, exists := evaluatedByte[s[i]]; exists {
-				return nil, fmt.Errorf("TRANSLATE: found duplicated source character: %c", s[i])
+		sourceIdx := map[byte]int{}
+		for idx, c := range s {
+			if _, exists := sourceIdx[c]; exists {
+				return nil, fmt.Errorf("TRANSLATE: found duplicated source character: %c", c)
+			}
+			sourceIdx[c] = idx
+		}
+		ret := make([]byte, 0, len(e))
+		for _, c := range e {
+			idx, exists := sourceIdx[c]
+			if !exists {
+				ret = append(ret, c)
+				continue
 			}
-			if len(t) > i {
-				e = bytes.ReplaceAll(e, []byte{s[i]}, []byte{t[i]})
-			} else {
-				e = bytes.ReplaceAll(e, []byte{s[i]}, []byte{})
+			if idx < len(t) {
+				ret = append(ret, t[idx])
 			}
-			evaluatedByte[s[i]] = struct{}{}
 		}
-		return BytesValue(e), nil
+		return BytesValue(ret), nil
 	}
 	return nil, fmt.Errorf("TRANSLATE: expression type is must be STRING or BYTES type")
 }
@@ -1238,11 +1288,28 @@ func TRIM(v, cutsetV Value) (Value, error) {
 		if err != nil {
 			return nil, err
 		}
-		return BytesValue(bytes.Trim(b, cutset)), nil
+		return BytesValue(trimBytes(b, []byte(cutset), true, true)), nil
 	}
 	return nil, fmt.Errorf("TRIM: expression type is must be STRING or BYTES type")
 }
 
+// trimBytes removes the leading and trailing bytes contained in cutset.
+// Unlike bytes.Trim, cutset is handled byte by byte instead of UTF-8 characters.
+func trimBytes(b, cutset []byte, left, right bool) []byte {
+	start, end := 0, len(b)
+	if left {
+		for start < end && bytes.IndexByte(cutset, b[start]) >= 0 {
+			start++
+		}
+	}
+	if right {
+		for end > start && bytes.IndexByte(cutset, b[end-1]) >= 0 {
+			end--
+		}
+	}
+	return b[start:end]
+}
+
 func UNICODE(v string) (Value, error) {
 	runes := []rune(v)
 	if len(runes) == 0 {
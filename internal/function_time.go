@@ -17,25 +17,22 @@ func CURRENT_TIME_WITH_TIME(v time.Time) (Value, error) {
 	return TimeValue(v), nil
 }
 
+// TIME constructs TIME from the parts, DATETIME, or TIMESTAMP with the optional time zone ( UTC by default ).
+// The result is the wall clock on 1970-01-01 in UTC that is the canonical representation of TIME.
 func TIME(args ...Value) (Value, error) {
 	if len(args) == 3 {
-		hour, err := args[0].ToInt64()
-		if err != nil {
-			return nil, err
-		}
-		min, err := args[1].ToInt64()
-		if err != nil {
-			return nil, err
+		parts := make([]int, 0, len(args))
+		for _, arg := range args {
+			part, err := arg.ToInt64()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, int(part))
 		}
-		sec, err := args[2].ToInt64()
-		if err != nil {
-			return nil, err
+		if parts[0] < 0 || parts[0] > 23 || parts[1] < 0 || parts[1] > 59 || parts[2] < 0 || parts[2] > 59 {
+			return nil, fmt.Errorf("TIME: invalid time %d:%d:%d", parts[0], parts[1], parts[2])
 		}
-		loc, err := toLocation("")
-		if err != nil {
-			return nil, err
-		}
-		return TimeValue(time.Date(0, 0, 0, int(hour), int(min), int(sec), 0, loc)), nil
+		return TimeValue(time.Date(1970, 1, 1, parts[0], parts[1], parts[2], 0, time.UTC)), nil
 	}
 	if len(args) != 1 && len(args) != 2 {
 		return nil, fmt.Errorf("TIME: invalid argument num %d", len(args))
@@ -46,24 +43,24 @@ func TIME(args ...Value) (Value, error) {
 		if err != nil {
 			return nil, err
 		}
+		var zone string
 		if len(args) == 2 {
-			zone, err := args[1].ToString()
-			if err != nil {
-				return nil, err
-			}
-			loc, err := toLocation(zone)
+			zone, err = args[1].ToString()
 			if err != nil {
 				return nil, err
 			}
-			return TimeValue(t.In(loc)), nil
 		}
-		return TimeValue(t), nil
-	case DatetimeValue:
+		loc, err := toLocation(zone)
+		if err != nil {
+			return nil, err
+		}
+		return TimeValue(civilTime(t.In(loc))), nil
+	case DatetimeValue, TimeValue:
 		t, err := args[0].ToTime()
 		if err != nil {
 			return nil, err
 		}
-		return TimeValue(t), nil
+		return TimeValue(civilTime(t)), nil
 	}
 	return nil, fmt.Errorf("TIME: invalid first argument type %T", args[0])
 }
@@ -14,7 +14,7 @@ func CURRENT_TIMESTAMP(zone string) (Value, error) {
 }
 
 func CURRENT_TIMESTAMP_WITH_TIME(v time.Time) (Value, error) {
-	return TimestampValue(v), nil
+	return TimestampValue(timestampKey(v)), nil
 }
 
 func STRING(t time.Time, zone string) (Value, error) {
@@ -22,7 +22,12 @@ func STRING(t time.Time, zone string) (Value, error) {
 	if err != nil {
 		return nil, err
 	}
-	return StringValue(t.In(loc).Format("2006-01-02 15:04:05.999999999+00")), nil
+	t = t.In(loc)
+	layout := "2006-01-02 15:04:05.999999-07"
+	if _, offset := t.Zone(); offset%(60*60) != 0 {
+		layout += ":00"
+	}
+	return StringValue(t.Format(layout)), nil
 }
 
 func TIMESTAMP(v Value, zone string) (Value, error) {
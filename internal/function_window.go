@@ -47,26 +47,13 @@ func (f *WINDOW_ARRAY_AGG) Done(agg *WindowFuncAggregatedStatus) (Value, error)
 		if len(values) == 0 {
 			return nil
 		}
-		var (
-			filteredValues []Value
-			valueMap       = map[string]struct{}{}
-		)
+		var filteredValues []Value
 		for _, v := range values[start : end+1] {
 			if agg.IgnoreNulls() {
 				if v == nil {
 					continue
 				}
 			}
-			if agg.Distinct() {
-				key, err := v.ToString()
-				if err != nil {
-					return err
-				}
-				if _, exists := valueMap[key]; exists {
-					continue
-				}
-				valueMap[key] = struct{}{}
-			}
 			filteredValues = append(filteredValues, v)
 		}
 		ret.values = filteredValues
@@ -90,42 +77,16 @@ func (f *WINDOW_AVG) Done(agg *WindowFuncAggregatedStatus) (Value, error) {
 		if len(values) == 0 {
 			return nil
 		}
-		var (
-			sum      Value
-			valueMap = map[string]struct{}{}
-		)
+		var acc sumAccumulator
 		for _, value := range values[start : end+1] {
 			if value == nil {
 				continue
 			}
-			if agg.Distinct() {
-				key, err := value.ToString()
-				if err != nil {
-					return err
-				}
-				if _, exists := valueMap[key]; exists {
-					continue
-				}
-				valueMap[key] = struct{}{}
-			}
-			if sum == nil {
-				f64, err := value.ToFloat64()
-				if err != nil {
-					return err
-				}
-				sum = FloatValue(f64)
-			} else {
-				added, err := sum.Add(value)
-				if err != nil {
-					return err
-				}
-				sum = added
+			if err := acc.add(value); err != nil {
+				return err
 			}
 		}
-		if sum == nil {
-			return nil
-		}
-		ret, err := sum.Div(FloatValue(float64(len(values[start : end+1]))))
+		ret, err := acc.avg()
 		if err != nil {
 			return err
 		}
@@ -147,21 +108,10 @@ func (f *WINDOW_COUNT) Step(v Value, opt *WindowFuncStatus, agg *WindowFuncAggre
 func (f *WINDOW_COUNT) Done(agg *WindowFuncAggregatedStatus) (Value, error) {
 	var count int64
 	if err := agg.Done(func(values []Value, start, end int) error {
-		valueMap := map[string]struct{}{}
 		for _, v := range values[start : end+1] {
 			if v == nil {
 				continue
 			}
-			if agg.Distinct() {
-				key, err := v.ToString()
-				if err != nil {
-					return err
-				}
-				if _, exists := valueMap[key]; exists {
-					continue
-				}
-				valueMap[key] = struct{}{}
-			}
 			count++
 		}
 		return nil
@@ -306,21 +256,10 @@ func (f *WINDOW_STRING_AGG) Step(v Value, delim string, opt *WindowFuncStatus, a
 func (f *WINDOW_STRING_AGG) Done(agg *WindowFuncAggregatedStatus) (Value, error) {
 	var strValues []string
 	if err := agg.Done(func(values []Value, start, end int) error {
-		valueMap := map[string]struct{}{}
 		for _, value := range values[start : end+1] {
 			if value == nil {
 				continue
 			}
-			if agg.Distinct() {
-				key, err := value.ToString()
-				if err != nil {
-					return err
-				}
-				if _, exists := valueMap[key]; exists {
-					continue
-				}
-				valueMap[key] = struct{}{}
-			}
 			text, err := value.ToString()
 			if err != nil {
 				return err
@@ -347,31 +286,20 @@ func (f *WINDOW_SUM) Step(v Value, opt *WindowFuncStatus, agg *WindowFuncAggrega
 func (f *WINDOW_SUM) Done(agg *WindowFuncAggregatedStatus) (Value, error) {
 	var sum Value
 	if err := agg.Done(func(values []Value, start, end int) error {
-		valueMap := map[string]struct{}{}
+		var acc sumAccumulator
 		for _, value := range values[start : end+1] {
 			if value == nil {
 				continue
 			}
-			if agg.Distinct() {
-				key, err := value.ToString()
-				if err != nil {
-					return err
-				}
-				if _, exists := valueMap[key]; exists {
-					continue
-				}
-				valueMap[key] = struct{}{}
-			}
-			if sum == nil {
-				sum = value
-			} else {
-				added, err := sum.Add(value)
-				if err != nil {
-					return err
-				}
-				sum = added
+			if err := acc.add(value); err != nil {
+				return err
 			}
 		}
+		ret, err := acc.sum()
+		if err != nil {
+			return err
+		}
+		sum = ret
 		return nil
 	}); err != nil {
 		return nil, err
@@ -476,7 +404,7 @@ func (f *WINDOW_NTH_VALUE) Done(agg *WindowFuncAggregatedStatus) (Value, error)
 			return nil
 		}
 		num := f.num - 1
-		if 0 <= f.num && f.num < int64(len(filteredValues)) {
+		if 0 <= num && num < int64(len(filteredValues)) {
 			nthValue = filteredValues[num]
 		}
 		return nil
@@ -102,15 +102,19 @@ type WindowBoundary struct {
 	Offset int64              `json:"offset"`
 }
 
-func getWindowFrameUnitOptionFuncSQL(frameUnit ast.FrameUnit) string {
+// getWindowFrameUnitOptionFuncSQL returns an error for the frame units other than ROWS and RANGE ( e.g. GROUPS ),
+// because the window functions cannot compute their frames and must not be evaluated with a wrong frame.
+func getWindowFrameUnitOptionFuncSQL(frameUnit ast.FrameUnit) (string, error) {
 	var typ WindowFrameUnitType
 	switch frameUnit {
 	case ast.FrameUnitRows:
 		typ = WindowFrameUnitRows
 	case ast.FrameUnitRange:
 		typ = WindowFrameUnitRange
+	default:
+		return "", fmt.Errorf("window frame unit %d is not supported", frameUnit)
 	}
-	return fmt.Sprintf("zetasqlite_window_frame_unit(%d)", typ)
+	return fmt.Sprintf("zetasqlite_window_frame_unit(%d)", typ), nil
 }
 
 func toWindowBoundaryType(boundaryType ast.BoundaryType) WindowBoundaryType {
@@ -149,8 +153,8 @@ func getWindowPartitionOptionFuncSQL(column string) string {
 	return fmt.Sprintf("zetasqlite_window_partition(%s)", column)
 }
 
-func getWindowRowIDOptionFuncSQL() string {
-	return "zetasqlite_window_rowid(`row_id`)"
+func getWindowRowIDOptionFuncSQL(column string) string {
+	return fmt.Sprintf("zetasqlite_window_rowid(`%s`)", column)
 }
 
 func getWindowOrderByOptionFuncSQL(column string, isAsc bool) string {
@@ -395,7 +399,7 @@ func (s *WindowFuncAggregatedStatus) Step(value Value, status *WindowFuncStatus)
 		})
 	}
 	s.Values = append(s.Values, v)
-	return nil
+	return s.opt.checkBufferedValues(len(s.Values))
 }
 
 func (s *WindowFuncAggregatedStatus) Done(cb func([]Value, int, int) error) error {
@@ -434,11 +438,11 @@ func (s *WindowFuncAggregatedStatus) Done(cb func([]Value, int, int) error) erro
 		})
 	}
 	s.SortedValues = sortedValues
-	start, err := s.getIndexFromBoundary(s.Start)
+	start, err := s.getIndexFromBoundary(s.Start, true)
 	if err != nil {
 		return fmt.Errorf("failed to get start index: %w", err)
 	}
-	end, err := s.getIndexFromBoundary(s.End)
+	end, err := s.getIndexFromBoundary(s.End, false)
 	if err != nil {
 		return fmt.Errorf("failed to get end index: %w", err)
 	}
@@ -455,15 +459,62 @@ func (s *WindowFuncAggregatedStatus) Done(cb func([]Value, int, int) error) erro
 	if end >= len(resultValues) {
 		end = len(resultValues) - 1
 	}
+	if start > end {
+		// empty frame
+		return nil
+	}
+	if s.Distinct() {
+		distinctValues, err := distinctWindowFrameValues(resultValues[start : end+1])
+		if err != nil {
+			return err
+		}
+		return cb(distinctValues, 0, len(distinctValues)-1)
+	}
 	return cb(resultValues, start, end)
 }
 
+// distinctWindowFrameValues returns the values of the frame by removing the duplicated values.
+// The values are compared by the canonical encoding, so the values having the same meaning ( e.g. the same instant of TIMESTAMP in different locations ) are treated as the same value.
+func distinctWindowFrameValues(values []Value) ([]Value, error) {
+	var (
+		distinctValues = make([]Value, 0, len(values))
+		valueMap       = map[string]struct{}{}
+	)
+	for _, value := range values {
+		key, err := distinctValueKey(value)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := valueMap[key]; exists {
+			continue
+		}
+		valueMap[key] = struct{}{}
+		distinctValues = append(distinctValues, value)
+	}
+	return distinctValues, nil
+}
+
+func distinctValueKey(v Value) (string, error) {
+	if v == nil {
+		return "null", nil
+	}
+	if f, ok := v.(FloatValue); ok && f == 0 {
+		// +0 and -0 are the same value.
+		v = FloatValue(0)
+	}
+	encoded, err := EncodeValue(v)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%T:%v", encoded, encoded), nil
+}
+
 func (s *WindowFuncAggregatedStatus) IgnoreNulls() bool {
 	return s.opt.IgnoreNulls
 }
 
 func (s *WindowFuncAggregatedStatus) Distinct() bool {
-	return s.opt.Distinct
+	return s.opt != nil && s.opt.Distinct
 }
 
 func (s *WindowFuncAggregatedStatus) FilteredValues() []*WindowOrderedValue {
@@ -477,12 +528,12 @@ func (s *WindowFuncAggregatedStatus) Partition() string {
 	return s.PartitionedValues[s.RowID-1].Partition
 }
 
-func (s *WindowFuncAggregatedStatus) getIndexFromBoundary(boundary *WindowBoundary) (int, error) {
+func (s *WindowFuncAggregatedStatus) getIndexFromBoundary(boundary *WindowBoundary, isStart bool) (int, error) {
 	switch s.FrameUnit {
 	case WindowFrameUnitRows:
 		return s.getIndexFromBoundaryByRows(boundary)
 	case WindowFrameUnitRange:
-		return s.getIndexFromBoundaryByRange(boundary)
+		return s.getIndexFromBoundaryByRange(boundary, isStart)
 	default:
 		return s.currentIndexByRows()
 	}
@@ -537,97 +588,158 @@ func (s *WindowFuncAggregatedStatus) partitionedCurrentIndexByRows() (int, error
 	return 0, fmt.Errorf("failed to find current index")
 }
 
-func (s *WindowFuncAggregatedStatus) getIndexFromBoundaryByRange(boundary *WindowBoundary) (int, error) {
+// getIndexFromBoundaryByRange returns the index of the frame boundary for RANGE frame unit.
+// The rows having the same ordering values as the current row ( peers ) are always in the same frame,
+// so CURRENT ROW means the first peer for the start boundary and the last peer for the end boundary.
+func (s *WindowFuncAggregatedStatus) getIndexFromBoundaryByRange(boundary *WindowBoundary, isStart bool) (int, error) {
 	switch boundary.Type {
 	case WindowUnboundedPrecedingType:
 		return 0, nil
 	case WindowUnboundedFollowingType:
 		return len(s.FilteredValues()) - 1, nil
 	case WindowCurrentRowType:
-		value, err := s.currentRangeValue()
+		return s.peerIndexByRange(isStart)
+	case WindowOffsetPrecedingType, WindowOffsetFollowingType:
+		orderBy, err := s.currentRangeOrderBy()
 		if err != nil {
 			return 0, err
 		}
-		return s.lookupMaxIndexFromRangeValue(value)
-	case WindowOffsetPrecedingType:
-		value, err := s.currentRangeValue()
-		if err != nil {
-			return 0, err
+		if orderBy.Value == nil {
+			// NULL +/- offset is NULL, so the frame consists of the rows ordered by NULL.
+			return s.peerIndexByRange(isStart)
 		}
-		sub, err := value.Sub(IntValue(boundary.Offset))
-		if err != nil {
-			return 0, err
+		// PRECEDING means the direction to the first row of the sorted values,
+		// so the offset is added to the current value when the rows are sorted in descending order.
+		var rangeValue Value
+		if (boundary.Type == WindowOffsetPrecedingType) == orderBy.IsAsc {
+			rangeValue, err = orderBy.Value.Sub(IntValue(boundary.Offset))
+		} else {
+			rangeValue, err = orderBy.Value.Add(IntValue(boundary.Offset))
 		}
-		return s.lookupMinIndexFromRangeValue(sub)
-	case WindowOffsetFollowingType:
-		value, err := s.currentRangeValue()
 		if err != nil {
 			return 0, err
 		}
-		add, err := value.Add(IntValue(boundary.Offset))
-		if err != nil {
-			return 0, err
+		if isStart {
+			return s.lookupMinIndexFromRangeValue(rangeValue, orderBy.IsAsc)
 		}
-		return s.lookupMaxIndexFromRangeValue(add)
+		return s.lookupMaxIndexFromRangeValue(rangeValue, orderBy.IsAsc)
 	}
 	return 0, fmt.Errorf("unsupported boundary type %d", boundary.Type)
 }
 
-func (s *WindowFuncAggregatedStatus) currentRangeValue() (Value, error) {
-	if len(s.PartitionedValues) != 0 {
-		return s.partitionedCurrentRangeValue()
+// peerIndexByRange returns the index of the first peer of the current row if isFirst is true,
+// otherwise returns the index of the last peer.
+func (s *WindowFuncAggregatedStatus) peerIndexByRange(isFirst bool) (int, error) {
+	cur, err := s.currentIndexByRows()
+	if err != nil {
+		return 0, err
 	}
-	curRowID := int(s.RowID - 1)
-	curValue := s.Values[curRowID]
-	if len(curValue.OrderBy) == 0 {
-		return nil, fmt.Errorf("required order by column for analytic range scanning")
+	curValue := s.SortedValues[cur]
+	idx := cur
+	if isFirst {
+		for idx > 0 && isPeerWindowOrderedValue(s.SortedValues[idx-1], curValue) {
+			idx--
+		}
+		return idx, nil
+	}
+	for idx < len(s.SortedValues)-1 && isPeerWindowOrderedValue(s.SortedValues[idx+1], curValue) {
+		idx++
+	}
+	return idx, nil
+}
+
+func isPeerWindowOrderedValue(a, b *WindowOrderedValue) bool {
+	if len(a.OrderBy) != len(b.OrderBy) {
+		return false
+	}
+	for idx := range a.OrderBy {
+		av := a.OrderBy[idx].Value
+		bv := b.OrderBy[idx].Value
+		if av == nil || bv == nil {
+			if av != bv {
+				return false
+			}
+			continue
+		}
+		cond, err := av.EQ(bv)
+		if err != nil || !cond {
+			return false
+		}
 	}
-	return curValue.OrderBy[len(curValue.OrderBy)-1].Value, nil
+	return true
 }
 
-func (s *WindowFuncAggregatedStatus) partitionedCurrentRangeValue() (Value, error) {
+func (s *WindowFuncAggregatedStatus) currentRangeOrderBy() (*WindowOrderBy, error) {
 	curRowID := int(s.RowID - 1)
-	curValue := s.PartitionedValues[curRowID]
-	if len(curValue.Value.OrderBy) == 0 {
+	var curValue *WindowOrderedValue
+	if len(s.PartitionedValues) != 0 {
+		curValue = s.PartitionedValues[curRowID].Value
+	} else {
+		curValue = s.Values[curRowID]
+	}
+	if len(curValue.OrderBy) == 0 {
 		return nil, fmt.Errorf("required order by column for analytic range scanning")
 	}
-	return curValue.Value.OrderBy[len(curValue.Value.OrderBy)-1].Value, nil
+	return curValue.OrderBy[len(curValue.OrderBy)-1], nil
 }
 
-func (s *WindowFuncAggregatedStatus) lookupMinIndexFromRangeValue(rangeValue Value) (int, error) {
-	minIndex := -1
-	for idx := len(s.SortedValues) - 1; idx >= 0; idx-- {
-		value := s.SortedValues[idx]
+// lookupMinIndexFromRangeValue returns the first index of the row not ordered before rangeValue.
+// If there is no such row, returns the length of the sorted values.
+func (s *WindowFuncAggregatedStatus) lookupMinIndexFromRangeValue(rangeValue Value, isAsc bool) (int, error) {
+	for idx, value := range s.SortedValues {
 		if len(value.OrderBy) == 0 {
 			continue
 		}
 		target := value.OrderBy[len(value.OrderBy)-1].Value
-		cond, err := rangeValue.LTE(target)
+		if target == nil {
+			continue
+		}
+		var (
+			cond bool
+			err  error
+		)
+		if isAsc {
+			cond, err = target.GTE(rangeValue)
+		} else {
+			cond, err = target.LTE(rangeValue)
+		}
 		if err != nil {
 			return 0, err
 		}
 		if cond {
-			minIndex = idx
+			return idx, nil
 		}
 	}
-	return minIndex, nil
+	return len(s.SortedValues), nil
 }
 
-func (s *WindowFuncAggregatedStatus) lookupMaxIndexFromRangeValue(rangeValue Value) (int, error) {
-	maxIndex := -1
-	for idx := 0; idx < len(s.SortedValues); idx++ {
+// lookupMaxIndexFromRangeValue returns the last index of the row not ordered after rangeValue.
+// If there is no such row, returns -1.
+func (s *WindowFuncAggregatedStatus) lookupMaxIndexFromRangeValue(rangeValue Value, isAsc bool) (int, error) {
+	for idx := len(s.SortedValues) - 1; idx >= 0; idx-- {
 		value := s.SortedValues[idx]
 		if len(value.OrderBy) == 0 {
 			continue
 		}
 		target := value.OrderBy[len(value.OrderBy)-1].Value
-		cond, err := rangeValue.GTE(target)
+		if target == nil {
+			continue
+		}
+		var (
+			cond bool
+			err  error
+		)
+		if isAsc {
+			cond, err = target.LTE(rangeValue)
+		} else {
+			cond, err = target.GTE(rangeValue)
+		}
 		if err != nil {
 			return 0, err
 		}
 		if cond {
-			maxIndex = idx
+			return idx, nil
 		}
 	}
-	return maxIndex, nil
+	return -1, nil
 }
@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-zetasql"
+	parsed_ast "github.com/goccy/go-zetasql/ast"
+)
+
+// groupByAllClause is the location of GROUP BY ALL in the query.
+type groupByAllClause struct {
+	start    int
+	allStart int
+}
+
+func (c *groupByAllClause) end() int {
+	return c.allStart + len("ALL")
+}
+
+// rewriteGroupByAll replaces GROUP BY ALL of the query by GROUP BY the ordinals of the select list items to be grouped,
+// because the parser of ZetaSQL doesn't support GROUP BY ALL.
+// The items containing aggregate or analytic function calls and the items referring no columns ( e.g. constants and query parameters ) are not grouped.
+// If no item is grouped, the clause is removed to aggregate all rows.
+func rewriteGroupByAll(query string, opt *zetasql.ParserOptions) (string, error) {
+	clauses := scanGroupByAllClauses(query)
+	if len(clauses) == 0 {
+		return query, nil
+	}
+	// ALL is replaced by the ordinal of the same length to parse the query with keeping the locations of the nodes.
+	placeholder := []byte(query)
+	clauseByOffset := make(map[int]*groupByAllClause, len(clauses))
+	for _, clause := range clauses {
+		copy(placeholder[clause.allStart:], "1  ")
+		clauseByOffset[clause.allStart] = clause
+	}
+	stmts, err := parseScriptStatements(string(placeholder), opt)
+	if err != nil {
+		return "", err
+	}
+	replacements := make(map[int]string, len(clauses))
+	for _, stmt := range stmts {
+		if err := parsed_ast.Walk(stmt, func(n parsed_ast.Node) error {
+			sel, ok := n.(*parsed_ast.SelectNode)
+			if !ok || sel.GroupBy() == nil {
+				return nil
+			}
+			for _, item := range sel.GroupBy().GroupingItems() {
+				if item.Expression() == nil {
+					continue
+				}
+				loc := item.Expression().ParseLocationRange()
+				if loc == nil {
+					continue
+				}
+				if _, exists := clauseByOffset[loc.Start().ByteOffset()]; !exists {
+					continue
+				}
+				if len(sel.GroupBy().GroupingItems()) != 1 {
+					return fmt.Errorf("GROUP BY ALL cannot be used with other grouping items")
+				}
+				ordinals, err := groupByAllOrdinals(sel)
+				if err != nil {
+					return err
+				}
+				if len(ordinals) == 0 {
+					replacements[loc.Start().ByteOffset()] = ""
+				} else {
+					replacements[loc.Start().ByteOffset()] = fmt.Sprintf("GROUP BY %s", strings.Join(ordinals, ", "))
+				}
+			}
+			return nil
+		}); err != nil {
+			return "", err
+		}
+	}
+	sort.Slice(clauses, func(i, j int) bool { return clauses[i].start > clauses[j].start })
+	for _, clause := range clauses {
+		replacement, exists := replacements[clause.allStart]
+		if !exists {
+			// GROUP BY ALL appears at the place other than GROUP BY clause of SELECT ( e.g. the window specification ).
+			continue
+		}
+		query = query[:clause.start] + replacement + query[clause.end():]
+	}
+	return query, nil
+}
+
+// scanGroupByAllClauses returns the locations of GROUP BY ALL by skipping the comments, the string literals and the quoted identifiers.
+func scanGroupByAllClauses(query string) []*groupByAllClause {
+	var clauses []*groupByAllClause
+	s := &withClauseScanner{src: query}
+	for {
+		s.skipSpaces()
+		if s.pos >= len(s.src) {
+			return clauses
+		}
+		switch c := s.src[s.pos]; {
+		case c == '\'' || c == '"' || c == '`':
+			if !s.skipQuoted(c) {
+				return clauses
+			}
+		case isIdentifierChar(c):
+			start := s.pos
+			if s.consumeKeyword("GROUP") {
+				s.skipSpaces()
+				if !s.consumeKeyword("BY") {
+					continue
+				}
+				s.skipSpaces()
+				if s.hasKeyword("ALL") {
+					clauses = append(clauses, &groupByAllClause{start: start, allStart: s.pos})
+					s.pos += len("ALL")
+				}
+				continue
+			}
+			for s.pos < len(s.src) && isIdentifierChar(s.src[s.pos]) {
+				s.pos++
+			}
+		default:
+			s.pos++
+		}
+	}
+}
+
+// groupByAllOrdinals returns the ordinals of the select list items grouped by GROUP BY ALL.
+func groupByAllOrdinals(sel *parsed_ast.SelectNode) ([]string, error) {
+	var ordinals []string
+	for idx, column := range sel.SelectList().Columns() {
+		switch column.Expression().(type) {
+		case *parsed_ast.StarNode,
+			*parsed_ast.StarWithModifiersNode,
+			*parsed_ast.DotStarNode,
+			*parsed_ast.DotStarWithModifiersNode:
+			return nil, fmt.Errorf("GROUP BY ALL is unsupported with SELECT *")
+		}
+		refersColumn, aggregated := inspectGroupByAllExpr(column.Expression())
+		if refersColumn && !aggregated {
+			ordinals = append(ordinals, fmt.Sprint(idx+1))
+		}
+	}
+	return ordinals, nil
+}
+
+// inspectGroupByAllExpr returns whether the expression refers the columns and whether it contains aggregate or analytic function calls.
+// The subqueries are not inspected because they are evaluated for each group.
+func inspectGroupByAllExpr(node parsed_ast.Node) (bool, bool) {
+	switch n := node.(type) {
+	case nil, *parsed_ast.ExpressionSubqueryNode:
+		return false, false
+	case *parsed_ast.AnalyticFunctionCallNode:
+		return false, true
+	case *parsed_ast.PathExpressionNode:
+		return true, false
+	case *parsed_ast.FunctionCallNode:
+		if isAggregateFunctionName(n.Function()) {
+			return false, true
+		}
+		var refersColumn bool
+		for _, arg := range n.Arguments() {
+			argRefersColumn, aggregated := inspectGroupByAllExpr(arg)
+			if aggregated {
+				return false, true
+			}
+			refersColumn = refersColumn || argRefersColumn
+		}
+		return refersColumn, false
+	}
+	if node.IsType() {
+		// the type name of CAST is a path expression, but it doesn't refer the column.
+		return false, false
+	}
+	var refersColumn bool
+	for i := 0; i < node.NumChildren(); i++ {
+		childRefersColumn, aggregated := inspectGroupByAllExpr(node.Child(i))
+		if aggregated {
+			return false, true
+		}
+		refersColumn = refersColumn || childRefersColumn
+	}
+	return refersColumn, false
+}
+
+func isAggregateFunctionName(path *parsed_ast.PathExpressionNode) bool {
+	names := make([]string, 0, len(path.Names()))
+	for _, name := range path.Names() {
+		names = append(names, strings.ToLower(name.Name()))
+	}
+	// ARRAY is registered as the aggregate function for ARRAY subquery.
+	name := strings.Join(names, "_")
+	if name == "array" {
+		return false
+	}
+	for _, info := range aggregateFuncs {
+		if info.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,426 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-zetasql/types"
+)
+
+const (
+	informationSchemaName             = "INFORMATION_SCHEMA"
+	tablesViewName                    = "TABLES"
+	tableOptionsViewName              = "TABLE_OPTIONS"
+	objectPrivilegesViewName          = "OBJECT_PRIVILEGES"
+	rowAccessPoliciesViewName         = "ROW_ACCESS_POLICIES"
+	columnsViewName                   = "COLUMNS"
+	partitionsViewName                = "PARTITIONS"
+	informationSchemaTableAliasPrefix = "zetasqlite_information_schema_"
+	informationSchemaObjectTypeTable  = "TABLE"
+	informationSchemaObjectTypeView   = "VIEW"
+	informationSchemaTableTypeTable   = "BASE TABLE"
+)
+
+type informationSchemaColumn struct {
+	name string
+	typ  types.Type
+}
+
+// informationSchemaViews is the columns of the supported INFORMATION_SCHEMA views.
+var informationSchemaViews = map[string][]*informationSchemaColumn{
+	tablesViewName: {
+		{name: "table_catalog", typ: types.StringType()},
+		{name: "table_schema", typ: types.StringType()},
+		{name: "table_name", typ: types.StringType()},
+		{name: "table_type", typ: types.StringType()},
+		{name: "is_insertable_into", typ: types.StringType()},
+		{name: "creation_time", typ: types.TimestampType()},
+	},
+	tableOptionsViewName: {
+		{name: "table_catalog", typ: types.StringType()},
+		{name: "table_schema", typ: types.StringType()},
+		{name: "table_name", typ: types.StringType()},
+		{name: "option_name", typ: types.StringType()},
+		{name: "option_type", typ: types.StringType()},
+		{name: "option_value", typ: types.StringType()},
+	},
+	objectPrivilegesViewName: {
+		{name: "object_catalog", typ: types.StringType()},
+		{name: "object_schema", typ: types.StringType()},
+		{name: "object_name", typ: types.StringType()},
+		{name: "object_type", typ: types.StringType()},
+		{name: "privilege_type", typ: types.StringType()},
+		{name: "grantee", typ: types.StringType()},
+	},
+	rowAccessPoliciesViewName: {
+		{name: "table_catalog", typ: types.StringType()},
+		{name: "table_schema", typ: types.StringType()},
+		{name: "table_name", typ: types.StringType()},
+		{name: "row_access_policy_name", typ: types.StringType()},
+		{name: "filter_predicate", typ: types.StringType()},
+		{name: "creation_time", typ: types.TimestampType()},
+		{name: "last_modified_time", typ: types.TimestampType()},
+	},
+	columnsViewName: {
+		{name: "table_catalog", typ: types.StringType()},
+		{name: "table_schema", typ: types.StringType()},
+		{name: "table_name", typ: types.StringType()},
+		{name: "column_name", typ: types.StringType()},
+		{name: "ordinal_position", typ: types.Int64Type()},
+		{name: "is_nullable", typ: types.StringType()},
+		{name: "data_type", typ: types.StringType()},
+		{name: "is_partitioning_column", typ: types.StringType()},
+		{name: "clustering_ordinal_position", typ: types.Int64Type()},
+	},
+	partitionsViewName: {
+		{name: "table_catalog", typ: types.StringType()},
+		{name: "table_schema", typ: types.StringType()},
+		{name: "table_name", typ: types.StringType()},
+		{name: "partition_id", typ: types.StringType()},
+		{name: "total_rows", typ: types.Int64Type()},
+		{name: "last_modified_time", typ: types.TimestampType()},
+	},
+}
+
+func (c *Catalog) informationSchemaViewName(path []string) string {
+	normalizedPath := splitPath(path)
+	if len(normalizedPath) < 2 {
+		return ""
+	}
+	if !strings.EqualFold(normalizedPath[len(normalizedPath)-2], informationSchemaName) {
+		return ""
+	}
+	viewName := strings.ToUpper(normalizedPath[len(normalizedPath)-1])
+	if isJobsView(viewName) {
+		return viewName
+	}
+	if _, exists := informationSchemaViews[viewName]; !exists {
+		return ""
+	}
+	return viewName
+}
+
+func (c *Catalog) isInformationSchemaTable(path []string) bool {
+	return c.informationSchemaViewName(path) != ""
+}
+
+// isRegionQualifier reports whether the path segment is the location qualifier of INFORMATION_SCHEMA ( e.g. `region-us` ).
+func isRegionQualifier(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), "region-")
+}
+
+// InformationSchemaTable is the INFORMATION_SCHEMA view built from the table specs.
+// queries are the queries computing the rows from the tables on demand ( e.g. the row counts of the partitions ).
+// The rows of JOBS view are read from jobs when the query is formatted,
+// because the analyzed statement referring to the view may be cached while the jobs are recorded.
+type InformationSchemaTable struct {
+	name      string
+	viewName  string
+	columns   []*informationSchemaColumn
+	rows      [][]interface{}
+	queries   []string
+	jobs      *jobLog
+	projectID string
+}
+
+func (c *Catalog) createInformationSchemaTable(path []string) (types.Table, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	normalizedPath := splitPath(path)
+	prefix := normalizedPath[:len(normalizedPath)-2]
+	// the view qualified by the region ( e.g. `region-us`.INFORMATION_SCHEMA.TABLES ) covers all datasets of the project,
+	// so the region is removed and the rest of the prefix is compared with the beginning of the table path.
+	isRegionQualified := len(prefix) > 0 && isRegionQualifier(prefix[len(prefix)-1])
+	if isRegionQualified {
+		prefix = prefix[:len(prefix)-1]
+	}
+	if viewName := c.informationSchemaViewName(path); isJobsView(viewName) {
+		return c.createJobsTable(normalizedPath, viewName, prefix)
+	}
+	specs := make([]*TableSpec, 0, len(c.tableMap))
+	for _, spec := range c.tableMap {
+		namePath := splitPath(spec.NamePath)
+		schemaPath := namePath[:len(namePath)-1]
+		if len(schemaPath) < len(prefix) {
+			continue
+		}
+		matchedPath := schemaPath[len(schemaPath)-len(prefix):]
+		if isRegionQualified {
+			matchedPath = schemaPath[:len(prefix)]
+		}
+		if strings.Join(matchedPath, ".") != strings.Join(prefix, ".") {
+			continue
+		}
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].TableName() < specs[j].TableName()
+	})
+	viewName := c.informationSchemaViewName(path)
+	columns := informationSchemaViews[viewName]
+	var (
+		rows    [][]interface{}
+		queries []string
+	)
+	for _, spec := range specs {
+		namePath := splitPath(spec.NamePath)
+		var catalogName, schemaName interface{}
+		if len(namePath) >= 3 {
+			catalogName = namePath[len(namePath)-3]
+		}
+		if len(namePath) >= 2 {
+			schemaName = namePath[len(namePath)-2]
+		}
+		tableName := namePath[len(namePath)-1]
+		switch viewName {
+		case tablesViewName:
+			tableType, isInsertableInto := informationSchemaTableTypeTable, "YES"
+			if spec.IsView {
+				tableType, isInsertableInto = informationSchemaObjectTypeView, "NO"
+			}
+			rows = append(rows, []interface{}{
+				catalogName,
+				schemaName,
+				tableName,
+				tableType,
+				isInsertableInto,
+				spec.CreatedAt,
+			})
+		case tableOptionsViewName:
+			for _, option := range spec.Options {
+				rows = append(rows, []interface{}{
+					catalogName,
+					schemaName,
+					tableName,
+					option.Name,
+					option.Type,
+					option.Value,
+				})
+			}
+		case objectPrivilegesViewName:
+			objectType := informationSchemaObjectTypeTable
+			if spec.IsView {
+				objectType = informationSchemaObjectTypeView
+			}
+			for _, grant := range spec.Grants {
+				rows = append(rows, []interface{}{
+					catalogName,
+					schemaName,
+					tableName,
+					objectType,
+					grant.Privilege,
+					grant.Grantee,
+				})
+			}
+		case rowAccessPoliciesViewName:
+			for _, policy := range spec.RowAccessPolicies {
+				rows = append(rows, []interface{}{
+					catalogName,
+					schemaName,
+					tableName,
+					policy.Name,
+					policy.FilterPredicate,
+					policy.CreatedAt,
+					policy.UpdatedAt,
+				})
+			}
+		case columnsViewName:
+			for idx, column := range spec.Columns {
+				isNullable := "YES"
+				if column.IsNotNull {
+					isNullable = "NO"
+				}
+				isPartitioningColumn := "NO"
+				if spec.Partition != nil && strings.EqualFold(spec.Partition.Column, column.Name) {
+					isPartitioningColumn = "YES"
+				}
+				var clusteringPosition interface{}
+				for pos, name := range spec.ClusterBy {
+					if strings.EqualFold(name, column.Name) {
+						clusteringPosition = int64(pos + 1)
+						break
+					}
+				}
+				rows = append(rows, []interface{}{
+					catalogName,
+					schemaName,
+					tableName,
+					column.Name,
+					int64(idx + 1),
+					isNullable,
+					column.Type.FormatType(),
+					isPartitioningColumn,
+					clusteringPosition,
+				})
+			}
+		case partitionsViewName:
+			if spec.IsView {
+				continue
+			}
+			query, err := partitionsQuery(columns, spec, catalogName, schemaName, tableName)
+			if err != nil {
+				return nil, err
+			}
+			queries = append(queries, query)
+		}
+	}
+	return &InformationSchemaTable{
+		name:     strings.Join(normalizedPath, "."),
+		viewName: viewName,
+		columns:  columns,
+		rows:     rows,
+		queries:  queries,
+	}, nil
+}
+
+// partitionsQuery returns the query to count the rows of each partition of the table.
+// The table without partitioning is reported as a single partition whose id is NULL.
+func partitionsQuery(columns []*informationSchemaColumn, spec *TableSpec, catalogName, schemaName, tableName interface{}) (string, error) {
+	partitionID := "NULL"
+	if spec.Partition != nil {
+		partitionID = spec.Partition.partitionIDQuery()
+	}
+	formattedColumns := make([]string, 0, len(columns))
+	for idx, value := range []interface{}{catalogName, schemaName, tableName} {
+		formatted, err := formatInformationSchemaValue(columns[idx], value)
+		if err != nil {
+			return "", err
+		}
+		formattedColumns = append(formattedColumns, formatted)
+	}
+	lastModifiedTime, err := formatInformationSchemaValue(columns[5], spec.UpdatedAt)
+	if err != nil {
+		return "", err
+	}
+	formattedColumns = append(formattedColumns, "`partition_id`", "COUNT(*) AS `total_rows`", lastModifiedTime)
+	return fmt.Sprintf(
+		"SELECT %s FROM (SELECT %s AS `partition_id` FROM `%s`) GROUP BY `partition_id`",
+		strings.Join(formattedColumns, ","),
+		partitionID,
+		spec.TableName(),
+	), nil
+}
+
+// createJobsTable creates JOBS view. If the project is specified as prefix, only the jobs of the project are listed.
+func (c *Catalog) createJobsTable(normalizedPath []string, viewName string, prefix []string) (types.Table, error) {
+	columns, err := jobsViewColumns()
+	if err != nil {
+		return nil, err
+	}
+	var projectID string
+	if len(prefix) != 0 {
+		projectID = prefix[0]
+	}
+	return &InformationSchemaTable{
+		name:      strings.Join(normalizedPath, "."),
+		viewName:  viewName,
+		columns:   columns,
+		jobs:      c.jobs,
+		projectID: projectID,
+	}, nil
+}
+
+func (t *InformationSchemaTable) FormatSQL(ctx context.Context) (string, error) {
+	rows := t.rows
+	if t.jobs != nil {
+		rows = t.jobs.rows(t.projectID)
+	}
+	if len(rows) == 0 && len(t.queries) == 0 {
+		columns := make([]string, 0, len(t.columns))
+		for _, column := range t.columns {
+			columns = append(columns, fmt.Sprintf("NULL AS `%s`", column.name))
+		}
+		return fmt.Sprintf("SELECT %s LIMIT 0", strings.Join(columns, ",")), nil
+	}
+	queries := make([]string, 0, len(rows)+len(t.queries))
+	for _, row := range rows {
+		columns := make([]string, 0, len(row))
+		for idx, value := range row {
+			column, err := formatInformationSchemaValue(t.columns[idx], value)
+			if err != nil {
+				return "", err
+			}
+			columns = append(columns, column)
+		}
+		queries = append(queries, fmt.Sprintf("SELECT %s", strings.Join(columns, ",")))
+	}
+	queries = append(queries, t.queries...)
+	return strings.Join(queries, " UNION ALL "), nil
+}
+
+func formatInformationSchemaValue(column *informationSchemaColumn, value interface{}) (string, error) {
+	if value == nil {
+		return fmt.Sprintf("NULL AS `%s`", column.name), nil
+	}
+	if tv, ok := value.(time.Time); ok && tv.IsZero() {
+		return fmt.Sprintf("NULL AS `%s`", column.name), nil
+	}
+	encoded, err := EncodeGoValue(column.typ, value)
+	if err != nil {
+		return "", err
+	}
+	switch v := encoded.(type) {
+	case string:
+		return fmt.Sprintf("'%s' AS `%s`", v, column.name), nil
+	default:
+		return fmt.Sprintf("%v AS `%s`", v, column.name), nil
+	}
+}
+
+func (t *InformationSchemaTable) Name() string {
+	return t.name
+}
+
+func (t *InformationSchemaTable) FullName() string {
+	return informationSchemaTableAliasPrefix + strings.ToLower(t.viewName)
+}
+
+func (t *InformationSchemaTable) NumColumns() int {
+	return len(t.columns)
+}
+
+func (t *InformationSchemaTable) Column(idx int) types.Column {
+	column := t.columns[idx]
+	return types.NewSimpleColumn(t.name, column.name, column.typ)
+}
+
+func (t *InformationSchemaTable) PrimaryKey() []int {
+	return nil
+}
+
+func (t *InformationSchemaTable) FindColumnByName(name string) types.Column {
+	for _, column := range t.columns {
+		if strings.EqualFold(column.name, name) {
+			return types.NewSimpleColumn(t.name, column.name, column.typ)
+		}
+	}
+	return nil
+}
+
+func (t *InformationSchemaTable) IsValueTable() bool {
+	return false
+}
+
+func (t *InformationSchemaTable) SerializationID() int64 {
+	return 0
+}
+
+func (t *InformationSchemaTable) CreateEvaluatorTableIterator(columnIdxs []int) (*types.EvaluatorTableIterator, error) {
+	return nil, nil
+}
+
+func (t *InformationSchemaTable) AnonymizationInfo() *types.AnonymizationInfo {
+	return nil
+}
+
+func (t *InformationSchemaTable) SupportsAnonymization() bool {
+	return false
+}
+
+func (t *InformationSchemaTable) TableTypeName(mode types.ProductMode) string {
+	return ""
+}
@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+)
+
+const insertValuesSavepointName = "zetasqlite_insert_values"
+
+// insertValues is INSERT ... VALUES statement whose values are all literals.
+// The rows are inserted by the batches bound as parameters instead of the formatted SQL that inlines all literals,
+// because formatting and parsing the huge statement are slow and it exceeds the limits of SQLite for many rows.
+type insertValues struct {
+	table       string
+	columnNames []string
+	rows        [][]Value
+}
+
+// newInsertValues returns nil if the statement has less than two rows or any value isn't literal,
+// then the statement is executed by the formatted SQL.
+func newInsertValues(ctx context.Context, node *ast.InsertStmtNode) (*insertValues, error) {
+	if node.Query() != nil {
+		return nil, nil
+	}
+	rowList := node.RowList()
+	if len(rowList) < 2 {
+		return nil, nil
+	}
+	rows := make([][]Value, 0, len(rowList))
+	for _, row := range rowList {
+		values := make([]Value, 0, len(row.ValueList()))
+		for _, dmlValue := range row.ValueList() {
+			literal, ok := dmlValue.Value().(*ast.LiteralNode)
+			if !ok {
+				return nil, nil
+			}
+			value, err := ValueFromZetaSQLValue(literal.Value())
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		rows = append(rows, values)
+	}
+	table, err := getTableName(ctx, node.TableScan())
+	if err != nil {
+		return nil, err
+	}
+	columnNames := make([]string, 0, len(node.InsertColumnList()))
+	for _, col := range node.InsertColumnList() {
+		columnNames = append(columnNames, fmt.Sprintf("`%s`", col.Name()))
+	}
+	if len(columnNames) == 0 {
+		return nil, nil
+	}
+	return &insertValues{
+		table:       table,
+		columnNames: columnNames,
+		rows:        rows,
+	}, nil
+}
+
+// exec inserts the rows in the savepoint, so that no row is inserted if any batch fails like the single statement.
+func (v *insertValues) exec(ctx context.Context, conn *Conn) (sql.Result, error) {
+	result := &insertValuesResult{}
+	if err := conn.withSavepoint(ctx, insertValuesSavepointName, func() error {
+		batchRows := bulkInsertBatchRows(len(v.columnNames))
+		var batchStmt *sql.Stmt
+		defer func() {
+			if batchStmt != nil {
+				batchStmt.Close()
+			}
+		}()
+		for start := 0; start < len(v.rows); start += batchRows {
+			end := start + batchRows
+			if end > len(v.rows) {
+				end = len(v.rows)
+			}
+			args, err := v.encodeRows(v.rows[start:end])
+			if err != nil {
+				return err
+			}
+			var r sql.Result
+			if end-start == batchRows {
+				if batchStmt == nil {
+					stmt, err := conn.PrepareContext(ctx, v.query(batchRows))
+					if err != nil {
+						return fmt.Errorf("failed to prepare %s: %w", v.query(batchRows), err)
+					}
+					batchStmt = stmt
+				}
+				r, err = batchStmt.ExecContext(ctx, args...)
+			} else {
+				r, err = conn.ExecContext(ctx, v.query(end-start), args...)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to exec %s: %w", v.query(end-start), translateConstraintError(err))
+			}
+			if err := result.add(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (v *insertValues) encodeRows(rows [][]Value) ([]interface{}, error) {
+	args := make([]interface{}, 0, len(rows)*len(v.columnNames))
+	for _, row := range rows {
+		for _, value := range row {
+			encoded, err := EncodeValue(value)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, encoded)
+		}
+	}
+	return args, nil
+}
+
+func (v *insertValues) query(rowNum int) string {
+	return bulkInsertQuery(v.table, v.columnNames, rowNum)
+}
+
+// formatSQL returns the statement inlining all values as literals. It is used only to prepare the statement.
+func (v *insertValues) formatSQL() (string, error) {
+	rows := make([]string, 0, len(v.rows))
+	for _, row := range v.rows {
+		literals := make([]string, 0, len(row))
+		for _, value := range row {
+			literal, err := LiteralFromValue(value)
+			if err != nil {
+				return "", err
+			}
+			literals = append(literals, literal)
+		}
+		rows = append(rows, fmt.Sprintf("(%s)", strings.Join(literals, ",")))
+	}
+	return fmt.Sprintf(
+		"INSERT INTO `%s` (%s) VALUES %s",
+		v.table,
+		strings.Join(v.columnNames, ","),
+		strings.Join(rows, ","),
+	), nil
+}
+
+// insertValuesResult is the sum of the results of the batches.
+type insertValuesResult struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r *insertValuesResult) add(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	r.rowsAffected += n
+	r.lastInsertID = id
+	return nil
+}
+
+func (r *insertValuesResult) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+func (r *insertValuesResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
@@ -0,0 +1,424 @@
+package internal
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	parsed_ast "github.com/goccy/go-zetasql/ast"
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+	"github.com/goccy/go-zetasql/types"
+)
+
+const (
+	jobsViewName               = "JOBS"
+	jobsByProjectViewName      = "JOBS_BY_PROJECT"
+	defaultMaxRecordedJobs     = 1000
+	jobStatementTypeScript     = "SCRIPT"
+	queryLabelSystemVariable   = "query_label"
+	jobIDPrefix                = "zetasqlite_job_"
+	jobTypeQuery               = "QUERY"
+	jobStateDone               = "DONE"
+	jobErrorReasonInvalidQuery = "invalidQuery"
+	jobLabelKeyValueSeparator  = ":"
+)
+
+// jobLabel is the label set by @@query_label ( e.g. SET @@query_label = "team:audit,env:test" ).
+type jobLabel struct {
+	key   string
+	value string
+}
+
+// job is the record of the statements executed by a single Exec/Query call like the query job of BigQuery.
+type job struct {
+	id               string
+	projectID        string
+	userEmail        string
+	query            string
+	statementType    string
+	labels           []*jobLabel
+	priority         string
+	referencedTables [][]string
+	createdAt        time.Time
+	endedAt          time.Time
+	errorMessage     string
+}
+
+func newJob(query string, numStatements int) *job {
+	j := &job{query: query, createdAt: time.Now()}
+	if numStatements > 1 {
+		j.statementType = jobStatementTypeScript
+	}
+	return j
+}
+
+// setStatementType sets the statement type of the job executing a single statement.
+func (j *job) setStatementType(node ast.StatementNode) {
+	if j == nil || j.statementType == jobStatementTypeScript {
+		return
+	}
+	j.statementType = statementTypeFromNode(node)
+}
+
+// setScript marks the job as the script because the statement runs the other statements ( e.g. FOR ... IN ).
+func (j *job) setScript() {
+	if j == nil {
+		return
+	}
+	j.statementType = jobStatementTypeScript
+}
+
+// statementTypeFromNode returns the statement type reported by the JOBS view of BigQuery.
+func statementTypeFromNode(node ast.StatementNode) string {
+	switch n := node.(type) {
+	case *ast.QueryStmtNode:
+		return "SELECT"
+	case *ast.InsertStmtNode:
+		return "INSERT"
+	case *ast.UpdateStmtNode:
+		return "UPDATE"
+	case *ast.DeleteStmtNode:
+		return "DELETE"
+	case *ast.MergeStmtNode:
+		return "MERGE"
+	case *ast.TruncateStmtNode:
+		return "TRUNCATE_TABLE"
+	case *ast.CreateTableStmtNode:
+		return "CREATE_TABLE"
+	case *ast.CreateTableAsSelectStmtNode:
+		return "CREATE_TABLE_AS_SELECT"
+	case *ast.CreateViewStmtNode:
+		return "CREATE_VIEW"
+	case *ast.CreateFunctionStmtNode:
+		return "CREATE_FUNCTION"
+	case *ast.DropStmtNode:
+		return fmt.Sprintf("DROP_%s", strings.ToUpper(n.ObjectType()))
+	case *ast.DropFunctionStmtNode:
+		return "DROP_FUNCTION"
+	case *ast.AlterTableStmtNode, *ast.AlterTableSetOptionsStmtNode, *ast.RenameStmtNode:
+		return "ALTER_TABLE"
+	case *ast.AlterViewStmtNode:
+		return "ALTER_VIEW"
+	case *ast.AlterMaterializedViewStmtNode:
+		return "ALTER_MATERIALIZED_VIEW"
+	case *ast.GrantStmtNode:
+		return "GRANT"
+	case *ast.RevokeStmtNode:
+		return "REVOKE"
+	case *ast.CreateRowAccessPolicyStmtNode:
+		return "CREATE_ROW_ACCESS_POLICY"
+	case *ast.DropRowAccessPolicyStmtNode:
+		return "DROP_ROW_ACCESS_POLICY"
+	case *ast.AlterRowAccessPolicyStmtNode:
+		return "ALTER_ROW_ACCESS_POLICY"
+	case *ast.AlterAllRowAccessPoliciesStmtNode:
+		return "DROP_ALL_ROW_ACCESS_POLICIES"
+	case *ast.BeginStmtNode:
+		return "BEGIN_TRANSACTION"
+	case *ast.CommitStmtNode:
+		return "COMMIT_TRANSACTION"
+	}
+	return ""
+}
+
+// jobLog is the jobs recorded by all connections to the same database.
+// The oldest jobs are discarded when the number of the jobs exceeds maxJobs.
+type jobLog struct {
+	mu        sync.Mutex
+	isEnabled bool
+	maxJobs   int
+	jobs      []*job
+	lastID    int64
+}
+
+func newJobLog() *jobLog {
+	return &jobLog{maxJobs: defaultMaxRecordedJobs}
+}
+
+func (l *jobLog) setEnabled(enabled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.isEnabled = enabled
+}
+
+func (l *jobLog) enabled() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.isEnabled
+}
+
+func (l *jobLog) setMaxJobs(num int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxJobs = num
+	l.discardOldJobs()
+}
+
+func (l *jobLog) add(j *job) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastID++
+	j.id = fmt.Sprintf("%s%d", jobIDPrefix, l.lastID)
+	l.jobs = append(l.jobs, j)
+	l.discardOldJobs()
+}
+
+func (l *jobLog) discardOldJobs() {
+	if l.maxJobs <= 0 || len(l.jobs) <= l.maxJobs {
+		return
+	}
+	l.jobs = append([]*job{}, l.jobs[len(l.jobs)-l.maxJobs:]...)
+}
+
+// rows returns the rows of JOBS view. If projectID is not empty, only the jobs of the project are returned.
+func (l *jobLog) rows(projectID string) [][]interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var rows [][]interface{}
+	for _, j := range l.jobs {
+		if projectID != "" && j.projectID != projectID {
+			continue
+		}
+		rows = append(rows, j.row())
+	}
+	return rows
+}
+
+func (j *job) row() []interface{} {
+	referencedTables := make([]map[string]interface{}, 0, len(j.referencedTables))
+	for _, path := range j.referencedTables {
+		table := map[string]interface{}{"project_id": nil, "dataset_id": nil, "table_id": path[len(path)-1]}
+		if len(path) >= 2 {
+			table["dataset_id"] = path[len(path)-2]
+		}
+		if len(path) >= 3 {
+			table["project_id"] = path[len(path)-3]
+		}
+		referencedTables = append(referencedTables, table)
+	}
+	labels := make([]map[string]interface{}, 0, len(j.labels))
+	for _, label := range j.labels {
+		labels = append(labels, map[string]interface{}{"key": label.key, "value": label.value})
+	}
+	var (
+		projectID, userEmail, statementType interface{}
+		errorResult                         interface{}
+	)
+	if j.projectID != "" {
+		projectID = j.projectID
+	}
+	if j.userEmail != "" {
+		userEmail = j.userEmail
+	}
+	if j.statementType != "" {
+		statementType = j.statementType
+	}
+	if j.errorMessage != "" {
+		errorResult = map[string]interface{}{"reason": jobErrorReasonInvalidQuery, "message": j.errorMessage}
+	}
+	return []interface{}{
+		j.createdAt,
+		projectID,
+		userEmail,
+		j.id,
+		jobTypeQuery,
+		statementType,
+		j.priority,
+		j.createdAt,
+		j.endedAt,
+		j.query,
+		jobStateDone,
+		referencedTables,
+		labels,
+		errorResult,
+	}
+}
+
+func isJobsView(viewName string) bool {
+	return viewName == jobsViewName || viewName == jobsByProjectViewName
+}
+
+// jobsViewColumns returns the columns of JOBS view. The columns are the subset of the documented ones of BigQuery.
+func jobsViewColumns() ([]*informationSchemaColumn, error) {
+	referencedTablesType, err := newStructArrayType("project_id", "dataset_id", "table_id")
+	if err != nil {
+		return nil, err
+	}
+	labelsType, err := newStructArrayType("key", "value")
+	if err != nil {
+		return nil, err
+	}
+	errorResultType, err := types.NewStructType([]*types.StructField{
+		types.NewStructField("reason", types.StringType()),
+		types.NewStructField("message", types.StringType()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create type of error_result: %w", err)
+	}
+	return []*informationSchemaColumn{
+		{name: "creation_time", typ: types.TimestampType()},
+		{name: "project_id", typ: types.StringType()},
+		{name: "user_email", typ: types.StringType()},
+		{name: "job_id", typ: types.StringType()},
+		{name: "job_type", typ: types.StringType()},
+		{name: "statement_type", typ: types.StringType()},
+		{name: "priority", typ: types.StringType()},
+		{name: "start_time", typ: types.TimestampType()},
+		{name: "end_time", typ: types.TimestampType()},
+		{name: "query", typ: types.StringType()},
+		{name: "state", typ: types.StringType()},
+		{name: "referenced_tables", typ: referencedTablesType},
+		{name: "labels", typ: labelsType},
+		{name: "error_result", typ: errorResultType},
+	}, nil
+}
+
+// newStructArrayType creates ARRAY<STRUCT<...>> type whose fields are all STRING.
+func newStructArrayType(fieldNames ...string) (types.Type, error) {
+	fields := make([]*types.StructField, 0, len(fieldNames))
+	for _, name := range fieldNames {
+		fields = append(fields, types.NewStructField(name, types.StringType()))
+	}
+	structType, err := types.NewStructType(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create struct type: %w", err)
+	}
+	arrayType, err := types.NewArrayType(structType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create array type: %w", err)
+	}
+	return arrayType, nil
+}
+
+func (c *Catalog) SetJobRecordingMode(enabled bool) {
+	c.jobs.setEnabled(enabled)
+}
+
+func (c *Catalog) SetMaxRecordedJobs(num int) {
+	c.jobs.setMaxJobs(num)
+}
+
+func (a *Analyzer) SetJobRecordingMode(enabled bool) {
+	a.catalog.SetJobRecordingMode(enabled)
+}
+
+func (a *Analyzer) SetMaxRecordedJobs(num int) {
+	a.catalog.SetMaxRecordedJobs(num)
+}
+
+// RecordJob records the statements executed by the Exec/Query call as a job if the job recording mode is enabled,
+// and passes the log of the call to the query logger. The labels and the priority specified by the context are applied to all statements.
+// The job is recorded even if the statements fail, and the error is reported as error_result of the JOBS view.
+func (a *Analyzer) RecordJob(ctx context.Context, conn *Conn, query string, err error) {
+	labels := a.queryLabelsFromContext(ctx)
+	priority := queryPriorityFromContext(ctx)
+	conn.stats.Labels = labelsToMap(labels)
+	conn.stats.Priority = priority
+	a.LogQuery(ctx, query, conn.stats, err)
+	if !a.catalog.jobs.enabled() {
+		return
+	}
+	j := conn.job
+	if j == nil {
+		// failed to parse the query.
+		j = newJob(query, 0)
+	}
+	if len(a.namePath.path) != 0 {
+		j.projectID = a.namePath.path[0]
+	}
+	j.userEmail = a.sessionUser
+	j.labels = labels
+	j.priority = priority
+	for _, table := range conn.stats.ReferencedTables {
+		path := []string{table.Name}
+		if spec := a.catalog.tableSpec(table.Name); spec != nil {
+			path = splitPath(spec.NamePath)
+		}
+		j.referencedTables = append(j.referencedTables, path)
+	}
+	if err != nil {
+		j.errorMessage = err.Error()
+	}
+	j.endedAt = time.Now()
+	a.catalog.jobs.add(j)
+}
+
+// SystemVariableAssignmentStmtAction sets the system variable by SET @@name = value statement.
+// Currently, @@query_label to label the jobs and @@time_zone to set the default time zone are supported.
+type SystemVariableAssignmentStmtAction struct {
+	apply func()
+}
+
+func (a *Analyzer) newSystemVariableAssignmentStmtAction(node *parsed_ast.SystemVariableAssignmentNode) (*SystemVariableAssignmentStmtAction, error) {
+	var names []string
+	for _, name := range node.SystemVariable().Path().Names() {
+		names = append(names, name.Name())
+	}
+	variable := strings.ToLower(strings.Join(names, "."))
+	if variable != queryLabelSystemVariable && variable != timeZoneSystemVariable {
+		return nil, fmt.Errorf("unsupported system variable @@%s", variable)
+	}
+	var value string
+	switch expr := node.Expression().(type) {
+	case *parsed_ast.StringLiteralNode:
+		value = expr.Value()
+	case *parsed_ast.NullLiteralNode:
+	default:
+		return nil, fmt.Errorf("@@%s must be a string literal", variable)
+	}
+	if variable == timeZoneSystemVariable {
+		if value != "" {
+			if _, err := toLocation(value); err != nil {
+				return nil, fmt.Errorf("invalid @@%s %q: %w", timeZoneSystemVariable, value, err)
+			}
+		}
+		return &SystemVariableAssignmentStmtAction{apply: func() { a.timeZone = value }}, nil
+	}
+	labels, err := parseQueryLabel(value)
+	if err != nil {
+		return nil, err
+	}
+	return &SystemVariableAssignmentStmtAction{apply: func() { a.queryLabels = labels }}, nil
+}
+
+// parseQueryLabel parses the comma separated key:value pairs ( e.g. "team:audit,env:test" ).
+func parseQueryLabel(value string) ([]*jobLabel, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var labels []*jobLabel
+	for _, pair := range strings.Split(value, ",") {
+		key, labelValue, found := strings.Cut(pair, jobLabelKeyValueSeparator)
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid @@%s %q: label must be key:value format", queryLabelSystemVariable, pair)
+		}
+		labels = append(labels, &jobLabel{key: key, value: strings.TrimSpace(labelValue)})
+	}
+	return labels, nil
+}
+
+func (a *SystemVariableAssignmentStmtAction) Prepare(ctx context.Context, conn *Conn) (driver.Stmt, error) {
+	return nil, nil
+}
+
+func (a *SystemVariableAssignmentStmtAction) ExecContext(ctx context.Context, conn *Conn) (driver.Result, error) {
+	a.apply()
+	return &Result{conn: conn}, nil
+}
+
+func (a *SystemVariableAssignmentStmtAction) QueryContext(ctx context.Context, conn *Conn) (*Rows, error) {
+	a.apply()
+	return &Rows{conn: conn}, nil
+}
+
+func (a *SystemVariableAssignmentStmtAction) Args() []interface{} {
+	return nil
+}
+
+func (a *SystemVariableAssignmentStmtAction) Cleanup(ctx context.Context, conn *Conn) error {
+	return nil
+}
@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"github.com/goccy/go-zetasql"
+)
+
+// LanguageFeature is the feature of ZetaSQL gating the syntax and the functions accepted by the analysis.
+type LanguageFeature = zetasql.LanguageFeature
+
+// defaultLanguageFeatures are the features supported by BigQuery GA which zetasqlite can run.
+var defaultLanguageFeatures = []zetasql.LanguageFeature{
+	zetasql.FeatureAnalyticFunctions,
+	zetasql.FeatureNamedArguments,
+	zetasql.FeatureNumericType,
+	zetasql.FeatureBignumericType,
+	zetasql.FeatureV13DecimalAlias,
+	zetasql.FeatureCreateTableNotNull,
+	zetasql.FeatureCheckConstraint,
+	zetasql.FeatureCreateTablePartitionBy,
+	zetasql.FeatureCreateTableClusterBy,
+	zetasql.FeatureParameterizedTypes,
+	zetasql.FeatureTablesample,
+	zetasql.FeatureTimestampNanos,
+	zetasql.FeatureV11HavingInAggregate,
+	zetasql.FeatureV11NullHandlingModifierInAggregate,
+	zetasql.FeatureV11NullHandlingModifierInAnalytic,
+	zetasql.FeatureV11OrderByCollate,
+	zetasql.FeatureV11SelectStarExceptReplace,
+	zetasql.FeatureV12SafeFunctionCall,
+	zetasql.FeatureJsonType,
+	zetasql.FeatureJsonArrayFunctions,
+	zetasql.FeatureJsonStrictNumberParsing,
+	zetasql.FeatureV13IsDistinct,
+	zetasql.FeatureV13FormatInCast,
+	zetasql.FeatureV13DateArithmetics,
+	zetasql.FeatureV11OrderByInAggregate,
+	zetasql.FeatureV11LimitInAggregate,
+	zetasql.FeatureV13DateTimeConstructors,
+	zetasql.FeatureV13ExtendedDateTimeSignatures,
+	zetasql.FeatureV12CivilTime,
+	zetasql.FeatureV12WeekWithWeekday,
+	zetasql.FeatureV12GroupByStruct,
+	zetasql.FeatureIntervalType,
+	zetasql.FeatureGroupByRollup,
+	zetasql.FeatureV13NullsFirstLastInOrderBy,
+	zetasql.FeatureV13Qualify,
+	zetasql.FeatureV13AllowDashesInTableName,
+	zetasql.FeatureGeography,
+	zetasql.FeatureV13ExtendedGeographyParsers,
+	zetasql.FeatureTemplateFunctions,
+	zetasql.FeatureV11WithOnSubquery,
+	zetasql.FeatureV13Pivot,
+	zetasql.FeatureV13Unpivot,
+	zetasql.FeatureV13FilterFields,
+}
+
+// DefaultLanguageFeatures returns the copy of the language features enabled by default.
+func DefaultLanguageFeatures() []LanguageFeature {
+	return append([]LanguageFeature{}, defaultLanguageFeatures...)
+}
+
+// LanguageFeatures returns the enabled language features.
+func (a *Analyzer) LanguageFeatures() []LanguageFeature {
+	return append([]LanguageFeature{}, a.languageFeatures...)
+}
+
+// SetLanguageFeatures replaces the enabled language features.
+// The analysis cache is cleared because the result of the analysis depends on the features.
+func (a *Analyzer) SetLanguageFeatures(features []LanguageFeature) error {
+	features = append([]LanguageFeature{}, features...)
+	opt, err := newAnalyzerOptions(features)
+	if err != nil {
+		return err
+	}
+	a.opt = opt
+	a.languageFeatures = features
+	if a.analysisCache != nil {
+		a.analysisCache = newAnalysisCache(defaultAnalysisCacheSize)
+	}
+	return nil
+}
+
+// EnableLanguageFeatures enables the language features in addition to the enabled ones.
+func (a *Analyzer) EnableLanguageFeatures(features ...LanguageFeature) error {
+	enabled := a.LanguageFeatures()
+	for _, feature := range features {
+		if !containsLanguageFeature(enabled, feature) {
+			enabled = append(enabled, feature)
+		}
+	}
+	return a.SetLanguageFeatures(enabled)
+}
+
+// DisableLanguageFeatures disables the language features.
+func (a *Analyzer) DisableLanguageFeatures(features ...LanguageFeature) error {
+	enabled := make([]LanguageFeature, 0, len(a.languageFeatures))
+	for _, feature := range a.languageFeatures {
+		if !containsLanguageFeature(features, feature) {
+			enabled = append(enabled, feature)
+		}
+	}
+	return a.SetLanguageFeatures(enabled)
+}
+
+func containsLanguageFeature(features []LanguageFeature, feature LanguageFeature) bool {
+	for _, f := range features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
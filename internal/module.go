@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+)
+
+// ModuleResolver returns the source of the module specified by name path of IMPORT MODULE statement.
+type ModuleResolver func(namePath []string) (string, error)
+
+func (a *Analyzer) SetModuleResolver(resolver ModuleResolver) {
+	a.moduleResolver = resolver
+}
+
+// newModuleAnalyzer creates the analyzer to analyze the statements in the module.
+// The module has its own catalog, so the private functions of the module are not visible from the importing session.
+func (a *Analyzer) newModuleAnalyzer(moduleName string) (*Analyzer, error) {
+	catalog, err := a.catalog.newSnapshotCatalog()
+	if err != nil {
+		return nil, err
+	}
+	analyzer, err := NewAnalyzer(catalog)
+	if err != nil {
+		return nil, err
+	}
+	if err := analyzer.SetLanguageFeatures(a.languageFeatures); err != nil {
+		return nil, err
+	}
+	analyzer.moduleResolver = a.moduleResolver
+	analyzer.importStack = append(append([]string{}, a.importStack...), moduleName)
+	return analyzer, nil
+}
+
+// importModule loads the module and returns the public functions of it.
+// The name path of returned functions is prefixed by the alias of the module.
+func (a *Analyzer) importModule(ctx context.Context, node *ast.ImportStmtNode) ([]*FunctionSpec, error) {
+	if node.ImportKind() != ast.ImportKindModule {
+		return nil, fmt.Errorf("currently IMPORT statement supports MODULE only")
+	}
+	namePath := node.NamePath()
+	functions, err := a.loadModule(ctx, namePath)
+	if err != nil {
+		return nil, err
+	}
+	aliasPath := node.AliasPath()
+	if len(aliasPath) == 0 {
+		aliasPath = namePath[len(namePath)-1:]
+	}
+	specs := make([]*FunctionSpec, 0, len(functions))
+	for _, fn := range functions {
+		path := append(append([]string{}, aliasPath...), fn.NamePath...)
+		spec := a.catalog.copyFunctionSpec(fn, a.namePath.mergePath(path))
+		spec.IsTemp = true
+		spec.Options = fn.Options
+		spec.Description = fn.Description
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+func (a *Analyzer) loadModule(ctx context.Context, namePath []string) ([]*FunctionSpec, error) {
+	moduleName := strings.Join(namePath, ".")
+	if a.moduleResolver == nil {
+		return nil, fmt.Errorf("failed to import module %s: module resolver is not set", moduleName)
+	}
+	for idx, imported := range a.importStack {
+		if strings.EqualFold(imported, moduleName) {
+			cycle := append(append([]string{}, a.importStack[idx:]...), moduleName)
+			return nil, fmt.Errorf("circular module import detected: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	source, err := a.moduleResolver(namePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve module %s: %w", moduleName, err)
+	}
+	analyzer, err := a.newModuleAnalyzer(moduleName)
+	if err != nil {
+		return nil, err
+	}
+	functions, err := analyzer.analyzeModule(ctx, namePath, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load module %s: %w", moduleName, err)
+	}
+	return functions, nil
+}
+
+// analyzeModule analyzes the module source and returns the public functions defined in it.
+// The module source must begin with MODULE statement and consists of IMPORT and CREATE FUNCTION statements.
+func (a *Analyzer) analyzeModule(ctx context.Context, namePath []string, source string) ([]*FunctionSpec, error) {
+	stmts, err := a.parseScript(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse statements: %w", err)
+	}
+	var functions []*FunctionSpec
+	for idx, stmt := range stmts {
+		node, _, err := a.analyzeStmt(source, stmt, nil)
+		if err != nil {
+			return nil, err
+		}
+		if idx == 0 {
+			moduleStmt, ok := node.(*ast.ModuleStmtNode)
+			if !ok {
+				return nil, fmt.Errorf("module must begin with MODULE statement")
+			}
+			if !strings.EqualFold(strings.Join(moduleStmt.NamePath(), "."), strings.Join(namePath, ".")) {
+				return nil, fmt.Errorf(
+					"module name %s does not match the imported name %s",
+					strings.Join(moduleStmt.NamePath(), "."),
+					strings.Join(namePath, "."),
+				)
+			}
+			continue
+		}
+		switch n := node.(type) {
+		case *ast.ModuleStmtNode:
+			return nil, fmt.Errorf("MODULE statement must be the first statement of the module")
+		case *ast.ImportStmtNode:
+			specs, err := a.importModule(ctx, n)
+			if err != nil {
+				return nil, err
+			}
+			for _, spec := range specs {
+				if err := a.catalog.addFunctionSpecWithLock(spec); err != nil {
+					return nil, fmt.Errorf("failed to add function spec: %w", err)
+				}
+			}
+		case *ast.CreateFunctionStmtNode:
+			stmtCtx := a.stmtContext(a.context(ctx, a.funcMap(), node, stmt), node)
+			spec, err := a.newFunctionSpecFromStmt(stmtCtx, source, n)
+			if err != nil {
+				return nil, err
+			}
+			if err := a.catalog.addFunctionSpecWithLock(spec); err != nil {
+				return nil, fmt.Errorf("failed to add function spec: %w", err)
+			}
+			switch n.CreateScope() {
+			case ast.CreateScopePrivate, ast.CreateScopeTemp:
+			default:
+				functions = append(functions, spec)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported stmt in module %s", node.DebugString())
+		}
+	}
+	if len(stmts) == 0 {
+		return nil, fmt.Errorf("module must begin with MODULE statement")
+	}
+	return functions, nil
+}
@@ -27,6 +27,12 @@ func TestNamePath(t *testing.T) {
 	if diff := cmp.Diff(namePath.mergePath([]string{"table1"}), []string{"project1", "dataset1", "table1"}); diff != "" {
 		t.Errorf("(-want +got):\n%s", diff)
 	}
+	if diff := cmp.Diff(namePath.mergePath([]string{"my-dataset.テーブル"}), []string{"project1", "my-dataset", "テーブル"}); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(namePath.format([]string{"my-project", "my-dataset.my-table"}), "my-project_my-dataset_my-table"); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
 	if diff := cmp.Diff(namePath.mergePath([]string{"project2", "dataset2", "INFORMATION_SCHEMA", "TABLES"}), []string{"project2", "dataset2", "INFORMATION_SCHEMA", "TABLES"}); diff != "" {
 		t.Errorf("(-want +got):\n%s", diff)
 	}
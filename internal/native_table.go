@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-zetasql/types"
+)
+
+// nativeTableCatalogPrefix is the prefix of the tables used by zetasqlite itself.
+const nativeTableCatalogPrefix = "zetasqlite_"
+
+func (c *Catalog) SetAutoRegisterNativeTableMode(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.isAutoRegisterNativeTableMode = enabled
+}
+
+func (c *Catalog) isNativeTable(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	spec, exists := c.tableMap[name]
+	if !exists {
+		return false
+	}
+	return spec.IsNative
+}
+
+// registerNativeTable looks up the table that was created without zetasqlite from sqlite_master,
+// and registers it to the catalog with types inferred from column affinities.
+// If the table does not exist, returns false.
+func (c *Catalog) registerNativeTable(path []string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.isAutoRegisterNativeTableMode || c.db == nil || len(path) == 0 {
+		return false, nil
+	}
+	name := formatPath(path)
+	if _, exists := c.tableMap[name]; exists {
+		return false, nil
+	}
+	if strings.HasPrefix(name, nativeTableCatalogPrefix) || strings.HasPrefix(name, "sqlite_") {
+		return false, nil
+	}
+	var count int64
+	if err := c.db.QueryRow(
+		`SELECT COUNT(*) FROM sqlite_master WHERE type IN ('table', 'view') AND name = ?`,
+		name,
+	).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to find native table %s: %w", name, err)
+	}
+	if count == 0 {
+		return false, nil
+	}
+	columns, err := c.nativeTableColumns(name)
+	if err != nil {
+		return false, err
+	}
+	if err := c.addTableSpec(&TableSpec{
+		IsNative: true,
+		NamePath: path,
+		Columns:  columns,
+	}); err != nil {
+		return false, fmt.Errorf("failed to add native table spec to catalog: %w", err)
+	}
+	return true, nil
+}
+
+func (c *Catalog) nativeTableColumns(name string) ([]*ColumnSpec, error) {
+	rows, err := c.db.Query(fmt.Sprintf("PRAGMA table_info(`%s`)", name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table info of %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	var columns []*ColumnSpec
+	for rows.Next() {
+		var (
+			cid       int64
+			colName   string
+			colType   string
+			notNull   bool
+			dfltValue sql.NullString
+			pk        int64
+		)
+		if err := rows.Scan(&cid, &colName, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return nil, fmt.Errorf("failed to scan table info of %s: %w", name, err)
+		}
+		columns = append(columns, &ColumnSpec{
+			Name:      colName,
+			Type:      &Type{Kind: int(nativeColumnTypeKind(colType))},
+			IsNotNull: notNull,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("failed to find columns of native table %s", name)
+	}
+	return columns, nil
+}
+
+// nativeColumnTypeKind returns the type kind from the declared column type
+// according to the affinity rules of SQLite ( https://www.sqlite.org/datatype3.html#determination_of_column_affinity ).
+func nativeColumnTypeKind(declType string) types.TypeKind {
+	typ := strings.ToUpper(declType)
+	switch {
+	case strings.Contains(typ, "INT"):
+		return types.INT64
+	case strings.Contains(typ, "CHAR"), strings.Contains(typ, "CLOB"), strings.Contains(typ, "TEXT"):
+		return types.STRING
+	case typ == "", strings.Contains(typ, "BLOB"):
+		return types.BYTES
+	}
+	// REAL and NUMERIC affinity.
+	return types.DOUBLE
+}
+
+// decodeNativeValue converts the value stored in the native table to the value encoded by zetasqlite.
+// Values written by zetasqlite are already encoded, so they are returned as is.
+func decodeNativeValue(v interface{}, kind types.TypeKind) (interface{}, error) {
+	if isNullValue(v) {
+		return nil, nil
+	}
+	var value Value
+	switch vv := v.(type) {
+	case int64:
+		value = IntValue(vv)
+	case float64:
+		value = FloatValue(vv)
+	case bool:
+		value = BoolValue(vv)
+	case []byte:
+		value = BytesValue(vv)
+	case string:
+		if _, err := DecodeValue(vv); err == nil {
+			return vv, nil
+		}
+		value = StringValue(vv)
+	default:
+		return nil, fmt.Errorf("unexpected native value type: %T", v)
+	}
+	switch kind {
+	case types.INT64:
+		i64, err := value.ToInt64()
+		if err != nil {
+			return nil, err
+		}
+		return EncodeValue(IntValue(i64))
+	case types.DOUBLE:
+		f64, err := value.ToFloat64()
+		if err != nil {
+			return nil, err
+		}
+		return EncodeValue(FloatValue(f64))
+	case types.STRING:
+		if b, ok := v.([]byte); ok {
+			return EncodeValue(StringValue(b))
+		}
+		s, err := value.ToString()
+		if err != nil {
+			return nil, err
+		}
+		return EncodeValue(StringValue(s))
+	case types.BYTES:
+		b, err := value.ToBytes()
+		if err != nil {
+			return nil, err
+		}
+		return EncodeValue(BytesValue(b))
+	}
+	return EncodeValue(value)
+}
@@ -0,0 +1,282 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+	"github.com/goccy/go-zetasql/types"
+)
+
+// OptionSpec is the value specified by OPTIONS(...) clause of DDL.
+type OptionSpec struct {
+	Name string `json:"name"`
+	// Type is the type name of the value ( e.g. STRING, TIMESTAMP, ARRAY<STRUCT<STRING, STRING>> ).
+	Type string `json:"type"`
+	// Value is the value formatted as SQL literal ( the same format as option_value of INFORMATION_SCHEMA.TABLE_OPTIONS ).
+	Value string `json:"value"`
+}
+
+// optionExpr is the option value formatted to SQLite expression.
+// The value is evaluated when the statement is executed because the expression may refer the current time.
+type optionExpr struct {
+	name     string
+	typeName string
+	expr     string
+}
+
+type evaluatedOption struct {
+	spec  *OptionSpec
+	value Value
+}
+
+func newOptionExprs(ctx context.Context, options []*ast.OptionNode) ([]*optionExpr, error) {
+	exprs := make([]*optionExpr, 0, len(options))
+	for _, option := range options {
+		expr, err := newNode(option).FormatSQL(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to format option %s: %w", option.Name(), err)
+		}
+		exprs = append(exprs, &optionExpr{
+			name:     strings.ToLower(option.Name()),
+			typeName: option.Value().Type().TypeName(types.ProductExternal),
+			expr:     expr,
+		})
+	}
+	return exprs, nil
+}
+
+func evalOptions(ctx context.Context, conn *Conn, exprs []*optionExpr) ([]*evaluatedOption, error) {
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	columns := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		columns = append(columns, expr.expr)
+	}
+	values := make([]interface{}, len(exprs))
+	dest := make([]interface{}, len(exprs))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := conn.QueryRowContext(ctx, fmt.Sprintf("SELECT %s", strings.Join(columns, ","))).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to evaluate options: %w", err)
+	}
+	evaluated := make([]*evaluatedOption, 0, len(exprs))
+	for i, expr := range exprs {
+		value, err := DecodeValue(values[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode option %s: %w", expr.name, err)
+		}
+		literal := "NULL"
+		if value != nil {
+			literal = value.Format('T')
+		}
+		evaluated = append(evaluated, &evaluatedOption{
+			spec: &OptionSpec{
+				Name:  expr.name,
+				Type:  expr.typeName,
+				Value: literal,
+			},
+			value: value,
+		})
+	}
+	return evaluated, nil
+}
+
+// applyOptions evaluates the options and merges them to the table spec.
+// The option set to NULL is removed.
+func (s *TableSpec) applyOptions(ctx context.Context, conn *Conn, exprs []*optionExpr) error {
+	options, err := evalOptions(ctx, conn, exprs)
+	if err != nil {
+		return err
+	}
+	for _, option := range options {
+		s.Options = mergeOptionSpec(s.Options, option)
+		switch option.spec.Name {
+		case "description":
+			if option.value == nil {
+				s.Description = ""
+				continue
+			}
+			desc, err := option.value.ToString()
+			if err != nil {
+				return fmt.Errorf("failed to get description: %w", err)
+			}
+			s.Description = desc
+		case "labels":
+			if option.value == nil {
+				s.Labels = nil
+				continue
+			}
+			labels, err := labelsFromValue(option.value)
+			if err != nil {
+				return err
+			}
+			s.Labels = labels
+		case "expiration_timestamp":
+			if option.value == nil {
+				s.ExpirationTimestamp = nil
+				continue
+			}
+			t, err := option.value.ToTime()
+			if err != nil {
+				return fmt.Errorf("failed to get expiration_timestamp: %w", err)
+			}
+			s.ExpirationTimestamp = &t
+		case "require_partition_filter":
+			if option.value == nil {
+				s.RequirePartitionFilter = false
+				continue
+			}
+			required, err := option.value.ToBool()
+			if err != nil {
+				return fmt.Errorf("failed to get require_partition_filter: %w", err)
+			}
+			s.RequirePartitionFilter = required
+		}
+	}
+	return nil
+}
+
+// applyOptions evaluates the options and merges them to the function spec.
+func (s *FunctionSpec) applyOptions(ctx context.Context, conn *Conn, exprs []*optionExpr) error {
+	options, err := evalOptions(ctx, conn, exprs)
+	if err != nil {
+		return err
+	}
+	for _, option := range options {
+		s.Options = mergeOptionSpec(s.Options, option)
+		if option.spec.Name != "description" {
+			continue
+		}
+		if option.value == nil {
+			s.Description = ""
+			continue
+		}
+		desc, err := option.value.ToString()
+		if err != nil {
+			return fmt.Errorf("failed to get description: %w", err)
+		}
+		s.Description = desc
+	}
+	return nil
+}
+
+func mergeOptionSpec(specs []*OptionSpec, option *evaluatedOption) []*OptionSpec {
+	merged := make([]*OptionSpec, 0, len(specs)+1)
+	for _, spec := range specs {
+		if spec.Name == option.spec.Name {
+			continue
+		}
+		merged = append(merged, spec)
+	}
+	if option.value == nil {
+		return merged
+	}
+	return append(merged, option.spec)
+}
+
+func labelsFromValue(v Value) (map[string]string, error) {
+	array, err := v.ToArray()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get labels: %w", err)
+	}
+	labels := make(map[string]string, len(array.values))
+	for _, elem := range array.values {
+		if elem == nil {
+			continue
+		}
+		st, err := elem.ToStruct()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get label: %w", err)
+		}
+		if len(st.values) != 2 || st.values[0] == nil || st.values[1] == nil {
+			return nil, fmt.Errorf("label must be a pair of key and value")
+		}
+		key, err := st.values[0].ToString()
+		if err != nil {
+			return nil, err
+		}
+		value, err := st.values[1].ToString()
+		if err != nil {
+			return nil, err
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// isExpired reports whether the table has passed expiration_timestamp option.
+func (s *TableSpec) isExpired(now time.Time) bool {
+	if s.ExpirationTimestamp == nil {
+		return false
+	}
+	return !now.Before(*s.ExpirationTimestamp)
+}
+
+// SetTableExpirationMode enables to treat the table that has passed expiration_timestamp option as not found.
+func (c *Catalog) SetTableExpirationMode(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.isTableExpirationMode = enabled
+}
+
+func (c *Catalog) isTableExpirationModeEnabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.isTableExpirationMode
+}
+
+func (c *Catalog) tableSpec(name string) *TableSpec {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tableMap[name]
+}
+
+func (c *Catalog) validateTableExpiration(path []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.isTableExpirationMode {
+		return nil
+	}
+	now := time.Now()
+	for _, spec := range c.findTableSpecsByPath(path) {
+		if spec.isExpired(now) {
+			return fmt.Errorf(
+				"table not found: %s ( expired at %s )",
+				strings.Join(path, "."),
+				spec.ExpirationTimestamp.UTC().Format(time.RFC3339),
+			)
+		}
+	}
+	return nil
+}
+
+// findTableSpecsByPath returns the table specs whose name path ends with the specified path.
+// The path may be omitted the prefix of the name path or be joined by dot in the single identifier.
+func (c *Catalog) findTableSpecsByPath(path []string) []*TableSpec {
+	normalizedPath := splitPath(path)
+	var specs []*TableSpec
+	for _, spec := range c.tableMap {
+		namePath := splitPath(spec.NamePath)
+		if len(namePath) < len(normalizedPath) {
+			continue
+		}
+		if strings.Join(namePath[len(namePath)-len(normalizedPath):], ".") == strings.Join(normalizedPath, ".") {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+func splitPath(path []string) []string {
+	var ret []string
+	for _, p := range path {
+		ret = append(ret, strings.Split(p, ".")...)
+	}
+	return ret
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-zetasql"
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+	"github.com/goccy/go-zetasql/types"
+)
+
+// queryParameterTypes is the types of the named query parameters declared by WithQueryParameterTypes.
+// The keys are lowercase names because Name() value of ast.ParameterNode always returns lowercase name.
+type queryParameterTypes map[string]types.Type
+
+// resolveQueryParameterTypes resolves the type names ( e.g. ARRAY<INT64> ) of the declared query parameters.
+func (a *Analyzer) resolveQueryParameterTypes(typeNames map[string]string) (queryParameterTypes, error) {
+	if len(typeNames) == 0 {
+		return nil, nil
+	}
+	opt, err := newAnalyzerOptions(a.languageFeatures)
+	if err != nil {
+		return nil, err
+	}
+	paramTypes := queryParameterTypes{}
+	for name, typeName := range typeNames {
+		// go-zetasql doesn't implement AnalyzeType, so the type is resolved by the cast expression.
+		out, err := zetasql.AnalyzeExpression(fmt.Sprintf("CAST(NULL AS %s)", typeName), a.catalog, opt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid type %s of query parameter @%s: %w", typeName, name, err)
+		}
+		typ, err := newType(out.Expr().Type()).ToZetaSQLType()
+		if err != nil {
+			return nil, fmt.Errorf("invalid type %s of query parameter @%s: %w", typeName, name, err)
+		}
+		paramTypes[strings.ToLower(name)] = typ
+	}
+	return paramTypes, nil
+}
+
+// addTo declares the query parameters to the analyzer options.
+// The undeclared parameters are still allowed and typed by the analysis.
+func (t queryParameterTypes) addTo(opt *zetasql.AnalyzerOptions) error {
+	for name, typ := range t {
+		if err := opt.AddQueryParameter(name, typ); err != nil {
+			return fmt.Errorf("failed to declare query parameter @%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// cacheKey returns the stable representation of the declared types to identify the result of the analysis.
+func (t queryParameterTypes) cacheKey() string {
+	if len(t) == 0 {
+		return ""
+	}
+	params := make([]string, 0, len(t))
+	for name, typ := range t {
+		params = append(params, fmt.Sprintf("%s:%s", name, typ.TypeName(types.ProductInternal)))
+	}
+	sort.Strings(params)
+	return strings.Join(params, ",")
+}
+
+// validateNamedValues checks the values passed by Exec/Query against the declared types.
+func (t queryParameterTypes) validateNamedValues(values []driver.NamedValue) error {
+	for _, value := range values {
+		if value.Name == "" {
+			continue
+		}
+		if err := t.validate(strings.ToLower(value.Name), value.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateValues checks the values ordered by the parameters of the prepared statement against the declared types.
+func (t queryParameterTypes) validateValues(params []*ast.ParameterNode, values []interface{}) error {
+	for idx, param := range params {
+		if idx >= len(values) {
+			break
+		}
+		if err := t.validate(param.Name(), values[idx]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t queryParameterTypes) validate(name string, v interface{}) error {
+	typ, exists := t[name]
+	if !exists {
+		return nil
+	}
+	value, err := ValueFromGoValue(v)
+	if err != nil {
+		return fmt.Errorf("failed to convert query parameter @%s: %w", name, err)
+	}
+	if value == nil || isCoercibleValue(value, typ) {
+		return nil
+	}
+	return fmt.Errorf(
+		"query parameter @%s is declared as %s but the value has type %s",
+		name,
+		typ.TypeName(types.ProductExternal),
+		valueTypeName(value),
+	)
+}
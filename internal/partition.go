@@ -0,0 +1,313 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+)
+
+const (
+	partitionIDFuncName = "zetasqlite_partition_id"
+
+	partitionGranularityHour  = "HOUR"
+	partitionGranularityDay   = "DAY"
+	partitionGranularityMonth = "MONTH"
+	partitionGranularityYear  = "YEAR"
+	partitionGranularityRange = "RANGE"
+
+	nullPartitionID          = "__NULL__"
+	unpartitionedPartitionID = "__UNPARTITIONED__"
+)
+
+// PartitionSpec is the partitioning of the table specified by PARTITION BY clause.
+// The rows are not stored separately for each partition,
+// the spec is used to report the partitions and to require the filter over the partitioning column.
+type PartitionSpec struct {
+	Column     string `json:"column"`
+	Expression string `json:"expression"`
+	// Granularity is the time unit ( HOUR, DAY, MONTH or YEAR ) of the time-unit column partitioning,
+	// or RANGE for the integer-range partitioning.
+	Granularity   string `json:"granularity"`
+	RangeStart    int64  `json:"rangeStart,omitempty"`
+	RangeEnd      int64  `json:"rangeEnd,omitempty"`
+	RangeInterval int64  `json:"rangeInterval,omitempty"`
+}
+
+// newPartitionSpec creates the spec from PARTITION BY clause.
+// columnNames is the map from the column id referred by the expression to the column name of the table.
+func newPartitionSpec(query string, exprs []ast.ExprNode, columnNames map[int]string) (*PartitionSpec, error) {
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	if len(exprs) != 1 {
+		return nil, fmt.Errorf("only one partitioning expression is supported")
+	}
+	expr := exprs[0]
+	spec := &PartitionSpec{Expression: sourceText(query, expr)}
+	if _, ok := expr.(*ast.ColumnRefNode); ok {
+		column, err := partitionColumnName(expr, columnNames)
+		if err != nil {
+			return nil, err
+		}
+		spec.Column = column
+		spec.Granularity = partitionGranularityDay
+		return spec, nil
+	}
+	call, ok := expr.(*ast.FunctionCallNode)
+	if !ok {
+		return nil, fmt.Errorf("unsupported partitioning expression %s", spec.Expression)
+	}
+	args := call.ArgumentList()
+	if len(args) == 0 {
+		return nil, fmt.Errorf("unsupported partitioning expression %s", spec.Expression)
+	}
+	column, err := partitionColumnName(args[0], columnNames)
+	if err != nil {
+		return nil, err
+	}
+	spec.Column = column
+	switch call.Function().FullName(false) {
+	case "date":
+		spec.Granularity = partitionGranularityDay
+	case "date_trunc", "datetime_trunc", "timestamp_trunc":
+		if len(args) < 2 {
+			return nil, fmt.Errorf("unsupported partitioning expression %s", spec.Expression)
+		}
+		part, ok := args[1].(*ast.LiteralNode)
+		if !ok {
+			return nil, fmt.Errorf("unsupported partitioning expression %s", spec.Expression)
+		}
+		granularity := part.Value().EnumName()
+		switch granularity {
+		case partitionGranularityHour, partitionGranularityDay, partitionGranularityMonth, partitionGranularityYear:
+		default:
+			return nil, fmt.Errorf("unsupported partitioning granularity %s", granularity)
+		}
+		spec.Granularity = granularity
+	case "range_bucket":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("unsupported partitioning expression %s", spec.Expression)
+		}
+		start, end, interval, err := partitionRange(args[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to get partitioning range of %s: %w", spec.Expression, err)
+		}
+		spec.Granularity = partitionGranularityRange
+		spec.RangeStart = start
+		spec.RangeEnd = end
+		spec.RangeInterval = interval
+	default:
+		return nil, fmt.Errorf("unsupported partitioning expression %s", spec.Expression)
+	}
+	return spec, nil
+}
+
+func partitionColumnName(expr ast.ExprNode, columnNames map[int]string) (string, error) {
+	ref, ok := expr.(*ast.ColumnRefNode)
+	if !ok {
+		return "", fmt.Errorf("partitioning expression must refer to the column directly")
+	}
+	if name, exists := columnNames[ref.Column().ColumnID()]; exists {
+		return name, nil
+	}
+	return ref.Column().Name(), nil
+}
+
+// partitionRange returns the range of the integer-range partitioning specified by GENERATE_ARRAY(start, end, interval).
+func partitionRange(expr ast.ExprNode) (int64, int64, int64, error) {
+	call, ok := expr.(*ast.FunctionCallNode)
+	if !ok || call.Function().FullName(false) != "generate_array" {
+		return 0, 0, 0, fmt.Errorf("range must be specified by GENERATE_ARRAY")
+	}
+	args := call.ArgumentList()
+	if len(args) != 3 {
+		return 0, 0, 0, fmt.Errorf("GENERATE_ARRAY must have start, end and interval arguments")
+	}
+	values := make([]int64, 0, len(args))
+	for _, arg := range args {
+		literal, ok := arg.(*ast.LiteralNode)
+		if !ok {
+			return 0, 0, 0, fmt.Errorf("arguments of GENERATE_ARRAY must be literals")
+		}
+		values = append(values, literal.Value().Int64Value())
+	}
+	if values[2] <= 0 {
+		return 0, 0, 0, fmt.Errorf("interval of GENERATE_ARRAY must be positive")
+	}
+	return values[0], values[1], values[2], nil
+}
+
+func newClusterBy(exprs []ast.ExprNode, columnNames map[int]string) ([]string, error) {
+	columns := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		column, err := partitionColumnName(expr, columnNames)
+		if err != nil {
+			return nil, fmt.Errorf("clustering expression must refer to the column directly")
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// sourceText returns the text of the node in the original query.
+func sourceText(query string, node ast.Node) string {
+	loc := node.ParseLocationRange()
+	if loc == nil {
+		return ""
+	}
+	start := loc.Start().ByteOffset()
+	end := loc.End().ByteOffset()
+	if start < 0 || end < start || len(query) < end {
+		return ""
+	}
+	return query[start:end]
+}
+
+// partitionIDQuery returns the expression to compute the partition id of the row.
+func (s *PartitionSpec) partitionIDQuery() string {
+	return fmt.Sprintf(
+		"%s(`%s`,'%s',%d,%d,%d)",
+		partitionIDFuncName, s.Column, s.Granularity, s.RangeStart, s.RangeEnd, s.RangeInterval,
+	)
+}
+
+// partitionID computes the partition id in the same format as INFORMATION_SCHEMA.PARTITIONS of BigQuery.
+func partitionID(v interface{}, granularity string, start, end, interval int64) (interface{}, error) {
+	value, err := DecodeValue(v)
+	if err != nil {
+		return nil, err
+	}
+	id, err := formatPartitionID(value, granularity, start, end, interval)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeValue(StringValue(id))
+}
+
+func formatPartitionID(value Value, granularity string, start, end, interval int64) (string, error) {
+	if value == nil {
+		return nullPartitionID, nil
+	}
+	if granularity == partitionGranularityRange {
+		i, err := value.ToInt64()
+		if err != nil {
+			return "", err
+		}
+		if i < start || end <= i {
+			return unpartitionedPartitionID, nil
+		}
+		return fmt.Sprint(start + (i-start)/interval*interval), nil
+	}
+	t, err := value.ToTime()
+	if err != nil {
+		return "", err
+	}
+	if _, ok := value.(TimestampValue); ok {
+		t = t.UTC()
+	}
+	switch granularity {
+	case partitionGranularityHour:
+		return t.Format("2006010215"), nil
+	case partitionGranularityDay:
+		return t.Format("20060102"), nil
+	case partitionGranularityMonth:
+		return t.Format("200601"), nil
+	case partitionGranularityYear:
+		return t.Format("2006"), nil
+	}
+	return "", fmt.Errorf("unexpected partitioning granularity %s", granularity)
+}
+
+// validatePartitionFilter reports an error if the statement reads the table with require_partition_filter option
+// without filtering by the partitioning column.
+func (a *Analyzer) validatePartitionFilter(ctx context.Context, node ast.StatementNode) error {
+	var (
+		requiredColumns = map[int]string{}
+		tableNames      = map[int]string{}
+		filteredColumns = map[int]struct{}{}
+	)
+	collectFilteredColumns := func(expr ast.Node) {
+		if expr == nil {
+			return
+		}
+		_ = ast.Walk(expr, func(n ast.Node) error {
+			if ref, ok := n.(*ast.ColumnRefNode); ok {
+				filteredColumns[ref.Column().ColumnID()] = struct{}{}
+			}
+			return nil
+		})
+	}
+	var target ast.Node = node
+	if insert, ok := node.(*ast.InsertStmtNode); ok {
+		// the rows can be inserted without the filter, only the query to select the rows is validated.
+		if insert.Query() == nil {
+			return nil
+		}
+		target = insert.Query()
+	}
+	if err := ast.Walk(target, func(n ast.Node) error {
+		switch n := n.(type) {
+		case *ast.TableScanNode:
+			switch n.Table().(type) {
+			case *WildcardTable, *InformationSchemaTable:
+				return nil
+			}
+			name, err := getTableName(ctx, n)
+			if err != nil {
+				return nil
+			}
+			spec := a.catalog.tableSpec(name)
+			if spec == nil || spec.Partition == nil || !spec.RequirePartitionFilter {
+				return nil
+			}
+			columnID := -1
+			for _, col := range n.ColumnList() {
+				if strings.EqualFold(col.Name(), spec.Partition.Column) {
+					columnID = col.ColumnID()
+					break
+				}
+			}
+			requiredColumns[columnID] = spec.Partition.Column
+			tableNames[columnID] = name
+		case *ast.FilterScanNode:
+			collectFilteredColumns(n.FilterExpr())
+		case *ast.JoinScanNode:
+			collectFilteredColumns(n.JoinExpr())
+		case *ast.DeleteStmtNode:
+			collectFilteredColumns(n.WhereExpr())
+		case *ast.UpdateStmtNode:
+			collectFilteredColumns(n.WhereExpr())
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for columnID, column := range requiredColumns {
+		if _, exists := filteredColumns[columnID]; exists {
+			continue
+		}
+		return fmt.Errorf(
+			"cannot query over table %s without a filter over column %s that can be used for partition elimination",
+			tableNames[columnID], column,
+		)
+	}
+	return nil
+}
+
+func (s *TableSpec) setPartitioning(query string, partitionBy, clusterBy []ast.ExprNode, columnNames map[int]string) error {
+	partition, err := newPartitionSpec(query, partitionBy, columnNames)
+	if err != nil {
+		return err
+	}
+	clusterColumns, err := newClusterBy(clusterBy, columnNames)
+	if err != nil {
+		return err
+	}
+	s.Partition = partition
+	if len(clusterColumns) != 0 {
+		s.ClusterBy = clusterColumns
+	}
+	return nil
+}
@@ -0,0 +1,465 @@
+package internal
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type sqlTokenKind int
+
+const (
+	sqlWordToken sqlTokenKind = iota
+	sqlIdentToken
+	sqlStringToken
+	sqlNumberToken
+	sqlParamToken
+	sqlOperatorToken
+	sqlLeftParenToken
+	sqlRightParenToken
+	sqlCommaToken
+	sqlSemicolonToken
+	sqlCommentToken
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+}
+
+func (t *sqlToken) isWord(words ...string) bool {
+	if t.kind != sqlWordToken {
+		return false
+	}
+	for _, word := range words {
+		if strings.EqualFold(t.text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+const prettyFormatIndent = "  "
+
+// generatedColumnNamePattern matches the column name made unique by the column id ( see uniqueColumnName ).
+var generatedColumnNamePattern = regexp.MustCompile(`^(.+)#(\d+)$`)
+
+// prettyFormatKeywords are the words that are followed by a space instead of being called as a function.
+var prettyFormatKeywords = map[string]struct{}{
+	"AND": {}, "AS": {}, "BY": {}, "ELSE": {}, "EXISTS": {}, "FROM": {}, "IN": {}, "INTO": {},
+	"JOIN": {}, "NOT": {}, "ON": {}, "OR": {}, "OVER": {}, "SELECT": {}, "THEN": {}, "USING": {},
+	"VALUES": {}, "WHEN": {}, "WHERE": {}, "WITH": {}, "RECURSIVE": {}, "ALL": {}, "UNION": {},
+	"INTERSECT": {}, "EXCEPT": {}, "HAVING": {}, "LIMIT": {}, "OFFSET": {}, "DISTINCT": {}, "CASE": {},
+	"END": {}, "IS": {}, "LIKE": {}, "GLOB": {}, "BETWEEN": {}, "SET": {}, "TABLE": {}, "VIEW": {},
+	"PARTITION": {}, "ROWS": {}, "RANGE": {}, "GROUPS": {}, "WINDOW": {}, "FILTER": {},
+}
+
+// FormatPrettySQL formats the SQLite query generated by the formatter to the indented and deterministic form.
+// In addition to the layout, the redundant wrappers of sub queries are removed
+// and the generated column names are renumbered in order of appearance,
+// so that an unrelated change of the query does not affect the whole output.
+// The tokens of the query are not changed otherwise, so the formatted query has the same semantics.
+func FormatPrettySQL(query string) string {
+	tokens := tokenizeSQL(query)
+	tokens = collapseRedundantSubqueries(tokens)
+	tokens = renumberGeneratedColumnNames(tokens)
+	return layoutSQLTokens(tokens)
+}
+
+func tokenizeSQL(query string) []*sqlToken {
+	var tokens []*sqlToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			tokens = append(tokens, &sqlToken{kind: sqlCommentToken, text: query[i : i+end]})
+			i += end
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i
+			} else {
+				end += 4
+			}
+			tokens = append(tokens, &sqlToken{kind: sqlCommentToken, text: query[i : i+end]})
+			i += end
+		case c == '\'':
+			end := quotedEnd(query, i, '\'')
+			tokens = append(tokens, &sqlToken{kind: sqlStringToken, text: query[i:end]})
+			i = end
+		case c == '`' || c == '"':
+			end := quotedEnd(query, i, c)
+			tokens = append(tokens, &sqlToken{kind: sqlIdentToken, text: query[i:end]})
+			i = end
+		case c == '[':
+			end := strings.IndexByte(query[i:], ']')
+			if end < 0 {
+				end = len(query) - i - 1
+			}
+			tokens = append(tokens, &sqlToken{kind: sqlIdentToken, text: query[i : i+end+1]})
+			i += end + 1
+		case isDigit(c) || (c == '.' && i+1 < len(query) && isDigit(query[i+1])):
+			end := i + 1
+			isHex := c == '0' && end < len(query) && (query[end] == 'x' || query[end] == 'X')
+			for end < len(query) {
+				cc := query[end]
+				if isWordChar(cc) || cc == '.' {
+					end++
+					continue
+				}
+				if !isHex && (cc == '+' || cc == '-') && (query[end-1] == 'e' || query[end-1] == 'E') {
+					end++
+					continue
+				}
+				break
+			}
+			tokens = append(tokens, &sqlToken{kind: sqlNumberToken, text: query[i:end]})
+			i = end
+		case isWordChar(c):
+			end := i + 1
+			for end < len(query) && (isWordChar(query[end]) || query[end] == '$') {
+				end++
+			}
+			if end-i == 1 && (c == 'x' || c == 'X') && end < len(query) && query[end] == '\'' {
+				// blob literal
+				end = quotedEnd(query, end, '\'')
+				tokens = append(tokens, &sqlToken{kind: sqlStringToken, text: query[i:end]})
+				i = end
+				continue
+			}
+			tokens = append(tokens, &sqlToken{kind: sqlWordToken, text: query[i:end]})
+			i = end
+		case c == '?' || c == '@' || c == ':' || c == '$' || c == '#':
+			end := i + 1
+			for end < len(query) && isWordChar(query[end]) {
+				end++
+			}
+			kind := sqlParamToken
+			if end == i+1 && c != '?' {
+				kind = sqlOperatorToken
+			}
+			tokens = append(tokens, &sqlToken{kind: kind, text: query[i:end]})
+			i = end
+		case c == '(':
+			tokens = append(tokens, &sqlToken{kind: sqlLeftParenToken, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, &sqlToken{kind: sqlRightParenToken, text: ")"})
+			i++
+		case c == ',':
+			tokens = append(tokens, &sqlToken{kind: sqlCommaToken, text: ","})
+			i++
+		case c == ';':
+			tokens = append(tokens, &sqlToken{kind: sqlSemicolonToken, text: ";"})
+			i++
+		default:
+			end := i + 1
+			for _, op := range []string{"->>", "->", "||", "<=", ">=", "<>", "!=", "==", "<<", ">>"} {
+				if strings.HasPrefix(query[i:], op) {
+					end = i + len(op)
+					break
+				}
+			}
+			tokens = append(tokens, &sqlToken{kind: sqlOperatorToken, text: query[i:end]})
+			i = end
+		}
+	}
+	return tokens
+}
+
+// quotedEnd returns the position next to the closing quote of the quoted text starting at start.
+// The doubled quote is treated as the escaped quote.
+func quotedEnd(query string, start int, quote byte) int {
+	for i := start + 1; i < len(query); i++ {
+		if query[i] != quote {
+			continue
+		}
+		if i+1 < len(query) && query[i+1] == quote {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(query)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || isDigit(c) || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c >= 0x80
+}
+
+// matchingParen returns the index of the right paren matching the left paren at start.
+func matchingParen(tokens []*sqlToken, start int) int {
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		switch tokens[i].kind {
+		case sqlLeftParenToken:
+			depth++
+		case sqlRightParenToken:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// collapseRedundantSubqueries replaces ( SELECT * FROM ( SELECT ... ) ) by ( SELECT ... ).
+// The outer query selects all columns of the inner query as is, and it is enclosed by parens,
+// so the inner query can be used in place of it.
+func collapseRedundantSubqueries(tokens []*sqlToken) []*sqlToken {
+	for {
+		collapsed := false
+		for i := 0; i+5 < len(tokens); i++ {
+			if tokens[i].kind != sqlLeftParenToken ||
+				!tokens[i+1].isWord("SELECT") ||
+				tokens[i+2].text != "*" ||
+				!tokens[i+3].isWord("FROM") ||
+				tokens[i+4].kind != sqlLeftParenToken ||
+				!tokens[i+5].isWord("SELECT") {
+				continue
+			}
+			innerEnd := matchingParen(tokens, i+4)
+			if innerEnd < 0 || innerEnd+1 >= len(tokens) || tokens[innerEnd+1].kind != sqlRightParenToken {
+				continue
+			}
+			result := make([]*sqlToken, 0, len(tokens)-5)
+			result = append(result, tokens[:i+1]...)
+			result = append(result, tokens[i+5:innerEnd]...)
+			result = append(result, tokens[innerEnd+1:]...)
+			tokens = result
+			collapsed = true
+			break
+		}
+		if !collapsed {
+			return tokens
+		}
+	}
+}
+
+// renumberGeneratedColumnNames renames the generated column names like `name#12` in order of appearance.
+// If the output column names of the statement have the same form, they are kept as is,
+// because the renamed columns might conflict with them.
+func renumberGeneratedColumnNames(tokens []*sqlToken) []*sqlToken {
+	var (
+		renameMap = map[string]string{}
+		depth     int
+		seq       int
+	)
+	for idx, token := range tokens {
+		switch token.kind {
+		case sqlLeftParenToken:
+			depth++
+		case sqlRightParenToken:
+			depth--
+		case sqlIdentToken:
+			name, ok := backquotedName(token.text)
+			if !ok {
+				continue
+			}
+			matched := generatedColumnNamePattern.FindStringSubmatch(name)
+			if matched == nil {
+				continue
+			}
+			if depth == 0 && idx > 0 && tokens[idx-1].isWord("AS") {
+				return tokens
+			}
+			if _, exists := renameMap[name]; exists {
+				continue
+			}
+			seq++
+			renameMap[name] = matched[1] + "#" + strconv.Itoa(seq)
+		}
+	}
+	renamed := make([]*sqlToken, 0, len(tokens))
+	for _, token := range tokens {
+		if token.kind == sqlIdentToken {
+			if name, ok := backquotedName(token.text); ok {
+				if newName, exists := renameMap[name]; exists {
+					token = &sqlToken{kind: sqlIdentToken, text: "`" + strings.ReplaceAll(newName, "`", "``") + "`"}
+				}
+			}
+		}
+		renamed = append(renamed, token)
+	}
+	return renamed
+}
+
+func backquotedName(text string) (string, bool) {
+	if len(text) < 2 || text[0] != '`' || text[len(text)-1] != '`' {
+		return "", false
+	}
+	return strings.ReplaceAll(text[1:len(text)-1], "``", "`"), true
+}
+
+type sqlLayout struct {
+	buf       strings.Builder
+	indent    int
+	parens    []bool   // whether each open paren encloses a sub query
+	clauses   []string // the clause outside of each open paren
+	clause    string
+	lineIsNew bool
+	prev      *sqlToken
+	isUnary   bool // whether prev is an unary operator
+}
+
+func (l *sqlLayout) newline(indent int) {
+	if l.prev == nil {
+		return
+	}
+	l.buf.WriteString("\n")
+	l.buf.WriteString(strings.Repeat(prettyFormatIndent, indent))
+	l.lineIsNew = true
+}
+
+func (l *sqlLayout) isClauseLevel() bool {
+	return len(l.parens) == 0 || l.parens[len(l.parens)-1]
+}
+
+func (l *sqlLayout) write(token *sqlToken) {
+	if !l.lineIsNew && l.needsSpace(token) {
+		l.buf.WriteString(" ")
+	}
+	l.buf.WriteString(token.text)
+	l.isUnary = l.isUnaryOperator(token)
+	l.lineIsNew = false
+	l.prev = token
+}
+
+func (l *sqlLayout) isUnaryOperator(token *sqlToken) bool {
+	if token.text != "-" && token.text != "+" && token.text != "~" {
+		return false
+	}
+	prev := l.prev
+	if prev == nil {
+		return true
+	}
+	switch prev.kind {
+	case sqlOperatorToken, sqlCommaToken, sqlLeftParenToken:
+		return true
+	case sqlWordToken:
+		_, isKeyword := prettyFormatKeywords[strings.ToUpper(prev.text)]
+		return isKeyword
+	}
+	return false
+}
+
+func (l *sqlLayout) needsSpace(token *sqlToken) bool {
+	prev := l.prev
+	if prev == nil {
+		return false
+	}
+	switch {
+	case l.isUnary:
+		return token.kind == sqlOperatorToken
+	case prev.kind == sqlLeftParenToken:
+		return false
+	case token.kind == sqlRightParenToken, token.kind == sqlCommaToken, token.kind == sqlSemicolonToken:
+		return false
+	case token.text == "." || prev.text == ".":
+		return false
+	case token.kind == sqlLeftParenToken && prev.kind == sqlWordToken:
+		_, isKeyword := prettyFormatKeywords[strings.ToUpper(prev.text)]
+		return isKeyword
+	}
+	return true
+}
+
+// clauseKeyword returns the keyword starting a clause at tokens[i], or empty string.
+func clauseKeyword(tokens []*sqlToken, i int) string {
+	token := tokens[i]
+	if token.kind != sqlWordToken {
+		return ""
+	}
+	word := strings.ToUpper(token.text)
+	switch word {
+	case "SELECT", "FROM", "WHERE", "HAVING", "LIMIT", "WINDOW", "UNION", "INTERSECT", "EXCEPT", "VALUES", "SET", "RETURNING":
+		return word
+	case "GROUP", "ORDER":
+		if i+1 < len(tokens) && tokens[i+1].isWord("BY") {
+			return word
+		}
+	case "LEFT", "RIGHT", "FULL", "INNER", "CROSS", "NATURAL":
+		if i > 0 && tokens[i-1].isWord("LEFT", "RIGHT", "FULL", "INNER", "CROSS", "NATURAL") {
+			return ""
+		}
+		for j := i + 1; j < len(tokens) && j <= i+3; j++ {
+			if tokens[j].isWord("JOIN") {
+				return "JOIN"
+			}
+		}
+	case "JOIN":
+		if i > 0 && tokens[i-1].isWord("LEFT", "RIGHT", "FULL", "INNER", "CROSS", "NATURAL", "OUTER") {
+			return ""
+		}
+		return word
+	}
+	return ""
+}
+
+func layoutSQLTokens(tokens []*sqlToken) string {
+	l := &sqlLayout{}
+	for i, token := range tokens {
+		switch token.kind {
+		case sqlLeftParenToken:
+			isSubquery := i+1 < len(tokens) && tokens[i+1].isWord("SELECT", "WITH", "VALUES")
+			l.write(token)
+			l.parens = append(l.parens, isSubquery)
+			l.clauses = append(l.clauses, l.clause)
+			if isSubquery {
+				l.indent++
+				l.clause = ""
+				l.newline(l.indent)
+			}
+			continue
+		case sqlRightParenToken:
+			if len(l.parens) > 0 {
+				isSubquery := l.parens[len(l.parens)-1]
+				l.parens = l.parens[:len(l.parens)-1]
+				l.clause = l.clauses[len(l.clauses)-1]
+				l.clauses = l.clauses[:len(l.clauses)-1]
+				if isSubquery {
+					l.indent--
+					l.newline(l.indent)
+				}
+			}
+			l.write(token)
+			continue
+		case sqlCommaToken:
+			l.write(token)
+			if l.isClauseLevel() && l.clause == "SELECT" {
+				l.newline(l.indent + 1)
+			}
+			continue
+		case sqlSemicolonToken:
+			l.write(token)
+			l.clause = ""
+			l.newline(l.indent)
+			continue
+		case sqlCommentToken:
+			l.write(token)
+			l.newline(l.indent)
+			continue
+		}
+		if l.isClauseLevel() {
+			if keyword := clauseKeyword(tokens, i); keyword != "" {
+				if !l.lineIsNew {
+					l.newline(l.indent)
+				}
+				l.clause = keyword
+			}
+		}
+		l.write(token)
+	}
+	return strings.TrimSpace(l.buf.String())
+}
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFormatPrettySQL(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{
+			name:  "collapse redundant sub query and renumber columns",
+			query: "SELECT `a#7` AS `a`, `b#9` AS `b` FROM (SELECT * FROM (SELECT `x` AS `a#7`, zetasqlite_add(`y`, 1) AS `b#9` FROM `t` WHERE `x` > 1 GROUP BY `x` ORDER BY `x` LIMIT 10))",
+			expected: "SELECT `a#1` AS `a`,\n" +
+				"  `b#2` AS `b`\n" +
+				"FROM (\n" +
+				"  SELECT `x` AS `a#1`,\n" +
+				"    zetasqlite_add(`y`, 1) AS `b#2`\n" +
+				"  FROM `t`\n" +
+				"  WHERE `x` > 1\n" +
+				"  GROUP BY `x`\n" +
+				"  ORDER BY `x`\n" +
+				"  LIMIT 10\n" +
+				")",
+		},
+		{
+			name:  "literals and joins",
+			query: "INSERT INTO `t` (`a`,`b`) SELECT 'it''s', X'00ff' UNION ALL SELECT -1.5e-3, ?1 FROM `u` LEFT OUTER JOIN `v` ON `u`.`id` = `v`.`id`",
+			expected: "INSERT INTO `t` (`a`, `b`)\n" +
+				"SELECT 'it''s',\n" +
+				"  X'00ff'\n" +
+				"UNION ALL\n" +
+				"SELECT -1.5e-3,\n" +
+				"  ?1\n" +
+				"FROM `u`\n" +
+				"LEFT OUTER JOIN `v` ON `u`.`id` = `v`.`id`",
+		},
+		{
+			name:  "keep output column names",
+			query: "SELECT `x#5` AS `x#5` FROM (SELECT 1 AS `x#5`)",
+			expected: "SELECT `x#5` AS `x#5`\n" +
+				"FROM (\n" +
+				"  SELECT 1 AS `x#5`\n" +
+				")",
+		},
+		{
+			name:  "window clause is not split",
+			query: "SELECT zetasqlite_window_sum(`a#3`) OVER (PARTITION BY `b#4` ORDER BY `a#3`) AS `s` FROM `t` WHERE `a#3` IN (SELECT * FROM (SELECT `id` FROM `u`))",
+			expected: "SELECT zetasqlite_window_sum(`a#1`) OVER (PARTITION BY `b#2` ORDER BY `a#1`) AS `s`\n" +
+				"FROM `t`\n" +
+				"WHERE `a#1` IN (\n" +
+				"  SELECT `id`\n" +
+				"  FROM `u`\n" +
+				")",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			got := FormatPrettySQL(test.query)
+			if diff := cmp.Diff(test.expected, got); diff != "" {
+				t.Errorf("(-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(got, FormatPrettySQL(got)); diff != "" {
+				t.Errorf("formatting is not idempotent (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
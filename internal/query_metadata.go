@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	QueryPriorityInteractive = "INTERACTIVE"
+	QueryPriorityBatch       = "BATCH"
+)
+
+// QueryMetadata is the labels, the priority and the timeout of the query specified by the context.
+type QueryMetadata struct {
+	Labels   map[string]string
+	Priority string
+	Timeout  time.Duration
+}
+
+func (m *QueryMetadata) clone() *QueryMetadata {
+	if m == nil {
+		return &QueryMetadata{}
+	}
+	labels := make(map[string]string, len(m.Labels))
+	for key, value := range m.Labels {
+		labels[key] = value
+	}
+	return &QueryMetadata{Labels: labels, Priority: m.Priority, Timeout: m.Timeout}
+}
+
+func WithQueryMetadata(ctx context.Context, metadata *QueryMetadata) context.Context {
+	return context.WithValue(ctx, queryMetadataKey{}, metadata)
+}
+
+func QueryMetadataFromContext(ctx context.Context) *QueryMetadata {
+	value := ctx.Value(queryMetadataKey{})
+	if value == nil {
+		return nil
+	}
+	return value.(*QueryMetadata)
+}
+
+// WithQueryLabels adds the labels to the labels specified by the parent context.
+func WithQueryLabels(ctx context.Context, labels map[string]string) context.Context {
+	metadata := QueryMetadataFromContext(ctx).clone()
+	for key, value := range labels {
+		metadata.Labels[key] = value
+	}
+	return WithQueryMetadata(ctx, metadata)
+}
+
+func WithQueryPriority(ctx context.Context, priority string) context.Context {
+	metadata := QueryMetadataFromContext(ctx).clone()
+	metadata.Priority = priority
+	return WithQueryMetadata(ctx, metadata)
+}
+
+func WithQueryTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	metadata := QueryMetadataFromContext(ctx).clone()
+	metadata.Timeout = timeout
+	return WithQueryMetadata(ctx, metadata)
+}
+
+// WithQueryDeadline returns the context canceled when the timeout specified by WithQueryTimeout elapses.
+// If the timeout is not specified, the context is returned as it is.
+func WithQueryDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	metadata := QueryMetadataFromContext(ctx)
+	if metadata == nil || metadata.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, metadata.Timeout)
+}
+
+func validateQueryMetadata(ctx context.Context) error {
+	metadata := QueryMetadataFromContext(ctx)
+	if metadata == nil {
+		return nil
+	}
+	switch metadata.Priority {
+	case "", QueryPriorityInteractive, QueryPriorityBatch:
+	default:
+		return fmt.Errorf("unsupported query priority %q", metadata.Priority)
+	}
+	for key := range metadata.Labels {
+		if key == "" {
+			return fmt.Errorf("query label key must not be empty")
+		}
+	}
+	return nil
+}
+
+// QueryLog is the record of the Exec/Query call passed to the query logger.
+type QueryLog struct {
+	Query    string
+	Labels   map[string]string
+	Priority string
+	// Stats is the statistics of the statements. nil for the prepared statements.
+	// For queries, the rows are not read yet when the log is passed.
+	Stats *QueryStats
+	Err   error
+}
+
+// QueryLogger is called after each Exec/Query call and each execution of the prepared statements.
+type QueryLogger func(ctx context.Context, log *QueryLog)
+
+func (a *Analyzer) SetQueryLogger(logger QueryLogger) {
+	a.queryLogger = logger
+}
+
+// queryLabelsFromContext returns the labels set by @@query_label overwritten by the labels specified by the context.
+func (a *Analyzer) queryLabelsFromContext(ctx context.Context) []*jobLabel {
+	metadata := QueryMetadataFromContext(ctx)
+	if metadata == nil || len(metadata.Labels) == 0 {
+		return a.queryLabels
+	}
+	keys := make([]string, 0, len(metadata.Labels))
+	for key := range metadata.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	labels := make([]*jobLabel, 0, len(a.queryLabels)+len(keys))
+	for _, label := range a.queryLabels {
+		if _, exists := metadata.Labels[label.key]; exists {
+			continue
+		}
+		labels = append(labels, label)
+	}
+	for _, key := range keys {
+		labels = append(labels, &jobLabel{key: key, value: metadata.Labels[key]})
+	}
+	return labels
+}
+
+func queryPriorityFromContext(ctx context.Context) string {
+	metadata := QueryMetadataFromContext(ctx)
+	if metadata == nil || metadata.Priority == "" {
+		return QueryPriorityInteractive
+	}
+	return metadata.Priority
+}
+
+// LogQuery passes the log of the Exec/Query call to the query logger.
+func (a *Analyzer) LogQuery(ctx context.Context, query string, stats *QueryStats, err error) {
+	if a.queryLogger == nil {
+		return
+	}
+	a.queryLogger(ctx, &QueryLog{
+		Query:    query,
+		Labels:   labelsToMap(a.queryLabelsFromContext(ctx)),
+		Priority: queryPriorityFromContext(ctx),
+		Stats:    stats,
+		Err:      err,
+	})
+}
+
+func labelsToMap(labels []*jobLabel) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(labels))
+	for _, label := range labels {
+		m[label.key] = label.value
+	}
+	return m
+}
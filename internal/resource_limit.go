@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrResourcesExceeded is the error matched with the error returned when the query exceeds the limit
+// set by SetMaxResultRows or SetMaxBufferedValues.
+var ErrResourcesExceeded = errors.New("resources exceeded during query execution")
+
+const (
+	resourceResultRows     = "result rows"
+	resourceBufferedValues = "buffered values"
+)
+
+// ResourcesExceededError is the error of the query exceeding the limit of the resource.
+type ResourcesExceededError struct {
+	// Resource is the kind of the exceeded resource ( "result rows" or "buffered values" ).
+	Resource string
+	// Limit is the maximum number of the resource.
+	Limit int64
+}
+
+func (e *ResourcesExceededError) Error() string {
+	return fmt.Sprintf("%s: the number of %s exceeds the limit of %d", ErrResourcesExceeded, e.Resource, e.Limit)
+}
+
+func (e *ResourcesExceededError) Is(target error) bool {
+	return target == ErrResourcesExceeded
+}
+
+// SetMaxResultRows specifies the maximum number of the rows returned by a query. Zero or less means unlimited.
+func (a *Analyzer) SetMaxResultRows(num int64) {
+	a.maxResultRows = num
+}
+
+// SetMaxBufferedValues specifies the maximum number of the values buffered by an aggregate or analytic function
+// ( e.g. ARRAY_AGG and the window frames ). Zero or less means unlimited.
+func (a *Analyzer) SetMaxBufferedValues(num int64) {
+	a.maxBufferedValues = num
+}
+
+// maxBufferedValuesOptionFuncSQL returns the option of the aggregate and analytic function calls to limit the buffered values.
+// The empty string is returned if the number of the buffered values is unlimited.
+func maxBufferedValuesOptionFuncSQL(ctx context.Context) string {
+	analyzer := analyzerFromContext(ctx)
+	if analyzer == nil || analyzer.maxBufferedValues <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("zetasqlite_max_buffered_values(%d)", analyzer.maxBufferedValues)
+}
+
+// checkBufferedValues returns ResourcesExceededError if the number of the buffered values exceeds the limit of the option.
+func (o *AggregatorOption) checkBufferedValues(num int) error {
+	if o == nil || o.MaxBufferedValues <= 0 || int64(num) <= o.MaxBufferedValues {
+		return nil
+	}
+	return &ResourcesExceededError{Resource: resourceBufferedValues, Limit: o.MaxBufferedValues}
+}
+
+// resourceLimit is the limits of the resources used by the query. Zero or less means unlimited.
+type resourceLimit struct {
+	maxResultRows     int64
+	maxBufferedValues int64
+}
+
+// checkResultRows returns ResourcesExceededError if the number of the returned rows exceeds the limit.
+func (l *resourceLimit) checkResultRows(num int64) error {
+	if l == nil || l.maxResultRows <= 0 || num <= l.maxResultRows {
+		return nil
+	}
+	return &ResourcesExceededError{Resource: resourceResultRows, Limit: l.maxResultRows}
+}
+
+// translateError converts the error of the aggregate or analytic function exceeding the limit to ResourcesExceededError,
+// because the error returned by the function registered to SQLite reaches the caller as the text of the SQLite error.
+func (l *resourceLimit) translateError(err error) error {
+	if err == nil || l == nil || l.maxBufferedValues <= 0 || errors.Is(err, ErrResourcesExceeded) {
+		return err
+	}
+	if !strings.Contains(err.Error(), ErrResourcesExceeded.Error()) {
+		return err
+	}
+	return &ResourcesExceededError{Resource: resourceBufferedValues, Limit: l.maxBufferedValues}
+}
@@ -11,6 +11,10 @@ func (r *Result) ChangedCatalog() *ChangedCatalog {
 	return r.conn.cc
 }
 
+func (r *Result) Stats() *QueryStats {
+	return r.conn.stats
+}
+
 func (r *Result) LastInsertId() (int64, error) {
 	if r.result == nil {
 		return 0, nil
@@ -24,3 +28,14 @@ func (r *Result) RowsAffected() (int64, error) {
 	}
 	return r.result.RowsAffected()
 }
+
+// rowsAffectedResult is the result of the statement executed as multiple statements ( e.g. MERGE ).
+type rowsAffectedResult int64
+
+func (r rowsAffectedResult) LastInsertId() (int64, error) {
+	return 0, nil
+}
+
+func (r rowsAffectedResult) RowsAffected() (int64, error) {
+	return int64(r), nil
+}
@@ -7,27 +7,41 @@ import (
 	"fmt"
 	"io"
 	"reflect"
-	"time"
 
 	"github.com/goccy/go-json"
 	"github.com/goccy/go-zetasql/types"
 )
 
 type Rows struct {
-	rows    *sql.Rows
-	conn    *Conn
-	columns []*ColumnSpec
-	actions []StmtAction
+	rows     *sql.Rows
+	conn     *Conn
+	columns  []*ColumnSpec
+	actions  []StmtAction
+	cancel   context.CancelFunc
+	limit    *resourceLimit
+	returned int64
 }
 
 func (r *Rows) ChangedCatalog() *ChangedCatalog {
 	return r.conn.cc
 }
 
+func (r *Rows) Stats() *QueryStats {
+	if r.conn == nil {
+		return nil
+	}
+	return r.conn.stats
+}
+
 func (r *Rows) SetActions(actions []StmtAction) {
 	r.actions = actions
 }
 
+// SetCancel sets the function called when the rows are closed to release the deadline of the query.
+func (r *Rows) SetCancel(cancel context.CancelFunc) {
+	r.cancel = cancel
+}
+
 func (r *Rows) Columns() []string {
 	colNames := make([]string, 0, len(r.columns))
 	for _, col := range r.columns {
@@ -43,6 +57,12 @@ func (r *Rows) ColumnTypeDatabaseTypeName(i int) string {
 
 func (r *Rows) Close() (e error) {
 	defer func() {
+		if r.conn != nil {
+			r.conn.FinishStats()
+		}
+		if r.cancel != nil {
+			r.cancel()
+		}
 		eg := new(ErrorGroup)
 		eg.Add(e)
 		for _, action := range r.actions {
@@ -72,13 +92,20 @@ func (r *Rows) Next(dest []driver.Value) error {
 	}
 	if !r.rows.Next() {
 		if err := r.rows.Err(); err != nil {
-			return err
+			return r.limit.translateError(err)
 		}
 		return io.EOF
 	}
 	if err := r.rows.Err(); err != nil {
+		return r.limit.translateError(err)
+	}
+	r.returned++
+	if err := r.limit.checkResultRows(r.returned); err != nil {
 		return err
 	}
+	if r.conn != nil {
+		r.conn.stats.RowsReturned++
+	}
 	colTypes := r.columnTypes()
 	values := make([]interface{}, 0, len(dest))
 	for i := 0; i < len(dest); i++ {
@@ -97,6 +124,43 @@ func (r *Rows) Next(dest []driver.Value) error {
 	return retErr
 }
 
+// nextValues returns the values of the next row decoded by the column types. It returns io.EOF if there are no more rows.
+func (r *Rows) nextValues() ([]Value, error) {
+	if r.rows == nil || !r.rows.Next() {
+		if r.rows != nil {
+			if err := r.rows.Err(); err != nil {
+				return nil, err
+			}
+		}
+		return nil, io.EOF
+	}
+	values := make([]interface{}, 0, len(r.columns))
+	for i := 0; i < len(r.columns); i++ {
+		var v interface{}
+		values = append(values, &v)
+	}
+	if err := r.rows.Scan(values...); err != nil {
+		return nil, err
+	}
+	ret := make([]Value, 0, len(r.columns))
+	for idx, column := range r.columns {
+		decoded, err := DecodeValue(reflect.ValueOf(values[idx]).Elem().Interface())
+		if err != nil {
+			return nil, err
+		}
+		t, err := column.Type.ToZetaSQLType()
+		if err != nil {
+			return nil, err
+		}
+		value, err := CastValue(t, decoded)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, value)
+	}
+	return ret, nil
+}
+
 func (r *Rows) assignValue(src interface{}, dst reflect.Value, typ *Type) error {
 	if src == nil {
 		dst.Set(reflect.New(dst.Type()).Elem())
@@ -275,10 +339,7 @@ func (r *Rows) assignInterfaceValue(src Value, dst reflect.Value, typ *Type) err
 		if err != nil {
 			return err
 		}
-		unixmicro := t.UnixMicro()
-		sec := unixmicro / int64(time.Millisecond)
-		nsec := unixmicro - sec*int64(time.Millisecond)
-		dst.Set(reflect.ValueOf(fmt.Sprintf("%d.%d", sec, nsec)))
+		dst.Set(reflect.ValueOf(formatTimestampValue(t)))
 	case types.INTERVAL:
 		s, err := src.ToString()
 		if err != nil {
@@ -0,0 +1,477 @@
+package internal
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	parsed_ast "github.com/goccy/go-zetasql/ast"
+	"github.com/goccy/go-zetasql/types"
+)
+
+// forInVariableParamPrefix is the prefix of the query parameter bound to the variable of FOR ... IN loop.
+const forInVariableParamPrefix = "zetasqlite_for_"
+
+var scriptVariableNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ForInStmtAction runs FOR ... IN loop.
+// The references of the variable in the body are replaced by the query parameter of STRUCT type,
+// and the body is run with each row bound to the parameter while the rows of the query are read one by one.
+type ForInStmtAction struct {
+	analyzer   *Analyzer
+	query      string
+	body       string
+	label      string
+	paramName  string
+	args       []driver.NamedValue
+	paramTypes queryParameterTypes
+}
+
+func (a *Analyzer) newForInStmtAction(query string, args []driver.NamedValue, paramTypes queryParameterTypes, node *parsed_ast.ForInStatementNode) (*ForInStmtAction, error) {
+	variable := node.Variable().Name()
+	if !scriptVariableNameRe.MatchString(variable) {
+		return nil, fmt.Errorf("unsupported variable name %s of FOR ... IN statement", variable)
+	}
+	paramName := forInVariableParamPrefix + strings.ToLower(variable)
+	loopQuery, err := nodeText(query, node.Query())
+	if err != nil {
+		return nil, err
+	}
+	var body []string
+	if list := node.Body(); list != nil {
+		for _, stmt := range list.StatementList() {
+			replaced, err := replaceVariableReferences(query, stmt, variable, "@"+paramName)
+			if err != nil {
+				return nil, err
+			}
+			body = append(body, replaced)
+		}
+	}
+	var label string
+	if l := node.Label(); l != nil {
+		label = l.Name().Name()
+	}
+	return &ForInStmtAction{
+		analyzer:   a,
+		query:      loopQuery,
+		body:       strings.Join(body, ";\n"),
+		label:      label,
+		paramName:  paramName,
+		args:       args,
+		paramTypes: paramTypes,
+	}, nil
+}
+
+// nodeText returns the text of the node in the query.
+func nodeText(query string, node parsed_ast.Node) (string, error) {
+	loc := node.ParseLocationRange()
+	if loc == nil {
+		return "", fmt.Errorf("failed to get location of %s", node.Kind())
+	}
+	return query[loc.Start().ByteOffset():loc.End().ByteOffset()], nil
+}
+
+// replaceVariableReferences returns the text of the statement whose references of the variable are replaced by replacement.
+// The variable is referred as the beginning of the path expression ( e.g. rec.table_name ) except the table path.
+func replaceVariableReferences(query string, stmt parsed_ast.StatementNode, variable, replacement string) (string, error) {
+	loc := stmt.ParseLocationRange()
+	if loc == nil {
+		return "", fmt.Errorf("failed to get location of %s", stmt.Kind())
+	}
+	stmtStart, stmtEnd := loc.Start().ByteOffset(), loc.End().ByteOffset()
+	type reference struct {
+		pathStart int
+		start     int
+		end       int
+	}
+	var references []*reference
+	tablePaths := map[int]struct{}{}
+	if err := parsed_ast.Walk(stmt, func(node parsed_ast.Node) error {
+		switch n := node.(type) {
+		case *parsed_ast.TablePathExpressionNode:
+			if path := n.PathExpr(); path != nil {
+				tablePaths[path.ParseLocationRange().Start().ByteOffset()] = struct{}{}
+			}
+		case *parsed_ast.PathExpressionNode:
+			names := n.Names()
+			if len(names) == 0 || !strings.EqualFold(names[0].Name(), variable) {
+				return nil
+			}
+			nameLoc := names[0].ParseLocationRange()
+			references = append(references, &reference{
+				pathStart: n.ParseLocationRange().Start().ByteOffset(),
+				start:     nameLoc.Start().ByteOffset(),
+				end:       nameLoc.End().ByteOffset(),
+			})
+		}
+		return nil
+	}); err != nil {
+		return "", err
+	}
+	sort.Slice(references, func(i, j int) bool {
+		return references[i].start < references[j].start
+	})
+	var (
+		b   strings.Builder
+		pos = stmtStart
+	)
+	for _, ref := range references {
+		if _, exists := tablePaths[ref.pathStart]; exists {
+			continue
+		}
+		if ref.start < pos {
+			continue
+		}
+		b.WriteString(query[pos:ref.start])
+		b.WriteString(replacement)
+		pos = ref.end
+	}
+	b.WriteString(query[pos:stmtEnd])
+	return b.String(), nil
+}
+
+func (a *ForInStmtAction) Prepare(ctx context.Context, conn *Conn) (driver.Stmt, error) {
+	return nil, fmt.Errorf("FOR ... IN statement cannot be prepared")
+}
+
+func (a *ForInStmtAction) ExecContext(ctx context.Context, conn *Conn) (driver.Result, error) {
+	if err := a.run(ctx, conn); err != nil {
+		return nil, err
+	}
+	return &Result{conn: conn}, nil
+}
+
+func (a *ForInStmtAction) QueryContext(ctx context.Context, conn *Conn) (*Rows, error) {
+	if err := a.run(ctx, conn); err != nil {
+		return nil, err
+	}
+	return &Rows{conn: conn}, nil
+}
+
+func (a *ForInStmtAction) run(ctx context.Context, conn *Conn) (e error) {
+	q, err := a.analyzer.queryScript(ctx, conn, a.query, a.args, a.paramTypes)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := q.close(ctx); err != nil && e == nil {
+			e = err
+		}
+	}()
+	fields := make([]*types.StructField, 0, len(q.rows.columns))
+	keys := make([]string, 0, len(q.rows.columns))
+	for _, column := range q.rows.columns {
+		typ, err := column.Type.ToZetaSQLType()
+		if err != nil {
+			return err
+		}
+		fields = append(fields, types.NewStructField(column.Name, typ))
+		keys = append(keys, column.Name)
+	}
+	rowType, err := types.NewStructType(fields)
+	if err != nil {
+		return fmt.Errorf("failed to create type of FOR ... IN variable: %w", err)
+	}
+	paramTypes := queryParameterTypes{a.paramName: rowType}
+	for name, typ := range a.paramTypes {
+		paramTypes[name] = typ
+	}
+	for {
+		values, err := q.rows.nextValues()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if a.body == "" {
+			continue
+		}
+		row := &StructValue{keys: keys, values: values, m: map[string]Value{}}
+		for idx, key := range keys {
+			row.m[key] = values[idx]
+		}
+		args := make([]driver.NamedValue, 0, len(a.args)+1)
+		args = append(args, a.args...)
+		args = append(args, driver.NamedValue{Name: a.paramName, Value: row})
+		actionFuncs, err := a.analyzer.analyzeScript(ctx, conn, a.body, args, paramTypes)
+		if err != nil {
+			return err
+		}
+		if _, err := execScriptActions(ctx, conn, actionFuncs); err != nil {
+			var control *loopControlError
+			if !errors.As(err, &control) || !control.isTargetLoop(a.label) {
+				return err
+			}
+			if control.isBreak {
+				return nil
+			}
+		}
+	}
+}
+
+func (a *ForInStmtAction) Args() []interface{} {
+	return nil
+}
+
+func (a *ForInStmtAction) Cleanup(ctx context.Context, conn *Conn) error {
+	return nil
+}
+
+// ExecuteImmediateStmtAction runs EXECUTE IMMEDIATE statement.
+// The SQL and the arguments of USING clause are evaluated by the query, then the SQL is run with the arguments as the query parameters.
+type ExecuteImmediateStmtAction struct {
+	analyzer    *Analyzer
+	query       string
+	argNames    []string
+	args        []driver.NamedValue
+	paramTypes  queryParameterTypes
+	queryAction StmtAction
+}
+
+func (a *Analyzer) newExecuteImmediateStmtAction(query string, args []driver.NamedValue, paramTypes queryParameterTypes, node *parsed_ast.ExecuteImmediateStatementNode) (*ExecuteImmediateStmtAction, error) {
+	if node.IntoClause() != nil {
+		return nil, fmt.Errorf("INTO clause of EXECUTE IMMEDIATE statement is unsupported")
+	}
+	sql, err := nodeText(query, node.SQL())
+	if err != nil {
+		return nil, err
+	}
+	exprs := []string{sql}
+	var argNames []string
+	if using := node.UsingClause(); using != nil {
+		for _, arg := range using.Arguments() {
+			expr, err := nodeText(query, arg.Expression())
+			if err != nil {
+				return nil, err
+			}
+			exprs = append(exprs, expr)
+			var name string
+			if alias := arg.Alias(); alias != nil {
+				name = alias.Identifier().Name()
+			}
+			argNames = append(argNames, name)
+		}
+	}
+	return &ExecuteImmediateStmtAction{
+		analyzer:   a,
+		query:      fmt.Sprintf("SELECT %s", strings.Join(exprs, ", ")),
+		argNames:   argNames,
+		args:       args,
+		paramTypes: paramTypes,
+	}, nil
+}
+
+func (a *ExecuteImmediateStmtAction) Prepare(ctx context.Context, conn *Conn) (driver.Stmt, error) {
+	return nil, fmt.Errorf("EXECUTE IMMEDIATE statement cannot be prepared")
+}
+
+// analyzeSQL evaluates the SQL and the arguments, and returns the actions of the SQL.
+func (a *ExecuteImmediateStmtAction) analyzeSQL(ctx context.Context, conn *Conn) (_ []StmtActionFunc, e error) {
+	q, err := a.analyzer.queryScript(ctx, conn, a.query, a.args, a.paramTypes)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := q.close(ctx); err != nil && e == nil {
+			e = err
+		}
+	}()
+	values, err := q.rows.nextValues()
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate SQL of EXECUTE IMMEDIATE statement: %w", err)
+	}
+	if values[0] == nil {
+		return nil, fmt.Errorf("SQL of EXECUTE IMMEDIATE statement must not be NULL")
+	}
+	sql, err := values[0].ToString()
+	if err != nil {
+		return nil, err
+	}
+	args := make([]driver.NamedValue, 0, len(a.argNames))
+	paramTypes := queryParameterTypes{}
+	for idx, name := range a.argNames {
+		value := values[idx+1]
+		if name == "" {
+			args = append(args, driver.NamedValue{Ordinal: idx + 1, Value: value})
+			continue
+		}
+		typ, err := q.rows.columns[idx+1].Type.ToZetaSQLType()
+		if err != nil {
+			return nil, err
+		}
+		paramTypes[strings.ToLower(name)] = typ
+		args = append(args, driver.NamedValue{Name: name, Value: value})
+	}
+	return a.analyzer.analyzeScript(ctx, conn, sql, args, paramTypes)
+}
+
+func (a *ExecuteImmediateStmtAction) ExecContext(ctx context.Context, conn *Conn) (driver.Result, error) {
+	actionFuncs, err := a.analyzeSQL(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	return execScriptActions(ctx, conn, actionFuncs)
+}
+
+// QueryContext returns the rows of the last statement of the SQL.
+func (a *ExecuteImmediateStmtAction) QueryContext(ctx context.Context, conn *Conn) (*Rows, error) {
+	actionFuncs, err := a.analyzeSQL(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	if len(actionFuncs) == 0 {
+		return &Rows{conn: conn}, nil
+	}
+	last := len(actionFuncs) - 1
+	if _, err := execScriptActions(ctx, conn, actionFuncs[:last]); err != nil {
+		return nil, err
+	}
+	action, err := actionFuncs[last]()
+	if err != nil {
+		return nil, err
+	}
+	// the action is cleaned up after the rows are read.
+	a.queryAction = action
+	return action.QueryContext(ctx, conn)
+}
+
+func (a *ExecuteImmediateStmtAction) Args() []interface{} {
+	return nil
+}
+
+func (a *ExecuteImmediateStmtAction) Cleanup(ctx context.Context, conn *Conn) error {
+	if a.queryAction == nil {
+		return nil
+	}
+	return a.queryAction.Cleanup(ctx, conn)
+}
+
+// loopControlError is returned by BREAK or CONTINUE statement to stop running the body of the loop.
+type loopControlError struct {
+	isBreak bool
+	label   string
+}
+
+func (e *loopControlError) Error() string {
+	keyword := "CONTINUE"
+	if e.isBreak {
+		keyword = "BREAK"
+	}
+	if e.label != "" {
+		return fmt.Sprintf("%s %s must be inside the loop labeled %s", keyword, e.label, e.label)
+	}
+	return fmt.Sprintf("%s must be inside a loop", keyword)
+}
+
+func (e *loopControlError) isTargetLoop(label string) bool {
+	return e.label == "" || strings.EqualFold(e.label, label)
+}
+
+// LoopControlStmtAction runs BREAK or CONTINUE statement.
+type LoopControlStmtAction struct {
+	err *loopControlError
+}
+
+func newLoopControlStmtAction(isBreak bool, label *parsed_ast.LabelNode) *LoopControlStmtAction {
+	err := &loopControlError{isBreak: isBreak}
+	if label != nil {
+		err.label = label.Name().Name()
+	}
+	return &LoopControlStmtAction{err: err}
+}
+
+func (a *LoopControlStmtAction) Prepare(ctx context.Context, conn *Conn) (driver.Stmt, error) {
+	return nil, a.err
+}
+
+func (a *LoopControlStmtAction) ExecContext(ctx context.Context, conn *Conn) (driver.Result, error) {
+	return nil, a.err
+}
+
+func (a *LoopControlStmtAction) QueryContext(ctx context.Context, conn *Conn) (*Rows, error) {
+	return nil, a.err
+}
+
+func (a *LoopControlStmtAction) Args() []interface{} {
+	return nil
+}
+
+func (a *LoopControlStmtAction) Cleanup(ctx context.Context, conn *Conn) error {
+	return nil
+}
+
+// scriptQuery is the rows of the query run by the script statement.
+type scriptQuery struct {
+	conn   *Conn
+	action StmtAction
+	rows   *Rows
+}
+
+func (a *Analyzer) queryScript(ctx context.Context, conn *Conn, query string, args []driver.NamedValue, paramTypes queryParameterTypes) (*scriptQuery, error) {
+	actionFuncs, err := a.analyzeScript(ctx, conn, query, args, paramTypes)
+	if err != nil {
+		return nil, err
+	}
+	if len(actionFuncs) != 1 {
+		return nil, fmt.Errorf("%s must be a single query", query)
+	}
+	action, err := actionFuncs[0]()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := action.(*QueryStmtAction); !ok {
+		return nil, fmt.Errorf("%s must be a query", query)
+	}
+	rows, err := action.QueryContext(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	return &scriptQuery{conn: conn, action: action, rows: rows}, nil
+}
+
+func (q *scriptQuery) close(ctx context.Context) error {
+	eg := new(ErrorGroup)
+	if q.rows.rows != nil {
+		eg.Add(q.rows.rows.Close())
+	}
+	eg.Add(q.action.Cleanup(ctx, q.conn))
+	if eg.HasError() {
+		return eg
+	}
+	return nil
+}
+
+// execScriptActions runs the statements in the script and returns the result of the last statement.
+// The error of the statement is returned as it is, so that the loop can handle BREAK and CONTINUE statements.
+func execScriptActions(ctx context.Context, conn *Conn, actionFuncs []StmtActionFunc) (_ driver.Result, e error) {
+	var actions []StmtAction
+	defer func() {
+		eg := new(ErrorGroup)
+		for _, action := range actions {
+			eg.Add(action.Cleanup(ctx, conn))
+		}
+		if eg.HasError() && e == nil {
+			e = eg
+		}
+	}()
+	var result driver.Result = &Result{conn: conn}
+	for _, actionFunc := range actionFuncs {
+		action, err := actionFunc()
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+		r, err := action.ExecContext(ctx, conn)
+		if err != nil {
+			return nil, err
+		}
+		result = r
+	}
+	return result, nil
+}
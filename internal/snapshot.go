@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// DatabaseSnapshot is the copy of the database contents and the catalog at the time of taking it.
+// The contents are copied to the in-memory database by the backup API of SQLite,
+// so restoring it is much faster than executing the statements to create the same state again.
+// The temporary tables are not included.
+type DatabaseSnapshot struct {
+	db        *sqlite3.SQLiteConn
+	tables    []*TableSpec
+	functions []*FunctionSpec
+	takenAt   time.Time
+}
+
+// Close releases the in-memory database holding the contents.
+func (s *DatabaseSnapshot) Close() error {
+	return s.db.Close()
+}
+
+func (c *Catalog) TakeSnapshot(ctx context.Context, conn *Conn) (*DatabaseSnapshot, error) {
+	if conn.tx != nil {
+		return nil, fmt.Errorf("failed to take snapshot: cannot take snapshot in the transaction")
+	}
+	if err := c.Sync(ctx, conn); err != nil {
+		return nil, fmt.Errorf("failed to sync catalog: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	db, err := openSnapshotDatabase()
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.rawConn(func(src *sqlite3.SQLiteConn) error {
+		return copyDatabase(db, src)
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to take snapshot: %w", err)
+	}
+	snapshot := &DatabaseSnapshot{db: db, takenAt: time.Now()}
+	for _, spec := range c.tables {
+		if spec.IsTemp {
+			continue
+		}
+		snapshot.tables = append(snapshot.tables, spec)
+	}
+	for _, spec := range c.functions {
+		if spec.IsTemp {
+			continue
+		}
+		snapshot.functions = append(snapshot.functions, spec)
+	}
+	return snapshot, nil
+}
+
+// RestoreSnapshot replaces the database contents and the catalog with the snapshot.
+// The catalog generation is bumped, so the analyzed statements cached before restoring are never used.
+func (c *Catalog) RestoreSnapshot(ctx context.Context, conn *Conn, snapshot *DatabaseSnapshot) error {
+	if conn.tx != nil {
+		return fmt.Errorf("failed to restore snapshot: cannot restore snapshot in the transaction")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := conn.rawConn(func(dst *sqlite3.SQLiteConn) error {
+		return copyDatabase(dst, snapshot.db)
+	}); err != nil {
+		return fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+	if err := c.resetCatalog(snapshot.tables, snapshot.functions); err != nil {
+		return fmt.Errorf("failed to restore catalog: %w", err)
+	}
+	c.lastSyncedAt = snapshot.takenAt
+	return nil
+}
+
+func openSnapshotDatabase() (*sqlite3.SQLiteConn, error) {
+	conn, err := (&sqlite3.SQLiteDriver{}).Open(":memory:")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database for snapshot: %w", err)
+	}
+	return conn.(*sqlite3.SQLiteConn), nil
+}
+
+func copyDatabase(dst, src *sqlite3.SQLiteConn) error {
+	backup, err := dst.Backup("main", src, "main")
+	if err != nil {
+		return fmt.Errorf("failed to start backup: %w", err)
+	}
+	if _, err := backup.Step(-1); err != nil {
+		_ = backup.Finish()
+		return fmt.Errorf("failed to copy database: %w", err)
+	}
+	if err := backup.Finish(); err != nil {
+		return fmt.Errorf("failed to finish backup: %w", err)
+	}
+	return nil
+}
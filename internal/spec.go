@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
@@ -34,15 +35,17 @@ func (t *NameWithType) FunctionArgumentType() (*types.FunctionArgumentType, erro
 }
 
 type FunctionSpec struct {
-	IsTemp    bool            `json:"isTemp"`
-	NamePath  []string        `json:"name"`
-	Language  string          `json:"language"`
-	Args      []*NameWithType `json:"args"`
-	Return    *Type           `json:"return"`
-	Body      string          `json:"body"`
-	Code      string          `json:"code"`
-	UpdatedAt time.Time       `json:"updatedAt"`
-	CreatedAt time.Time       `json:"createdAt"`
+	IsTemp      bool            `json:"isTemp"`
+	NamePath    []string        `json:"name"`
+	Language    string          `json:"language"`
+	Args        []*NameWithType `json:"args"`
+	Return      *Type           `json:"return"`
+	Body        string          `json:"body"`
+	Code        string          `json:"code"`
+	Options     []*OptionSpec   `json:"options"`
+	Description string          `json:"description"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+	CreatedAt   time.Time       `json:"createdAt"`
 }
 
 func (s *FunctionSpec) FuncName() string {
@@ -93,25 +96,97 @@ func (s *FunctionSpec) CallSQL(ctx context.Context, callNode *ast.BaseFunctionCa
 		body = runtimeSpec.Body
 	} else {
 		body = s.Body
+		coercedArgs, err := s.coerceArguments(ctx, args, argValues)
+		if err != nil {
+			return "", err
+		}
+		argValues = coercedArgs
+	}
+	return fmt.Sprintf("( %s )", s.replaceArguments(body, argValues)), nil
+}
+
+// coerceArguments casts the arguments whose types differ from the declared types of the function.
+// The analyzer may coerce the argument without the cast node ( e.g. STRING literal passed as DATE argument ),
+// so the argument is cast explicitly so that the body compares the values of the declared types.
+func (s *FunctionSpec) coerceArguments(ctx context.Context, args []ast.ExprNode, argValues []string) ([]string, error) {
+	coerced := make([]string, 0, len(argValues))
+	for idx, argValue := range argValues {
+		if idx >= len(args) || idx >= len(s.Args) || s.Args[idx].Type.SignatureKind != types.ArgTypeFixed {
+			coerced = append(coerced, argValue)
+			continue
+		}
+		declaredType := s.Args[idx].Type
+		argType := newType(args[idx].Type())
+		if argType.FormatType() == declaredType.FormatType() {
+			coerced = append(coerced, argValue)
+			continue
+		}
+		casted, err := formatCast(ctx, argValue, argType, declaredType, false)
+		if err != nil {
+			return nil, err
+		}
+		coerced = append(coerced, casted)
 	}
+	return coerced, nil
+}
+
+// argumentPlaceholder returns the token that refers to the argument in the function body.
+// The token is enclosed with delimiters so that it is not confused with other identifiers or arguments whose names share the prefix.
+func argumentPlaceholder(name string) string {
+	return fmt.Sprintf("@@zetasqlite_arg_%s@@", name)
+}
+
+func (s *FunctionSpec) replaceArguments(body string, argValues []string) string {
+	if !strings.Contains(body, "@@zetasqlite_arg_") {
+		return s.replaceLegacyArguments(body, argValues)
+	}
+	replacePairs := make([]string, 0, len(s.Args)*2)
 	for i := 0; i < len(s.Args); i++ {
-		argRef := fmt.Sprintf("@%s", s.Args[i].Name)
-		value := argValues[i]
-		body = strings.Replace(body, argRef, value, -1)
+		replacePairs = append(replacePairs, argumentPlaceholder(s.Args[i].Name), argValues[i])
 	}
-	return fmt.Sprintf("( %s )", body), nil
+	return strings.NewReplacer(replacePairs...).Replace(body)
+}
+
+// replaceLegacyArguments replaces the arguments of the function body saved by the older version ( referred as @name ).
+// Longer names are replaced first so that the argument is not replaced by another argument that has the same prefix.
+func (s *FunctionSpec) replaceLegacyArguments(body string, argValues []string) string {
+	indexes := make([]int, 0, len(s.Args))
+	for i := 0; i < len(s.Args); i++ {
+		indexes = append(indexes, i)
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return len(s.Args[indexes[i]].Name) > len(s.Args[indexes[j]].Name)
+	})
+	replacePairs := make([]string, 0, len(s.Args)*2)
+	for _, idx := range indexes {
+		replacePairs = append(replacePairs, fmt.Sprintf("@%s", s.Args[idx].Name), argValues[idx])
+	}
+	return strings.NewReplacer(replacePairs...).Replace(body)
 }
 
 type TableSpec struct {
-	IsTemp     bool           `json:"isTemp"`
-	IsView     bool           `json:"isView"`
-	NamePath   []string       `json:"namePath"`
-	Columns    []*ColumnSpec  `json:"columns"`
-	PrimaryKey []string       `json:"primaryKey"`
-	CreateMode ast.CreateMode `json:"createMode"`
-	Query      string         `json:"query"`
-	UpdatedAt  time.Time      `json:"updatedAt"`
-	CreatedAt  time.Time      `json:"createdAt"`
+	IsTemp                 bool                   `json:"isTemp"`
+	IsView                 bool                   `json:"isView"`
+	IsNative               bool                   `json:"isNative"`
+	NamePath               []string               `json:"namePath"`
+	Columns                []*ColumnSpec          `json:"columns"`
+	PrimaryKey             []string               `json:"primaryKey"`
+	CreateMode             ast.CreateMode         `json:"createMode"`
+	Query                  string                 `json:"query"`
+	Definition             string                 `json:"definition"`
+	Options                []*OptionSpec          `json:"options"`
+	Description            string                 `json:"description"`
+	Labels                 map[string]string      `json:"labels"`
+	ExpirationTimestamp    *time.Time             `json:"expirationTimestamp"`
+	Grants                 []*GrantSpec           `json:"grants"`
+	RowAccessPolicies      []*RowAccessPolicySpec `json:"rowAccessPolicies"`
+	CheckConstraints       []*CheckConstraintSpec `json:"checkConstraints"`
+	Partition              *PartitionSpec         `json:"partition"`
+	ClusterBy              []string               `json:"clusterBy"`
+	RequirePartitionFilter bool                   `json:"requirePartitionFilter"`
+	InvalidReason          string                 `json:"invalidReason"`
+	UpdatedAt              time.Time              `json:"updatedAt"`
+	CreatedAt              time.Time              `json:"createdAt"`
 }
 
 func (s *TableSpec) Column(name string) *ColumnSpec {
@@ -139,9 +214,13 @@ func (s *TableSpec) SQLiteSchema() string {
 		columns = append(columns, c.SQLiteSchema())
 	}
 	if len(s.PrimaryKey) != 0 {
+		primaryKeys := make([]string, 0, len(s.PrimaryKey))
+		for _, key := range s.PrimaryKey {
+			primaryKeys = append(primaryKeys, fmt.Sprintf("`%s`", key))
+		}
 		columns = append(
 			columns,
-			fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(s.PrimaryKey, ",")),
+			fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaryKeys, ",")),
 		)
 	}
 	var stmt string
@@ -363,7 +442,7 @@ func newFunctionSpec(ctx context.Context, namePath *NamePath, stmt *ast.CreateFu
 		argParams := make([]string, 0, len(args))
 		argNames := make([]string, 0, len(args))
 		for _, arg := range args {
-			argParams = append(argParams, fmt.Sprintf("@%s", arg.Name))
+			argParams = append(argParams, argumentPlaceholder(arg.Name))
 			argNames = append(argNames, arg.Name)
 		}
 		if len(argParams) == 0 {
@@ -545,6 +624,7 @@ func newTableAsViewSpec(namePath *NamePath, query string, stmt *ast.CreateViewSt
 		Columns:    newColumnsFromOutputColumns(stmt.OutputColumnList()),
 		CreateMode: stmt.CreateMode(),
 		Query:      fmt.Sprintf("SELECT %s FROM (%s)", strings.Join(outputColumns, ","), query),
+		Definition: stmt.SQL(),
 		UpdatedAt:  now,
 		CreatedAt:  now,
 	}
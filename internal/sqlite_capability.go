@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteQueryer is the part of sqlite3.SQLiteConn used by the capability check.
+type sqliteQueryer interface {
+	Query(query string, args []driver.Value) (driver.Rows, error)
+}
+
+var (
+	sqliteCapabilityOnce sync.Once
+	sqliteCapabilityErr  error
+)
+
+// checkSQLiteCapabilities verifies that the SQLite linked to the driver behaves as the formatted queries expect.
+// The result only depends on the SQLite library, so the check runs only once in the process.
+func checkSQLiteCapabilities(conn sqliteQueryer) error {
+	sqliteCapabilityOnce.Do(func() {
+		sqliteCapabilityErr = checkUnnestCapability(conn, formatUnnestSource)
+	})
+	return sqliteCapabilityErr
+}
+
+// unnestCapabilityValues is the array to check UNNEST. It contains each representation of the encoded elements
+// ( the native INT64 and FLOAT64, the encoded string and NULL ), and the expected rows are ordered by the offset.
+var unnestCapabilityValues = []Value{
+	IntValue(10),
+	StringValue("a"),
+	nil,
+	FloatValue(1.5),
+}
+
+// checkUnnestCapability runs UNNEST of the small array with source and compares the rows with the array.
+// The error describes how to build the driver with the required SQLite features.
+func checkUnnestCapability(conn sqliteQueryer, source func(arrayExpr, alias string) string) error {
+	arrayExpr, err := LiteralFromValue(&ArrayValue{values: unnestCapabilityValues})
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("SELECT `t`.key, `t`.value FROM %s", source(arrayExpr, "t"))
+	rows, err := conn.Query(query, nil)
+	if err != nil {
+		return newUnnestCapabilityError(err)
+	}
+	defer rows.Close()
+
+	var got [][]driver.Value
+	for {
+		row := make([]driver.Value, 2)
+		if err := rows.Next(row); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return newUnnestCapabilityError(err)
+		}
+		got = append(got, row)
+	}
+	if err := validateUnnestRows(got); err != nil {
+		return newUnnestCapabilityError(err)
+	}
+	return nil
+}
+
+func validateUnnestRows(rows [][]driver.Value) error {
+	if len(rows) != len(unnestCapabilityValues) {
+		return fmt.Errorf("expected %d rows but got %d rows", len(unnestCapabilityValues), len(rows))
+	}
+	for idx, row := range rows {
+		offset, ok := row[0].(int64)
+		if !ok || offset != int64(idx) {
+			return fmt.Errorf("expected offset %d at row %d but got %v", idx, idx, row[0])
+		}
+		expected, err := EncodeValue(unnestCapabilityValues[idx])
+		if err != nil {
+			return err
+		}
+		value, err := DecodeValue(row[1])
+		if err != nil {
+			return fmt.Errorf("failed to decode element at row %d: %w", idx, err)
+		}
+		actual, err := EncodeValue(value)
+		if err != nil {
+			return err
+		}
+		if actual != expected {
+			return fmt.Errorf("expected element %v at row %d but got %v", expected, idx, row[1])
+		}
+	}
+	return nil
+}
+
+func newUnnestCapabilityError(err error) error {
+	return fmt.Errorf(
+		"zetasqlite: SQLite %s doesn't support UNNEST by %s: %w. "+
+			"build with the JSON1 extension of SQLite 3.38.0 or later ( e.g. without the libsqlite3 build tag ), "+
+			"or with the sqlite_vtable build tag to use the table-valued function of zetasqlite instead",
+		sqliteVersion(),
+		unnestSourceName,
+		err,
+	)
+}
+
+func sqliteVersion() string {
+	version, _, _ := sqlite3.Version()
+	return version
+}
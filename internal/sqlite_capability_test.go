@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type fakeSQLiteQueryer struct {
+	rows [][]driver.Value
+	err  error
+}
+
+func (q *fakeSQLiteQueryer) Query(_ string, _ []driver.Value) (driver.Rows, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	return &fakeSQLiteRows{rows: q.rows}, nil
+}
+
+type fakeSQLiteRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeSQLiteRows) Columns() []string { return []string{"key", "value"} }
+
+func (r *fakeSQLiteRows) Close() error { return nil }
+
+func (r *fakeSQLiteRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func unnestCapabilityRows(t *testing.T) [][]driver.Value {
+	t.Helper()
+	rows := make([][]driver.Value, 0, len(unnestCapabilityValues))
+	for idx, value := range unnestCapabilityValues {
+		v, err := EncodeValue(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, []driver.Value{int64(idx), v})
+	}
+	return rows
+}
+
+func TestCheckUnnestCapability(t *testing.T) {
+	t.Run("supported", func(t *testing.T) {
+		if err := checkUnnestCapability(&fakeSQLiteQueryer{rows: unnestCapabilityRows(t)}, formatUnnestSource); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("missing function", func(t *testing.T) {
+		err := checkUnnestCapability(&fakeSQLiteQueryer{err: errors.New("no such table: json_each")}, formatUnnestSource)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		for _, expected := range []string{"no such table: json_each", "sqlite_vtable build tag"} {
+			if !strings.Contains(err.Error(), expected) {
+				t.Fatalf("error must contain %q: %v", expected, err)
+			}
+		}
+	})
+	t.Run("unordered rows", func(t *testing.T) {
+		rows := unnestCapabilityRows(t)
+		rows[0], rows[1] = rows[1], rows[0]
+		if err := checkUnnestCapability(&fakeSQLiteQueryer{rows: rows}, formatUnnestSource); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("changed element", func(t *testing.T) {
+		rows := unnestCapabilityRows(t)
+		rows[1][1] = "a"
+		if err := checkUnnestCapability(&fakeSQLiteQueryer{rows: rows}, formatUnnestSource); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("missing rows", func(t *testing.T) {
+		rows := unnestCapabilityRows(t)
+		if err := checkUnnestCapability(&fakeSQLiteQueryer{rows: rows[:2]}, formatUnnestSource); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/goccy/go-zetasql"
+	parsed_ast "github.com/goccy/go-zetasql/ast"
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+)
+
+// Kind is the kind of the statement to route it or to restrict it by the read-only mode.
+type Kind string
+
+const (
+	// KindSelect is the query statement.
+	KindSelect Kind = "SELECT"
+	// KindDML is INSERT, UPDATE, DELETE, MERGE and TRUNCATE TABLE statements.
+	KindDML Kind = "DML"
+	// KindDDL is the statement to change the catalog such as CREATE, DROP, ALTER, GRANT and IMPORT statements.
+	KindDDL Kind = "DDL"
+	// KindScript is the multi-statement query and the procedural language statements
+	// such as BEGIN...END, DECLARE, SET, IF, LOOP, EXECUTE IMMEDIATE and the transaction statements.
+	KindScript Kind = "SCRIPT"
+)
+
+// ErrReadOnly is the error matched with the error returned when the statement is rejected by the read-only mode.
+var ErrReadOnly = errors.New("connection is read-only")
+
+// ReadOnlyError is the error of the statement rejected by the read-only mode.
+type ReadOnlyError struct {
+	Kind Kind
+}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("%s: %s statement is not allowed", ErrReadOnly, e.Kind)
+}
+
+func (e *ReadOnlyError) Is(target error) bool {
+	return target == ErrReadOnly
+}
+
+// StatementKind parses the query and returns the kind of it. The query including multiple statements is KindScript.
+// The catalog is not needed because the kind is determined by the type of the parsed statement.
+func StatementKind(query string) (Kind, error) {
+	opt, err := newAnalyzerOptions(defaultLanguageFeatures)
+	if err != nil {
+		return "", err
+	}
+	query, err = rewriteGroupByAll(query, opt.ParserOptions())
+	if err != nil {
+		return "", fmt.Errorf("failed to parse statements: %w", err)
+	}
+	stmts, err := parseScriptStatements(query, opt.ParserOptions())
+	if err != nil {
+		return "", fmt.Errorf("failed to parse statements: %w", err)
+	}
+	return statementKindOfScript(stmts), nil
+}
+
+func parseScriptStatements(query string, opt *zetasql.ParserOptions) ([]parsed_ast.StatementNode, error) {
+	loc := zetasql.NewParseResumeLocation(query)
+	var stmts []parsed_ast.StatementNode
+	for {
+		stmt, isEnd, err := zetasql.ParseNextScriptStatement(loc, opt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse statement: %w", err)
+		}
+		stmts = append(stmts, stmt)
+		if isEnd {
+			break
+		}
+	}
+	return stmts, nil
+}
+
+func statementKindOfScript(stmts []parsed_ast.StatementNode) Kind {
+	if len(stmts) != 1 {
+		return KindScript
+	}
+	return parsedStatementKind(stmts[0])
+}
+
+func parsedStatementKind(stmt parsed_ast.StatementNode) Kind {
+	switch s := stmt.(type) {
+	case *parsed_ast.HintedStatementNode:
+		return parsedStatementKind(s.Statement())
+	case *parsed_ast.QueryStatementNode:
+		return KindSelect
+	case *parsed_ast.InsertStatementNode,
+		*parsed_ast.UpdateStatementNode,
+		*parsed_ast.DeleteStatementNode,
+		*parsed_ast.MergeStatementNode,
+		*parsed_ast.TrucateStatementNode:
+		return KindDML
+	case *parsed_ast.BeginEndBlockNode,
+		*parsed_ast.VariableDeclarationNode,
+		*parsed_ast.SingleAssignmentNode,
+		*parsed_ast.ParameterAssignmentNode,
+		*parsed_ast.SystemVariableAssignmentNode,
+		*parsed_ast.AssignmentFromStructNode,
+		*parsed_ast.IfStatementNode,
+		*parsed_ast.CaseStatementNode,
+		*parsed_ast.LoopStatementNode,
+		*parsed_ast.WhileStatementNode,
+		*parsed_ast.RepeatStatementNode,
+		*parsed_ast.ForInStatementNode,
+		*parsed_ast.BreakStatementNode,
+		*parsed_ast.ContinueStatementNode,
+		*parsed_ast.ReturnStatementNode,
+		*parsed_ast.RaiseStatementNode,
+		*parsed_ast.ExecuteImmediateStatementNode,
+		*parsed_ast.CallStatementNode,
+		*parsed_ast.BeginStatementNode,
+		*parsed_ast.CommitStatementNode,
+		*parsed_ast.RollbackStatementNode:
+		return KindScript
+	}
+	return KindDDL
+}
+
+// resolvedStatementKind returns the kind of the analyzed statement. It is checked in addition to the parsed statement,
+// so that the read-only mode never depends on how the statement is written.
+func resolvedStatementKind(stmt ast.StatementNode) Kind {
+	switch stmt.Kind() {
+	case ast.QueryStmt:
+		return KindSelect
+	case ast.InsertStmt, ast.UpdateStmt, ast.DeleteStmt, ast.MergeStmt, ast.TruncateStmt:
+		return KindDML
+	case ast.BeginStmt, ast.CommitStmt, ast.RollbackStmt, ast.ExecuteImmediateStmt,
+		ast.AssignmentStmt, ast.CallStmt:
+		return KindScript
+	}
+	return KindDDL
+}
+
+// SetReadOnlyMode when enabled, the statements other than a single query statement are rejected by ReadOnlyError.
+func (a *Analyzer) SetReadOnlyMode(enabled bool) {
+	a.isReadOnlyMode = enabled
+}
+
+// validateReadOnlyScript rejects the script before analyzing any statement in it,
+// so that no statement of the script is executed in the read-only mode.
+func (a *Analyzer) validateReadOnlyScript(stmts []parsed_ast.StatementNode) error {
+	if !a.isReadOnlyMode {
+		return nil
+	}
+	if kind := statementKindOfScript(stmts); kind != KindSelect {
+		return &ReadOnlyError{Kind: kind}
+	}
+	return nil
+}
+
+func (a *Analyzer) validateReadOnlyStmt(stmt ast.StatementNode) error {
+	if !a.isReadOnlyMode {
+		return nil
+	}
+	if kind := resolvedStatementKind(stmt); kind != KindSelect {
+		return &ReadOnlyError{Kind: kind}
+	}
+	return nil
+}
+
+// validateReadOnlyOperation rejects the operation changing the database by the API such as InsertRows.
+func (a *Analyzer) validateReadOnlyOperation(kind Kind) error {
+	if !a.isReadOnlyMode {
+		return nil
+	}
+	return &ReadOnlyError{Kind: kind}
+}
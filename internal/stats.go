@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"time"
+
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+)
+
+// QueryStats is the statistics of the statements executed by a single Exec/Query call.
+type QueryStats struct {
+	// Duration is the wall time taken to execute the statements.
+	// For queries, the time spent reading the rows until Close is included.
+	Duration time.Duration
+	// RowsReturned is the number of rows read from the result of the query.
+	RowsReturned int64
+	// ReferencedTables is the list of tables referenced by the statements.
+	ReferencedTables []*ReferencedTable
+	// DMLStats is the number of rows changed by INSERT, UPDATE, DELETE and MERGE statements.
+	// nil if no DML statement is executed.
+	DMLStats *DMLStats
+	// Labels is the labels set by @@query_label and the context ( see WithQueryLabels ).
+	Labels map[string]string
+	// Priority is the priority specified by the context ( see WithQueryPriority ). INTERACTIVE by default.
+	Priority string
+
+	startedAt time.Time
+}
+
+// ReferencedTable represents a table referenced by a statement.
+type ReferencedTable struct {
+	Name    string
+	Columns []string
+	// RowsRead is the number of rows stored in the table when the statement was executed.
+	// Since zetasqlite scans tables as a whole, this is an upper bound of the rows read by the statement.
+	RowsRead int64
+}
+
+// DMLStats is the number of rows changed by each action like dmlStats of the job statistics of BigQuery.
+// The rows changed by all actions of MERGE statement are counted by each action.
+type DMLStats struct {
+	InsertedRowCount int64
+	UpdatedRowCount  int64
+	DeletedRowCount  int64
+}
+
+// AffectedRowCount returns the total number of changed rows ( numDmlAffectedRows of BigQuery ).
+func (s *DMLStats) AffectedRowCount() int64 {
+	return s.InsertedRowCount + s.UpdatedRowCount + s.DeletedRowCount
+}
+
+type dmlKind int
+
+const (
+	dmlKindNone dmlKind = iota
+	dmlKindInsert
+	dmlKindUpdate
+	dmlKindDelete
+)
+
+func dmlKindFromNode(node ast.Node) dmlKind {
+	switch node.(type) {
+	case *ast.InsertStmtNode:
+		return dmlKindInsert
+	case *ast.UpdateStmtNode:
+		return dmlKindUpdate
+	case *ast.DeleteStmtNode:
+		return dmlKindDelete
+	}
+	return dmlKindNone
+}
+
+func newQueryStats() *QueryStats {
+	return &QueryStats{startedAt: time.Now()}
+}
+
+func (s *QueryStats) finish() {
+	s.Duration = time.Since(s.startedAt)
+}
+
+// addDMLResult counts the rows changed by the statement and returns the number of them.
+func (s *QueryStats) addDMLResult(kind dmlKind, result driver.Result) (int64, error) {
+	if kind == dmlKindNone || result == nil {
+		return 0, nil
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if s.DMLStats == nil {
+		s.DMLStats = &DMLStats{}
+	}
+	switch kind {
+	case dmlKindInsert:
+		s.DMLStats.InsertedRowCount += n
+	case dmlKindUpdate:
+		s.DMLStats.UpdatedRowCount += n
+	case dmlKindDelete:
+		s.DMLStats.DeletedRowCount += n
+	}
+	return n, nil
+}
+
+func (s *QueryStats) addReferencedTables(ctx context.Context, conn *Conn, tables []*ReferencedTable) error {
+	for _, table := range tables {
+		var count int64
+		if err := conn.QueryRowContext(
+			ctx,
+			fmt.Sprintf("SELECT COUNT(*) FROM `%s`", table.Name),
+		).Scan(&count); err != nil {
+			return fmt.Errorf("failed to count rows of %s: %w", table.Name, err)
+		}
+		s.ReferencedTables = append(s.ReferencedTables, &ReferencedTable{
+			Name:     table.Name,
+			Columns:  table.Columns,
+			RowsRead: count,
+		})
+	}
+	return nil
+}
+
+func getReferencedTablesFromNode(ctx context.Context, node ast.Node) ([]*ReferencedTable, error) {
+	var (
+		tables         []*ReferencedTable
+		nameToTableMap = map[string]*ReferencedTable{}
+		nameToColMap   = map[string]map[string]struct{}{}
+	)
+	if err := ast.Walk(node, func(n ast.Node) error {
+		scan, ok := n.(*ast.TableScanNode)
+		if !ok {
+			return nil
+		}
+		switch scan.Table().(type) {
+		case *WildcardTable, *InformationSchemaTable, *withClauseTable:
+			return nil
+		}
+		name, err := getTableName(ctx, scan)
+		if err != nil {
+			return err
+		}
+		table, exists := nameToTableMap[name]
+		if !exists {
+			table = &ReferencedTable{Name: name}
+			tables = append(tables, table)
+			nameToTableMap[name] = table
+			nameToColMap[name] = map[string]struct{}{}
+		}
+		for _, col := range scan.ColumnList() {
+			if _, exists := nameToColMap[name][col.Name()]; exists {
+				continue
+			}
+			nameToColMap[name][col.Name()] = struct{}{}
+			table.Columns = append(table.Columns, col.Name())
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get referenced tables: %w", err)
+	}
+	for _, table := range tables {
+		sort.Strings(table.Columns)
+	}
+	return tables, nil
+}
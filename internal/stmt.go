@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
+	"reflect"
 
 	ast "github.com/goccy/go-zetasql/resolved_ast"
 )
@@ -42,6 +43,9 @@ func (s *CreateTableStmt) Exec(args []driver.Value) (driver.Result, error) {
 	if _, err := s.stmt.Exec(args); err != nil {
 		return nil, err
 	}
+	if err := s.spec.createCheckConstraintTriggers(context.Background(), s.conn); err != nil {
+		return nil, err
+	}
 	if err := s.catalog.AddNewTableSpec(context.Background(), s.conn, s.spec); err != nil {
 		return nil, fmt.Errorf("failed to add new table spec: %w", err)
 	}
@@ -129,20 +133,52 @@ type DMLStmt struct {
 	stmt           *sql.Stmt
 	args           []*ast.ParameterNode
 	formattedQuery string
+	validator      *insertValidator
+	paramTypes     queryParameterTypes
 }
 
-func newDMLStmt(stmt *sql.Stmt, args []*ast.ParameterNode, formattedQuery string) *DMLStmt {
+func newDMLStmt(stmt *sql.Stmt, args []*ast.ParameterNode, formattedQuery string, validator *insertValidator, paramTypes queryParameterTypes) *DMLStmt {
 	return &DMLStmt{
 		stmt:           stmt,
 		args:           args,
 		formattedQuery: formattedQuery,
+		validator:      validator,
+		paramTypes:     paramTypes,
 	}
 }
 
-func (s *DMLStmt) CheckNamedValue(value *driver.NamedValue) error {
+// CheckNamedValue converts the value implementing driver.Valuer before binding it.
+// The other values are accepted as is because they are converted by the parameter type of the statement.
+func CheckNamedValue(value *driver.NamedValue) error {
+	valuer, ok := value.Value.(driver.Valuer)
+	if !ok {
+		return nil
+	}
+	if rv := reflect.ValueOf(valuer); rv.Kind() == reflect.Ptr && rv.IsNil() &&
+		rv.Type().Elem().Implements(reflect.TypeOf((*driver.Valuer)(nil)).Elem()) {
+		// the nil pointer to the type implementing Value by the value receiver is regarded as NULL like database/sql.
+		value.Value = nil
+		return nil
+	}
+	v, err := valuer.Value()
+	if err != nil {
+		return fmt.Errorf("failed to convert parameter %s by driver.Valuer: %w", namedValueName(value), err)
+	}
+	value.Value = v
 	return nil
 }
 
+func namedValueName(value *driver.NamedValue) string {
+	if value.Name != "" {
+		return value.Name
+	}
+	return fmt.Sprint(value.Ordinal)
+}
+
+func (s *DMLStmt) CheckNamedValue(value *driver.NamedValue) error {
+	return CheckNamedValue(value)
+}
+
 func (s *DMLStmt) Close() error {
 	return s.stmt.Close()
 }
@@ -156,6 +192,12 @@ func (s *DMLStmt) Exec(args []driver.Value) (driver.Result, error) {
 	for _, arg := range args {
 		values = append(values, arg)
 	}
+	if err := s.paramTypes.validateValues(s.args, values); err != nil {
+		return nil, err
+	}
+	if err := s.validator.validate(values); err != nil {
+		return nil, err
+	}
 	newArgs, err := EncodeGoValues(values, s.args)
 	if err != nil {
 		return nil, err
@@ -166,7 +208,7 @@ func (s *DMLStmt) Exec(args []driver.Value) (driver.Result, error) {
 			"failed to execute query %s: args %v: %w",
 			s.formattedQuery,
 			newArgs,
-			err,
+			translateConstraintError(err),
 		)
 	}
 	return result, nil
@@ -189,19 +231,23 @@ type QueryStmt struct {
 	args           []*ast.ParameterNode
 	formattedQuery string
 	outputColumns  []*ColumnSpec
+	paramTypes     queryParameterTypes
+	limit          *resourceLimit
 }
 
-func newQueryStmt(stmt *sql.Stmt, args []*ast.ParameterNode, formattedQuery string, outputColumns []*ColumnSpec) *QueryStmt {
+func newQueryStmt(stmt *sql.Stmt, args []*ast.ParameterNode, formattedQuery string, outputColumns []*ColumnSpec, paramTypes queryParameterTypes, limit *resourceLimit) *QueryStmt {
 	return &QueryStmt{
 		stmt:           stmt,
 		args:           args,
 		formattedQuery: formattedQuery,
 		outputColumns:  outputColumns,
+		paramTypes:     paramTypes,
+		limit:          limit,
 	}
 }
 
 func (s *QueryStmt) CheckNamedValue(value *driver.NamedValue) error {
-	return nil
+	return CheckNamedValue(value)
 }
 
 func (s *QueryStmt) Close() error {
@@ -229,6 +275,9 @@ func (s *QueryStmt) Query(args []driver.Value) (driver.Rows, error) {
 	for _, arg := range args {
 		values = append(values, arg)
 	}
+	if err := s.paramTypes.validateValues(s.args, values); err != nil {
+		return nil, err
+	}
 	newArgs, err := EncodeGoValues(values, s.args)
 	if err != nil {
 		return nil, err
@@ -250,7 +299,7 @@ func (s *QueryStmt) Query(args []driver.Value) (driver.Rows, error) {
 			err,
 		)
 	}
-	return &Rows{rows: rows, columns: s.outputColumns}, nil
+	return &Rows{rows: rows, columns: s.outputColumns, limit: s.limit}, nil
 }
 
 func (s *QueryStmt) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
@@ -5,6 +5,7 @@ import (
 	"database/sql/driver"
 	"fmt"
 	"strings"
+	"time"
 
 	ast "github.com/goccy/go-zetasql/resolved_ast"
 )
@@ -17,10 +18,14 @@ type StmtAction interface {
 	Args() []interface{}
 }
 
+// createOrReplaceSavepointName is the savepoint to roll back CREATE OR REPLACE statements.
+const createOrReplaceSavepointName = "zetasqlite_create_or_replace"
+
 type CreateTableStmtAction struct {
 	query           string
 	args            []interface{}
 	spec            *TableSpec
+	options         []*optionExpr
 	catalog         *Catalog
 	isAutoIndexMode bool
 }
@@ -34,6 +39,9 @@ func (a *CreateTableStmtAction) Prepare(ctx context.Context, conn *Conn) (driver
 			return nil, err
 		}
 	}
+	if err := a.spec.applyOptions(ctx, conn, a.options); err != nil {
+		return nil, err
+	}
 	stmt, err := conn.PrepareContext(ctx, a.spec.SQLiteSchema())
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare %s: %w", a.query, err)
@@ -48,7 +56,7 @@ func (a *CreateTableStmtAction) createIndexAutomatically(ctx context.Context, co
 		}
 		indexName := fmt.Sprintf("zetasqlite_autoindex_%s_%s", col.Name, strings.Join(a.spec.NamePath, "_"))
 		createIndexQuery := fmt.Sprintf(
-			"CREATE INDEX IF NOT EXISTS %s ON `%s`(`%s`)",
+			"CREATE INDEX IF NOT EXISTS `%s` ON `%s`(`%s`)",
 			indexName,
 			a.spec.TableName(),
 			col.Name,
@@ -62,21 +70,35 @@ func (a *CreateTableStmtAction) createIndexAutomatically(ctx context.Context, co
 
 func (a *CreateTableStmtAction) exec(ctx context.Context, conn *Conn) error {
 	if a.spec.CreateMode == ast.CreateOrReplaceMode {
-		if _, err := conn.ExecContext(
-			ctx,
-			fmt.Sprintf("DROP TABLE IF EXISTS `%s`", a.spec.TableName()),
-		); err != nil {
-			return err
-		}
+		// BigQuery replaces the table atomically, so the existing table must be kept if creating the new one fails.
+		return conn.withSavepoint(ctx, createOrReplaceSavepointName, func() error {
+			if _, err := conn.ExecContext(
+				ctx,
+				fmt.Sprintf("DROP TABLE IF EXISTS `%s`", a.spec.TableName()),
+			); err != nil {
+				return err
+			}
+			return a.create(ctx, conn)
+		})
 	}
+	return a.create(ctx, conn)
+}
+
+func (a *CreateTableStmtAction) create(ctx context.Context, conn *Conn) error {
 	if _, err := conn.ExecContext(ctx, a.spec.SQLiteSchema(), a.args...); err != nil {
 		return fmt.Errorf("failed to exec %s: %w", a.query, err)
 	}
+	if err := a.spec.createCheckConstraintTriggers(ctx, conn); err != nil {
+		return err
+	}
 	if a.isAutoIndexMode {
 		if err := a.createIndexAutomatically(ctx, conn); err != nil {
 			return err
 		}
 	}
+	if err := a.spec.applyOptions(ctx, conn, a.options); err != nil {
+		return err
+	}
 	if err := a.catalog.AddNewTableSpec(ctx, conn, a.spec); err != nil {
 		return fmt.Errorf("failed to add new table spec: %w", err)
 	}
@@ -124,6 +146,7 @@ func (a *CreateTableStmtAction) Cleanup(ctx context.Context, conn *Conn) error {
 type CreateViewStmtAction struct {
 	query   string
 	spec    *TableSpec
+	options []*optionExpr
 	catalog *Catalog
 }
 
@@ -136,6 +159,9 @@ func (a *CreateViewStmtAction) Prepare(ctx context.Context, conn *Conn) (driver.
 			return nil, err
 		}
 	}
+	if err := a.spec.applyOptions(ctx, conn, a.options); err != nil {
+		return nil, err
+	}
 	stmt, err := conn.PrepareContext(ctx, a.spec.SQLiteSchema())
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare %s: %w", a.query, err)
@@ -145,17 +171,26 @@ func (a *CreateViewStmtAction) Prepare(ctx context.Context, conn *Conn) (driver.
 
 func (a *CreateViewStmtAction) exec(ctx context.Context, conn *Conn) error {
 	if a.spec.CreateMode == ast.CreateOrReplaceMode {
-		if _, err := conn.ExecContext(
-			ctx,
-			fmt.Sprintf("DROP VIEW IF EXISTS `%s`", a.spec.TableName()),
-		); err != nil {
-			return err
-		}
+		return conn.withSavepoint(ctx, createOrReplaceSavepointName, func() error {
+			if _, err := conn.ExecContext(
+				ctx,
+				fmt.Sprintf("DROP VIEW IF EXISTS `%s`", a.spec.TableName()),
+			); err != nil {
+				return err
+			}
+			return a.create(ctx, conn)
+		})
 	}
+	return a.create(ctx, conn)
+}
+
+func (a *CreateViewStmtAction) create(ctx context.Context, conn *Conn) error {
 	if _, err := conn.ExecContext(ctx, a.spec.SQLiteSchema()); err != nil {
 		return fmt.Errorf("failed to exec %s: %w", a.query, err)
 	}
-
+	if err := a.spec.applyOptions(ctx, conn, a.options); err != nil {
+		return err
+	}
 	if err := a.catalog.AddNewTableSpec(ctx, conn, a.spec); err != nil {
 		return fmt.Errorf("failed to add new view spec: %w", err)
 	}
@@ -199,15 +234,22 @@ func (a *CreateViewStmtAction) Args() []interface{} {
 
 type CreateFunctionStmtAction struct {
 	spec    *FunctionSpec
+	options []*optionExpr
 	catalog *Catalog
 	funcMap map[string]*FunctionSpec
 }
 
 func (a *CreateFunctionStmtAction) Prepare(ctx context.Context, conn *Conn) (driver.Stmt, error) {
+	if err := a.spec.applyOptions(ctx, conn, a.options); err != nil {
+		return nil, err
+	}
 	return newCreateFunctionStmt(conn, a.catalog, a.spec), nil
 }
 
 func (a *CreateFunctionStmtAction) exec(ctx context.Context, conn *Conn) error {
+	if err := a.spec.applyOptions(ctx, conn, a.options); err != nil {
+		return err
+	}
 	if err := a.catalog.AddNewFunctionSpec(ctx, conn, a.spec); err != nil {
 		return fmt.Errorf("failed to add new function spec: %w", err)
 	}
@@ -305,25 +347,342 @@ func (a *DropStmtAction) Cleanup(ctx context.Context, conn *Conn) error {
 	return nil
 }
 
+type AlterTableStmtAction struct {
+	name       string
+	options    []*optionExpr
+	isIfExists bool
+	catalog    *Catalog
+}
+
+func (a *AlterTableStmtAction) exec(ctx context.Context, conn *Conn) error {
+	spec := a.catalog.tableSpec(a.name)
+	if spec == nil {
+		if a.isIfExists {
+			return nil
+		}
+		return fmt.Errorf("failed to find table %s", a.name)
+	}
+	newSpec := new(TableSpec)
+	*newSpec = *spec
+	newSpec.Options = append([]*OptionSpec{}, spec.Options...)
+	if err := newSpec.applyOptions(ctx, conn, a.options); err != nil {
+		return err
+	}
+	newSpec.UpdatedAt = time.Now()
+	if err := a.catalog.AddNewTableSpec(ctx, conn, newSpec); err != nil {
+		return fmt.Errorf("failed to update table spec: %w", err)
+	}
+	if !newSpec.IsTemp {
+		conn.updateTable(newSpec)
+	}
+	return nil
+}
+
+func (a *AlterTableStmtAction) Prepare(ctx context.Context, conn *Conn) (driver.Stmt, error) {
+	return nil, nil
+}
+
+func (a *AlterTableStmtAction) ExecContext(ctx context.Context, conn *Conn) (driver.Result, error) {
+	if err := a.exec(ctx, conn); err != nil {
+		return nil, err
+	}
+	return &Result{conn: conn}, nil
+}
+
+func (a *AlterTableStmtAction) QueryContext(ctx context.Context, conn *Conn) (*Rows, error) {
+	if err := a.exec(ctx, conn); err != nil {
+		return nil, err
+	}
+	return &Rows{conn: conn}, nil
+}
+
+func (a *AlterTableStmtAction) Args() []interface{} {
+	return nil
+}
+
+func (a *AlterTableStmtAction) Cleanup(ctx context.Context, conn *Conn) error {
+	return nil
+}
+
+// RenameTableStmtAction renames the table or the view by ALTER TABLE RENAME TO or RENAME statement.
+type RenameTableStmtAction struct {
+	name        string
+	newName     string
+	newNamePath []string
+	isIfExists  bool
+	catalog     *Catalog
+}
+
+func (a *RenameTableStmtAction) exec(ctx context.Context, conn *Conn) error {
+	spec := a.catalog.tableSpec(a.name)
+	if spec == nil {
+		if a.isIfExists {
+			return nil
+		}
+		return fmt.Errorf("failed to find table %s", a.name)
+	}
+	if a.catalog.tableSpec(a.newName) != nil {
+		return fmt.Errorf("failed to rename table %s: table %s already exists", a.name, a.newName)
+	}
+	newSpec := new(TableSpec)
+	*newSpec = *spec
+	newSpec.NamePath = a.newNamePath
+	newSpec.UpdatedAt = time.Now()
+	if err := a.renameSQLiteTable(ctx, conn, spec, newSpec); err != nil {
+		return err
+	}
+	if err := a.catalog.DeleteTableSpec(ctx, conn, a.name); err != nil {
+		return fmt.Errorf("failed to delete table spec: %w", err)
+	}
+	conn.deleteTable(spec)
+	if err := a.catalog.AddNewTableSpec(ctx, conn, newSpec); err != nil {
+		return fmt.Errorf("failed to add new table spec: %w", err)
+	}
+	if !newSpec.IsTemp {
+		conn.addTable(newSpec)
+	}
+	invalidated, err := a.catalog.InvalidateDependentViews(
+		ctx, conn, spec.TableName(), fmt.Sprintf("%s was renamed to %s", a.name, a.newName),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to invalidate views: %w", err)
+	}
+	for _, view := range invalidated {
+		if !view.IsTemp {
+			conn.updateTable(view)
+		}
+	}
+	return nil
+}
+
+func (a *RenameTableStmtAction) renameSQLiteTable(ctx context.Context, conn *Conn, spec, newSpec *TableSpec) error {
+	if spec.IsView {
+		// SQLite cannot rename the view, so recreate it with the new name.
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("DROP VIEW `%s`", spec.TableName())); err != nil {
+			return fmt.Errorf("failed to rename view %s: %w", a.name, err)
+		}
+		if _, err := conn.ExecContext(ctx, newSpec.SQLiteSchema()); err != nil {
+			return fmt.Errorf("failed to rename view %s: %w", a.name, err)
+		}
+		return nil
+	}
+	// the triggers enforcing CHECK constraints are named after the table, so recreate them.
+	if err := spec.dropCheckConstraintTriggers(ctx, conn); err != nil {
+		return err
+	}
+	// the dependent views must keep referring to the old name like BigQuery,
+	// so disable SQLite rewriting the references in the views.
+	for _, query := range []string{
+		"PRAGMA legacy_alter_table = ON",
+		fmt.Sprintf("ALTER TABLE `%s` RENAME TO `%s`", spec.TableName(), newSpec.TableName()),
+		"PRAGMA legacy_alter_table = OFF",
+	} {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to rename table %s: %w", a.name, err)
+		}
+	}
+	return newSpec.createCheckConstraintTriggers(ctx, conn)
+}
+
+func (a *RenameTableStmtAction) Prepare(ctx context.Context, conn *Conn) (driver.Stmt, error) {
+	return nil, nil
+}
+
+func (a *RenameTableStmtAction) ExecContext(ctx context.Context, conn *Conn) (driver.Result, error) {
+	if err := a.exec(ctx, conn); err != nil {
+		return nil, err
+	}
+	return &Result{conn: conn}, nil
+}
+
+func (a *RenameTableStmtAction) QueryContext(ctx context.Context, conn *Conn) (*Rows, error) {
+	if err := a.exec(ctx, conn); err != nil {
+		return nil, err
+	}
+	return &Rows{conn: conn}, nil
+}
+
+func (a *RenameTableStmtAction) Args() []interface{} {
+	return nil
+}
+
+func (a *RenameTableStmtAction) Cleanup(ctx context.Context, conn *Conn) error {
+	return nil
+}
+
+// AccessControlStmtAction records the grants and row access policies of the table to the table spec.
+type AccessControlStmtAction struct {
+	name       string
+	isIfExists bool
+	catalog    *Catalog
+	update     accessControlUpdater
+}
+
+func (a *AccessControlStmtAction) exec(ctx context.Context, conn *Conn) error {
+	spec := a.catalog.tableSpec(a.name)
+	if spec == nil {
+		if a.isIfExists {
+			return nil
+		}
+		return fmt.Errorf("failed to find table %s", a.name)
+	}
+	now := time.Now()
+	newSpec := new(TableSpec)
+	*newSpec = *spec
+	newSpec.Grants = append([]*GrantSpec{}, spec.Grants...)
+	newSpec.RowAccessPolicies = append([]*RowAccessPolicySpec{}, spec.RowAccessPolicies...)
+	if err := a.update(newSpec, now); err != nil {
+		return err
+	}
+	newSpec.UpdatedAt = now
+	if err := a.catalog.AddNewTableSpec(ctx, conn, newSpec); err != nil {
+		return fmt.Errorf("failed to update table spec: %w", err)
+	}
+	if !newSpec.IsTemp {
+		conn.updateTable(newSpec)
+	}
+	return nil
+}
+
+func (a *AccessControlStmtAction) Prepare(ctx context.Context, conn *Conn) (driver.Stmt, error) {
+	return nil, nil
+}
+
+func (a *AccessControlStmtAction) ExecContext(ctx context.Context, conn *Conn) (driver.Result, error) {
+	if err := a.exec(ctx, conn); err != nil {
+		return nil, err
+	}
+	return &Result{conn: conn}, nil
+}
+
+func (a *AccessControlStmtAction) QueryContext(ctx context.Context, conn *Conn) (*Rows, error) {
+	if err := a.exec(ctx, conn); err != nil {
+		return nil, err
+	}
+	return &Rows{conn: conn}, nil
+}
+
+func (a *AccessControlStmtAction) Args() []interface{} {
+	return nil
+}
+
+func (a *AccessControlStmtAction) Cleanup(ctx context.Context, conn *Conn) error {
+	return nil
+}
+
+// ImportStmtAction registers the public functions of the imported module.
+// Like temporary functions, they are available until the end of the script.
+type ImportStmtAction struct {
+	specs   []*FunctionSpec
+	catalog *Catalog
+	funcMap map[string]*FunctionSpec
+}
+
+func (a *ImportStmtAction) exec(ctx context.Context, conn *Conn) error {
+	for _, spec := range a.specs {
+		if err := a.catalog.AddNewFunctionSpec(ctx, conn, spec); err != nil {
+			return fmt.Errorf("failed to add new function spec: %w", err)
+		}
+		a.funcMap[spec.FuncName()] = spec
+	}
+	return nil
+}
+
+func (a *ImportStmtAction) Prepare(ctx context.Context, conn *Conn) (driver.Stmt, error) {
+	return nil, nil
+}
+
+func (a *ImportStmtAction) ExecContext(ctx context.Context, conn *Conn) (driver.Result, error) {
+	if err := a.exec(ctx, conn); err != nil {
+		return nil, err
+	}
+	return &Result{conn: conn}, nil
+}
+
+func (a *ImportStmtAction) QueryContext(ctx context.Context, conn *Conn) (*Rows, error) {
+	if err := a.exec(ctx, conn); err != nil {
+		return nil, err
+	}
+	return &Rows{conn: conn}, nil
+}
+
+func (a *ImportStmtAction) Args() []interface{} {
+	return nil
+}
+
+func (a *ImportStmtAction) Cleanup(ctx context.Context, conn *Conn) error {
+	for _, spec := range a.specs {
+		funcName := spec.FuncName()
+		if err := a.catalog.DeleteFunctionSpec(ctx, conn, funcName); err != nil {
+			return fmt.Errorf("failed to delete function spec: %w", err)
+		}
+		delete(a.funcMap, funcName)
+	}
+	return nil
+}
+
 type DMLStmtAction struct {
-	query          string
-	params         []*ast.ParameterNode
-	args           []interface{}
-	formattedQuery string
+	kind             dmlKind
+	query            string
+	params           []*ast.ParameterNode
+	args             []interface{}
+	formattedQuery   string
+	referencedTables []*ReferencedTable
+	validator        *insertValidator
+	paramTypes       queryParameterTypes
+	insertValues     *insertValues
+	errorContext     *sqliteErrorContext
+}
+
+// sqliteQuery returns the single statement of SQLite. INSERT ... VALUES executed by the batches is formatted here.
+func (a *DMLStmtAction) sqliteQuery() (string, error) {
+	if a.insertValues == nil {
+		return a.formattedQuery, nil
+	}
+	q, err := a.insertValues.formatSQL()
+	if err != nil {
+		return "", fmt.Errorf("failed to format query %s: %w", a.query, err)
+	}
+	return q, nil
 }
 
 func (a *DMLStmtAction) Prepare(ctx context.Context, conn *Conn) (driver.Stmt, error) {
-	s, err := conn.PrepareContext(ctx, a.formattedQuery)
+	formattedQuery, err := a.sqliteQuery()
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare %s: %w", a.query, err)
+		return nil, err
 	}
-	return newDMLStmt(s, a.params, a.formattedQuery), nil
+	s, err := conn.PrepareContext(ctx, formattedQuery)
+	if err != nil {
+		return nil, a.errorContext.wrap(formattedQuery, err)
+	}
+	return newDMLStmt(s, a.params, formattedQuery, a.validator, a.paramTypes), nil
 }
 
 func (a *DMLStmtAction) exec(ctx context.Context, conn *Conn) (driver.Result, error) {
-	result, err := conn.ExecContext(ctx, a.formattedQuery, a.args...)
+	if err := conn.stats.addReferencedTables(ctx, conn, a.referencedTables); err != nil {
+		return nil, err
+	}
+	var (
+		result driver.Result
+		err    error
+	)
+	if a.insertValues != nil {
+		result, err = a.insertValues.exec(ctx, conn)
+	} else {
+		result, err = conn.ExecContext(ctx, a.formattedQuery, a.args...)
+		if err != nil {
+			err = translateConstraintError(err)
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to exec %s: %w", a.formattedQuery, err)
+		sqliteQuery, formatErr := a.sqliteQuery()
+		if formatErr != nil {
+			return nil, err
+		}
+		return nil, a.errorContext.wrap(sqliteQuery, err)
+	}
+	if _, err := conn.stats.addDMLResult(a.kind, result); err != nil {
+		return nil, err
 	}
 	return result, nil
 }
@@ -352,25 +711,32 @@ func (a *DMLStmtAction) Cleanup(ctx context.Context, conn *Conn) error {
 }
 
 type QueryStmtAction struct {
-	query          string
-	params         []*ast.ParameterNode
-	args           []interface{}
-	formattedQuery string
-	outputColumns  []*ColumnSpec
-	isExplainMode  bool
+	query            string
+	params           []*ast.ParameterNode
+	args             []interface{}
+	formattedQuery   string
+	outputColumns    []*ColumnSpec
+	isExplainMode    bool
+	referencedTables []*ReferencedTable
+	paramTypes       queryParameterTypes
+	errorContext     *sqliteErrorContext
+	resourceLimit    *resourceLimit
 }
 
 func (a *QueryStmtAction) Prepare(ctx context.Context, conn *Conn) (driver.Stmt, error) {
 	s, err := conn.PrepareContext(ctx, a.formattedQuery)
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare %s: %w", a.query, err)
+		return nil, a.errorContext.wrap(a.formattedQuery, err)
 	}
-	return newQueryStmt(s, a.params, a.formattedQuery, a.outputColumns), nil
+	return newQueryStmt(s, a.params, a.formattedQuery, a.outputColumns, a.paramTypes, a.resourceLimit), nil
 }
 
 func (a *QueryStmtAction) ExecContext(ctx context.Context, conn *Conn) (driver.Result, error) {
+	if err := conn.stats.addReferencedTables(ctx, conn, a.referencedTables); err != nil {
+		return nil, err
+	}
 	if _, err := conn.ExecContext(ctx, a.formattedQuery, a.args...); err != nil {
-		return nil, fmt.Errorf("failed to query %s: %w", a.query, err)
+		return nil, a.errorContext.wrap(a.formattedQuery, err)
 	}
 	return &Result{conn: conn}, nil
 }
@@ -406,14 +772,17 @@ func (a *QueryStmtAction) QueryContext(ctx context.Context, conn *Conn) (*Rows,
 		}
 		return &Rows{}, nil
 	}
+	if err := conn.stats.addReferencedTables(ctx, conn, a.referencedTables); err != nil {
+		return nil, err
+	}
 	rows, err := conn.QueryContext(ctx, a.formattedQuery, a.args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query %s: %w", a.query, err)
+		return nil, a.errorContext.wrap(a.formattedQuery, a.resourceLimit.translateError(err))
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("failed to query %s: %w", a.query, err)
+		return nil, a.errorContext.wrap(a.formattedQuery, a.resourceLimit.translateError(err))
 	}
-	return &Rows{conn: conn, rows: rows, columns: a.outputColumns}, nil
+	return &Rows{conn: conn, rows: rows, columns: a.outputColumns, limit: a.resourceLimit}, nil
 }
 
 func (a *QueryStmtAction) Args() []interface{} {
@@ -506,31 +875,46 @@ func (a *TruncateStmtAction) Cleanup(ctx context.Context, conn *Conn) error {
 }
 
 type MergeStmtAction struct {
-	stmts []string
+	stmts []*mergeStmt
+}
+
+// mergeStmt is one of the statements decomposed from MERGE statement.
+type mergeStmt struct {
+	query string
+	kind  dmlKind
 }
 
 func (a *MergeStmtAction) Prepare(ctx context.Context, conn *Conn) (driver.Stmt, error) {
 	return nil, nil
 }
 
-func (a *MergeStmtAction) exec(ctx context.Context, conn *Conn) error {
+// exec executes the decomposed statements and returns the total number of rows changed by them.
+func (a *MergeStmtAction) exec(ctx context.Context, conn *Conn) (int64, error) {
+	var affected int64
 	for _, stmt := range a.stmts {
-		if _, err := conn.ExecContext(ctx, stmt); err != nil {
-			return fmt.Errorf("failed to exec merge statement %s: %w", stmt, err)
+		result, err := conn.ExecContext(ctx, stmt.query)
+		if err != nil {
+			return 0, fmt.Errorf("failed to exec merge statement %s: %w", stmt.query, err)
+		}
+		n, err := conn.stats.addDMLResult(stmt.kind, result)
+		if err != nil {
+			return 0, err
 		}
+		affected += n
 	}
-	return nil
+	return affected, nil
 }
 
 func (a *MergeStmtAction) ExecContext(ctx context.Context, conn *Conn) (driver.Result, error) {
-	if err := a.exec(ctx, conn); err != nil {
+	affected, err := a.exec(ctx, conn)
+	if err != nil {
 		return nil, err
 	}
-	return &Result{conn: conn}, nil
+	return &Result{conn: conn, result: rowsAffectedResult(affected)}, nil
 }
 
 func (a *MergeStmtAction) QueryContext(ctx context.Context, conn *Conn) (*Rows, error) {
-	if err := a.exec(ctx, conn); err != nil {
+	if _, err := a.exec(ctx, conn); err != nil {
 		return nil, err
 	}
 	return &Rows{conn: conn}, nil
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+	"github.com/goccy/go-zetasql/types"
+)
+
+const timeZoneSystemVariable = "time_zone"
+
+// SetTimeZone sets the default time zone of the session.
+// It is used by the functions and the casts whose time zone is omitted. The empty zone means UTC.
+func (a *Analyzer) SetTimeZone(zone string) error {
+	if zone != "" {
+		if _, err := toLocation(zone); err != nil {
+			return fmt.Errorf("invalid time zone %q: %w", zone, err)
+		}
+	}
+	a.timeZone = zone
+	return nil
+}
+
+// defaultTimeZone returns the default time zone of the session that formats the query.
+// The empty zone means that the time zone is not set, so the functions use UTC.
+func defaultTimeZone(ctx context.Context) string {
+	analyzer := analyzerFromContext(ctx)
+	if analyzer == nil {
+		return ""
+	}
+	return analyzer.timeZone
+}
+
+// timeZoneDependentFuncs is the functions that use the default time zone if the time zone argument is omitted.
+// The value reports whether the time zone argument is omitted from the arguments of the call.
+var timeZoneDependentFuncs = map[string]func(args []ast.ExprNode) bool{
+	"current_date":     func(args []ast.ExprNode) bool { return len(args) == 0 },
+	"current_datetime": func(args []ast.ExprNode) bool { return len(args) == 0 },
+	"current_time":     func(args []ast.ExprNode) bool { return len(args) == 0 },
+	"$extract": func(args []ast.ExprNode) bool {
+		return len(args) == 2 && isTimestampArg(args[0])
+	},
+	"$extract_date": func(args []ast.ExprNode) bool {
+		return len(args) == 1 && isTimestampArg(args[0])
+	},
+	"date":     func(args []ast.ExprNode) bool { return len(args) == 1 && isTimestampArg(args[0]) },
+	"datetime": func(args []ast.ExprNode) bool { return len(args) == 1 && isTimestampArg(args[0]) },
+	"time":     func(args []ast.ExprNode) bool { return len(args) == 1 && isTimestampArg(args[0]) },
+	"string":   func(args []ast.ExprNode) bool { return len(args) == 1 && isTimestampArg(args[0]) },
+	"timestamp": func(args []ast.ExprNode) bool {
+		if len(args) != 1 {
+			return false
+		}
+		switch args[0].Type().Kind() {
+		case types.STRING, types.DATE, types.DATETIME:
+			return true
+		}
+		return false
+	},
+	"timestamp_trunc":  func(args []ast.ExprNode) bool { return len(args) == 2 },
+	"format_timestamp": func(args []ast.ExprNode) bool { return len(args) == 2 },
+	"parse_timestamp":  func(args []ast.ExprNode) bool { return len(args) == 2 },
+}
+
+func isTimestampArg(arg ast.ExprNode) bool {
+	return arg.Type().Kind() == types.TIMESTAMP
+}
+
+// defaultTimeZoneArg returns the default time zone literal to append to the arguments of the function call.
+// It returns the empty string if the time zone is not set or the call doesn't depend on it.
+func defaultTimeZoneArg(ctx context.Context, funcName string, node *ast.BaseFunctionCallNode) (string, error) {
+	zone := defaultTimeZone(ctx)
+	if zone == "" {
+		return "", nil
+	}
+	isOmitted, exists := timeZoneDependentFuncs[funcName]
+	if !exists || !isOmitted(node.ArgumentList()) {
+		return "", nil
+	}
+	return LiteralFromValue(StringValue(zone))
+}
@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	ast "github.com/goccy/go-zetasql/resolved_ast"
+)
+
+// TranspiledStmt is the SQLite query translated from a statement.
+type TranspiledStmt struct {
+	// Query is the SQLite query. It is empty if the statement only changes the catalog ( e.g. CREATE FUNCTION ).
+	Query string `json:"query"`
+	// Functions is the names of SQLite functions and collations used by Query.
+	// These must be registered on the SQLite that executes Query.
+	Functions []string `json:"functions"`
+}
+
+type SQLiteFunctionKind string
+
+const (
+	ScalarSQLiteFunctionKind    SQLiteFunctionKind = "scalar"
+	AggregateSQLiteFunctionKind SQLiteFunctionKind = "aggregate"
+	WindowSQLiteFunctionKind    SQLiteFunctionKind = "window"
+	CollationSQLiteFunctionKind SQLiteFunctionKind = "collation"
+)
+
+// SQLiteFunctionInfo is the function registered on SQLite by RegisterFunctions.
+type SQLiteFunctionInfo struct {
+	Name string             `json:"name"`
+	Kind SQLiteFunctionKind `json:"kind"`
+}
+
+var sqliteFunctionNamePattern = regexp.MustCompile(`zetasqlite_[a-zA-Z0-9_]+`)
+
+// SQLiteFunctions returns all functions and collations registered by RegisterFunctions sorted by name.
+func SQLiteFunctions() []*SQLiteFunctionInfo {
+	setupFuncMap()
+
+	funcMapMu.RLock()
+	defer funcMapMu.RUnlock()
+
+	funcs := []*SQLiteFunctionInfo{{Name: collationName, Kind: CollationSQLiteFunctionKind}}
+	for _, v := range builtinFuncs {
+		funcs = append(funcs, &SQLiteFunctionInfo{Name: v.Name, Kind: ScalarSQLiteFunctionKind})
+	}
+	for _, values := range normalFuncMap {
+		for _, v := range values {
+			funcs = append(funcs, &SQLiteFunctionInfo{Name: v.Name, Kind: ScalarSQLiteFunctionKind})
+		}
+	}
+	for _, values := range aggregateFuncMap {
+		for _, v := range values {
+			funcs = append(funcs, &SQLiteFunctionInfo{Name: v.Name, Kind: AggregateSQLiteFunctionKind})
+		}
+	}
+	for _, values := range windowFuncMap {
+		for _, v := range values {
+			funcs = append(funcs, &SQLiteFunctionInfo{Name: v.Name, Kind: WindowSQLiteFunctionKind})
+		}
+	}
+	sort.Slice(funcs, func(i, j int) bool {
+		return funcs[i].Name < funcs[j].Name
+	})
+	return funcs
+}
+
+// NewCatalogFromSpecs creates a catalog that is not connected to the database from the snapshot of table and function specs.
+func NewCatalogFromSpecs(tables []*TableSpec, functions []*FunctionSpec) (*Catalog, error) {
+	catalog := NewCatalog(nil)
+	if err := catalog.resetCatalog(tables, functions); err != nil {
+		return nil, fmt.Errorf("failed to create catalog from specs: %w", err)
+	}
+	return catalog, nil
+}
+
+// Transpile translates the query to SQLite queries without executing them.
+// The tables and functions created by the query are added to the catalog, but are not saved to the database.
+func (a *Analyzer) Transpile(ctx context.Context, query string) ([]*TranspiledStmt, error) {
+	query, err := rewriteGroupByAll(query, a.opt.ParserOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse statements: %w", err)
+	}
+	stmts, err := a.parseScript(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse statements: %w", err)
+	}
+	transpiledStmts := make([]*TranspiledStmt, 0, len(stmts))
+	for _, stmt := range stmts {
+		stmtNode, _, err := a.analyzeStmt(query, stmt, nil)
+		if err != nil {
+			return nil, err
+		}
+		stmtCtx := a.stmtContext(a.context(ctx, a.funcMap(), stmtNode, stmt), stmtNode)
+		formattedQuery, err := a.transpileStmt(stmtCtx, query, stmtNode)
+		if err != nil {
+			return nil, err
+		}
+		transpiledStmts = append(transpiledStmts, &TranspiledStmt{
+			Query:     formattedQuery,
+			Functions: requiredSQLiteFunctions(formattedQuery),
+		})
+	}
+	return transpiledStmts, nil
+}
+
+func (a *Analyzer) transpileStmt(ctx context.Context, query string, node ast.StatementNode) (string, error) {
+	switch node.Kind() {
+	case ast.CreateTableStmt:
+		spec := newTableSpec(a.namePath, node.(*ast.CreateTableStmtNode))
+		if err := a.catalog.addTableSpecWithLock(spec); err != nil {
+			return "", err
+		}
+		return spec.SQLiteSchema(), nil
+	case ast.CreateTableAsSelectStmt:
+		action, err := a.newCreateTableAsSelectStmtAction(ctx, query, nil, node.(*ast.CreateTableAsSelectStmtNode))
+		if err != nil {
+			return "", err
+		}
+		if err := a.catalog.addTableSpecWithLock(action.spec); err != nil {
+			return "", err
+		}
+		return action.spec.SQLiteSchema(), nil
+	case ast.CreateViewStmt:
+		action, err := a.newCreateViewStmtAction(ctx, query, nil, node.(*ast.CreateViewStmtNode))
+		if err != nil {
+			return "", err
+		}
+		if err := a.catalog.addTableSpecWithLock(action.spec); err != nil {
+			return "", err
+		}
+		return action.spec.SQLiteSchema(), nil
+	case ast.CreateFunctionStmt:
+		spec, err := a.newFunctionSpecFromStmt(ctx, query, node.(*ast.CreateFunctionStmtNode))
+		if err != nil {
+			return "", err
+		}
+		if err := a.catalog.addFunctionSpecWithLock(spec); err != nil {
+			return "", err
+		}
+		return "", nil
+	case ast.DropStmt:
+		action, err := a.newDropStmtAction(ctx, query, nil, node.(*ast.DropStmtNode))
+		if err != nil {
+			return "", err
+		}
+		return action.formattedQuery, nil
+	case ast.InsertStmt, ast.UpdateStmt, ast.DeleteStmt:
+		action, err := a.newDMLStmtAction(ctx, query, nil, node)
+		if err != nil {
+			return "", err
+		}
+		return action.sqliteQuery()
+	case ast.TruncateStmt:
+		action, err := a.newTruncateStmtAction(ctx, query, nil, node.(*ast.TruncateStmtNode))
+		if err != nil {
+			return "", err
+		}
+		return action.query, nil
+	case ast.QueryStmt:
+		action, err := a.newQueryStmtAction(ctx, query, nil, node.(*ast.QueryStmtNode))
+		if err != nil {
+			return "", err
+		}
+		return action.formattedQuery, nil
+	}
+	return "", fmt.Errorf("unsupported stmt for transpile %s", node.DebugString())
+}
+
+func requiredSQLiteFunctions(query string) []string {
+	registered := map[string]struct{}{}
+	for _, fn := range SQLiteFunctions() {
+		registered[fn.Name] = struct{}{}
+	}
+	found := map[string]struct{}{}
+	names := []string{}
+	for _, name := range sqliteFunctionNamePattern.FindAllString(query, -1) {
+		if _, exists := registered[name]; !exists {
+			continue
+		}
+		if _, exists := found[name]; exists {
+			continue
+		}
+		found[name] = struct{}{}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
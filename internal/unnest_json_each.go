@@ -0,0 +1,24 @@
+//go:build !sqlite_vtable && !vtable
+// +build !sqlite_vtable,!vtable
+
+package internal
+
+import (
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// unnestSourceName is the table-valued function that produces the elements of the array.
+// Without the virtual table support of go-sqlite3, UNNEST relies on json_each of the JSON1 extension,
+// so checkSQLiteCapabilities verifies its behavior on the first connection.
+const unnestSourceName = "json_each"
+
+// formatUnnestSource returns the table-valued function that has `key` ( the offset ) and `value` ( the element ) columns of the array.
+func formatUnnestSource(arrayExpr, alias string) string {
+	return fmt.Sprintf("json_each(zetasqlite_decode_array(%s)) AS `%s`", arrayExpr, alias)
+}
+
+func registerUnnestModule(_ *sqlite3.SQLiteConn) error {
+	return nil
+}
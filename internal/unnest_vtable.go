@@ -0,0 +1,149 @@
+//go:build sqlite_vtable || vtable
+// +build sqlite_vtable vtable
+
+package internal
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// unnestSourceName is the table-valued function that produces the elements of the array.
+// It decodes the array by itself, so UNNEST doesn't depend on the JSON1 extension and its behavior of each SQLite version.
+const unnestSourceName = "zetasqlite_unnest"
+
+// formatUnnestSource returns the table-valued function that has `key` ( the offset ) and `value` ( the element ) columns of the array.
+func formatUnnestSource(arrayExpr, alias string) string {
+	return fmt.Sprintf("%s(%s) AS `%s`", unnestSourceName, arrayExpr, alias)
+}
+
+func registerUnnestModule(conn *sqlite3.SQLiteConn) error {
+	if err := conn.CreateModule(unnestSourceName, &unnestModule{}); err != nil {
+		return fmt.Errorf("failed to register module %s: %w", unnestSourceName, err)
+	}
+	return nil
+}
+
+const (
+	unnestKeyColumn = iota
+	unnestValueColumn
+	unnestArrayColumn
+)
+
+// unnestModule is the eponymous-only virtual table used as the table-valued function ( e.g. zetasqlite_unnest(array) ).
+// The array is passed as the hidden column and the rows are produced in the order of the elements.
+type unnestModule struct{}
+
+func (m *unnestModule) EponymousOnlyModule() {}
+
+func (m *unnestModule) Create(conn *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Connect(conn, args)
+}
+
+func (m *unnestModule) Connect(conn *sqlite3.SQLiteConn, _ []string) (sqlite3.VTab, error) {
+	if err := conn.DeclareVTab("CREATE TABLE x(key INTEGER, value, array HIDDEN)"); err != nil {
+		return nil, fmt.Errorf("failed to declare %s: %w", unnestSourceName, err)
+	}
+	return &unnestTable{}, nil
+}
+
+func (m *unnestModule) DestroyModule() {}
+
+type unnestTable struct{}
+
+func (t *unnestTable) BestIndex(constraints []sqlite3.InfoConstraint, _ []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	used := make([]bool, len(constraints))
+	for idx, constraint := range constraints {
+		if constraint.Column == unnestArrayColumn && constraint.Op == sqlite3.OpEQ && constraint.Usable {
+			used[idx] = true
+			return &sqlite3.IndexResult{Used: used, IdxNum: 1, EstimatedCost: 1}, nil
+		}
+	}
+	// the array isn't available in this plan ( e.g. it refers to the table joined later ),
+	// so the plan is made expensive enough not to be chosen.
+	return &sqlite3.IndexResult{Used: used, EstimatedCost: math.MaxFloat64}, nil
+}
+
+func (t *unnestTable) Disconnect() error { return nil }
+
+func (t *unnestTable) Destroy() error { return nil }
+
+func (t *unnestTable) Open() (sqlite3.VTabCursor, error) {
+	return &unnestCursor{}, nil
+}
+
+type unnestCursor struct {
+	values []interface{}
+	offset int
+}
+
+func (c *unnestCursor) Filter(idxNum int, _ string, vals []interface{}) error {
+	c.values = nil
+	c.offset = 0
+	if idxNum == 0 || len(vals) == 0 {
+		return nil
+	}
+	decoded, err := DecodeValue(vals[0])
+	if err != nil {
+		return fmt.Errorf("failed to decode array of %s: %w", unnestSourceName, err)
+	}
+	if decoded == nil {
+		return nil
+	}
+	array, err := decoded.ToArray()
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, 0, len(array.values))
+	for _, value := range array.values {
+		v, err := EncodeValue(value)
+		if err != nil {
+			return err
+		}
+		values = append(values, v)
+	}
+	c.values = values
+	return nil
+}
+
+func (c *unnestCursor) Next() error {
+	c.offset++
+	return nil
+}
+
+func (c *unnestCursor) EOF() bool {
+	return c.offset >= len(c.values)
+}
+
+func (c *unnestCursor) Column(ctx *sqlite3.SQLiteContext, col int) error {
+	switch col {
+	case unnestKeyColumn:
+		ctx.ResultInt64(int64(c.offset))
+	case unnestValueColumn:
+		switch v := c.values[c.offset].(type) {
+		case nil:
+			ctx.ResultNull()
+		case int64:
+			ctx.ResultInt64(v)
+		case float64:
+			ctx.ResultDouble(v)
+		case bool:
+			ctx.ResultBool(v)
+		case string:
+			ctx.ResultText(v)
+		default:
+			return fmt.Errorf("unexpected element type %T of %s", v, unnestSourceName)
+		}
+	case unnestArrayColumn:
+		ctx.ResultNull()
+	}
+	return nil
+}
+
+func (c *unnestCursor) Rowid() (int64, error) {
+	return int64(c.offset), nil
+}
+
+func (c *unnestCursor) Close() error { return nil }
@@ -576,6 +576,14 @@ func (fv FloatValue) ToInt64() (int64, error) {
 }
 
 func (fv FloatValue) ToString() (string, error) {
+	switch f := float64(fv); {
+	case math.IsNaN(f):
+		return "nan", nil
+	case math.IsInf(f, 1):
+		return "inf", nil
+	case math.IsInf(f, -1):
+		return "-inf", nil
+	}
 	return fmt.Sprint(fv), nil
 }
 
@@ -627,6 +635,26 @@ func (fv FloatValue) Interface() interface{} {
 	return float64(fv)
 }
 
+// compareNaN compares the values in the order of BigQuery where NaN is smaller than the other non-NULL values.
+// It returns false if neither value is NaN.
+func compareNaN(a, b Value) (int, bool) {
+	aIsNaN, bIsNaN := isNaNValue(a), isNaNValue(b)
+	switch {
+	case aIsNaN && bIsNaN:
+		return 0, true
+	case aIsNaN:
+		return -1, true
+	case bIsNaN:
+		return 1, true
+	}
+	return 0, false
+}
+
+func isNaNValue(v Value) bool {
+	f, ok := v.(FloatValue)
+	return ok && math.IsNaN(float64(f))
+}
+
 type NumericValue struct {
 	*big.Rat
 	isBigNumeric bool
@@ -1027,6 +1055,9 @@ type ArrayValue struct {
 
 func (av *ArrayValue) Has(v Value) (bool, error) {
 	for _, val := range av.values {
+		if val == nil {
+			continue
+		}
 		cond, err := val.EQ(v)
 		if err != nil {
 			return false, err
@@ -1038,6 +1069,15 @@ func (av *ArrayValue) Has(v Value) (bool, error) {
 	return false, nil
 }
 
+// equalNullableValue compares the elements of composite values.
+// NULL elements are considered equal to each other so that logically identical arrays and structs compare equal.
+func equalNullableValue(a, b Value) (bool, error) {
+	if a == nil || b == nil {
+		return a == nil && b == nil, nil
+	}
+	return a.EQ(b)
+}
+
 func (av *ArrayValue) Add(v Value) (Value, error) {
 	return nil, fmt.Errorf("add operation is unsupported for array %v", av)
 }
@@ -1063,7 +1103,7 @@ func (av *ArrayValue) EQ(v Value) (bool, error) {
 		return false, nil
 	}
 	for idx, value := range av.values {
-		cond, err := arr.values[idx].EQ(value)
+		cond, err := equalNullableValue(arr.values[idx], value)
 		if err != nil {
 			return false, err
 		}
@@ -1283,7 +1323,7 @@ func (sv *StructValue) EQ(v Value) (bool, error) {
 		return false, nil
 	}
 	for key := range sv.m {
-		cond, err := st.m[key].EQ(sv.m[key])
+		cond, err := equalNullableValue(st.m[key], sv.m[key])
 		if err != nil {
 			return false, err
 		}
@@ -1468,6 +1508,39 @@ func (sv *StructValue) Interface() interface{} {
 	return fields
 }
 
+// civilDate, civilDatetime and civilTime normalize the civil time values to compare them by the wall clock regardless of the location.
+// This matches the encoded representation, so the comparison result is consistent with the result of GROUP BY or DISTINCT.
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func civilDatetime(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC).Truncate(time.Microsecond)
+}
+
+func civilTime(t time.Time) time.Time {
+	return time.Date(1970, 1, 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC).Truncate(time.Microsecond)
+}
+
+// timestampKey truncates the timestamp to microsecond precision that is the precision of the encoded representation.
+func timestampKey(t time.Time) time.Time {
+	return t.Truncate(time.Microsecond)
+}
+
+// formatTimestampValue formats the timestamp as the seconds since the Unix epoch with six fractional digits ( e.g. 1136214245.000001 ).
+// The fractional part is never negative, so the timestamps before the epoch are formatted like -1.500000 ( 1969-12-31 23:59:59.5 ).
+func formatTimestampValue(t time.Time) string {
+	microSecondsInSecond := int64(time.Second / time.Microsecond)
+	micros := t.UnixMicro()
+	sec := micros / microSecondsInSecond
+	remainder := micros % microSecondsInSecond
+	if remainder < 0 {
+		sec--
+		remainder += microSecondsInSecond
+	}
+	return fmt.Sprintf("%d.%06d", sec, remainder)
+}
+
 type DateValue time.Time
 
 func (d DateValue) AddDateWithInterval(v int, interval string) (Value, error) {
@@ -1546,7 +1619,7 @@ func (d DateValue) EQ(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(d).Equal(v2), nil
+	return civilDate(time.Time(d)).Equal(civilDate(v2)), nil
 }
 
 func (d DateValue) GT(v Value) (bool, error) {
@@ -1554,7 +1627,7 @@ func (d DateValue) GT(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(d).After(v2), nil
+	return civilDate(time.Time(d)).After(civilDate(v2)), nil
 }
 
 func (d DateValue) GTE(v Value) (bool, error) {
@@ -1562,7 +1635,7 @@ func (d DateValue) GTE(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(d).Equal(v2) || time.Time(d).After(v2), nil
+	return civilDate(time.Time(d)).Equal(civilDate(v2)) || civilDate(time.Time(d)).After(civilDate(v2)), nil
 }
 
 func (d DateValue) LT(v Value) (bool, error) {
@@ -1570,7 +1643,7 @@ func (d DateValue) LT(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(d).Before(v2), nil
+	return civilDate(time.Time(d)).Before(civilDate(v2)), nil
 }
 
 func (d DateValue) LTE(v Value) (bool, error) {
@@ -1578,7 +1651,7 @@ func (d DateValue) LTE(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(d).Equal(v2) || time.Time(d).Before(v2), nil
+	return civilDate(time.Time(d)).Equal(civilDate(v2)) || civilDate(time.Time(d)).Before(civilDate(v2)), nil
 }
 
 func (d DateValue) ToInt64() (int64, error) {
@@ -1698,7 +1771,7 @@ func (d DatetimeValue) EQ(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(d).Equal(v2), nil
+	return civilDatetime(time.Time(d)).Equal(civilDatetime(v2)), nil
 }
 
 func (d DatetimeValue) GT(v Value) (bool, error) {
@@ -1706,7 +1779,7 @@ func (d DatetimeValue) GT(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(d).After(v2), nil
+	return civilDatetime(time.Time(d)).After(civilDatetime(v2)), nil
 }
 
 func (d DatetimeValue) GTE(v Value) (bool, error) {
@@ -1714,7 +1787,7 @@ func (d DatetimeValue) GTE(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(d).Equal(v2) || time.Time(d).After(v2), nil
+	return civilDatetime(time.Time(d)).Equal(civilDatetime(v2)) || civilDatetime(time.Time(d)).After(civilDatetime(v2)), nil
 }
 
 func (d DatetimeValue) LT(v Value) (bool, error) {
@@ -1722,7 +1795,7 @@ func (d DatetimeValue) LT(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(d).Before(v2), nil
+	return civilDatetime(time.Time(d)).Before(civilDatetime(v2)), nil
 }
 
 func (d DatetimeValue) LTE(v Value) (bool, error) {
@@ -1730,7 +1803,7 @@ func (d DatetimeValue) LTE(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(d).Equal(v2) || time.Time(d).Before(v2), nil
+	return civilDatetime(time.Time(d)).Equal(civilDatetime(v2)) || civilDatetime(time.Time(d)).Before(civilDatetime(v2)), nil
 }
 
 func (d DatetimeValue) ToInt64() (int64, error) {
@@ -1769,8 +1842,10 @@ func (d DatetimeValue) ToJSON() (string, error) {
 	return d.ToString()
 }
 
+// ToTime returns the wall clock of the datetime in UTC. The datetime may be created in any location ( e.g. CURRENT_DATETIME ),
+// so the location is dropped to treat it as the civil time regardless of the producer.
 func (d DatetimeValue) ToTime() (time.Time, error) {
-	return time.Time(d), nil
+	return civilDatetime(time.Time(d)), nil
 }
 
 func (d DatetimeValue) ToRat() (*big.Rat, error) {
@@ -1815,7 +1890,7 @@ func (t TimeValue) EQ(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(t).Equal(v2), nil
+	return civilTime(time.Time(t)).Equal(civilTime(v2)), nil
 }
 
 func (t TimeValue) GT(v Value) (bool, error) {
@@ -1823,7 +1898,7 @@ func (t TimeValue) GT(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(t).After(v2), nil
+	return civilTime(time.Time(t)).After(civilTime(v2)), nil
 }
 
 func (t TimeValue) GTE(v Value) (bool, error) {
@@ -1831,7 +1906,7 @@ func (t TimeValue) GTE(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(t).Equal(v2) || time.Time(t).After(v2), nil
+	return civilTime(time.Time(t)).Equal(civilTime(v2)) || civilTime(time.Time(t)).After(civilTime(v2)), nil
 }
 
 func (t TimeValue) LT(v Value) (bool, error) {
@@ -1839,7 +1914,7 @@ func (t TimeValue) LT(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(t).Before(v2), nil
+	return civilTime(time.Time(t)).Before(civilTime(v2)), nil
 }
 
 func (t TimeValue) LTE(v Value) (bool, error) {
@@ -1847,7 +1922,7 @@ func (t TimeValue) LTE(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(t).Equal(v2) || time.Time(t).Before(v2), nil
+	return civilTime(time.Time(t)).Equal(civilTime(v2)) || civilTime(time.Time(t)).Before(civilTime(v2)), nil
 }
 
 func (t TimeValue) ToInt64() (int64, error) {
@@ -1886,8 +1961,9 @@ func (t TimeValue) ToJSON() (string, error) {
 	return t.ToString()
 }
 
+// ToTime returns the wall clock of the time on 1970-01-01 in UTC.
 func (t TimeValue) ToTime() (time.Time, error) {
-	return time.Time(t), nil
+	return civilTime(time.Time(t)), nil
 }
 
 func (t TimeValue) ToRat() (*big.Rat, error) {
@@ -1982,7 +2058,7 @@ func (t TimestampValue) EQ(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(t).Equal(v2), nil
+	return timestampKey(time.Time(t)).Equal(timestampKey(v2)), nil
 }
 
 func (t TimestampValue) GT(v Value) (bool, error) {
@@ -1990,7 +2066,7 @@ func (t TimestampValue) GT(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(t).After(v2), nil
+	return timestampKey(time.Time(t)).After(timestampKey(v2)), nil
 }
 
 func (t TimestampValue) GTE(v Value) (bool, error) {
@@ -1998,7 +2074,7 @@ func (t TimestampValue) GTE(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(t).Equal(v2) || time.Time(t).After(v2), nil
+	return timestampKey(time.Time(t)).Equal(timestampKey(v2)) || timestampKey(time.Time(t)).After(timestampKey(v2)), nil
 }
 
 func (t TimestampValue) LT(v Value) (bool, error) {
@@ -2006,7 +2082,7 @@ func (t TimestampValue) LT(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(t).Before(v2), nil
+	return timestampKey(time.Time(t)).Before(timestampKey(v2)), nil
 }
 
 func (t TimestampValue) LTE(v Value) (bool, error) {
@@ -2014,7 +2090,7 @@ func (t TimestampValue) LTE(v Value) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to convert %v to time.Time", v)
 	}
-	return time.Time(t).Equal(v2) || time.Time(t).Before(v2), nil
+	return timestampKey(time.Time(t)).Equal(timestampKey(v2)) || timestampKey(time.Time(t)).Before(timestampKey(v2)), nil
 }
 
 func (t TimestampValue) ToInt64() (int64, error) {
@@ -2062,8 +2138,7 @@ func (t TimestampValue) ToRat() (*big.Rat, error) {
 }
 
 func (t TimestampValue) Format(verb rune) string {
-	const timestampPrintableFormat = "2006-01-02 15:04:05"
-	formatted := time.Time(t).UTC().Format(timestampPrintableFormat) + "+00"
+	formatted := formatTimestampPrintable(time.Time(t))
 	switch verb {
 	case 't':
 		return formatted
@@ -2074,7 +2149,22 @@ func (t TimestampValue) Format(verb rune) string {
 }
 
 func (t TimestampValue) Interface() interface{} {
-	return time.Time(t).Format(time.RFC3339)
+	return timestampKey(time.Time(t)).Format(time.RFC3339Nano)
+}
+
+// formatTimestampPrintable formats the timestamp in UTC like BigQuery.
+// The fractional seconds are printed in milliseconds or microseconds only if they are not zero.
+func formatTimestampPrintable(t time.Time) string {
+	t = timestampKey(t).UTC()
+	layout := "2006-01-02 15:04:05"
+	switch {
+	case t.Nanosecond() == 0:
+	case t.Nanosecond()%int(time.Millisecond) == 0:
+		layout += ".000"
+	default:
+		layout += ".000000"
+	}
+	return t.Format(layout) + "+00"
 }
 
 type IntervalValue struct {
@@ -2472,3 +2562,18 @@ func isNullValue(v interface{}) bool {
 	}
 	return false
 }
+
+// isNull reports whether the value represents NULL.
+// SQL NULL is the only representation of NULL of any type, and it is converted from the nil Value.
+// The typed nil pointer ( e.g. (*ArrayValue)(nil) ) and SafeValue wrapping NULL are also regarded as NULL,
+// so that they are never encoded into the non-NULL value.
+func isNull(v Value) bool {
+	if v == nil {
+		return true
+	}
+	if safe, ok := v.(*SafeValue); ok {
+		return safe == nil || isNull(safe.value)
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"math/rand"
 	"testing"
 	"time"
 )
@@ -29,3 +30,125 @@ func TestTimestampValue(t *testing.T) {
 		t.Fatalf("failed to format timestamp")
 	}
 }
+
+func TestCompositeValueEquality(t *testing.T) {
+	t.Run("array with null elements", func(t *testing.T) {
+		a := &ArrayValue{values: []Value{IntValue(1), nil, IntValue(3)}}
+		b := &ArrayValue{values: []Value{IntValue(1), nil, IntValue(3)}}
+		c := &ArrayValue{values: []Value{IntValue(1), IntValue(2), IntValue(3)}}
+		if eq, err := a.EQ(b); err != nil || !eq {
+			t.Fatalf("expected equal arrays: %v", err)
+		}
+		if eq, err := a.EQ(c); err != nil || eq {
+			t.Fatalf("expected not equal arrays: %v", err)
+		}
+		if has, err := a.Has(IntValue(3)); err != nil || !has {
+			t.Fatalf("expected to find element: %v", err)
+		}
+		if has, err := a.Has(IntValue(2)); err != nil || has {
+			t.Fatalf("expected not to find element: %v", err)
+		}
+	})
+	t.Run("canonical encoding", func(t *testing.T) {
+		literal := &ArrayValue{values: []Value{IntValue(1), nil, StringValue("a")}}
+		concatenated, err := ARRAY_CONCAT(
+			&ArrayValue{values: []Value{IntValue(1)}},
+			&ArrayValue{values: []Value{nil, StringValue("a")}},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encodedLiteral, err := EncodeValue(literal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encodedConcatenated, err := EncodeValue(concatenated)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encodedLiteral != encodedConcatenated {
+			t.Fatalf("expected same encoding but got %v and %v", encodedLiteral, encodedConcatenated)
+		}
+		decoded, err := DecodeValue(encodedLiteral)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reencoded, err := EncodeValue(decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reencoded != encodedLiteral {
+			t.Fatalf("expected stable encoding but got %v and %v", encodedLiteral, reencoded)
+		}
+	})
+}
+
+func TestTemporalValueComparison(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	locations := []*time.Location{time.UTC, time.FixedZone("JST", 9*60*60), time.FixedZone("EST", -5*60*60)}
+	randomTime := func() time.Time {
+		sec := r.Int63n(int64(100 * 365 * 24 * time.Hour / time.Second))
+		usec := r.Int63n(1000000)
+		return time.Unix(sec, usec*int64(time.Microsecond)).In(locations[r.Intn(len(locations))])
+	}
+	for i := 0; i < 1000; i++ {
+		a := randomTime()
+		b := randomTime()
+		if r.Intn(4) == 0 {
+			// same instant with different offset
+			b = a.In(locations[r.Intn(len(locations))])
+		}
+		encodedA, err := EncodeValue(TimestampValue(a))
+		if err != nil {
+			t.Fatal(err)
+		}
+		encodedB, err := EncodeValue(TimestampValue(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		va, err := DecodeValue(encodedA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vb, err := DecodeValue(encodedB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, test := range []struct {
+			name     string
+			compare  func(Value) (bool, error)
+			expected bool
+		}{
+			{name: "EQ", compare: va.EQ, expected: a.Equal(b)},
+			{name: "GT", compare: va.GT, expected: a.After(b)},
+			{name: "GTE", compare: va.GTE, expected: !a.Before(b)},
+			{name: "LT", compare: va.LT, expected: a.Before(b)},
+			{name: "LTE", compare: va.LTE, expected: !a.After(b)},
+		} {
+			got, err := test.compare(vb)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.expected {
+				t.Fatalf("%s: unexpected result for %s and %s: expected %v but got %v", test.name, a, b, test.expected, got)
+			}
+		}
+		if (encodedA == encodedB) != a.Equal(b) {
+			t.Fatalf("encoding of %s and %s is not canonical", a, b)
+		}
+	}
+	t.Run("civil time values are compared by wall clock", func(t *testing.T) {
+		jst := time.FixedZone("JST", 9*60*60)
+		tokyo := time.Date(2020, 1, 1, 10, 0, 0, 0, jst)
+		utc := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+		if eq, err := DatetimeValue(tokyo).EQ(DatetimeValue(utc)); err != nil || !eq {
+			t.Fatalf("expected equal datetime values: %v", err)
+		}
+		if eq, err := DateValue(tokyo).EQ(DateValue(utc)); err != nil || !eq {
+			t.Fatalf("expected equal date values: %v", err)
+		}
+		if eq, err := TimeValue(utc.AddDate(1, 0, 0)).EQ(TimeValue(utc)); err != nil || !eq {
+			t.Fatalf("expected equal time values: %v", err)
+		}
+	})
+}
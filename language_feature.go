@@ -0,0 +1,18 @@
+package zetasqlite
+
+import (
+	internal "github.com/goccy/go-zetasqlite/internal"
+)
+
+// LanguageFeature is the language feature of ZetaSQL gating the syntax and the functions accepted by the analysis
+// ( e.g. zetasql.FeatureV13Qualify of github.com/goccy/go-zetasql ).
+type LanguageFeature = internal.LanguageFeature
+
+// DefaultLanguageFeatures are the language features enabled by default. They are the features supported by BigQuery GA
+// which zetasqlite can run: analytic functions, named arguments, NUMERIC, BIGNUMERIC, JSON, INTERVAL and GEOGRAPHY types,
+// NOT NULL, CHECK, PARTITION BY and CLUSTER BY of CREATE TABLE, parameterized types, TABLESAMPLE, nanoseconds of TIMESTAMP,
+// HAVING, ORDER BY, LIMIT and IGNORE/RESPECT NULLS in aggregate functions, SELECT * EXCEPT/REPLACE, SAFE. prefix,
+// IS DISTINCT FROM, FORMAT in CAST, date arithmetics, DATE/DATETIME/TIME constructors, civil time, GROUP BY STRUCT and ROLLUP,
+// NULLS FIRST/LAST, QUALIFY, dashes in table names, templated SQL functions, WITH in subqueries, PIVOT, UNPIVOT and FILTER_FIELDS.
+// Modifying it doesn't change the default of the connections.
+var DefaultLanguageFeatures = internal.DefaultLanguageFeatures()
@@ -7,6 +7,7 @@ import (
 	"math"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -25,6 +26,19 @@ func TestQuery(t *testing.T) {
 		t.Fatal(err)
 	}
 	defer db.Close()
+
+	// run all queries with the pretty-printed SQL as well to make sure that the normalization preserves the semantics.
+	sql.Register("zetasqlite-pretty-format", &zetasqlite.ZetaSQLiteDriver{
+		ConnectHook: func(conn *zetasqlite.ZetaSQLiteConn) error {
+			conn.SetPrettyFormatMode(true)
+			return nil
+		},
+	})
+	prettyFormatDB, err := sql.Open("zetasqlite-pretty-format", "file:zetasqlite_pretty_format?mode=memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer prettyFormatDB.Close()
 	floatCmpOpt := cmp.Comparer(func(x, y float64) bool {
 		if x == y {
 			return true
@@ -33,7 +47,7 @@ func TestQuery(t *testing.T) {
 		mean := math.Abs(x+y) / 2.0
 		return delta/mean < 0.00001
 	})
-	for _, test := range []struct {
+	tests := []struct {
 		name         string
 		query        string
 		args         []interface{}
@@ -61,6 +75,56 @@ UNION ALL
 (WITH toks2 AS (SELECT 2 AS x) SELECT COUNT(x) AS total_rows FROM toks2 WHERE x > 0 HAVING total_rows >= 0)`,
 			expectedRows: [][]interface{}{{int64(1)}, {int64(1)}},
 		},
+		{
+			name: "union distinct with struct",
+			query: `SELECT s.a, s.b FROM (
+  SELECT STRUCT(1 AS a, 'x' AS b) AS s
+  UNION DISTINCT SELECT STRUCT(1 AS c, 'x' AS d)
+  UNION DISTINCT SELECT (1, 'x')
+  UNION DISTINCT SELECT STRUCT(2 AS a, 'y' AS b)
+) ORDER BY s.a`,
+			expectedRows: [][]interface{}{{int64(1), "x"}, {int64(2), "y"}},
+		},
+		{
+			name: "intersect and except distinct with struct",
+			query: `SELECT
+  (SELECT COUNT(*) FROM (SELECT STRUCT(1 AS a) AS s INTERSECT DISTINCT SELECT STRUCT(1 AS b))),
+  (SELECT COUNT(*) FROM (SELECT STRUCT(1 AS a) AS s EXCEPT DISTINCT SELECT STRUCT(1 AS b)))`,
+			expectedRows: [][]interface{}{{int64(1), int64(0)}},
+		},
+		{
+			name: "union all with nested except distinct",
+			query: `SELECT x FROM (
+  SELECT 1 AS x UNION ALL SELECT 2 UNION ALL (SELECT 1 EXCEPT DISTINCT SELECT 2)
+) ORDER BY x`,
+			expectedRows: [][]interface{}{{int64(1)}, {int64(1)}, {int64(2)}},
+		},
+		{
+			name: "except distinct with nested union all",
+			query: `SELECT x FROM (
+  (SELECT 1 AS x UNION ALL SELECT 2 UNION ALL SELECT 3) EXCEPT DISTINCT (SELECT 2 UNION ALL SELECT 4)
+) ORDER BY x`,
+			expectedRows: [][]interface{}{{int64(1)}, {int64(3)}},
+		},
+		{
+			name: "except distinct chain with nested intersect distinct",
+			query: `SELECT x FROM (
+  SELECT x FROM UNNEST([1, 2, 3, 4]) AS x
+  EXCEPT DISTINCT (SELECT x FROM UNNEST([2, 3]) AS x INTERSECT DISTINCT SELECT x FROM UNNEST([3, 4]) AS x)
+  EXCEPT DISTINCT SELECT 1
+) ORDER BY x`,
+			expectedRows: [][]interface{}{{int64(2)}, {int64(4)}},
+		},
+		{
+			name:         "group by struct",
+			query:        `SELECT s.a, COUNT(*) FROM UNNEST([STRUCT(1 AS a), STRUCT(1 AS a), STRUCT(2 AS a)]) AS s GROUP BY s ORDER BY s.a`,
+			expectedRows: [][]interface{}{{int64(1), int64(2)}, {int64(2), int64(1)}},
+		},
+		{
+			name:        "union distinct with array",
+			query:       `SELECT [1, 2] AS arr UNION DISTINCT SELECT [1, 2]`,
+			expectedErr: "Column 1 in UNION DISTINCT has type that does not support set operation comparisons: ARRAY",
+		},
 		// priority 2 operator
 		{
 			name:         "unary plus operator",
@@ -215,6 +279,28 @@ UNION ALL
 			query:        `SELECT DATE "2020-09-10" NOT BETWEEN "2022-09-01" and "2022-10-01"`,
 			expectedRows: [][]interface{}{{true}},
 		},
+		{
+			name:         "between operator with null",
+			query:        `SELECT NULL BETWEEN 1 AND 2, 1 BETWEEN NULL AND 0, 1 BETWEEN NULL AND 2, 1 NOT BETWEEN 0 AND NULL`,
+			expectedRows: [][]interface{}{{nil, false, nil, nil}},
+		},
+		{
+			name: "between operator with timestamps of different offsets",
+			query: `
+SELECT COUNT(*) FROM UNNEST([TIMESTAMP "2020-01-01 00:00:00+00", TIMESTAMP "2020-01-01 00:00:00.000002+00"]) AS ts
+WHERE ts BETWEEN TIMESTAMP "2020-01-01 09:00:00+09" AND TIMESTAMP "2019-12-31 19:00:00.000001-05"`,
+			expectedRows: [][]interface{}{{int64(1)}},
+		},
+		{
+			name: "compare civil time values by wall clock",
+			query: `
+SELECT
+  DATETIME(TIMESTAMP "2020-01-01 01:00:00+00", "Asia/Tokyo") = DATETIME "2020-01-01 10:00:00",
+  DATE(TIMESTAMP "2020-01-01 20:00:00+00", "Asia/Tokyo") = DATE "2020-01-02",
+  TIME(TIMESTAMP "2020-01-01 10:00:00+00") = TIME "10:00:00",
+  TIME(TIMESTAMP "2020-01-02 09:00:00+00") < TIME "10:00:00"`,
+			expectedRows: [][]interface{}{{true, true, true, true}},
+		},
 		{
 			name:  "in operator",
 			query: `SELECT 3 IN (1, 2, 3, 4), null IN (1), null IN (null)`,
@@ -813,6 +899,29 @@ SELECT ARRAY_CONCAT_AGG(x) AS array_concat_agg FROM (
 				{int64(5), int64(1), int64(1)},
 			},
 		},
+		{
+			name: "window with input columns named rowid and row_id",
+			query: `
+WITH t AS (SELECT 1 AS rowid, 3 AS row_id, 10 AS x UNION ALL SELECT 2, 2, 20 UNION ALL SELECT 3, 1, 30)
+SELECT rowid, row_id, SUM(x) OVER (ORDER BY rowid) FROM t`,
+			expectedRows: [][]interface{}{
+				{int64(1), int64(3), int64(10)},
+				{int64(2), int64(2), int64(30)},
+				{int64(3), int64(1), int64(60)},
+			},
+		},
+		{
+			name: "window over nested window",
+			query: `
+SELECT x, r, SUM(r) OVER (ORDER BY x) FROM (
+  SELECT x, ROW_NUMBER() OVER (ORDER BY x DESC) AS r FROM UNNEST([1, 2, 3]) AS x
+)`,
+			expectedRows: [][]interface{}{
+				{int64(1), int64(3), int64(3)},
+				{int64(2), int64(2), int64(5)},
+				{int64(3), int64(1), int64(6)},
+			},
+		},
 		{
 			name:         "countif",
 			query:        `SELECT COUNTIF(x<0) AS num_negative, COUNTIF(x>0) AS num_positive FROM UNNEST([5, -2, 3, 6, -10, -7, 4, 0]) AS x`,
@@ -982,11 +1091,89 @@ SELECT LOGICAL_OR(x) AS logical_or FROM toks`,
 				{int64(2), int64(7)},
 			},
 		},
+		{
+			name: "distinct window aggregates over partitions with duplicates",
+			query: `
+WITH t AS (
+  SELECT x, MOD(x, 3) AS p,
+    COUNT(DISTINCT x) OVER (PARTITION BY MOD(x, 3)) AS cnt,
+    SUM(DISTINCT x) OVER (PARTITION BY MOD(x, 3)) AS total,
+    ARRAY_AGG(DISTINCT x) OVER (PARTITION BY MOD(x, 3)) AS arr
+  FROM UNNEST([1, 4, 4, 7, 1, 2, 2]) AS x
+)
+SELECT x, cnt, total, ARRAY(SELECT e FROM UNNEST(arr) AS e ORDER BY e) FROM t ORDER BY x`,
+			expectedRows: [][]interface{}{
+				{int64(1), int64(3), int64(12), []interface{}{int64(1), int64(4), int64(7)}},
+				{int64(1), int64(3), int64(12), []interface{}{int64(1), int64(4), int64(7)}},
+				{int64(2), int64(1), int64(2), []interface{}{int64(2)}},
+				{int64(2), int64(1), int64(2), []interface{}{int64(2)}},
+				{int64(4), int64(3), int64(12), []interface{}{int64(1), int64(4), int64(7)}},
+				{int64(4), int64(3), int64(12), []interface{}{int64(1), int64(4), int64(7)}},
+				{int64(7), int64(3), int64(12), []interface{}{int64(1), int64(4), int64(7)}},
+			},
+		},
+		{
+			name: "count distinct with window compares timestamps by instant",
+			query: `
+SELECT COUNT(DISTINCT t) OVER () FROM UNNEST([
+  TIMESTAMP "2024-01-01 09:00:00+09", TIMESTAMP "2024-01-01 00:00:00+00", TIMESTAMP "2024-01-01 01:00:00+00"
+]) AS t LIMIT 1`,
+			expectedRows: [][]interface{}{{int64(2)}},
+		},
+		{
+			name:        "distinct window aggregate with window order by",
+			query:       `SELECT COUNT(DISTINCT x) OVER (ORDER BY x) FROM UNNEST([1, 1, 2]) AS x`,
+			expectedErr: "DISTINCT is not allowed for analytic function with ORDER BY",
+		},
 		{
 			name:         "sum null",
 			query:        `SELECT SUM(x) AS sum FROM UNNEST([]) AS x`,
 			expectedRows: [][]interface{}{{nil}},
 		},
+		{
+			name:         "sum int64 near max",
+			query:        `SELECT SUM(x) AS sum FROM UNNEST([9223372036854775807, 1, -1]) AS x`,
+			expectedRows: [][]interface{}{{int64(9223372036854775807)}},
+		},
+		{
+			name:        "sum int64 overflow",
+			query:       `SELECT SUM(x) AS sum FROM UNNEST([9223372036854775807, 1]) AS x`,
+			expectedErr: "INT64 overflow",
+		},
+		{
+			name:         "avg int64 near max",
+			query:        `SELECT AVG(x) AS avg FROM UNNEST([9223372036854775807, 9223372036854775807, 9223372036854775806]) AS x`,
+			expectedRows: [][]interface{}{{float64(9223372036854775807)}},
+		},
+		{
+			name:         "sum and avg numeric",
+			query:        `SELECT SUM(x), AVG(x) FROM UNNEST([NUMERIC '0.000000001', NUMERIC '0.000000002', NUMERIC '1']) AS x`,
+			expectedRows: [][]interface{}{{"1.000000003", "0.333333334"}},
+		},
+		{
+			name:         "sum and avg bignumeric",
+			query:        `SELECT SUM(x), AVG(x) FROM UNNEST([BIGNUMERIC '0.00000000000000000000000000000000000001', BIGNUMERIC '1']) AS x`,
+			expectedRows: [][]interface{}{{"1.00000000000000000000000000000000000001", "0.50000000000000000000000000000000000001"}},
+		},
+		{
+			name:        "sum numeric overflow",
+			query:       `SELECT SUM(x) FROM UNNEST([NUMERIC '99999999999999999999999999999.999999999', NUMERIC '0.000000001']) AS x`,
+			expectedErr: "NUMERIC overflow",
+		},
+		{
+			name:  "sum numeric with window",
+			query: `SELECT x, SUM(x) OVER (ORDER BY x ROWS BETWEEN 1 PRECEDING AND CURRENT ROW) FROM UNNEST([NUMERIC '1.5', NUMERIC '2.5', NUMERIC '3']) AS x`,
+			expectedRows: [][]interface{}{
+				{"1.5", "1.5"},
+				{"2.5", "4"},
+				{"3", "5.5"},
+			},
+		},
+		{
+			name:        "filter_fields with struct",
+			query:       `SELECT FILTER_FIELDS(STRUCT(1 AS a, STRUCT(2 AS c) AS b), -b.c)`,
+			expectedErr: "FILTER_FIELDS() expected an input proto type for first argument",
+		},
 		{
 			name:        "safe sum",
 			query:       `SELECT SAFE.SUM(x) AS sum FROM UNNEST([1, 2, 3, 4, 5, 4, 3, 2, 1]) AS x`,
@@ -1546,6 +1733,106 @@ WINDOW item_window AS (
 				{"kale", int64(23), "vegetable", "kale"},
 			},
 		},
+		{
+			name: `window last_value with default frame`,
+			query: `
+WITH Produce AS
+ (SELECT 'kale' as item, 23 as purchases, 'vegetable' as category
+  UNION ALL SELECT 'banana', 2, 'fruit'
+  UNION ALL SELECT 'cabbage', 9, 'vegetable'
+  UNION ALL SELECT 'apple', 8, 'fruit'
+  UNION ALL SELECT 'leek', 2, 'vegetable'
+  UNION ALL SELECT 'lettuce', 10, 'vegetable')
+SELECT item, purchases, category, LAST_VALUE(item)
+  OVER (PARTITION BY category ORDER BY purchases) AS last_item
+FROM Produce
+ORDER BY category, purchases`,
+			expectedRows: [][]interface{}{
+				{"banana", int64(2), "fruit", "banana"},
+				{"apple", int64(8), "fruit", "apple"},
+				{"leek", int64(2), "vegetable", "leek"},
+				{"cabbage", int64(9), "vegetable", "cabbage"},
+				{"lettuce", int64(10), "vegetable", "lettuce"},
+				{"kale", int64(23), "vegetable", "kale"},
+			},
+		},
+		{
+			name: `window last_value with unbounded following`,
+			query: `
+WITH Produce AS
+ (SELECT 'kale' as item, 23 as purchases, 'vegetable' as category
+  UNION ALL SELECT 'banana', 2, 'fruit'
+  UNION ALL SELECT 'cabbage', 9, 'vegetable'
+  UNION ALL SELECT 'apple', 8, 'fruit'
+  UNION ALL SELECT 'leek', 2, 'vegetable'
+  UNION ALL SELECT 'lettuce', 10, 'vegetable')
+SELECT item, purchases, category, LAST_VALUE(item)
+  OVER (
+    PARTITION BY category
+    ORDER BY purchases
+    RANGE BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING
+  ) AS last_item
+FROM Produce
+ORDER BY category, purchases`,
+			expectedRows: [][]interface{}{
+				{"banana", int64(2), "fruit", "apple"},
+				{"apple", int64(8), "fruit", "apple"},
+				{"leek", int64(2), "vegetable", "kale"},
+				{"cabbage", int64(9), "vegetable", "kale"},
+				{"lettuce", int64(10), "vegetable", "kale"},
+				{"kale", int64(23), "vegetable", "kale"},
+			},
+		},
+		{
+			name: `window first_value and nth_value with default frame`,
+			query: `
+WITH Produce AS
+ (SELECT 'kale' as item, 23 as purchases, 'vegetable' as category
+  UNION ALL SELECT 'banana', 2, 'fruit'
+  UNION ALL SELECT 'cabbage', 9, 'vegetable'
+  UNION ALL SELECT 'apple', 8, 'fruit'
+  UNION ALL SELECT 'leek', 2, 'vegetable'
+  UNION ALL SELECT 'lettuce', 10, 'vegetable')
+SELECT item,
+  FIRST_VALUE(item) OVER (PARTITION BY category ORDER BY purchases DESC) AS first_item,
+  NTH_VALUE(item, 2) OVER (PARTITION BY category ORDER BY purchases DESC) AS second_item
+FROM Produce
+ORDER BY category, purchases DESC`,
+			expectedRows: [][]interface{}{
+				{"apple", "apple", nil},
+				{"banana", "apple", "banana"},
+				{"kale", "kale", nil},
+				{"lettuce", "kale", "lettuce"},
+				{"cabbage", "kale", "lettuce"},
+				{"leek", "kale", "lettuce"},
+			},
+		},
+		{
+			name: `window range frame starting from current row with peers`,
+			query: `
+SELECT x, y, MIN(y) OVER (ORDER BY x RANGE BETWEEN CURRENT ROW AND UNBOUNDED FOLLOWING) AS min_y
+FROM UNNEST([STRUCT(1 AS x, 5 AS y), (2, 1), (2, 9), (3, 7)])
+ORDER BY x, y`,
+			expectedRows: [][]interface{}{
+				{int64(1), int64(5), int64(1)},
+				{int64(2), int64(1), int64(1)},
+				{int64(2), int64(9), int64(1)},
+				{int64(3), int64(7), int64(7)},
+			},
+		},
+		{
+			name: `window range frame with descending order`,
+			query: `
+SELECT x, SUM(x) OVER (ORDER BY x DESC RANGE BETWEEN 1 PRECEDING AND CURRENT ROW) AS sum
+FROM UNNEST([1, 2, 3, 5]) AS x
+ORDER BY x DESC`,
+			expectedRows: [][]interface{}{
+				{int64(5), int64(5)},
+				{int64(3), int64(3)},
+				{int64(2), int64(5)},
+				{int64(1), int64(3)},
+			},
+		},
 		{
 			name: `nth_value`,
 			query: `
@@ -2321,6 +2608,33 @@ INNER JOIN unnest(['lettuce']) in_stock_items ON in_stock_items = item;`,
 				{"lettuce", true},
 			},
 		},
+		{
+			name: "array of struct literal",
+			query: `SELECT s.a, s.b FROM UNNEST([STRUCT(1 AS a, 'x' AS b), STRUCT(2, 'y'), NULL]) AS s WITH OFFSET AS o
+WHERE s IS NOT NULL ORDER BY o`,
+			expectedRows: [][]interface{}{{int64(1), "x"}, {int64(2), "y"}},
+		},
+		{
+			name: "struct literal containing array of struct",
+			query: `SELECT ARRAY_LENGTH(s.arr), (SELECT SUM(e.v) FROM UNNEST(s.arr) AS e)
+FROM UNNEST([
+  STRUCT([STRUCT(1 AS v), STRUCT(2 AS v)] AS arr),
+  STRUCT(ARRAY<STRUCT<v INT64>>[] AS arr)
+]) AS s WITH OFFSET AS o ORDER BY o`,
+			expectedRows: [][]interface{}{{int64(2), int64(3)}, {int64(0), nil}},
+		},
+		{
+			name: "struct literal having null composite fields",
+			query: `SELECT s.arr IS NULL, s.st IS NULL, s.st.x, s.f
+FROM UNNEST([STRUCT(CAST(NULL AS ARRAY<INT64>) AS arr, CAST(NULL AS STRUCT<x INT64>) AS st, CAST('inf' AS FLOAT64) AS f)]) AS s`,
+			expectedRows: [][]interface{}{{true, true, nil, math.Inf(1)}},
+		},
+		{
+			name: "array of struct literal re-aggregated",
+			query: `SELECT TO_JSON_STRING(ARRAY_AGG(STRUCT(s.b AS b, s.a AS a) ORDER BY s.a DESC))
+FROM UNNEST([STRUCT(1 AS a, 'x' AS b), STRUCT(2, 'y')]) AS s`,
+			expectedRows: [][]interface{}{{`[{"b":"y","a":2},{"b":"x","a":1}]`}},
+		},
 		{
 			name:  "array function with struct",
 			query: `SELECT ARRAY (SELECT AS STRUCT 1, 2, 3 UNION ALL SELECT AS STRUCT 4, 5, 6) AS new_array`,
@@ -2406,6 +2720,21 @@ SELECT ARRAY (
 				},
 			},
 		},
+		{
+			name:         "in unnest with null elements",
+			query:        `SELECT 2 IN UNNEST([NULL, 1, 2])`,
+			expectedRows: [][]interface{}{{true}},
+		},
+		{
+			name:        "array equality is not supported",
+			query:       `SELECT [1, 2] = [1, 2]`,
+			expectedErr: "failed to analyze: INVALID_ARGUMENT: Equality is not defined for arguments of type ARRAY<INT64> [at 1:8]",
+		},
+		{
+			name:        "array in group by is not supported",
+			query:       `SELECT arr FROM (SELECT [1, 2] AS arr) GROUP BY arr`,
+			expectedErr: "failed to analyze: INVALID_ARGUMENT: Grouping by expressions of type ARRAY is not allowed [at 1:49]",
+		},
 		{
 			name:         "array_length function",
 			query:        `SELECT ARRAY_LENGTH([1, 2, 3, 4]) as length`,
@@ -2477,6 +2806,30 @@ SELECT ARRAY_TO_STRING(list, '--', 'MISSING') AS text FROM items`,
 				{[]interface{}{int64(5)}},
 			},
 		},
+		{
+			name:  "unnest generate_array with offset",
+			query: `SELECT v, o FROM UNNEST(GENERATE_ARRAY(1, 5, 2)) AS v WITH OFFSET AS o`,
+			expectedRows: [][]interface{}{
+				{int64(1), int64(0)},
+				{int64(3), int64(1)},
+				{int64(5), int64(2)},
+			},
+		},
+		{
+			name:         "unnest generate_array with negative step",
+			query:        `SELECT ARRAY_AGG(v) FROM UNNEST(GENERATE_ARRAY(10, 0, -3)) AS v`,
+			expectedRows: [][]interface{}{{[]interface{}{int64(10), int64(7), int64(4), int64(1)}}},
+		},
+		{
+			name:         "unnest generate_array with null",
+			query:        `SELECT COUNT(*) FROM UNNEST(GENERATE_ARRAY(1, NULL)) AS v`,
+			expectedRows: [][]interface{}{{int64(0)}},
+		},
+		{
+			name:         "aggregate unnest generate_array of many elements",
+			query:        `SELECT COUNT(*), SUM(v) FROM UNNEST(GENERATE_ARRAY(1, 100000)) AS v`,
+			expectedRows: [][]interface{}{{int64(100000), int64(5000050000)}},
+		},
 		{
 			name:  "generate_date_array function",
 			query: `SELECT GENERATE_DATE_ARRAY('2016-10-05', '2016-10-08') AS example`,
@@ -2756,6 +3109,156 @@ ORDER BY sku, day`,
 				{int64(789), int64(3), float64(1.99)},
 			},
 		},
+		{
+			name: "group by rollup with having and order by nulls last",
+			query: `
+WITH Sales AS (
+  SELECT 123 AS sku, 1 AS day, 9.99 AS price UNION ALL
+  SELECT 123, 1, 8.99 UNION ALL
+  SELECT 456, 1, 4.56 UNION ALL
+  SELECT 123, 2, 9.99 UNION ALL
+  SELECT 789, 3, 1.00 UNION ALL
+  SELECT 456, 3, 4.25 UNION ALL
+  SELECT 789, 3, 0.99
+)
+SELECT
+  sku,
+  day,
+  SUM(price) AS total
+FROM Sales
+GROUP BY ROLLUP(sku, day)
+HAVING SUM(price) > 5
+ORDER BY sku NULLS LAST, day NULLS LAST`,
+			expectedRows: [][]interface{}{
+				{int64(123), int64(1), float64(18.98)},
+				{int64(123), int64(2), float64(9.99)},
+				{int64(123), nil, float64(28.97)},
+				{int64(456), nil, float64(8.81)},
+				{nil, nil, float64(39.77)},
+			},
+		},
+		{
+			name: "group by rollup with having on rolled-up column",
+			query: `
+WITH Sales AS (
+  SELECT 123 AS sku, 1 AS day, 9.99 AS price UNION ALL
+  SELECT 123, 1, 8.99 UNION ALL
+  SELECT 456, 1, 4.56 UNION ALL
+  SELECT 123, 2, 9.99 UNION ALL
+  SELECT 789, 3, 1.00 UNION ALL
+  SELECT 456, 3, 4.25 UNION ALL
+  SELECT 789, 3, 0.99
+)
+SELECT
+  sku,
+  day,
+  SUM(price) AS total
+FROM Sales
+GROUP BY ROLLUP(sku, day)
+HAVING day IS NULL
+ORDER BY sku NULLS LAST`,
+			expectedRows: [][]interface{}{
+				{int64(123), nil, float64(28.97)},
+				{int64(456), nil, float64(8.81)},
+				{int64(789), nil, float64(1.99)},
+				{nil, nil, float64(39.77)},
+			},
+		},
+		{
+			name: "group by all with aggregate over expression",
+			query: `
+WITH Sales AS (
+  SELECT 123 AS sku, 1 AS day, 9.99 AS price UNION ALL
+  SELECT 123, 1, 8.99 UNION ALL
+  SELECT 456, 1, 4.56 UNION ALL
+  SELECT 123, 2, 9.99 UNION ALL
+  SELECT 789, 3, 1.00 UNION ALL
+  SELECT 456, 3, 4.25 UNION ALL
+  SELECT 789, 3, 0.99
+)
+SELECT
+  sku,
+  day,
+  SUM(price * 2) AS total
+FROM Sales
+GROUP BY ALL
+ORDER BY sku, day`,
+			expectedRows: [][]interface{}{
+				{int64(123), int64(1), float64(37.96)},
+				{int64(123), int64(2), float64(19.98)},
+				{int64(456), int64(1), float64(9.12)},
+				{int64(456), int64(3), float64(8.5)},
+				{int64(789), int64(3), float64(3.98)},
+			},
+		},
+		{
+			name: "group by all with computed expression and constants",
+			query: `
+WITH Sales AS (
+  SELECT 123 AS sku, 1 AS day, 9.99 AS price UNION ALL
+  SELECT 123, 1, 8.99 UNION ALL
+  SELECT 456, 1, 4.56 UNION ALL
+  SELECT 123, 2, 9.99 UNION ALL
+  SELECT 789, 3, 1.00 UNION ALL
+  SELECT 456, 3, 4.25 UNION ALL
+  SELECT 789, 3, 0.99
+)
+SELECT
+  MOD(sku, 2) AS parity,
+  'sales' AS label,
+  1 + 1 AS two,
+  COUNT(*) AS cnt,
+  MAX(price) - MIN(price) AS spread
+FROM Sales
+GROUP BY ALL
+ORDER BY parity`,
+			expectedRows: [][]interface{}{
+				{int64(0), "sales", int64(2), int64(2), float64(0.31)},
+				{int64(1), "sales", int64(2), int64(5), float64(9)},
+			},
+		},
+		{
+			name: "group by all with having",
+			query: `
+WITH Sales AS (
+  SELECT 123 AS sku, 1 AS day, 9.99 AS price UNION ALL
+  SELECT 123, 1, 8.99 UNION ALL
+  SELECT 456, 1, 4.56 UNION ALL
+  SELECT 123, 2, 9.99 UNION ALL
+  SELECT 789, 3, 1.00 UNION ALL
+  SELECT 456, 3, 4.25 UNION ALL
+  SELECT 789, 3, 0.99
+)
+SELECT day, COUNT(DISTINCT sku) AS skus
+FROM Sales
+group by /* all columns */ all
+HAVING skus > 1
+ORDER BY day`,
+			expectedRows: [][]interface{}{
+				{int64(1), int64(2)},
+				{int64(3), int64(2)},
+			},
+		},
+		{
+			name: "group by all without grouped columns",
+			query: `
+WITH Sales AS (
+  SELECT 123 AS sku, 1 AS day, 9.99 AS price UNION ALL
+  SELECT 123, 1, 8.99 UNION ALL
+  SELECT 456, 1, 4.56 UNION ALL
+  SELECT 123, 2, 9.99 UNION ALL
+  SELECT 789, 3, 1.00 UNION ALL
+  SELECT 456, 3, 4.25 UNION ALL
+  SELECT 789, 3, 0.99
+)
+SELECT COUNT(*) AS cnt, SUM(day) AS days FROM Sales GROUP BY ALL`,
+			expectedRows: [][]interface{}{{int64(7), int64(14)}},
+		},
+		{
+			name:        "group by all with star",
+			query:       `SELECT * FROM (SELECT 1 AS x) GROUP BY ALL`,
+			expectedErr: "GROUP BY ALL is unsupported with SELECT *",
+		},
 		{
 			name: "group by having",
 			query: `
@@ -2985,6 +3488,43 @@ SELECT item FROM Produce WHERE Produce.category = 'vegetable' QUALIFY RANK() OVE
 				{"cabbage"},
 			},
 		},
+		{
+			name:         "analytic function in arithmetic expression",
+			query:        `SELECT x, x / SUM(x) OVER () AS pct FROM UNNEST([1, 3, 4]) AS x ORDER BY x`,
+			expectedRows: [][]interface{}{{int64(1), float64(0.125)}, {int64(3), float64(0.375)}, {int64(4), float64(0.5)}},
+		},
+		{
+			name: "analytic function in case expression",
+			query: `
+SELECT x, CASE WHEN ROW_NUMBER() OVER (ORDER BY x) = 1 THEN 'first' ELSE 'other' END
+FROM UNNEST([3, 1, 2]) AS x ORDER BY x`,
+			expectedRows: [][]interface{}{{int64(1), "first"}, {int64(2), "other"}, {int64(3), "other"}},
+		},
+		{
+			name: "analytic functions nested in expression",
+			query: `
+SELECT x, ROUND(100 * (x - MIN(x) OVER ()) / (MAX(x) OVER () - MIN(x) OVER ()), 1)
+FROM UNNEST([10, 20, 30]) AS x ORDER BY x`,
+			expectedRows: [][]interface{}{{int64(10), float64(0)}, {int64(20), float64(50)}, {int64(30), float64(100)}},
+		},
+		{
+			name: "analytic function in both select and qualify",
+			query: `
+SELECT x, ROW_NUMBER() OVER (PARTITION BY MOD(x, 2) ORDER BY x DESC) AS rn
+FROM UNNEST([1, 2, 3, 4, 5]) AS x
+QUALIFY ROW_NUMBER() OVER (PARTITION BY MOD(x, 2) ORDER BY x DESC) = 1
+ORDER BY x`,
+			expectedRows: [][]interface{}{{int64(4), int64(1)}, {int64(5), int64(1)}},
+		},
+		{
+			name: "analytic function in arithmetic expression of select and qualify",
+			query: `
+SELECT x, x / SUM(x) OVER () AS pct
+FROM UNNEST([1, 3, 4]) AS x
+QUALIFY x / SUM(x) OVER () > 0.3
+ORDER BY x`,
+			expectedRows: [][]interface{}{{int64(3), float64(0.375)}, {int64(4), float64(0.5)}},
+		},
 		{
 			name:        "invalid cast",
 			query:       `SELECT CAST("apple" AS INT64) AS not_a_number`,
@@ -3894,6 +4434,36 @@ WITH letters AS (
 			query:        `SELECT SUBSTRING('apple', 2), SUBSTRING('apple', 2, 2), SUBSTRING('apple', -2), SUBSTRING('apple', 1, 123), SUBSTRING('apple', 123)`,
 			expectedRows: [][]interface{}{{"pple", "pp", "le", "apple", ""}},
 		},
+		{
+			name:         "string functions with multi-byte characters",
+			query:        `SELECT LENGTH('日本語'), CHAR_LENGTH('日本語'), BYTE_LENGTH('日本語'), SUBSTR('héllo', 2, 1), SUBSTR('日本語', -2), REVERSE('日本語'), STRPOS('日本語テキスト', 'テ'), INSTR('日本語日本語', '本', 1, 2), INSTR('日本語日本語', '日', -1, 1)`,
+			expectedRows: [][]interface{}{{int64(3), int64(3), int64(9), "é", "本語", "語本日", int64(4), int64(5), int64(4)}},
+		},
+		{
+			name:         "string functions with combining characters",
+			query:        `SELECT LENGTH('e\u0301'), REVERSE('ae\u0301'), SUBSTR('ae\u0301', 2, 1), LEFT('e\u0301', 1)`,
+			expectedRows: [][]interface{}{{int64(2), "\u0301ea", "e", "e"}},
+		},
+		{
+			name:         "string functions with emoji and skin tone modifiers",
+			query:        `SELECT LENGTH('👍🏽'), REVERSE('👍🏽'), LEFT('👍🏽👍', 1), RIGHT('a👍🏽', 2), LPAD('👍', 3, '🏽'), RPAD('👍', 2, '🏽'), REGEXP_INSTR('日本👍🏽語', '語'), REGEXP_EXTRACT('日本👍🏽語', '.', 3)`,
+			expectedRows: [][]interface{}{{int64(2), "🏽👍", "👍", "👍🏽", "🏽🏽👍", "👍🏽", int64(5), "👍"}},
+		},
+		{
+			name:         "like operator with multi-byte characters",
+			query:        `SELECT '日本語' LIKE '日_語', '日本語' LIKE '___', '日本語' LIKE '__', '👍🏽' LIKE '_', 'a_c' LIKE 'a\\_c', 'abc' LIKE 'a\\_c', b'\xe6\x97\xa5' LIKE b'___'`,
+			expectedRows: [][]interface{}{{true, true, false, false, true, false, true}},
+		},
+		{
+			name:         "translate with multi-byte characters",
+			query:        `SELECT TRANSLATE('日本語', '日語', '月話'), TRANSLATE('👍🏽', '🏽', ''), TRANSLATE('abc', 'ab', 'ba')`,
+			expectedRows: [][]interface{}{{"月本話", "👍", "bac"}},
+		},
+		{
+			name:         "trim bytes by each byte",
+			query:        `SELECT TRIM(b'\xab\xcdapple\xab', b'\xab\xcd'), LTRIM(b'\xababc', b'\xab'), RTRIM(b'abc\xab', b'\xab')`,
+			expectedRows: [][]interface{}{{"YXBwbGU=", "YWJj", "YWJj"}},
+		},
 		{
 			name:         "to_base32",
 			query:        `SELECT TO_BASE32(b'abcde\xFF'), TO_BASE32(NULL)`,
@@ -4484,6 +5054,36 @@ SELECT date, EXTRACT(ISOYEAR FROM date), EXTRACT(YEAR FROM date), EXTRACT(MONTH
 				{"2008-12-25T05:30:00", "2008-12-24T21:30:00"},
 			},
 		},
+		{
+			name: "datetime from date and time",
+			query: `SELECT DATETIME(DATE "2024-02-29", TIME "13:04:05.123456"), DATETIME(DATE "2024-02-29"),
+                               DATETIME(DATETIME "2024-02-29 13:04:05")`,
+			expectedRows: [][]interface{}{
+				{"2024-02-29T13:04:05.123456", "2024-02-29T00:00:00", "2024-02-29T13:04:05"},
+			},
+		},
+		{
+			name:        "datetime with invalid parts",
+			query:       `SELECT DATETIME(2023, 2, 29, 0, 0, 0)`,
+			expectedErr: "DATETIME: invalid datetime 2023-2-29 0:0:0",
+		},
+		{
+			name: "cast between timestamp and civil time types",
+			query: `SELECT CAST(TIMESTAMP "2024-02-29 23:30:00+00" AS DATE), CAST(TIMESTAMP "2024-02-29 23:30:00+00" AS DATETIME),
+                               CAST(TIMESTAMP "2024-02-29 23:30:00+00" AS TIME), CAST(DATE "2024-02-29" AS DATETIME),
+                               CAST(DATETIME "2024-02-29 23:30:00" AS DATE), CAST(DATETIME "2024-02-29 23:30:00" AS TIME)`,
+			expectedRows: [][]interface{}{
+				{"2024-02-29", "2024-02-29T23:30:00", "23:30:00", "2024-02-29T00:00:00", "2024-02-29", "23:30:00"},
+			},
+		},
+		{
+			name: "compare civil time values built in different ways",
+			query: `SELECT DATETIME(2024, 2, 29, 13, 4, 5) = DATETIME "2024-02-29 13:04:05",
+                               DATETIME(DATE "2024-02-29", TIME "13:04:05") = CAST(TIMESTAMP "2024-02-29 13:04:05+00" AS DATETIME),
+                               TIME(13, 4, 5) = TIME(DATETIME "2024-02-29 13:04:05"),
+                               TIME(13, 4, 5) < TIME "13:04:06"`,
+			expectedRows: [][]interface{}{{true, true, true, true}},
+		},
 		{
 			name:  "datetime_add",
 			query: `SELECT DATETIME "2008-12-25 15:30:00", DATETIME_ADD(DATETIME "2008-12-25 15:30:00", INTERVAL 10 MINUTE)`,
@@ -4619,6 +5219,16 @@ SELECT date, EXTRACT(ISOYEAR FROM date), EXTRACT(YEAR FROM date), EXTRACT(MONTH
 			query:        `SELECT TIME(DATETIME "2008-12-25 15:30:00.000000")`,
 			expectedRows: [][]interface{}{{"15:30:00"}},
 		},
+		{
+			name:        "time with invalid parts",
+			query:       `SELECT TIME(24, 0, 0)`,
+			expectedErr: "TIME: invalid time 24:0:0",
+		},
+		{
+			name:         "time_diff between constructed time and literal",
+			query:        `SELECT TIME_DIFF(TIME(15, 30, 0), TIME "14:35:00", MINUTE), TIME_DIFF(TIME(TIMESTAMP "2024-02-29 15:30:00+00"), TIME(14, 35, 0), MINUTE)`,
+			expectedRows: [][]interface{}{{int64(55), int64(55)}},
+		},
 		{
 			name:         "time_add",
 			query:        `SELECT TIME_ADD(TIME "15:30:00", INTERVAL 10 MINUTE)`,
@@ -5062,6 +5672,27 @@ SELECT Add(3, 4);
 `,
 			expectedRows: [][]interface{}{{int64(7)}},
 		},
+		{
+			name: "create temp function with question marks and argument like literals",
+			query: `
+CREATE TEMP FUNCTION Describe(x STRING, xy STRING) AS (
+  -- what? @x ?
+  CONCAT(x, ' what? @x ', xy, ' ?')
+);
+SELECT Describe('1', '2');
+`,
+			expectedRows: [][]interface{}{{"1 what? @x 2 ?"}},
+		},
+		{
+			name: "create temp function with date argument coerced from string literal",
+			query: `
+CREATE TEMP FUNCTION CountSince(start_date DATE) AS (
+  (SELECT COUNT(*) FROM UNNEST([DATE '2024-01-01', DATE '2024-02-01', DATE '2024-03-01']) AS event_date WHERE event_date >= start_date)
+);
+SELECT CountSince('2024-02-01');
+`,
+			expectedRows: [][]interface{}{{int64(2)}},
+		},
 
 		// except
 		{
@@ -5553,6 +6184,45 @@ FROM
 			},
 		},
 
+		// deeply nested subqueries are composed onto the same SELECT level
+		{
+			name:         "deeply nested subqueries",
+			query:        "SELECT x + 1 AS y FROM " + strings.Repeat("(SELECT x FROM ", 100) + "(SELECT 1 AS x)" + strings.Repeat(")", 100),
+			expectedRows: [][]interface{}{{int64(2)}},
+		},
+		{
+			name: "deeply nested subqueries with aggregation",
+			query: "SELECT SUM(x) AS total FROM " + strings.Repeat("(SELECT * FROM ", 100) +
+				"(SELECT x FROM UNNEST([1, 2, 3]) AS x)" + strings.Repeat(")", 100),
+			expectedRows: [][]interface{}{{int64(6)}},
+		},
+
+		// correlated subquery
+		{
+			name: "correlated exists subquery",
+			query: `
+WITH users AS (SELECT 1 AS id, 'alice' AS name UNION ALL SELECT 2, 'bob' UNION ALL SELECT 3, 'carol'),
+orders AS (SELECT 1 AS id, 3 AS user_id UNION ALL SELECT 2, 1 UNION ALL SELECT 3, 1)
+SELECT name FROM users u WHERE EXISTS (SELECT 1 FROM orders o WHERE o.user_id = u.id) ORDER BY name`,
+			expectedRows: [][]interface{}{{"alice"}, {"carol"}},
+		},
+		{
+			name: "correlated not exists subquery",
+			query: `
+WITH users AS (SELECT 1 AS id, 'alice' AS name UNION ALL SELECT 2, 'bob' UNION ALL SELECT 3, 'carol'),
+orders AS (SELECT 1 AS id, 3 AS user_id UNION ALL SELECT 2, 1 UNION ALL SELECT 3, 1)
+SELECT name FROM users u WHERE NOT EXISTS (SELECT 1 FROM orders o WHERE o.user_id = u.id) ORDER BY name`,
+			expectedRows: [][]interface{}{{"bob"}},
+		},
+		{
+			name: "correlated scalar subquery at SELECT",
+			query: `
+WITH users AS (SELECT 1 AS id, 'alice' AS name UNION ALL SELECT 2, 'bob' UNION ALL SELECT 3, 'carol'),
+orders AS (SELECT 1 AS id, 3 AS user_id UNION ALL SELECT 2, 1 UNION ALL SELECT 3, 1)
+SELECT name, (SELECT COUNT(*) FROM orders o WHERE o.user_id = u.id) AS cnt FROM users u ORDER BY name`,
+			expectedRows: [][]interface{}{{"alice", int64(2)}, {"bob", int64(0)}, {"carol", int64(1)}},
+		},
+
 		// subquery expr
 		{
 			name:         "subquery expr with scalar type at SELECT",
@@ -5579,6 +6249,31 @@ FROM
 			query:        "SELECT ARRAY(SELECT * FROM UNNEST([1, 2, 3]))",
 			expectedRows: [][]interface{}{{[]interface{}{int64(1), int64(2), int64(3)}}},
 		},
+		{
+			name:         "subquery expr with array type and order by",
+			query:        "SELECT ARRAY(SELECT x FROM UNNEST([2, 1, 3]) AS x ORDER BY x DESC)",
+			expectedRows: [][]interface{}{{[]interface{}{int64(3), int64(2), int64(1)}}},
+		},
+		{
+			name:         "subquery expr with array type and order by limit",
+			query:        "SELECT ARRAY(SELECT x FROM UNNEST([2, 1, 3, 5, 4]) AS x ORDER BY x DESC LIMIT 3)",
+			expectedRows: [][]interface{}{{[]interface{}{int64(5), int64(4), int64(3)}}},
+		},
+		{
+			name:         "subquery expr with array type and order by another column",
+			query:        "SELECT ARRAY(SELECT v.name FROM UNNEST([STRUCT(2 AS id, 'b' AS name), (1, 'a'), (3, 'c')]) AS v ORDER BY v.id DESC)",
+			expectedRows: [][]interface{}{{[]interface{}{"c", "b", "a"}}},
+		},
+		{
+			name:         "subquery expr with array type and distinct order by",
+			query:        "SELECT ARRAY(SELECT DISTINCT x FROM UNNEST([2, 1, 2, 3]) AS x ORDER BY x)",
+			expectedRows: [][]interface{}{{[]interface{}{int64(1), int64(2), int64(3)}}},
+		},
+		{
+			name:        "subquery expr with array type and distinct order by another column",
+			query:       "SELECT ARRAY(SELECT DISTINCT v.name FROM UNNEST([STRUCT(2 AS id, 'b' AS name), (1, 'a')]) AS v ORDER BY v.id)",
+			expectedErr: "not visible after SELECT DISTINCT",
+		},
 		{
 			name:         "subquery expr with in type",
 			query:        "SELECT * FROM UNNEST([1, 2, 3]) AS val WHERE val IN (SELECT 1)",
@@ -5846,6 +6541,87 @@ SELECT c1 * @b * @c FROM t1;
 			},
 			expectedRows: [][]interface{}{{int64(6)}},
 		},
+		{
+			name: "int64 comparison with column, literal and param",
+			query: `
+WITH t AS (SELECT 1 AS x UNION ALL SELECT 2 UNION ALL SELECT 3 UNION ALL SELECT NULL)
+SELECT x, x = @a, x != 2, x < @a, x <= 2, x > 2, x >= @a FROM t WHERE x >= 1 OR x IS NULL ORDER BY x`,
+			args: []interface{}{sql.NamedArg{Name: "a", Value: 2}},
+			expectedRows: [][]interface{}{
+				{nil, nil, nil, nil, nil, nil, nil},
+				{int64(1), false, true, true, true, false, false},
+				{int64(2), true, false, false, true, false, true},
+				{int64(3), false, true, false, false, true, true},
+			},
+		},
+		{
+			name: "duplicate output column names",
+			query: `
+WITH t AS (SELECT 1 AS a, 2 AS b)
+SELECT a, a, b AS a, a + 10 AS a FROM t`,
+			expectedRows: [][]interface{}{{int64(1), int64(1), int64(2), int64(11)}},
+		},
+		{
+			name: "duplicate output column names with computed column",
+			query: `
+WITH t AS (SELECT 1 AS a UNION ALL SELECT 2)
+SELECT x, x, x + 1 AS x FROM (SELECT a * 10 AS x FROM t) ORDER BY 1`,
+			expectedRows: [][]interface{}{
+				{int64(10), int64(10), int64(11)},
+				{int64(20), int64(20), int64(21)},
+			},
+		},
+		{
+			name: "window frame with GROUPS unit",
+			query: `
+SELECT x, SUM(x) OVER (ORDER BY x GROUPS BETWEEN 1 PRECEDING AND CURRENT ROW)
+FROM UNNEST([1, 2, 2, 3]) AS x`,
+			expectedErr: "Syntax error",
+		},
+		{
+			name: "window frame with EXCLUDE clause",
+			query: `
+SELECT x, AVG(x) OVER (ORDER BY x ROWS BETWEEN 1 PRECEDING AND 1 FOLLOWING EXCLUDE CURRENT ROW)
+FROM UNNEST([1, 2, 2, 3]) AS x`,
+			expectedErr: "Syntax error",
+		},
+		{
+			name: "unnest array param with offset in join",
+			query: `
+SELECT v, o, t.n FROM UNNEST(CAST(@arr AS ARRAY<STRING>)) AS v WITH OFFSET AS o
+JOIN (SELECT 'b' AS k, 2 AS n UNION ALL SELECT 'c', 3 UNION ALL SELECT 'a', 1) AS t ON t.k = v
+ORDER BY o`,
+			args: []interface{}{sql.NamedArg{Name: "arr", Value: []string{"c", "a", "b"}}},
+			expectedRows: [][]interface{}{
+				{"c", int64(0), int64(3)},
+				{"a", int64(1), int64(1)},
+				{"b", int64(2), int64(2)},
+			},
+		},
+		{
+			name: "unnest array param with offset in aggregation",
+			query: `
+SELECT v, MIN(o) AS first_offset, COUNT(*) FROM UNNEST(CAST(@arr AS ARRAY<STRING>)) AS v WITH OFFSET AS o
+GROUP BY v ORDER BY first_offset`,
+			args: []interface{}{sql.NamedArg{Name: "arr", Value: []string{"c", "a", "c", "b"}}},
+			expectedRows: [][]interface{}{
+				{"c", int64(0), int64(2)},
+				{"a", int64(1), int64(1)},
+				{"b", int64(3), int64(1)},
+			},
+		},
+		{
+			name: "unnest array param with offset and second unnest",
+			query: `
+SELECT v, o, w, p FROM UNNEST(CAST(@arr AS ARRAY<STRING>)) AS v WITH OFFSET AS o, UNNEST(SPLIT(v, '')) AS w WITH OFFSET AS p
+ORDER BY o, p`,
+			args: []interface{}{sql.NamedArg{Name: "arr", Value: []string{"ba", "c"}}},
+			expectedRows: [][]interface{}{
+				{"ba", int64(0), "b", int64(0)},
+				{"ba", int64(0), "a", int64(1)},
+				{"c", int64(1), "c", int64(0)},
+			},
+		},
 
 		{
 			name: "single statement with positional params",
@@ -5872,62 +6648,91 @@ SELECT c1 * ? * ? FROM t1;
 			args:         []interface{}{int64(1), int64(2), int64(3)},
 			expectedRows: [][]interface{}{{int64(6)}},
 		},
+		{
+			name: "positional params in projection and filter",
+			query: `
+SELECT x + ? FROM UNNEST([1, 2, 3]) AS x WHERE x > ? ORDER BY x;
+`,
+			args:         []interface{}{int64(10), int64(1)},
+			expectedRows: [][]interface{}{{int64(12)}, {int64(13)}},
+		},
+		{
+			name: "named and positional params together",
+			query: `
+SELECT @a + ?;
+`,
+			args:        []interface{}{sql.NamedArg{Name: "a", Value: 1}, int64(2)},
+			expectedErr: "named parameter and positional parameter cannot be used together",
+		},
+	}
+	for _, mode := range []struct {
+		name string
+		db   *sql.DB
+	}{
+		{name: "default format", db: db},
+		{name: "pretty format", db: prettyFormatDB},
 	} {
-		test := test
-		t.Run(test.name, func(t *testing.T) {
-			rows, err := db.QueryContext(ctx, test.query, test.args...)
-			if err != nil {
-				if test.expectedErr == "" {
-					t.Fatal(err)
-				} else {
-					return
-				}
-			}
-			defer rows.Close()
-			columns, err := rows.Columns()
-			if err != nil {
-				t.Fatal(err)
-			}
-			columnNum := len(columns)
-			args := []interface{}{}
-			for i := 0; i < columnNum; i++ {
-				var v interface{}
-				args = append(args, &v)
-			}
-			rowNum := 0
-			for rows.Next() {
-				if err := rows.Scan(args...); err != nil {
-					t.Fatal(err)
-				}
-				derefArgs := []interface{}{}
-				for i := 0; i < len(args); i++ {
-					value := reflect.ValueOf(args[i]).Elem().Interface()
-					derefArgs = append(derefArgs, value)
-				}
-				if len(test.expectedRows) <= rowNum {
-					t.Fatalf("unexpected row %v. expected row num %d but got next row", derefArgs, len(test.expectedRows))
-				}
-				expectedRow := test.expectedRows[rowNum]
-				if len(derefArgs) != len(expectedRow) {
-					t.Fatalf("failed to get columns. expected %d but got %d", len(expectedRow), len(derefArgs))
-				}
-				if diff := cmp.Diff(expectedRow, derefArgs, floatCmpOpt); diff != "" {
-					t.Errorf("[%d]: (-want +got):\n%s", rowNum, diff)
-				}
-				rowNum++
-			}
-			rowsErr := rows.Err()
-			if test.expectedErr != "" {
-				if test.expectedErr != rowsErr.Error() {
-					t.Fatalf("unexpected error message: expected [%s] but got [%s]", test.expectedErr, rowsErr.Error())
-				}
-			} else {
-				if rowsErr != nil {
-					t.Fatal(rowsErr)
-				}
-			}
-			if len(test.expectedRows) != rowNum {
-				t.Fatalf("failed to get rows. expected %d but got %d", len(test.expectedRows), rowNum)
+		mode := mode
+		t.Run(mode.name, func(t *testing.T) {
+			db := mode.db
+			for _, test := range tests {
+				test := test
+				t.Run(test.name, func(t *testing.T) {
+					rows, err := db.QueryContext(ctx, test.query, test.args...)
+					if err != nil {
+						if test.expectedErr == "" {
+							t.Fatal(err)
+						} else {
+							return
+						}
+					}
+					defer rows.Close()
+					columns, err := rows.Columns()
+					if err != nil {
+						t.Fatal(err)
+					}
+					columnNum := len(columns)
+					args := []interface{}{}
+					for i := 0; i < columnNum; i++ {
+						var v interface{}
+						args = append(args, &v)
+					}
+					rowNum := 0
+					for rows.Next() {
+						if err := rows.Scan(args...); err != nil {
+							t.Fatal(err)
+						}
+						derefArgs := []interface{}{}
+						for i := 0; i < len(args); i++ {
+							value := reflect.ValueOf(args[i]).Elem().Interface()
+							derefArgs = append(derefArgs, value)
+						}
+						if len(test.expectedRows) <= rowNum {
+							t.Fatalf("unexpected row %v. expected row num %d but got next row", derefArgs, len(test.expectedRows))
+						}
+						expectedRow := test.expectedRows[rowNum]
+						if len(derefArgs) != len(expectedRow) {
+							t.Fatalf("failed to get columns. expected %d but got %d", len(expectedRow), len(derefArgs))
+						}
+						if diff := cmp.Diff(expectedRow, derefArgs, floatCmpOpt); diff != "" {
+							t.Errorf("[%d]: (-want +got):\n%s", rowNum, diff)
+						}
+						rowNum++
+					}
+					rowsErr := rows.Err()
+					if test.expectedErr != "" {
+						if test.expectedErr != rowsErr.Error() {
+							t.Fatalf("unexpected error message: expected [%s] but got [%s]", test.expectedErr, rowsErr.Error())
+						}
+					} else {
+						if rowsErr != nil {
+							t.Fatal(rowsErr)
+						}
+					}
+					if len(test.expectedRows) != rowNum {
+						t.Fatalf("failed to get rows. expected %d but got %d", len(test.expectedRows), rowNum)
+					}
+				})
 			}
 		})
 	}
@@ -5937,11 +6742,293 @@ SELECT c1 * ? * ? FROM t1;
 func createTimestampFormatFromTime(t time.Time) string {
 	unixmicro := t.UnixMicro()
 	sec := unixmicro / int64(time.Millisecond)
-	nsec := unixmicro - sec*int64(time.Millisecond)
-	return fmt.Sprintf("%d.%d", sec, nsec)
+	remainder := unixmicro % int64(time.Millisecond)
+	if remainder < 0 {
+		sec--
+		remainder += int64(time.Millisecond)
+	}
+	return fmt.Sprintf("%d.%06d", sec, remainder)
 }
 
 func createTimestampFormatFromString(v string) string {
 	t, _ := time.Parse("2006-01-02 15:04:05.999999+00", v)
 	return createTimestampFormatFromTime(t)
 }
+
+func TestFloatSpecialValues(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	nan := math.NaN()
+	posInf := math.Inf(1)
+	negInf := math.Inf(-1)
+	negZero := math.Copysign(0, -1)
+	sameFloat := func(x, y sql.NullFloat64) bool {
+		if x.Valid != y.Valid {
+			return false
+		}
+		if !x.Valid {
+			return true
+		}
+		if math.IsNaN(x.Float64) || math.IsNaN(y.Float64) {
+			return math.IsNaN(x.Float64) && math.IsNaN(y.Float64)
+		}
+		return x.Float64 == y.Float64 && math.Signbit(x.Float64) == math.Signbit(y.Float64)
+	}
+	formatFloat := func(v sql.NullFloat64) string {
+		if !v.Valid {
+			return "NULL"
+		}
+		if v.Float64 == 0 && math.Signbit(v.Float64) {
+			return "-0"
+		}
+		return fmt.Sprint(v.Float64)
+	}
+	queryFloats := func(t *testing.T, query string, args ...interface{}) []sql.NullFloat64 {
+		t.Helper()
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		columns, err := rows.Columns()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ret []sql.NullFloat64
+		for rows.Next() {
+			values := make([]sql.NullFloat64, len(columns))
+			dest := make([]interface{}, len(columns))
+			for i := range values {
+				dest[i] = &values[i]
+			}
+			if err := rows.Scan(dest...); err != nil {
+				t.Fatal(err)
+			}
+			ret = append(ret, values...)
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return ret
+	}
+	float := func(f float64) sql.NullFloat64 {
+		return sql.NullFloat64{Float64: f, Valid: true}
+	}
+	null := sql.NullFloat64{}
+
+	if _, err := db.ExecContext(ctx, `
+CREATE TABLE floats (id INT64, f FLOAT64);
+INSERT floats (id, f) VALUES
+  (1, CAST('nan' AS FLOAT64)),
+  (2, CAST('inf' AS FLOAT64)),
+  (3, CAST('-inf' AS FLOAT64)),
+  (4, CAST('-0' AS FLOAT64)),
+  (5, 0.5),
+  (6, NULL),
+  (7, CAST('nan' AS FLOAT64));
+`); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name     string
+		query    string
+		args     []interface{}
+		expected []sql.NullFloat64
+	}{
+		{
+			name:     "literals",
+			query:    `SELECT CAST('nan' AS FLOAT64), CAST('inf' AS FLOAT64), CAST('-inf' AS FLOAT64), CAST('-0' AS FLOAT64)`,
+			expected: []sql.NullFloat64{float(nan), float(posInf), float(negInf), float(negZero)},
+		},
+		{
+			name:     "arithmetic",
+			query:    `SELECT IEEE_DIVIDE(0, 0), IEEE_DIVIDE(1, 0), IEEE_DIVIDE(-1, 0), -1 * CAST(0 AS FLOAT64), CAST('inf' AS FLOAT64) - CAST('inf' AS FLOAT64)`,
+			expected: []sql.NullFloat64{float(nan), float(posInf), float(negInf), float(negZero), float(nan)},
+		},
+		{
+			name:     "aggregate",
+			query:    `SELECT SUM(x), AVG(x), MIN(x) FROM UNNEST([1.7976931348623157e308, 1.7976931348623157e308]) AS x`,
+			expected: []sql.NullFloat64{float(posInf), float(posInf), float(1.7976931348623157e308)},
+		},
+		{
+			name:     "aggregate with nan",
+			query:    `SELECT SUM(x) FROM UNNEST([1, CAST('nan' AS FLOAT64)]) AS x`,
+			expected: []sql.NullFloat64{float(nan)},
+		},
+		{
+			name:     "array elements",
+			query:    `SELECT arr[OFFSET(0)], arr[OFFSET(1)], arr[OFFSET(2)], arr[OFFSET(3)] FROM (SELECT [CAST('nan' AS FLOAT64), CAST('inf' AS FLOAT64), CAST('-inf' AS FLOAT64), CAST('-0' AS FLOAT64)] AS arr)`,
+			expected: []sql.NullFloat64{float(nan), float(posInf), float(negInf), float(negZero)},
+		},
+		{
+			name:     "struct fields",
+			query:    `SELECT s.a, s.b FROM (SELECT STRUCT(CAST('nan' AS FLOAT64) AS a, CAST('-0' AS FLOAT64) AS b) AS s)`,
+			expected: []sql.NullFloat64{float(nan), float(negZero)},
+		},
+		{
+			name:     "stored values",
+			query:    `SELECT f FROM floats WHERE id <= 4 ORDER BY id`,
+			expected: []sql.NullFloat64{float(nan), float(posInf), float(negInf), float(negZero)},
+		},
+		{
+			name:     "parameters",
+			query:    `SELECT ?, ?, ?, ?`,
+			args:     []interface{}{nan, posInf, negInf, negZero},
+			expected: []sql.NullFloat64{float(nan), float(posInf), float(negInf), float(negZero)},
+		},
+		{
+			name:     "order by",
+			query:    `SELECT f FROM floats ORDER BY f`,
+			expected: []sql.NullFloat64{null, float(nan), float(nan), float(negInf), float(negZero), float(0.5), float(posInf)},
+		},
+		{
+			name:     "order by desc",
+			query:    `SELECT f FROM floats ORDER BY f DESC`,
+			expected: []sql.NullFloat64{float(posInf), float(0.5), float(negZero), float(negInf), float(nan), float(nan), null},
+		},
+		{
+			name:     "group by",
+			query:    `SELECT f, COUNT(*) FROM floats GROUP BY f ORDER BY f`,
+			expected: []sql.NullFloat64{null, float(1), float(nan), float(2), float(negInf), float(1), float(negZero), float(1), float(0.5), float(1), float(posInf), float(1)},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			actual := queryFloats(t, test.query, test.args...)
+			if len(actual) != len(test.expected) {
+				t.Fatalf("expected %d values but got %d", len(test.expected), len(actual))
+			}
+			for idx := range test.expected {
+				if !sameFloat(test.expected[idx], actual[idx]) {
+					t.Errorf("[%d]: expected %s but got %s", idx, formatFloat(test.expected[idx]), formatFloat(actual[idx]))
+				}
+			}
+		})
+	}
+
+	t.Run("comparisons", func(t *testing.T) {
+		var (
+			isNaN, isInf                           bool
+			eq, ne, lt, gt, eqZero, castedToString sql.NullString
+		)
+		if err := db.QueryRowContext(ctx, `
+SELECT
+  IS_NAN(IEEE_DIVIDE(0, 0)),
+  IS_INF(IEEE_DIVIDE(1, 0)),
+  CAST(CAST('nan' AS FLOAT64) = CAST('nan' AS FLOAT64) AS STRING),
+  CAST(CAST('nan' AS FLOAT64) != CAST('nan' AS FLOAT64) AS STRING),
+  CAST(CAST('nan' AS FLOAT64) < 1 AS STRING),
+  CAST(CAST('nan' AS FLOAT64) > 1 AS STRING),
+  CAST(CAST('-0' AS FLOAT64) = 0 AS STRING),
+  CAST(CAST('-inf' AS FLOAT64) AS STRING)
+`).Scan(&isNaN, &isInf, &eq, &ne, &lt, &gt, &eqZero, &castedToString); err != nil {
+			t.Fatal(err)
+		}
+		if !isNaN || !isInf {
+			t.Fatalf("expected IS_NAN and IS_INF to be true but got %v and %v", isNaN, isInf)
+		}
+		actual := []string{eq.String, ne.String, lt.String, gt.String, eqZero.String, castedToString.String}
+		if diff := cmp.Diff([]string{"false", "true", "false", "false", "true", "-inf"}, actual); diff != "" {
+			t.Errorf("(-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestCastStringToScalar(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, test := range []struct {
+		typ      string
+		value    string
+		expected interface{}
+	}{
+		{typ: "BOOL", value: "true", expected: true},
+		{typ: "BOOL", value: "TRUE", expected: true},
+		{typ: "BOOL", value: "True", expected: true},
+		{typ: "BOOL", value: "false", expected: false},
+		{typ: "BOOL", value: "fAlSe", expected: false},
+		{typ: "BOOL", value: "t"},
+		{typ: "BOOL", value: "1"},
+		{typ: "BOOL", value: ""},
+		{typ: "BOOL", value: " true"},
+		{typ: "INT64", value: "123", expected: int64(123)},
+		{typ: "INT64", value: "-123", expected: int64(-123)},
+		{typ: "INT64", value: "+123", expected: int64(123)},
+		{typ: "INT64", value: " 123 ", expected: int64(123)},
+		{typ: "INT64", value: "0x1A", expected: int64(26)},
+		{typ: "INT64", value: "0X1a", expected: int64(26)},
+		{typ: "INT64", value: "-0x123", expected: int64(-291)},
+		{typ: "INT64", value: "9223372036854775807", expected: int64(9223372036854775807)},
+		{typ: "INT64", value: "9223372036854775808"},
+		{typ: "INT64", value: "1.0"},
+		{typ: "INT64", value: "1e3"},
+		{typ: "INT64", value: "1_000"},
+		{typ: "INT64", value: "0o17"},
+		{typ: "INT64", value: "0x"},
+		{typ: "INT64", value: ""},
+		{typ: "INT64", value: "abc"},
+		{typ: "FLOAT64", value: "1.5", expected: 1.5},
+		{typ: "FLOAT64", value: " -1.5 ", expected: -1.5},
+		{typ: "FLOAT64", value: "1.", expected: float64(1)},
+		{typ: "FLOAT64", value: ".5", expected: 0.5},
+		{typ: "FLOAT64", value: "1e3", expected: float64(1000)},
+		{typ: "FLOAT64", value: "1.5E-3", expected: 0.0015},
+		{typ: "FLOAT64", value: "inf", expected: math.Inf(1)},
+		{typ: "FLOAT64", value: "+inf", expected: math.Inf(1)},
+		{typ: "FLOAT64", value: "-INF", expected: math.Inf(-1)},
+		{typ: "FLOAT64", value: "Infinity", expected: math.Inf(1)},
+		{typ: "FLOAT64", value: "NaN", expected: math.NaN()},
+		{typ: "FLOAT64", value: "nan", expected: math.NaN()},
+		{typ: "FLOAT64", value: "--1"},
+		{typ: "FLOAT64", value: "1e"},
+		{typ: "FLOAT64", value: "0x1p-2"},
+		{typ: "FLOAT64", value: "1_000.5"},
+		{typ: "FLOAT64", value: ""},
+		{typ: "FLOAT64", value: "1e400"},
+	} {
+		test := test
+		t.Run(fmt.Sprintf("%s %q", test.typ, test.value), func(t *testing.T) {
+			var safeCasted interface{}
+			if err := db.QueryRowContext(
+				ctx, fmt.Sprintf("SELECT SAFE_CAST(@value AS %s)", test.typ), sql.Named("value", test.value),
+			).Scan(&safeCasted); err != nil {
+				t.Fatal(err)
+			}
+			var casted interface{}
+			castErr := db.QueryRowContext(
+				ctx, fmt.Sprintf("SELECT CAST(@value AS %s)", test.typ), sql.Named("value", test.value),
+			).Scan(&casted)
+			if test.expected == nil {
+				if safeCasted != nil {
+					t.Fatalf("expected SAFE_CAST to return NULL but got %v", safeCasted)
+				}
+				if castErr == nil {
+					t.Fatal("expected CAST to fail")
+				}
+				return
+			}
+			if castErr != nil {
+				t.Fatalf("expected CAST to succeed but got %v", castErr)
+			}
+			if expected, ok := test.expected.(float64); ok && math.IsNaN(expected) {
+				if f, ok := safeCasted.(float64); !ok || !math.IsNaN(f) {
+					t.Fatalf("expected NaN but got %v", safeCasted)
+				}
+				return
+			}
+			if !reflect.DeepEqual(test.expected, safeCasted) {
+				t.Fatalf("expected %v (%T) but got %v (%T)", test.expected, test.expected, safeCasted, safeCasted)
+			}
+		})
+	}
+}
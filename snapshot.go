@@ -0,0 +1,50 @@
+package zetasqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Snapshot copies the contents of the database and the catalog ( tables, views and functions ).
+// This is useful to set up the fixture once and restore it before each test case by RestoreSnapshot.
+// The temporary tables are not included. The snapshot must be closed after use to release the copied contents.
+func Snapshot(ctx context.Context, db *sql.DB) (*DatabaseSnapshot, error) {
+	var snapshot *DatabaseSnapshot
+	if err := withZetaSQLiteConn(ctx, db, func(conn *ZetaSQLiteConn) error {
+		s, err := conn.TakeSnapshot(ctx)
+		if err != nil {
+			return err
+		}
+		snapshot = s
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// RestoreSnapshot replaces the contents of the database and the catalog with the snapshot taken by Snapshot.
+// The tables and functions created after taking the snapshot are removed, and the cached analysis results are invalidated.
+// The snapshot can be restored any number of times.
+func RestoreSnapshot(ctx context.Context, db *sql.DB, snapshot *DatabaseSnapshot) error {
+	return withZetaSQLiteConn(ctx, db, func(conn *ZetaSQLiteConn) error {
+		return conn.RestoreSnapshot(ctx, snapshot)
+	})
+}
+
+func withZetaSQLiteConn(ctx context.Context, db *sql.DB, fn func(*ZetaSQLiteConn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(c interface{}) error {
+		zetasqliteConn, ok := c.(*ZetaSQLiteConn)
+		if !ok {
+			return fmt.Errorf("zetasqlite: sql.DB must be an instance created using the zetasqlite database driver")
+		}
+		return fn(zetasqliteConn)
+	})
+}
@@ -0,0 +1,29 @@
+package zetasqlite
+
+import (
+	internal "github.com/goccy/go-zetasqlite/internal"
+)
+
+// Kind is the kind of the statement returned by StatementKind.
+type Kind = internal.Kind
+
+const (
+	KindSelect = internal.KindSelect
+	KindDML    = internal.KindDML
+	KindDDL    = internal.KindDDL
+	KindScript = internal.KindScript
+)
+
+// ErrReadOnly is returned when the statement is rejected by the read-only connection ( see WithReadOnly ).
+// It can be checked by errors.Is, and the kind of the rejected statement is got by errors.As with *ReadOnlyError.
+var ErrReadOnly = internal.ErrReadOnly
+
+// ReadOnlyError is the error of the statement rejected by the read-only connection.
+type ReadOnlyError = internal.ReadOnlyError
+
+// StatementKind returns the kind of the query ( SELECT, DML, DDL or SCRIPT ) by parsing it.
+// The query including multiple statements, BEGIN...END block, the procedural language statements such as
+// EXECUTE IMMEDIATE and the transaction statements are SCRIPT. The tables in the query don't need to exist.
+func StatementKind(query string) (Kind, error) {
+	return internal.StatementKind(query)
+}
@@ -0,0 +1,39 @@
+package zetasqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	internal "github.com/goccy/go-zetasqlite/internal"
+)
+
+type (
+	QueryStats      = internal.QueryStats
+	ReferencedTable = internal.ReferencedTable
+	DMLStats        = internal.DMLStats
+)
+
+// QueryStatsFromRows retrieve statistics of the executed query from sql.Rows.
+// Duration is fixed when rows is closed.
+// NOTE: This API relies on the internal structure of sql.Rows, so not will work for all Go versions.
+func QueryStatsFromRows(rows *sql.Rows) (*QueryStats, error) {
+	zetasqliteRows, err := zetasqliteRowsFromRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	stats := zetasqliteRows.Stats()
+	if stats == nil {
+		return nil, fmt.Errorf("zetasqlite: statistics are not recorded for prepared statements")
+	}
+	return stats, nil
+}
+
+// QueryStatsFromResult retrieve statistics of the executed statements from sql.Result.
+// NOTE: This API relies on the internal structure of sql.Result, so not will work for all Go versions.
+func QueryStatsFromResult(result sql.Result) (*QueryStats, error) {
+	zetasqliteResult, err := zetasqliteResultFromResult(result)
+	if err != nil {
+		return nil, err
+	}
+	return zetasqliteResult.Stats(), nil
+}
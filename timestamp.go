@@ -9,6 +9,8 @@ import (
 
 // TimeFromTimestampValue zetasqlite returns string values ​​by default for timestamp values.
 // This function is a helper function to convert that value to time.Time type.
+// The value is the seconds since the Unix epoch with up to six fractional digits ( e.g. 1136214245.000001 ),
+// and the fractional part is always positive even if the timestamp is before the epoch.
 func TimeFromTimestampValue(v string) (time.Time, error) {
 	// ParseFloat is too imprecise to use, instead split into seconds and fractional seconds
 	parts := strings.Split(v, ".")
@@ -21,17 +23,20 @@ func TimeFromTimestampValue(v string) (time.Time, error) {
 	}
 	micros := int64(0)
 	if len(parts) == 2 {
-		// Pad fractional places to microseconds i.e. (.1 to 100000 micros)
 		microsString := parts[1]
+		if len(microsString) > 6 {
+			return time.Time{}, fmt.Errorf("invalid timestamp string (more than microsecond precision) %s", v)
+		}
+		// Pad fractional places to microseconds i.e. (.1 to 100000 micros)
 		for len(microsString) < 6 {
 			microsString += "0"
 		}
-		m, err := strconv.ParseInt(parts[1], 10, 64)
+		m, err := strconv.ParseUint(microsString, 10, 64)
 		if err != nil {
 			return time.Time{}, err
 		}
-		micros = m
+		micros = int64(m)
 	}
 	nanos := micros * int64(time.Microsecond)
-	return time.Unix(seconds, nanos), err
+	return time.Unix(seconds, nanos), nil
 }
@@ -1,7 +1,11 @@
 package zetasqlite_test
 
 import (
+	"context"
+	"database/sql"
+	"math/rand"
 	"os"
+	"sort"
 	"testing"
 	"time"
 
@@ -15,8 +19,10 @@ func TestTimestamp(t *testing.T) {
 		expected  string
 	}{
 		{name: "min does not round", timestamp: "-62135596800.0", expected: "0001-01-01T00:00:00.0Z"},
-		{name: "max does not round", timestamp: "2534023007999.999999", expected: "9999-12-31T23:59:59.999999999Z"},
+		{name: "max does not round", timestamp: "253402300799.999999", expected: "9999-12-31T23:59:59.999999Z"},
 		{name: "microsecond places are handled", timestamp: "0.1", expected: "1970-01-01T00:00:00.100000000Z"},
+		{name: "single microsecond", timestamp: "0.000001", expected: "1970-01-01T00:00:00.000001Z"},
+		{name: "before epoch", timestamp: "-1.500000", expected: "1969-12-31T23:59:59.5Z"},
 	} {
 		os.Setenv("TZ", "UTC")
 		t.Run(test.name, func(t *testing.T) {
@@ -28,13 +34,126 @@ func TestTimestamp(t *testing.T) {
 			if err != nil {
 				t.Fatalf("%s", err)
 			}
-			if (ti.IsZero()) && (expected.IsZero()) {
-				return
-			}
-
-			if ti.Equal(expected) {
+			if !ti.Equal(expected) {
 				t.Fatalf("expected %s got %s", expected, ti)
 			}
 		})
 	}
+	t.Run("sub-microsecond precision is rejected", func(t *testing.T) {
+		if _, err := zetasqlite.TimeFromTimestampValue("0.0000001"); err == nil {
+			t.Fatal("expected error for nanosecond precision")
+		}
+	})
+}
+
+func TestTimestampRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("zetasqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, "CREATE TABLE timestamp_round_trip (id INT64, ts TIMESTAMP)"); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		minMicros = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC).UnixMicro()
+		maxMicros = time.Date(9999, 12, 31, 23, 59, 59, 999999000, time.UTC).UnixMicro()
+		r         = rand.New(rand.NewSource(1))
+		expected  = make([]time.Time, 0, 100)
+	)
+	for i := 0; i < 100; i++ {
+		micros := minMicros + r.Int63n(maxMicros-minMicros)
+		ts := time.UnixMicro(micros).UTC()
+		expected = append(expected, ts)
+		// the nanoseconds of time.Time are truncated to microsecond precision.
+		param := ts.Add(time.Duration(r.Intn(1000)))
+		if _, err := db.ExecContext(ctx, "INSERT INTO timestamp_round_trip (id, ts) VALUES (?, ?)", i, param); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scanTimestamp := func(t *testing.T, row interface{ Scan(...interface{}) error }) time.Time {
+		t.Helper()
+		var v string
+		if err := row.Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		ts, err := zetasqlite.TimeFromTimestampValue(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+
+	t.Run("select", func(t *testing.T) {
+		rows, err := db.QueryContext(ctx, "SELECT ts FROM timestamp_round_trip ORDER BY id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var idx int
+		for rows.Next() {
+			if got := scanTimestamp(t, rows); !got.Equal(expected[idx]) {
+				t.Fatalf("unexpected timestamp at %d: expected %s got %s", idx, expected[idx], got)
+			}
+			idx++
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if idx != len(expected) {
+			t.Fatalf("unexpected rows num %d", idx)
+		}
+	})
+	t.Run("compare with param", func(t *testing.T) {
+		for idx, ts := range expected {
+			var id int64
+			if err := db.QueryRowContext(ctx, "SELECT id FROM timestamp_round_trip WHERE ts = ?", ts).Scan(&id); err != nil {
+				t.Fatal(err)
+			}
+			if id != int64(idx) {
+				t.Fatalf("unexpected id for %s: expected %d got %d", ts, idx, id)
+			}
+		}
+	})
+	sorted := make([]time.Time, len(expected))
+	copy(sorted, expected)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+	t.Run("aggregate", func(t *testing.T) {
+		if got := scanTimestamp(t, db.QueryRowContext(ctx, "SELECT MIN(ts) FROM timestamp_round_trip")); !got.Equal(sorted[0]) {
+			t.Fatalf("unexpected min: expected %s got %s", sorted[0], got)
+		}
+		if got := scanTimestamp(t, db.QueryRowContext(ctx, "SELECT MAX(ts) FROM timestamp_round_trip")); !got.Equal(sorted[len(sorted)-1]) {
+			t.Fatalf("unexpected max: expected %s got %s", sorted[len(sorted)-1], got)
+		}
+	})
+	t.Run("window order", func(t *testing.T) {
+		rows, err := db.QueryContext(ctx, "SELECT ts, ROW_NUMBER() OVER (ORDER BY ts) FROM timestamp_round_trip ORDER BY 2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var idx int
+		for rows.Next() {
+			var (
+				v   string
+				num int64
+			)
+			if err := rows.Scan(&v, &num); err != nil {
+				t.Fatal(err)
+			}
+			got, err := zetasqlite.TimeFromTimestampValue(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(sorted[idx]) || num != int64(idx+1) {
+				t.Fatalf("unexpected row at %d: expected %s got %s (%d)", idx, sorted[idx], got, num)
+			}
+			idx++
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+	})
 }
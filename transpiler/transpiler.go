@@ -0,0 +1,75 @@
+// Package transpiler translates BigQuery ( ZetaSQL ) queries to SQLite queries without executing them.
+// It is the same translation layer used by the zetasqlite driver,
+// so the translated queries can be executed by SQLite running in another environment ( e.g. WASM )
+// as long as the functions listed by SQLiteFunctions are registered on it.
+package transpiler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+
+	internal "github.com/goccy/go-zetasqlite/internal"
+)
+
+type (
+	Stmt               = internal.TranspiledStmt
+	SQLiteFunction     = internal.SQLiteFunctionInfo
+	SQLiteFunctionKind = internal.SQLiteFunctionKind
+	TableSpec          = internal.TableSpec
+	FunctionSpec       = internal.FunctionSpec
+)
+
+const (
+	ScalarSQLiteFunctionKind    = internal.ScalarSQLiteFunctionKind
+	AggregateSQLiteFunctionKind = internal.AggregateSQLiteFunctionKind
+	WindowSQLiteFunctionKind    = internal.WindowSQLiteFunctionKind
+	CollationSQLiteFunctionKind = internal.CollationSQLiteFunctionKind
+)
+
+// Transpiler translates queries by using the catalog built from a snapshot of table and function specs.
+// Tables and functions created by the translated queries are added to the catalog of Transpiler.
+type Transpiler struct {
+	analyzer *internal.Analyzer
+}
+
+// New creates Transpiler from the snapshot of the catalog.
+// The specs can be obtained from the catalog changes of the zetasqlite driver ( see zetasqlite.ChangedCatalog ).
+func New(tables []*TableSpec, functions []*FunctionSpec) (*Transpiler, error) {
+	catalog, err := internal.NewCatalogFromSpecs(tables, functions)
+	if err != nil {
+		return nil, err
+	}
+	analyzer, err := internal.NewAnalyzer(catalog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create analyzer: %w", err)
+	}
+	return &Transpiler{analyzer: analyzer}, nil
+}
+
+// SetNamePath set path to name path to be set as prefix.
+func (t *Transpiler) SetNamePath(path []string) error {
+	return t.analyzer.SetNamePath(path)
+}
+
+// SetPrettyFormat when enabled, the queries and DML statements are translated to the indented and normalized form,
+// which is useful for snapshot tests of the translation. Disabled by default.
+func (t *Transpiler) SetPrettyFormat(enabled bool) {
+	t.analyzer.SetPrettyFormatMode(enabled)
+}
+
+// Transpile translates each statement of the query to SQLite query.
+func (t *Transpiler) Transpile(ctx context.Context, query string) ([]*Stmt, error) {
+	return t.analyzer.Transpile(ctx, query)
+}
+
+// SQLiteFunctions returns the manifest of functions and collations that must be registered on SQLite to execute translated queries.
+func SQLiteFunctions() []*SQLiteFunction {
+	return internal.SQLiteFunctions()
+}
+
+// RegisterFunctions registers all functions listed by SQLiteFunctions to the connection.
+func RegisterFunctions(conn *sqlite3.SQLiteConn) error {
+	return internal.RegisterFunctions(conn)
+}
@@ -0,0 +1,146 @@
+package transpiler_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/goccy/go-zetasqlite/transpiler"
+)
+
+func TestTranspile(t *testing.T) {
+	ctx := context.Background()
+	tr, err := transpiler.New(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts, err := tr.Transpile(ctx, `
+CREATE TABLE items (id INT64, name STRING);
+INSERT INTO items (id, name) VALUES (1, 'a'), (2, 'b');
+SELECT id + 1, UPPER(name) FROM items ORDER BY id;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 3 {
+		t.Fatalf("failed to get transpiled statements: %d", len(stmts))
+	}
+	functions := map[string]struct{}{}
+	for _, fn := range transpiler.SQLiteFunctions() {
+		functions[fn.Name] = struct{}{}
+	}
+	for _, stmt := range stmts {
+		for _, name := range stmt.Functions {
+			if _, exists := functions[name]; !exists {
+				t.Fatalf("%s is not listed in the manifest", name)
+			}
+		}
+	}
+
+	sql.Register("zetasqlite-transpiler-test", &sqlite3.SQLiteDriver{
+		ConnectHook: transpiler.RegisterFunctions,
+	})
+	db, err := sql.Open("zetasqlite-transpiler-test", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range stmts[:2] {
+		if _, err := db.ExecContext(ctx, stmt.Query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rows, err := db.QueryContext(ctx, stmts[2].Query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var (
+			id   int64
+			name interface{}
+		)
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]int64{2, 3}, ids); diff != "" {
+		t.Errorf("(-want +got):\n%s", diff)
+	}
+}
+
+func TestTranspileComparisonPushdown(t *testing.T) {
+	ctx := context.Background()
+	tr, err := transpiler.New(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts, err := tr.Transpile(ctx, `
+CREATE TABLE events (id INT64, name STRING);
+SELECT name FROM events WHERE id = 2;
+SELECT id FROM events WHERE name = 'b';
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 3 {
+		t.Fatalf("failed to get transpiled statements: %d", len(stmts))
+	}
+	if strings.Contains(stmts[1].Query, "zetasqlite_equal") {
+		t.Fatalf("failed to push down comparison of INT64 column: %s", stmts[1].Query)
+	}
+	if !strings.Contains(stmts[2].Query, "zetasqlite_equal") {
+		t.Fatalf("comparison of encoded STRING column must not be pushed down: %s", stmts[2].Query)
+	}
+
+	sql.Register("zetasqlite-transpiler-pushdown-test", &sqlite3.SQLiteDriver{
+		ConnectHook: transpiler.RegisterFunctions,
+	})
+	db, err := sql.Open("zetasqlite-transpiler-pushdown-test", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.ExecContext(ctx, stmts[0].Query); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "CREATE INDEX events_id ON `events`(`id`)"); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.QueryContext(ctx, "EXPLAIN QUERY PLAN "+stmts[1].Query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var details []string
+	for rows.Next() {
+		var (
+			id, parent, notused int
+			detail              string
+		)
+		if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+			t.Fatal(err)
+		}
+		details = append(details, detail)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	plan := strings.Join(details, "\n")
+	if !strings.Contains(plan, "USING INDEX events_id (id=?)") {
+		t.Fatalf("failed to use index for equality filter:\n%s", plan)
+	}
+}